@@ -186,5 +186,14 @@ func DeleteAPIProviderHandler(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+	utils.SuccessWithMessage(&ctx, c, "已移入回收站", nil)
+}
+
+// ListAPIKindsHandler 获取支持的模型类型目录
+// GET /api/v1/api-provider/kinds
+func ListAPIKindsHandler(ctx context.Context, c *app.RequestContext) {
+	utils.SuccessWithMessage(&ctx, c, "查询成功", map[string]interface{}{
+		"total": len(models.APIKinds),
+		"list":  models.APIKinds,
+	})
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// CreateBatchHandler 上传CSV/JSONL主题文件，批量生成六要素模板（multipart表单字段 file）
+// POST /api/v1/batch
+func CreateBatchHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	var req services.BatchRequest
+	if err := c.Bind(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "请选择要上传的文件")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.Error("打开上传文件失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "读取文件失败")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.Error("读取上传文件失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "读取文件失败")
+		return
+	}
+
+	batch, err := services.CreateBatch(userMobile.(string), fileHeader.Filename, data, &req)
+	if err != nil {
+		utils.Warn("创建批量生成失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "批量生成已开始", batch)
+}
+
+// ListBatchesHandler 分页查询批量生成记录
+// GET /api/v1/batch?page=1&page_size=15
+func ListBatchesHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	batches, total, err := services.ListBatches(userMobile.(string), page, pageSize)
+	if err != nil {
+		utils.Error("查询批量生成记录失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, batches, total, page, min(pageSize, 100))
+}
+
+// GetBatchHandler 获取批量生成进度及各行结果
+// GET /api/v1/batch/:id
+func GetBatchHandler(ctx context.Context, c *app.RequestContext) {
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的批量生成ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	batch, err := services.GetBatch(userMobile.(string), uint(batchID))
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "批量生成记录不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", batch)
+}
+
+// DownloadBatchReportHandler 下载批量生成结果报告
+// GET /api/v1/batch/:id/report?format=csv
+func DownloadBatchReportHandler(ctx context.Context, c *app.RequestContext) {
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的批量生成ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	batch, err := services.GetBatch(userMobile.(string), uint(batchID))
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "批量生成记录不存在")
+		return
+	}
+
+	format := c.DefaultQuery("format", services.BatchFormatCSV)
+	data, contentType, fileName, err := services.BuildBatchReport(batch, format)
+	if err != nil {
+		if errors.Is(err, services.ErrBatchFormat) {
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+			return
+		}
+		utils.Error("生成批量生成报告失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "生成报告失败")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(200, contentType, data)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// respondEmbeddingError 按错误类型返回向量检索相关的错误码
+func respondEmbeddingError(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+	case err.Error() == "Provider not found", errors.Is(err, services.ErrEmbeddingNotConfigured):
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrEmbeddingUnsupported), errors.Is(err, services.ErrEmbeddingQuery),
+		errors.Is(err, services.ErrEmbeddingBackfillRunning):
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+	default:
+		utils.Error("向量检索处理失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, err.Error())
+	}
+}
+
+// GetEmbeddingSettingHandler 获取向量设置及回填进度
+// GET /api/v1/embedding/settings
+func GetEmbeddingSettingHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	status, err := services.GetEmbeddingStatus(userMobile.(string))
+	if err != nil {
+		respondEmbeddingError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, status)
+}
+
+// SaveEmbeddingSettingHandler 选择用于向量化的Provider与模型
+// PUT /api/v1/embedding/settings
+func SaveEmbeddingSettingHandler(ctx context.Context, c *app.RequestContext) {
+	var req services.EmbeddingSettingRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	setting, err := services.SaveEmbeddingSetting(userMobile.(string), &req)
+	if err != nil {
+		respondEmbeddingError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "保存成功", setting)
+}
+
+// DeleteEmbeddingSettingHandler 关闭向量化并删除已生成的向量
+// DELETE /api/v1/embedding/settings
+func DeleteEmbeddingSettingHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	if err := services.DeleteEmbeddingSetting(userMobile.(string)); err != nil {
+		respondEmbeddingError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "已关闭向量化", nil)
+}
+
+// StartEmbeddingBackfillHandler 在后台为已有模板生成向量，进度通过向量设置查询
+// POST /api/v1/embedding/backfill
+func StartEmbeddingBackfillHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	setting, err := services.StartEmbeddingBackfill(userMobile.(string))
+	if err != nil {
+		respondEmbeddingError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "回填已开始", setting)
+}
+
+// SearchSimilarTemplatesHandler 按语义检索模板
+// GET /api/v1/template/similar?q=&limit=10
+func SearchSimilarTemplatesHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	templates, err := templateService.SearchSimilarTemplates(ctx, userMobile.(string), c.Query("q"), limit)
+	if err != nil {
+		respondEmbeddingError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, templates)
+}
+
+// GetSimilarTemplatesHandler 查询与指定模板相似的其他模板
+// GET /api/v1/template/:id/similar?limit=10
+func GetSimilarTemplatesHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	templates, err := templateService.GetSimilarTemplates(ctx, userMobile.(string), id, limit)
+	if err != nil {
+		respondEmbeddingError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, templates)
+}
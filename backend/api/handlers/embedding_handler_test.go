@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+)
+
+func TestRespondEmbeddingError(t *testing.T) {
+	if err := utils.InitLogger(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"模板不存在", services.ErrTemplateNotFound, utils.CodeTemplateNotFound},
+		{"包装后的模板不存在", fmt.Errorf("查询相似模板失败: %w", services.ErrTemplateNotFound), utils.CodeTemplateNotFound},
+		{"未配置向量模型", services.ErrEmbeddingNotConfigured, utils.CodeNotFound},
+		{"查询内容无效", services.ErrEmbeddingQuery, utils.CodeInvalidParams},
+		{"其他错误", fmt.Errorf("database is closed"), utils.CodeServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.NewContext(0)
+			respondEmbeddingError(context.Background(), c, tt.err)
+
+			var resp utils.Response
+			if err := json.Unmarshal(c.Response.Body(), &resp); err != nil {
+				t.Fatalf("unmarshal response error = %v", err)
+			}
+			if resp.Code != tt.want {
+				t.Errorf("code = %d, want %d", resp.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// CreateEvaluationHandler 创建评测：并发调用多个API Provider并对比结果
+// POST /api/v1/evaluation
+func CreateEvaluationHandler(ctx context.Context, c *app.RequestContext) {
+	var req services.EvaluationRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	evaluation, err := services.CreateEvaluation(ctx, userMobile.(string), &req)
+	if err != nil {
+		if respondInputRejected(ctx, c, err) {
+			return
+		}
+		utils.Error("创建评测失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "评测完成", evaluation)
+}
+
+// ListEvaluationsHandler 分页查询评测记录
+// GET /api/v1/evaluation?template_id=1&page=1&page_size=15
+func ListEvaluationsHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	templateID, _ := strconv.ParseUint(c.Query("template_id"), 10, 64)
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	evaluations, total, err := services.ListEvaluations(userMobile.(string), templateID, page, pageSize)
+	if err != nil {
+		utils.Error("查询评测记录失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, evaluations, total, page, min(pageSize, 100))
+}
+
+// GetEvaluationHandler 获取评测详情及各Provider的对比结果
+// GET /api/v1/evaluation/:id
+func GetEvaluationHandler(ctx context.Context, c *app.RequestContext) {
+	evaluationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的评测ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	evaluation, err := services.GetEvaluation(userMobile.(string), uint(evaluationID))
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "评测记录不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", evaluation)
+}
+
+// DeleteEvaluationHandler 删除评测记录
+// DELETE /api/v1/evaluation/:id
+func DeleteEvaluationHandler(ctx context.Context, c *app.RequestContext) {
+	evaluationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的评测ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	if err := services.DeleteEvaluation(userMobile.(string), uint(evaluationID)); err != nil {
+		if errors.Is(err, services.ErrEvaluationNotFound) {
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, "评测记录不存在")
+			return
+		}
+		utils.Error("删除评测记录失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "删除失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+)
+
+func TestRespondInputRejected(t *testing.T) {
+	c := app.NewContext(0)
+	rejection := &services.InputRejection{Rule: services.InputRulePromptLength, Field: "prompt", Reason: "提示词过长"}
+	if !respondInputRejected(context.Background(), c, rejection) {
+		t.Fatal("respondInputRejected() = false, want true for *InputRejection")
+	}
+	var resp utils.Response
+	if err := json.Unmarshal(c.Response.Body(), &resp); err != nil {
+		t.Fatalf("unmarshal response error = %v", err)
+	}
+	if resp.Code != utils.CodeInputRejected || resp.Message != "提示词过长" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	// 其他错误不写响应，由调用方处理
+	c = app.NewContext(0)
+	if respondInputRejected(context.Background(), c, errors.New("database is closed")) {
+		t.Error("respondInputRejected() = true, want false for other errors")
+	}
+	if len(c.Response.Body()) != 0 {
+		t.Errorf("response body = %s, want empty", c.Response.Body())
+	}
+}
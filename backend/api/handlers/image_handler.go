@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// UploadImageHandler 上传生成请求使用的图片（multipart表单字段 file）
+func UploadImageHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "请选择要上传的图片")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.Error("打开上传图片失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "读取图片失败")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.Error("读取上传图片失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "读取图片失败")
+		return
+	}
+
+	image, err := services.SaveImage(userMobile.(string), fileHeader.Filename, data)
+	if err != nil {
+		if errors.Is(err, services.ErrImageTooLarge) || errors.Is(err, services.ErrImageTypeInvalid) {
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+			return
+		}
+		utils.Error("保存上传图片失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "上传失败")
+		return
+	}
+
+	utils.Info("图片上传成功", zap.Uint("image_id", image.ID), zap.String("mime_type", image.MimeType), zap.Int64("size", image.Size))
+	utils.SuccessWithMessage(&ctx, c, "上传成功", image)
+}
+
+// GetImageHandler 获取已上传的图片内容
+func GetImageHandler(ctx context.Context, c *app.RequestContext) {
+	imageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的图片ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	image, err := services.GetImage(userMobile.(string), uint(imageID))
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "图片不存在")
+		return
+	}
+	data, err := services.ReadImageData(image)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "图片不存在")
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(200, image.MimeType, data)
+}
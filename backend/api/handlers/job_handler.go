@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// SubmitJobHandler 提交异步生成任务，立即返回任务信息
+// POST /api/v1/jobs
+func SubmitJobHandler(ctx context.Context, c *app.RequestContext) {
+	var req services.GenerateRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+	req.User = userMobile.(string)
+
+	if err := services.ValidateGenerateRequest(req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+	if err := services.ScreenGenerateRequest(&req); err != nil {
+		if !respondInputRejected(ctx, c, err) {
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		}
+		return
+	}
+
+	provider, err := services.GetAPIProvider(userMobile.(string), req.ProviderID)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "API Provider不存在")
+		return
+	}
+
+	job, err := services.SubmitJob(userMobile.(string), provider, req)
+	if err != nil {
+		if errors.Is(err, services.ErrJobQueueFull) || errors.Is(err, services.ErrJobNotAvailable) {
+			utils.ResponseError(&ctx, c, utils.CodeServerError, err.Error())
+			return
+		}
+		utils.Error("提交任务失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "任务已提交", job)
+}
+
+// ListJobsHandler 分页查询任务
+// GET /api/v1/jobs?status=running&page=1&page_size=15
+func ListJobsHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	jobs, total, err := services.ListJobs(userMobile.(string), c.Query("status"), page, pageSize)
+	if err != nil {
+		utils.Error("查询任务失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, jobs, total, page, min(pageSize, 100))
+}
+
+// GetJobHandler 获取任务状态、进度与结果
+// GET /api/v1/jobs/:id
+func GetJobHandler(ctx context.Context, c *app.RequestContext) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的任务ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	job, err := services.GetJob(userMobile.(string), uint(jobID))
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "任务不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", job)
+}
+
+// CancelJobHandler 取消排队中或执行中的任务
+// POST /api/v1/jobs/:id/cancel
+func CancelJobHandler(ctx context.Context, c *app.RequestContext) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的任务ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	if err := services.CancelJob(userMobile.(string), uint(jobID)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, "任务不存在")
+		case errors.Is(err, services.ErrJobFinished):
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		default:
+			utils.Error("取消任务失败", zap.Error(err))
+			utils.ResponseError(&ctx, c, utils.CodeServerError, "取消失败")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "任务已取消", nil)
+}
+
+// StreamJobHandler 以SSE格式推送任务的生成内容，格式与生成接口一致
+// GET /api/v1/jobs/:id/stream
+func StreamJobHandler(ctx context.Context, c *app.RequestContext) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的任务ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	// 开始推送前确认任务存在，之后的错误通过SSE返回
+	if _, err := services.GetJob(userMobile.(string), uint(jobID)); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "任务不存在")
+		return
+	}
+
+	if err := services.StreamJob(ctx, userMobile.(string), uint(jobID), &sseSink{c: c}); err != nil {
+		utils.Warn("推送任务内容中断", zap.Uint64("job_id", jobID), zap.Error(err))
+	}
+}
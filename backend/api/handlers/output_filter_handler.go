@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// OutputFilterPreviewRequest 预览输出过滤效果请求
+type OutputFilterPreviewRequest struct {
+	Text       string `json:"text"`
+	TemplateID uint64 `json:"template_id,omitempty"` // 可选：按模板的设置过滤
+}
+
+// GetOutputFilterHandler 获取用户生效的输出过滤设置
+// GET /api/v1/output-filters
+func GetOutputFilterHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	setting, err := services.GetOutputFilterSetting(userMobile.(string), 0)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", setting)
+}
+
+// SaveOutputFilterHandler 保存用户的输出过滤设置，对未单独设置的模板及不指定模板的生成生效
+// PUT /api/v1/output-filters
+func SaveOutputFilterHandler(ctx context.Context, c *app.RequestContext) {
+	var req services.OutputFilterRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	setting, err := services.SaveOutputFilterSetting(userMobile.(string), 0, &req)
+	if err != nil {
+		utils.Warn("保存输出过滤设置失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "保存成功", setting)
+}
+
+// ListOutputFiltersHandler 获取支持的输出过滤器
+// GET /api/v1/output-filters/filters
+func ListOutputFiltersHandler(ctx context.Context, c *app.RequestContext) {
+	utils.SuccessWithMessage(&ctx, c, "获取成功", models.OutputFilters)
+}
+
+// PreviewOutputFilterHandler 按当前设置过滤文本，用于预览过滤效果
+// POST /api/v1/output-filters/preview
+func PreviewOutputFilterHandler(ctx context.Context, c *app.RequestContext) {
+	var req OutputFilterPreviewRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	setting, err := services.GetOutputFilterSetting(userMobile.(string), req.TemplateID)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", map[string]interface{}{
+		"text":    services.FilterOutput(userMobile.(string), req.TemplateID, req.Text),
+		"setting": setting,
+	})
+}
+
+// GetTemplateOutputFilterHandler 获取模板生效的输出过滤设置
+// GET /api/v1/output-filters/templates/:id
+func GetTemplateOutputFilterHandler(ctx context.Context, c *app.RequestContext) {
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || templateID == 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的模板ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	setting, err := services.GetOutputFilterSetting(userMobile.(string), templateID)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", setting)
+}
+
+// SaveTemplateOutputFilterHandler 单独设置模板的输出过滤
+// PUT /api/v1/output-filters/templates/:id
+func SaveTemplateOutputFilterHandler(ctx context.Context, c *app.RequestContext) {
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || templateID == 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的模板ID")
+		return
+	}
+
+	var req services.OutputFilterRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	setting, err := services.SaveOutputFilterSetting(userMobile.(string), templateID, &req)
+	if err != nil {
+		utils.Warn("保存模板输出过滤设置失败", zap.Uint64("template_id", templateID), zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "保存成功", setting)
+}
+
+// DeleteTemplateOutputFilterHandler 删除模板的输出过滤设置，恢复使用用户设置
+// DELETE /api/v1/output-filters/templates/:id
+func DeleteTemplateOutputFilterHandler(ctx context.Context, c *app.RequestContext) {
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || templateID == 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的模板ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	if err := services.DeleteOutputFilterSetting(userMobile.(string), templateID); err != nil {
+		if errors.Is(err, services.ErrOutputFilterNotFound) {
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+			return
+		}
+		utils.Error("删除模板输出过滤设置失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "删除失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+}
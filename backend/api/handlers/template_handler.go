@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/zsy619/cese-qoder/backend/services"
 	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
 )
 
 var templateService = &services.TemplateService{}
@@ -29,6 +33,9 @@ func CreateTemplateHandler(ctx context.Context, c *app.RequestContext) {
 
 	template, err := templateService.CreateTemplate(userMobile.(string), &req)
 	if err != nil {
+		if respondInputRejected(ctx, c, err) {
+			return
+		}
 		utils.ResponseError(&ctx, c, utils.CodeError, err.Error())
 		return
 	}
@@ -61,7 +68,7 @@ func GetTemplatesHandler(ctx context.Context, c *app.RequestContext) {
 	utils.PageSuccess(&ctx, c, templates, total, req.Page, req.PageSize)
 }
 
-// GetTemplateByIDHandler 获取模板详情处理器
+// GetTemplateByIDHandler 获取模板详情处理器，可查看自己的、公开的或共享给自己的模板
 // GET /api/v1/template/:id
 func GetTemplateByIDHandler(ctx context.Context, c *app.RequestContext) {
 	// 获取模板 ID
@@ -79,7 +86,7 @@ func GetTemplateByIDHandler(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	template, err := templateService.GetTemplateByID(userMobile.(string), id)
+	template, err := templateService.ViewTemplate(userMobile.(string), id)
 	if err != nil {
 		code := utils.CodeNotFound
 		if err.Error() == "模板不存在或无权访问" {
@@ -118,6 +125,9 @@ func UpdateTemplateHandler(ctx context.Context, c *app.RequestContext) {
 
 	template, err := templateService.UpdateTemplate(userMobile.(string), id, &req)
 	if err != nil {
+		if respondInputRejected(ctx, c, err) {
+			return
+		}
 		code := utils.CodeError
 		if err.Error() == "模板不存在或无权操作" {
 			code = utils.CodeTemplateNoAuth
@@ -157,5 +167,150 @@ func DeleteTemplateHandler(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+	utils.SuccessWithMessage(&ctx, c, "已移入回收站", nil)
+}
+
+// ExportTemplateHandler 导出单个模板
+// GET /api/v1/template/:id/export?format=markdown|coze|json|yaml|openai
+func ExportTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	file, err := templateService.ExportTemplate(userMobile.(string), id, c.DefaultQuery("format", services.TemplateFormatMarkdown))
+	if err != nil {
+		writeTemplateExportError(ctx, c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.FileName))
+	c.Data(200, file.ContentType, file.Data)
+}
+
+// ExportTemplatesHandler 将当前用户的全部模板导出为zip压缩包
+// GET /api/v1/template/export?format=markdown|coze|json|yaml|openai
+func ExportTemplatesHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	file, err := templateService.ExportTemplates(userMobile.(string), c.DefaultQuery("format", services.TemplateFormatMarkdown))
+	if err != nil {
+		writeTemplateExportError(ctx, c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.FileName))
+	c.Data(200, file.ContentType, file.Data)
+}
+
+// writeTemplateExportError 按错误类型返回导出失败的错误码
+func writeTemplateExportError(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, services.ErrTemplateExportFormat):
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+	case err.Error() == "模板不存在或无权访问":
+		utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+	default:
+		utils.Error("导出模板失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "导出失败")
+	}
+}
+
+// ImportTemplatesHandler 导入Markdown/JSON/YAML文件或zip压缩包（multipart表单字段 files，可多个）
+// preview=true 时只返回解析结果，不保存
+// POST /api/v1/template/import?preview=true
+func ImportTemplatesHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, services.ErrTemplateImportEmpty.Error())
+		return
+	}
+
+	var uploads []services.TemplateImportUpload
+	for _, field := range []string{"files", "file"} {
+		for _, fileHeader := range form.File[field] {
+			file, err := fileHeader.Open()
+			if err != nil {
+				utils.Error("打开上传文件失败", zap.Error(err))
+				utils.ResponseError(&ctx, c, utils.CodeServerError, "读取文件失败")
+				return
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				utils.Error("读取上传文件失败", zap.Error(err))
+				utils.ResponseError(&ctx, c, utils.CodeServerError, "读取文件失败")
+				return
+			}
+			uploads = append(uploads, services.TemplateImportUpload{Name: fileHeader.Filename, Data: data})
+		}
+	}
+
+	preview := c.Query("preview") == "true"
+	result, err := templateService.ImportTemplates(userMobile.(string), uploads, preview)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	message := "导入完成"
+	if preview {
+		message = "解析完成"
+	}
+	utils.SuccessWithMessage(&ctx, c, message, result)
+}
+
+// RenderTemplateHandler 用变量取值渲染模板，返回替换后的要素与完整提示词
+// POST /api/v1/template/:id/render
+func RenderTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	var req services.TemplateRenderRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	result, err := templateService.RenderTemplate(userMobile.(string), id, req.Values)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTemplateVariableValues):
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		case err.Error() == "模板不存在或无权访问":
+			utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+		default:
+			utils.Error("渲染模板失败", zap.Error(err))
+			utils.ResponseError(&ctx, c, utils.CodeServerError, "渲染失败")
+		}
+		return
+	}
+
+	utils.Success(&ctx, c, result)
 }
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// respondTemplateLintError 按错误类型返回模板质量检查相关的错误码
+func respondTemplateLintError(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case err.Error() == "模板不存在或无权访问":
+		utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+	case errors.Is(err, services.ErrTemplateLintProvider):
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+	default:
+		utils.Error("模板质量检查失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "检查失败")
+	}
+}
+
+// LintTemplateHandler 检查模板质量，返回评分与各要素的问题，指定 provider_id 时附带大模型评审
+// POST /api/v1/template/:id/lint
+func LintTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	// 请求体可为空，仅做规则检查
+	var req services.TemplateLintOptions
+	if len(c.Request.Body()) > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+			return
+		}
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	result, err := templateService.LintTemplateByID(ctx, userMobile.(string), id, req)
+	if err != nil {
+		respondTemplateLintError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, result)
+}
+
+// LintTemplateDraftHandler 检查尚未保存的模板内容
+// POST /api/v1/template/lint
+func LintTemplateDraftHandler(ctx context.Context, c *app.RequestContext) {
+	var req services.TemplateLintDraftRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	result, err := templateService.LintTemplateDraft(ctx, userMobile.(string), &req)
+	if err != nil {
+		respondTemplateLintError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, result)
+}
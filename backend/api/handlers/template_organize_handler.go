@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// TemplateTagRenameRequest 重命名标签请求
+type TemplateTagRenameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// TemplateTagMergeRequest 合并标签请求
+type TemplateTagMergeRequest struct {
+	TagIDs   []uint64 `json:"tag_ids" binding:"required"`   // 被合并的标签
+	TargetID uint64   `json:"target_id" binding:"required"` // 合并到的标签
+}
+
+// TemplateFolderRequest 创建或重命名文件夹请求
+type TemplateFolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// respondTemplateOrganizeError 按错误类型返回模板整理相关的错误码
+func respondTemplateOrganizeError(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case err.Error() == "模板不存在或无权操作":
+		utils.ResponseError(&ctx, c, utils.CodeTemplateNoAuth, err.Error())
+	case errors.Is(err, services.ErrTemplateTagNotFound), errors.Is(err, services.ErrTemplateFolderNotFound):
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrTemplateTagName), errors.Is(err, services.ErrTemplateTagLimit),
+		errors.Is(err, services.ErrTemplateFolderName), errors.Is(err, services.ErrTemplateFolderLimit),
+		errors.Is(err, services.ErrTemplateFolderExists):
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+	default:
+		utils.Error("处理模板整理失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, err.Error())
+	}
+}
+
+// OrganizeTemplateHandler 设置模板的标签、文件夹、收藏与置顶
+// PUT /api/v1/template/:id/organize
+func OrganizeTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	var req services.TemplateOrganizeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	template, err := templateService.OrganizeTemplate(userMobile.(string), id, &req)
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "保存成功", template)
+}
+
+// ListTemplateTagsHandler 查询标签及使用次数，支持按前缀联想
+// GET /api/v1/template/tags?prefix=&limit=10
+func ListTemplateTagsHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	tags, err := templateService.ListTemplateTags(userMobile.(string), c.Query("prefix"), limit)
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, tags)
+}
+
+// RenameTemplateTagHandler 重命名标签，新名称已存在时合并
+// PUT /api/v1/template/tags/:id
+func RenameTemplateTagHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "标签ID格式错误")
+		return
+	}
+
+	var req TemplateTagRenameRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	result, err := templateService.RenameTemplateTag(userMobile.(string), id, req.Name)
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	message := "重命名成功"
+	if result.Merged {
+		message = "已合并到同名标签"
+	}
+	utils.SuccessWithMessage(&ctx, c, message, result)
+}
+
+// MergeTemplateTagsHandler 将多个标签合并到目标标签
+// POST /api/v1/template/tags/merge
+func MergeTemplateTagsHandler(ctx context.Context, c *app.RequestContext) {
+	var req TemplateTagMergeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	tag, err := templateService.MergeTemplateTags(userMobile.(string), req.TagIDs, req.TargetID)
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "合并成功", tag)
+}
+
+// DeleteTemplateTagHandler 删除标签并从所有模板上移除
+// DELETE /api/v1/template/tags/:id
+func DeleteTemplateTagHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "标签ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	if err := templateService.DeleteTemplateTag(userMobile.(string), id); err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+}
+
+// ListTemplateFoldersHandler 查询文件夹及其中的模板数
+// GET /api/v1/template/folders
+func ListTemplateFoldersHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	folders, err := templateService.ListTemplateFolders(userMobile.(string))
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, folders)
+}
+
+// CreateTemplateFolderHandler 创建文件夹
+// POST /api/v1/template/folders
+func CreateTemplateFolderHandler(ctx context.Context, c *app.RequestContext) {
+	var req TemplateFolderRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	folder, err := templateService.CreateTemplateFolder(userMobile.(string), req.Name)
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "创建成功", folder)
+}
+
+// RenameTemplateFolderHandler 重命名文件夹
+// PUT /api/v1/template/folders/:id
+func RenameTemplateFolderHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "文件夹ID格式错误")
+		return
+	}
+
+	var req TemplateFolderRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	folder, err := templateService.RenameTemplateFolder(userMobile.(string), id, req.Name)
+	if err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "重命名成功", folder)
+}
+
+// DeleteTemplateFolderHandler 删除文件夹，其中的模板移回未分类
+// DELETE /api/v1/template/folders/:id
+func DeleteTemplateFolderHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "文件夹ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	if err := templateService.DeleteTemplateFolder(userMobile.(string), id); err != nil {
+		respondTemplateOrganizeError(ctx, c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+}
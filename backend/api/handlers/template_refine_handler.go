@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// refineSSESink 在生成接口的SSE格式之外，完成前发送修改结果与当前值的对比
+type refineSSESink struct {
+	sseSink
+}
+
+// Refined 发送对比事件
+func (s *refineSSESink) Refined(diff *services.TemplateFieldDiff) {
+	sendSSEData(s.c, map[string]interface{}{
+		"type": "diff",
+		"diff": diff,
+		"done": false,
+	})
+}
+
+// RefineTemplateElementHandler 按修改要求流式生成单个要素的新内容，完成前返回与当前值的对比，不自动保存
+// POST /api/v1/template/:id/refine
+func RefineTemplateElementHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	var req services.TemplateRefineRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	// 开始生成前的错误以JSON返回，之后的错误通过SSE返回
+	sink := &refineSSESink{sseSink{c: c}}
+	if err := templateService.RefineTemplateElement(ctx, userMobile.(string), id, &req, sink); err != nil {
+		switch {
+		case respondInputRejected(ctx, c, err):
+		case errors.Is(err, services.ErrTemplateRefine):
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		case err.Error() == "模板不存在或无权访问":
+			utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+		case err.Error() == "Provider not found":
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, "API Provider不存在")
+		default:
+			utils.Error("优化模板要素失败", zap.Error(err))
+			utils.ResponseError(&ctx, c, utils.CodeServerError, "优化失败")
+		}
+	}
+}
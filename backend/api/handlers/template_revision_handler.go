@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// RestoreTemplateRevisionRequest 恢复修订版本请求
+type RestoreTemplateRevisionRequest struct {
+	ChangeNote string `json:"change_note,omitempty"` // 可选：修改说明，默认为“恢复至版本N”
+}
+
+// respondTemplateRevisionError 按错误类型返回模板修订相关的错误码
+func respondTemplateRevisionError(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case err.Error() == "模板不存在或无权访问":
+		utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+	case errors.Is(err, services.ErrTemplateRevisionNotFound):
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrTemplateConflict):
+		utils.ResponseError(&ctx, c, utils.CodeError, err.Error())
+	default:
+		utils.Error("处理模板修订失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, err.Error())
+	}
+}
+
+// ListTemplateRevisionsHandler 分页查询模板的修订历史
+// GET /api/v1/template/:id/revisions?page=1&page_size=15
+func ListTemplateRevisionsHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	revisions, total, err := templateService.ListTemplateRevisions(userMobile.(string), id, page, pageSize)
+	if err != nil {
+		respondTemplateRevisionError(ctx, c, err)
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, revisions, total, page, min(pageSize, 100))
+}
+
+// GetTemplateRevisionHandler 获取指定修订版本的完整快照
+// GET /api/v1/template/:id/revisions/:version
+func GetTemplateRevisionHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "版本号格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	revision, err := templateService.GetTemplateRevision(userMobile.(string), id, version)
+	if err != nil {
+		respondTemplateRevisionError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, revision)
+}
+
+// DiffTemplateRevisionsHandler 逐字段对比两个修订版本，未指定 to 时与当前版本对比
+// GET /api/v1/template/:id/diff?from=1&to=3
+func DiffTemplateRevisionsHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from <= 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "版本号格式错误")
+		return
+	}
+	to, err := strconv.Atoi(c.DefaultQuery("to", "0"))
+	if err != nil || to < 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "版本号格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	diff, err := templateService.DiffTemplateRevisions(userMobile.(string), id, from, to)
+	if err != nil {
+		respondTemplateRevisionError(ctx, c, err)
+		return
+	}
+
+	utils.Success(&ctx, c, diff)
+}
+
+// RestoreTemplateRevisionHandler 将指定修订版本恢复为当前内容
+// POST /api/v1/template/:id/revisions/:version/restore
+func RestoreTemplateRevisionHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "版本号格式错误")
+		return
+	}
+
+	// 请求体可为空
+	var req RestoreTemplateRevisionRequest
+	if len(c.Request.Body()) > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+			return
+		}
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	template, err := templateService.RestoreTemplateRevision(userMobile.(string), id, version, req.ChangeNote)
+	if err != nil {
+		if errors.Is(err, services.ErrTemplateRevisionNotFound) || errors.Is(err, services.ErrTemplateConflict) || err.Error() == "模板不存在或无权访问" {
+			respondTemplateRevisionError(ctx, c, err)
+			return
+		}
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "恢复成功", template)
+}
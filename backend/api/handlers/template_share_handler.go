@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// GetTemplateSharingHandler 获取模板的可见范围与共享用户
+// GET /api/v1/template/:id/sharing
+func GetTemplateSharingHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	sharing, err := templateService.GetTemplateSharing(userMobile.(string), id)
+	if err != nil {
+		if err.Error() == "模板不存在或无权访问" {
+			utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+			return
+		}
+		utils.Error("获取模板共享设置失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	utils.Success(&ctx, c, sharing)
+}
+
+// SetTemplateSharingHandler 设置模板的可见范围（private / shared / public）
+// PUT /api/v1/template/:id/sharing
+func SetTemplateSharingHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	var req services.TemplateSharingRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	sharing, err := templateService.SetTemplateSharing(userMobile.(string), id, &req)
+	if err != nil {
+		if err.Error() == "模板不存在或无权访问" {
+			utils.ResponseError(&ctx, c, utils.CodeTemplateNoAuth, "模板不存在或无权操作")
+			return
+		}
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "设置成功", sharing)
+}
+
+// ListSharedTemplatesHandler 分页查询其他用户共享给自己的模板
+// GET /api/v1/template/shared?page=1&page_size=15
+func ListSharedTemplatesHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	templates, total, err := templateService.ListSharedTemplates(userMobile.(string), page, pageSize)
+	if err != nil {
+		utils.Error("查询共享模板失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, templates, total, page, min(pageSize, 100))
+}
+
+// ForkTemplateHandler 将公开或共享给自己的模板复制到自己的账户
+// POST /api/v1/template/:id/fork
+func ForkTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	template, err := templateService.ForkTemplate(userMobile.(string), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrForkOwnTemplate):
+			utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		case err.Error() == "模板不存在或无权访问":
+			utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+		default:
+			utils.Error("复制模板失败", zap.Error(err))
+			utils.ResponseError(&ctx, c, utils.CodeError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "复制成功", template)
+}
+
+// ListGalleryTemplatesHandler 模板广场：分页查询公开模板（无需认证）
+// GET /api/v1/gallery?keyword=写作&sort=popular&page=1&page_size=15
+func ListGalleryTemplatesHandler(ctx context.Context, c *app.RequestContext) {
+	var q services.GalleryQuery
+	if err := c.BindAndValidate(&q); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误: "+err.Error())
+		return
+	}
+	if q.Sort != "" && q.Sort != services.GallerySortPopular && q.Sort != services.GallerySortLatest {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "排序方式仅支持 popular、latest")
+		return
+	}
+
+	templates, total, err := services.ListGalleryTemplates(&q)
+	if err != nil {
+		utils.Error("查询模板广场失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	utils.PageSuccess(&ctx, c, templates, total, q.Page, q.PageSize)
+}
+
+// GetGalleryTemplateHandler 模板广场：获取公开模板详情（无需认证）
+// GET /api/v1/gallery/:id
+func GetGalleryTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "模板ID格式错误")
+		return
+	}
+
+	template, err := services.GetGalleryTemplate(id)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeTemplateNotFound, err.Error())
+		return
+	}
+
+	utils.Success(&ctx, c, template)
+}
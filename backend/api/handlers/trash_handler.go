@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// respondTrashError 按错误类型返回回收站相关的错误码
+func respondTrashError(ctx context.Context, c *app.RequestContext, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrTrashItemNotFound):
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrTrashType):
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+	default:
+		utils.Error(action+"失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, action+"失败")
+	}
+}
+
+// parseTrashItem 解析回收站项目ID与当前用户，失败时已返回错误响应
+func parseTrashItem(ctx context.Context, c *app.RequestContext) (uint64, string, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "ID格式错误")
+		return 0, "", false
+	}
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return 0, "", false
+	}
+	return id, userMobile.(string), true
+}
+
+// ListTrashHandler 分页查询回收站中的模板与API Provider
+// GET /api/v1/trash?type=template|api_provider&page=1&page_size=15
+func ListTrashHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未认证")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	items, total, err := services.ListTrash(userMobile.(string), c.Query("type"), page, pageSize)
+	if err != nil {
+		respondTrashError(ctx, c, "查询回收站", err)
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, items, total, page, min(pageSize, 100))
+}
+
+// RestoreTemplateHandler 从回收站恢复模板
+// POST /api/v1/trash/templates/:id/restore
+func RestoreTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, userMobile, ok := parseTrashItem(ctx, c)
+	if !ok {
+		return
+	}
+
+	template, err := templateService.RestoreTemplate(userMobile, id)
+	if err != nil {
+		respondTrashError(ctx, c, "恢复模板", err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "恢复成功", template)
+}
+
+// PurgeTemplateHandler 彻底删除回收站中的模板
+// DELETE /api/v1/trash/templates/:id
+func PurgeTemplateHandler(ctx context.Context, c *app.RequestContext) {
+	id, userMobile, ok := parseTrashItem(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := templateService.PurgeTemplate(userMobile, id); err != nil {
+		respondTrashError(ctx, c, "彻底删除模板", err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "已彻底删除", nil)
+}
+
+// RestoreAPIProviderHandler 从回收站恢复API Provider
+// POST /api/v1/trash/api-providers/:id/restore
+func RestoreAPIProviderHandler(ctx context.Context, c *app.RequestContext) {
+	id, userMobile, ok := parseTrashItem(ctx, c)
+	if !ok {
+		return
+	}
+
+	provider, err := services.RestoreAPIProvider(userMobile, uint(id))
+	if err != nil {
+		respondTrashError(ctx, c, "恢复API Provider", err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "恢复成功", provider)
+}
+
+// PurgeAPIProviderHandler 彻底删除回收站中的API Provider
+// DELETE /api/v1/trash/api-providers/:id
+func PurgeAPIProviderHandler(ctx context.Context, c *app.RequestContext) {
+	id, userMobile, ok := parseTrashItem(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := services.PurgeAPIProvider(userMobile, uint(id)); err != nil {
+		respondTrashError(ctx, c, "彻底删除API Provider", err)
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "已彻底删除", nil)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// SaveModelPricesRequest 保存价格表请求
+type SaveModelPricesRequest struct {
+	Prices []services.ModelPriceRequest `json:"prices"`
+}
+
+// GetModelPricesHandler 获取API Provider的模型价格表
+// GET /api/v1/api-provider/:id/prices
+func GetModelPricesHandler(ctx context.Context, c *app.RequestContext) {
+	providerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Provider ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	if _, err := services.GetAPIProvider(userMobile.(string), uint(providerID)); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "Provider不存在")
+		return
+	}
+
+	prices, err := services.ListModelPrices(uint(providerID))
+	if err != nil {
+		utils.Error("查询模型价格失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", prices)
+}
+
+// SaveModelPricesHandler 保存API Provider的模型价格表（整表替换）
+// PUT /api/v1/api-provider/:id/prices
+func SaveModelPricesHandler(ctx context.Context, c *app.RequestContext) {
+	providerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Provider ID")
+		return
+	}
+
+	var req SaveModelPricesRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	if _, err := services.GetAPIProvider(userMobile.(string), uint(providerID)); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "Provider不存在")
+		return
+	}
+
+	prices, err := services.SaveModelPrices(uint(providerID), req.Prices)
+	if err != nil {
+		utils.Warn("保存模型价格失败", zap.Uint64("provider_id", providerID), zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "保存成功", prices)
+}
+
+// GetSpendReportHandler 获取费用报告，format=csv 时下载CSV文件
+// GET /api/v1/usage/report?start_date=2025-10-01&end_date=2025-10-31&group_by=day,model&format=json
+func GetSpendReportHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, services.ErrSpendReportFormat.Error())
+		return
+	}
+
+	report, err := services.GetSpendReport(userMobile.(string), services.SpendReportQuery{
+		StartDate: c.Query("start_date"),
+		EndDate:   c.Query("end_date"),
+		GroupBy:   c.Query("group_by"),
+	})
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	if format == "json" {
+		utils.SuccessWithMessage(&ctx, c, "获取成功", report)
+		return
+	}
+
+	data, fileName, err := services.BuildSpendReportCSV(report)
+	if err != nil {
+		utils.Error("生成费用报告失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "生成报告失败")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(200, "text/csv; charset=utf-8", data)
+}
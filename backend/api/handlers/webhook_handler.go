@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/services"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// CreateWebhookHandler 注册Webhook，响应中包含签名密钥明文（仅此一次）
+// POST /api/v1/webhooks
+func CreateWebhookHandler(ctx context.Context, c *app.RequestContext) {
+	var req services.WebhookRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	webhook, err := services.CreateWebhook(userMobile.(string), &req)
+	if err != nil {
+		utils.Warn("创建Webhook失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "创建成功", webhook)
+}
+
+// ListWebhooksHandler 获取Webhook列表
+// GET /api/v1/webhooks
+func ListWebhooksHandler(ctx context.Context, c *app.RequestContext) {
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	webhooks, err := services.ListWebhooks(userMobile.(string))
+	if err != nil {
+		utils.Error("查询Webhook失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", webhooks)
+}
+
+// ListWebhookEventsHandler 获取支持订阅的事件类型
+// GET /api/v1/webhooks/events
+func ListWebhookEventsHandler(ctx context.Context, c *app.RequestContext) {
+	utils.SuccessWithMessage(&ctx, c, "获取成功", models.WebhookEvents)
+}
+
+// GetWebhookHandler 获取Webhook详情
+// GET /api/v1/webhooks/:id
+func GetWebhookHandler(ctx context.Context, c *app.RequestContext) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Webhook ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	webhook, err := services.GetWebhook(userMobile.(string), uint(webhookID))
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, "Webhook不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", webhook.ToResponse())
+}
+
+// UpdateWebhookHandler 更新Webhook
+// PUT /api/v1/webhooks/:id
+func UpdateWebhookHandler(ctx context.Context, c *app.RequestContext) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Webhook ID")
+		return
+	}
+
+	var req services.WebhookRequest
+	if err := c.BindJSON(&req); err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "参数错误")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(userMobile.(string), uint(webhookID), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, "Webhook不存在")
+			return
+		}
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "更新成功", webhook)
+}
+
+// DeleteWebhookHandler 删除Webhook及其投递记录
+// DELETE /api/v1/webhooks/:id
+func DeleteWebhookHandler(ctx context.Context, c *app.RequestContext) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Webhook ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	if err := services.DeleteWebhook(userMobile.(string), uint(webhookID)); err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, "Webhook不存在")
+			return
+		}
+		utils.Error("删除Webhook失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "删除失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "删除成功", nil)
+}
+
+// ListWebhookDeliveriesHandler 分页查询投递记录
+// GET /api/v1/webhooks/:id/deliveries?status=failed&page=1&page_size=15
+func ListWebhookDeliveriesHandler(ctx context.Context, c *app.RequestContext) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Webhook ID")
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	deliveries, total, err := services.ListWebhookDeliveries(userMobile.(string), uint(webhookID), c.Query("status"), page, pageSize)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, "Webhook不存在")
+			return
+		}
+		utils.Error("查询投递记录失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "查询失败")
+		return
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	utils.PageSuccess(&ctx, c, deliveries, total, page, min(pageSize, 100))
+}
+
+// GetWebhookDeliveryHandler 获取投递记录详情（含投递内容与响应内容）
+// GET /api/v1/webhooks/:id/deliveries/:delivery_id
+func GetWebhookDeliveryHandler(ctx context.Context, c *app.RequestContext) {
+	webhookID, deliveryID, ok := parseWebhookDeliveryParams(ctx, c)
+	if !ok {
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	delivery, err := services.GetWebhookDelivery(userMobile.(string), webhookID, deliveryID)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "获取成功", delivery)
+}
+
+// RedeliverWebhookHandler 重新投递
+// POST /api/v1/webhooks/:id/deliveries/:delivery_id/redeliver
+func RedeliverWebhookHandler(ctx context.Context, c *app.RequestContext) {
+	webhookID, deliveryID, ok := parseWebhookDeliveryParams(ctx, c)
+	if !ok {
+		return
+	}
+
+	userMobile, exists := c.Get("userMobile")
+	if !exists {
+		utils.ResponseError(&ctx, c, utils.CodeUnauthorized, "未授权")
+		return
+	}
+
+	delivery, err := services.RedeliverWebhook(userMobile.(string), webhookID, deliveryID)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) || errors.Is(err, services.ErrWebhookDeliveryNotFound) {
+			utils.ResponseError(&ctx, c, utils.CodeNotFound, err.Error())
+			return
+		}
+		utils.Error("重新投递失败", zap.Error(err))
+		utils.ResponseError(&ctx, c, utils.CodeServerError, "重新投递失败")
+		return
+	}
+
+	utils.SuccessWithMessage(&ctx, c, "已加入投递队列", delivery)
+}
+
+// parseWebhookDeliveryParams 解析路径中的Webhook ID与投递记录ID
+func parseWebhookDeliveryParams(ctx context.Context, c *app.RequestContext) (uint, uint, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的Webhook ID")
+		return 0, 0, false
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		utils.ResponseError(&ctx, c, utils.CodeInvalidParams, "无效的投递记录ID")
+		return 0, 0, false
+	}
+	return uint(webhookID), uint(deliveryID), true
+}
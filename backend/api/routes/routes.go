@@ -50,6 +50,39 @@ func RegisterRoutes(h *server.Hertz) {
 		template.GET("/:id", handlers.GetTemplateByIDHandler)
 		template.PUT("/:id", handlers.UpdateTemplateHandler)
 		template.DELETE("/:id", handlers.DeleteTemplateHandler)
+		template.GET("/:id/revisions", handlers.ListTemplateRevisionsHandler)
+		template.GET("/:id/revisions/:version", handlers.GetTemplateRevisionHandler)
+		template.POST("/:id/revisions/:version/restore", handlers.RestoreTemplateRevisionHandler)
+		template.GET("/:id/diff", handlers.DiffTemplateRevisionsHandler)
+		template.GET("/:id/export", handlers.ExportTemplateHandler)
+		template.POST("/:id/render", handlers.RenderTemplateHandler)
+		template.GET("/export", handlers.ExportTemplatesHandler)
+		template.POST("/import", handlers.ImportTemplatesHandler)
+		template.GET("/shared", handlers.ListSharedTemplatesHandler)
+		template.GET("/:id/sharing", handlers.GetTemplateSharingHandler)
+		template.PUT("/:id/sharing", handlers.SetTemplateSharingHandler)
+		template.POST("/:id/fork", handlers.ForkTemplateHandler)
+		template.PUT("/:id/organize", handlers.OrganizeTemplateHandler)
+		template.GET("/tags", handlers.ListTemplateTagsHandler)
+		template.POST("/tags/merge", handlers.MergeTemplateTagsHandler)
+		template.PUT("/tags/:id", handlers.RenameTemplateTagHandler)
+		template.DELETE("/tags/:id", handlers.DeleteTemplateTagHandler)
+		template.GET("/folders", handlers.ListTemplateFoldersHandler)
+		template.POST("/folders", handlers.CreateTemplateFolderHandler)
+		template.PUT("/folders/:id", handlers.RenameTemplateFolderHandler)
+		template.DELETE("/folders/:id", handlers.DeleteTemplateFolderHandler)
+		template.GET("/similar", handlers.SearchSimilarTemplatesHandler)
+		template.GET("/:id/similar", handlers.GetSimilarTemplatesHandler)
+		template.POST("/lint", handlers.LintTemplateDraftHandler)
+		template.POST("/:id/lint", handlers.LintTemplateHandler)
+		template.POST("/:id/refine", handlers.RefineTemplateElementHandler)
+	}
+
+	// ===== 模板广场路由（公开，无需认证）=====
+	gallery := v1.Group("/gallery")
+	{
+		gallery.GET("", handlers.ListGalleryTemplatesHandler)
+		gallery.GET("/:id", handlers.GetGalleryTemplateHandler)
 	}
 
 	// ===== API Provider路由（全部需要认证）=====
@@ -58,9 +91,12 @@ func RegisterRoutes(h *server.Hertz) {
 	{
 		apiProvider.POST("", handlers.CreateAPIProviderHandler)
 		apiProvider.GET("", handlers.ListAPIProvidersHandler)
+		apiProvider.GET("/kinds", handlers.ListAPIKindsHandler)
 		apiProvider.GET("/:id", handlers.GetAPIProviderHandler)
 		apiProvider.PUT("/:id", handlers.UpdateAPIProviderHandler)
 		apiProvider.DELETE("/:id", handlers.DeleteAPIProviderHandler)
+		apiProvider.GET("/:id/prices", handlers.GetModelPricesHandler)
+		apiProvider.PUT("/:id/prices", handlers.SaveModelPricesHandler)
 	}
 
 	// ===== AI生成路由（全部需要认证）=====
@@ -68,6 +104,96 @@ func RegisterRoutes(h *server.Hertz) {
 	generate.Use(middleware.AuthMiddleware())
 	{
 		generate.POST("", handlers.GenerateContentHandler)
+		generate.POST("/estimate", handlers.EstimateGenerateHandler)
+		generate.POST("/image", handlers.UploadImageHandler)
+		generate.GET("/image/:id", handlers.GetImageHandler)
+	}
+
+	// ===== 评测路由（全部需要认证）=====
+	evaluation := v1.Group("/evaluation")
+	evaluation.Use(middleware.AuthMiddleware())
+	{
+		evaluation.POST("", handlers.CreateEvaluationHandler)
+		evaluation.GET("", handlers.ListEvaluationsHandler)
+		evaluation.GET("/:id", handlers.GetEvaluationHandler)
+		evaluation.DELETE("/:id", handlers.DeleteEvaluationHandler)
+	}
+
+	// ===== 异步任务路由（全部需要认证）=====
+	jobs := v1.Group("/jobs")
+	jobs.Use(middleware.AuthMiddleware())
+	{
+		jobs.POST("", handlers.SubmitJobHandler)
+		jobs.GET("", handlers.ListJobsHandler)
+		jobs.GET("/:id", handlers.GetJobHandler)
+		jobs.POST("/:id/cancel", handlers.CancelJobHandler)
+		jobs.GET("/:id/stream", handlers.StreamJobHandler)
+	}
+
+	// ===== 批量生成路由（全部需要认证）=====
+	batch := v1.Group("/batch")
+	batch.Use(middleware.AuthMiddleware())
+	{
+		batch.POST("", handlers.CreateBatchHandler)
+		batch.GET("", handlers.ListBatchesHandler)
+		batch.GET("/:id", handlers.GetBatchHandler)
+		batch.GET("/:id/report", handlers.DownloadBatchReportHandler)
+	}
+
+	// ===== Webhook路由（全部需要认证）=====
+	webhooks := v1.Group("/webhooks")
+	webhooks.Use(middleware.AuthMiddleware())
+	{
+		webhooks.POST("", handlers.CreateWebhookHandler)
+		webhooks.GET("", handlers.ListWebhooksHandler)
+		webhooks.GET("/events", handlers.ListWebhookEventsHandler)
+		webhooks.GET("/:id", handlers.GetWebhookHandler)
+		webhooks.PUT("/:id", handlers.UpdateWebhookHandler)
+		webhooks.DELETE("/:id", handlers.DeleteWebhookHandler)
+		webhooks.GET("/:id/deliveries", handlers.ListWebhookDeliveriesHandler)
+		webhooks.GET("/:id/deliveries/:delivery_id", handlers.GetWebhookDeliveryHandler)
+		webhooks.POST("/:id/deliveries/:delivery_id/redeliver", handlers.RedeliverWebhookHandler)
+	}
+
+	// ===== 输出过滤路由（全部需要认证）=====
+	outputFilters := v1.Group("/output-filters")
+	outputFilters.Use(middleware.AuthMiddleware())
+	{
+		outputFilters.GET("", handlers.GetOutputFilterHandler)
+		outputFilters.PUT("", handlers.SaveOutputFilterHandler)
+		outputFilters.GET("/filters", handlers.ListOutputFiltersHandler)
+		outputFilters.POST("/preview", handlers.PreviewOutputFilterHandler)
+		outputFilters.GET("/templates/:id", handlers.GetTemplateOutputFilterHandler)
+		outputFilters.PUT("/templates/:id", handlers.SaveTemplateOutputFilterHandler)
+		outputFilters.DELETE("/templates/:id", handlers.DeleteTemplateOutputFilterHandler)
+	}
+
+	// ===== 向量检索设置路由（全部需要认证）=====
+	embedding := v1.Group("/embedding")
+	embedding.Use(middleware.AuthMiddleware())
+	{
+		embedding.GET("/settings", handlers.GetEmbeddingSettingHandler)
+		embedding.PUT("/settings", handlers.SaveEmbeddingSettingHandler)
+		embedding.DELETE("/settings", handlers.DeleteEmbeddingSettingHandler)
+		embedding.POST("/backfill", handlers.StartEmbeddingBackfillHandler)
+	}
+
+	// ===== 回收站路由（全部需要认证）=====
+	trash := v1.Group("/trash")
+	trash.Use(middleware.AuthMiddleware())
+	{
+		trash.GET("", handlers.ListTrashHandler)
+		trash.POST("/templates/:id/restore", handlers.RestoreTemplateHandler)
+		trash.DELETE("/templates/:id", handlers.PurgeTemplateHandler)
+		trash.POST("/api-providers/:id/restore", handlers.RestoreAPIProviderHandler)
+		trash.DELETE("/api-providers/:id", handlers.PurgeAPIProviderHandler)
+	}
+
+	// ===== 用量与费用路由（全部需要认证）=====
+	usage := v1.Group("/usage")
+	usage.Use(middleware.AuthMiddleware())
+	{
+		usage.GET("/report", handlers.GetSpendReportHandler)
 	}
 
 	// 健康检查接口
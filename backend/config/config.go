@@ -8,10 +8,20 @@ import (
 
 // AppConfig 应用配置
 type AppConfig struct {
-	Server ServerConfig `yaml:"server"`
-	DB     DBConfig     `yaml:"database"`
-	JWT    JWTConfig    `yaml:"jwt"`
-	Log    LogConfig    `yaml:"log"`
+	Server    ServerConfig    `yaml:"server"`
+	DB        DBConfig        `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Log       LogConfig       `yaml:"log"`
+	Upload    UploadConfig    `yaml:"upload"`
+	Job       JobConfig       `yaml:"job"`
+	Batch     BatchConfig     `yaml:"batch"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+	Output    OutputConfig    `yaml:"output"`
+	Input     InputConfig     `yaml:"input"`
+	Token     TokenConfig     `yaml:"token"`
+	Search    SearchConfig    `yaml:"search"`
+	Embedding EmbeddingConfig `yaml:"embedding"`
+	Trash     TrashConfig     `yaml:"trash"`
 }
 
 // ServerConfig 服务器配置
@@ -36,6 +46,94 @@ type LogConfig struct {
 	MaxAge     int    `yaml:"max_age"` // days
 }
 
+// UploadConfig 上传文件配置
+type UploadConfig struct {
+	Dir          string   `yaml:"dir"`            // 存储目录
+	MaxImageSize int      `yaml:"max_image_size"` // 单张图片最大大小（MB）
+	MaxImages    int      `yaml:"max_images"`     // 单次生成最多附带的图片数量
+	ImageTypes   []string `yaml:"image_types"`    // 允许的图片MIME类型
+}
+
+// JobConfig 异步生成任务配置
+type JobConfig struct {
+	Workers   int `yaml:"workers"`    // 并发执行的任务数
+	QueueSize int `yaml:"queue_size"` // 排队任务上限
+	Timeout   int `yaml:"timeout"`    // 单个任务超时时间（秒）
+}
+
+// BatchConfig 批量生成配置
+type BatchConfig struct {
+	Concurrency    int    `yaml:"concurrency"`     // 默认并发生成的行数
+	MaxConcurrency int    `yaml:"max_concurrency"` // 请求可指定的最大并发数
+	MaxRows        int    `yaml:"max_rows"`        // 单个文件最多的行数
+	MaxFileSize    int    `yaml:"max_file_size"`   // 上传文件最大大小（MB）
+	PromptDir      string `yaml:"prompt_dir"`      // 六要素提示词模板目录
+}
+
+// WebhookConfig Webhook投递配置
+type WebhookConfig struct {
+	Workers       int `yaml:"workers"`        // 并发投递数
+	Timeout       int `yaml:"timeout"`        // 单次投递超时时间（秒）
+	MaxAttempts   int `yaml:"max_attempts"`   // 最多投递次数（含首次）
+	RetryInterval int `yaml:"retry_interval"` // 首次重试间隔（秒），之后按指数退避
+
+	AllowPrivateNetwork bool `yaml:"allow_private_network"` // 是否允许回调本机或内网地址
+}
+
+// OutputConfig 生成内容输出过滤配置
+type OutputConfig struct {
+	Filters        []string `yaml:"filters"`         // 用户与模板均未设置时默认启用的过滤器
+	SensitiveWords []string `yaml:"sensitive_words"` // 敏感词
+	LexiconFile    string   `yaml:"lexicon_file"`    // 敏感词词库文件，每行一个词，# 开头为注释
+	Replacement    string   `yaml:"replacement"`     // 敏感词替换字符，按敏感词长度重复
+	MaxLength      int      `yaml:"max_length"`      // 默认截断长度（字符数）
+	TruncateSuffix string   `yaml:"truncate_suffix"` // 截断后追加的内容
+}
+
+// InputConfig 调用Provider前的输入检查配置
+type InputConfig struct {
+	MaxPromptLength int      `yaml:"max_prompt_length"` // 提示词最大字符数
+	MaxInputTokens  int      `yaml:"max_input_tokens"`  // 输入内容（系统消息、历史消息与提示词）的最大预估token数
+	BlockedPatterns []string `yaml:"blocked_patterns"`  // 禁止出现的内容（正则表达式）
+	InjectionCheck  bool     `yaml:"injection_check"`   // 是否检测关键信息中的提示词注入
+}
+
+// TokenConfig token计算与上下文窗口配置
+type TokenConfig struct {
+	BPEDir           string        `yaml:"bpe_dir"`            // tiktoken词表目录，包含 cl100k_base.tiktoken、o200k_base.tiktoken
+	DefaultMaxTokens int           `yaml:"default_max_tokens"` // 请求未指定 max_tokens 时的默认值
+	Models           []ModelConfig `yaml:"models"`             // 补充或覆盖内置模型目录
+}
+
+// ModelConfig 模型目录项，按模型名称前缀匹配
+type ModelConfig struct {
+	Prefix        string `yaml:"prefix"`         // 模型名称前缀
+	ContextWindow int    `yaml:"context_window"` // 上下文窗口（token数）
+	MaxOutput     int    `yaml:"max_output"`     // 单次最大输出token数
+	Encoding      string `yaml:"encoding"`       // OpenAI BPE编码：cl100k_base/o200k_base，为空时按字符估算
+}
+
+// SearchConfig 模板全文检索配置
+type SearchConfig struct {
+	Backend       string `yaml:"backend"`        // mysql：MySQL FULLTEXT（ngram）索引；memory：进程内索引，不依赖FULLTEXT
+	MaxResults    int    `yaml:"max_results"`    // 单次检索最多匹配的模板数
+	SnippetLength int    `yaml:"snippet_length"` // 高亮片段的最大字符数
+}
+
+// EmbeddingConfig 模板向量化（语义检索）配置
+type EmbeddingConfig struct {
+	BatchSize      int `yaml:"batch_size"`       // 单次调用向量接口的最大文本数
+	MaxInputLength int `yaml:"max_input_length"` // 单个模板参与向量化的最大字符数，超出部分截断
+	Timeout        int `yaml:"timeout"`          // 单次调用向量接口的超时时间（秒）
+	Concurrency    int `yaml:"concurrency"`      // 保存模板时后台向量化的最大并发数
+}
+
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	RetentionDays int `yaml:"retention_days"` // 删除的模板与API Provider在回收站中保留的天数，超出后彻底删除
+	PurgeInterval int `yaml:"purge_interval"` // 清理过期项目的间隔（分钟）
+}
+
 var globalConfig *AppConfig
 
 // LoadConfig 从 YAML 文件加载配置
@@ -46,8 +144,8 @@ func LoadConfig(configPath string) (*AppConfig, error) {
 		return nil, err
 	}
 
-	// 解析 YAML
-	var config AppConfig
+	// 解析 YAML，配置文件中未出现的配置项保留默认值
+	config := *GetDefaultConfig()
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
@@ -96,5 +194,62 @@ func GetDefaultConfig() *AppConfig {
 			MaxBackups: 7,
 			MaxAge:     30,
 		},
+		Upload: UploadConfig{
+			Dir:          "uploads",
+			MaxImageSize: 5,
+			MaxImages:    4,
+			ImageTypes:   []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+		},
+		Job: JobConfig{
+			Workers:   4,
+			QueueSize: 100,
+			Timeout:   600,
+		},
+		Batch: BatchConfig{
+			Concurrency:    3,
+			MaxConcurrency: 10,
+			MaxRows:        200,
+			MaxFileSize:    2,
+			PromptDir:      "../frontend/public/docs",
+		},
+		Webhook: WebhookConfig{
+			Workers:       2,
+			Timeout:       10,
+			MaxAttempts:   5,
+			RetryInterval: 30,
+		},
+		Output: OutputConfig{
+			Filters:        []string{},
+			SensitiveWords: []string{},
+			Replacement:    "*",
+			MaxLength:      4000,
+			TruncateSuffix: "……（内容已截断）",
+		},
+		Input: InputConfig{
+			MaxPromptLength: 20000,
+			MaxInputTokens:  16000,
+			BlockedPatterns: []string{},
+			InjectionCheck:  true,
+		},
+		Token: TokenConfig{
+			BPEDir:           "data/tokenizer",
+			DefaultMaxTokens: 2000,
+			Models:           []ModelConfig{},
+		},
+		Search: SearchConfig{
+			Backend:       "mysql",
+			MaxResults:    500,
+			SnippetLength: 80,
+		},
+		Embedding: EmbeddingConfig{
+			BatchSize:      16,
+			MaxInputLength: 6000,
+			Timeout:        30,
+			Concurrency:    4,
+		},
+		Trash: TrashConfig{
+			RetentionDays: 30,
+			PurgeInterval: 60,
+		},
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/zsy619/cese-qoder/backend/api/routes"
 	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/services"
 	"github.com/zsy619/cese-qoder/backend/utils"
 	"go.uber.org/zap"
 )
@@ -53,11 +54,41 @@ func main() {
 		// 即使数据库连接失败，也继续启动服务器以提供静态文件服务
 	} else {
 		utils.Info("Database connected successfully")
+
+		// 启动Webhook投递器，继续投递上次未完成的记录
+		services.StartWebhookDispatcher(appConfig.Webhook)
+
+		// 启动异步任务执行器，重新执行上次未完成的任务
+		if err := services.StartJobManager(appConfig.Job); err != nil {
+			utils.Error("Failed to start job manager", zap.Error(err))
+		}
+		// 上次未完成的批量生成标记为中断
+		if err := services.RecoverBatches(); err != nil {
+			utils.Error("Failed to recover batches", zap.Error(err))
+		}
+		// 继续执行上次因重启中断的模板向量回填
+		if err := services.RecoverEmbeddingBackfills(); err != nil {
+			utils.Error("Failed to recover embedding backfills", zap.Error(err))
+		}
+		// 定期彻底删除回收站中超过保留期的模板与API Provider
+		services.StartTrashPurger(appConfig.Trash)
 	}
 
 	// 5. 创建 Hertz 服务器实例
+	// 请求体大小需容纳上传的图片、批量生成文件与模板导入文件（Hertz默认4MB）
+	maxBodySize := 4 << 20
+	if uploadSize := (appConfig.Upload.MaxImageSize + 1) << 20; uploadSize > maxBodySize {
+		maxBodySize = uploadSize
+	}
+	if batchSize := (appConfig.Batch.MaxFileSize + 1) << 20; batchSize > maxBodySize {
+		maxBodySize = batchSize
+	}
+	if services.MaxTemplateImportBodySize > maxBodySize {
+		maxBodySize = services.MaxTemplateImportBodySize
+	}
 	h := server.Default(
 		server.WithHostPorts(fmt.Sprintf("%s:%d", appConfig.Server.Host, appConfig.Server.Port)),
+		server.WithMaxRequestBodySize(maxBodySize),
 	)
 
 	// 6. 注册路由
@@ -72,6 +103,13 @@ func main() {
 
 		utils.Info("Shutting down server...")
 
+		// 停止任务执行器，执行中的任务将在下次启动时重新执行
+		services.StopJobManager()
+		// 停止Webhook投递器，未投递的记录将在下次启动时继续投递
+		services.StopWebhookDispatcher()
+		// 停止回收站定期清理
+		services.StopTrashPurger()
+
 		// 关闭数据库连接
 		if err := config.CloseDB(); err != nil {
 			utils.Error("Error closing database", zap.Error(err))
@@ -0,0 +1,247 @@
+package models
+
+// 模型类型（api_kind）取值
+// 已有数据中的中文取值保持不变，避免迁移存量Provider
+const (
+	APIKindOpenAICompatible = "OpenAI Compatible"
+	APIKindOpenRouter       = "OpenRouter"
+	APIKindGemini           = "Google Gemini"
+	APIKindAzureOpenAI      = "Azure OpenAI"
+	APIKindAnthropic        = "Anthropic"
+	APIKindDeepSeek         = "DeepSeek"
+	APIKindOllama           = "Ollama"
+	APIKindQwen             = "阿里千问"
+	APIKindZhipu            = "智普"
+	APIKindQianfan          = "百度千帆"
+	APIKindMoonshot         = "Moonshot"
+	APIKindDoubao           = "豆包"
+	APIKindSpark            = "讯飞星火"
+	APIKindHunyuan          = "腾讯混元"
+	APIKindBedrock          = "Amazon Bedrock"
+	APIKindClaudeCode       = "Claude Code"
+	APIKindCoze             = "Coze"
+)
+
+// 认证方式
+const (
+	AuthSchemeBearer       = "bearer"        // Authorization: Bearer {api_key}
+	AuthSchemeAPIKeyHeader = "api-key"       // api-key: {api_key}
+	AuthSchemeXAPIKey      = "x-api-key"     // x-api-key: {api_key}
+	AuthSchemeQueryKey     = "query-key"     // ?key={api_key}
+	AuthSchemeZhipuJWT     = "zhipu-jwt"     // api_key 格式 {id}.{secret}，请求时签发JWT
+	AuthSchemeQianfanToken = "qianfan-token" // api_key 格式 {AK}:{SK}，换取access_token后放入查询参数
+	AuthSchemeNone         = "none"          // 无需认证
+)
+
+// APIKindInfo 模型类型目录项
+type APIKindInfo struct {
+	Kind        string   `json:"kind"`         // 模型类型，对应 api_kind
+	Label       string   `json:"label"`        // 显示名称
+	DefaultURL  string   `json:"default_url"`  // 默认API地址
+	Models      []string `json:"models"`       // 常用模型
+	AuthScheme  string   `json:"auth_scheme"`  // 认证方式
+	KeyHint     string   `json:"key_hint"`     // API Key填写提示
+	Description string   `json:"description"`  // 描述信息
+	StreamUsage bool     `json:"stream_usage"` // 流式响应是否返回token用量
+	Tools       bool     `json:"tools"`        // 是否支持工具调用
+	Vision      bool     `json:"vision"`       // 是否支持图片输入
+
+	EmbeddingModels []string `json:"embedding_models,omitempty"` // 常用向量模型，为空表示不支持向量化
+}
+
+// APIKinds 支持的模型类型目录（按前端展示顺序排列）
+var APIKinds = []APIKindInfo{
+	{
+		Kind:            APIKindOpenAICompatible,
+		Label:           "OpenAI Compatible",
+		DefaultURL:      "https://api.openai.com/v1",
+		Models:          []string{"gpt-4o", "gpt-4-turbo", "gpt-4", "gpt-3.5-turbo"},
+		AuthScheme:      AuthSchemeBearer,
+		KeyHint:         "sk-xxxxxx",
+		Description:     "OpenAI官方API及兼容OpenAI格式的服务",
+		StreamUsage:     true,
+		Tools:           true,
+		Vision:          true,
+		EmbeddingModels: []string{"text-embedding-3-small", "text-embedding-3-large"},
+	},
+	{
+		Kind:        APIKindOpenRouter,
+		Label:       "OpenRouter",
+		DefaultURL:  "https://openrouter.ai/api/v1",
+		Models:      []string{"openai/gpt-4", "anthropic/claude-3-opus", "google/gemini-pro"},
+		AuthScheme:  AuthSchemeBearer,
+		KeyHint:     "sk-or-xxxxxx",
+		Description: "多模型聚合路由服务",
+		Tools:       true,
+		Vision:      true,
+	},
+	{
+		Kind:        APIKindGemini,
+		Label:       "Google Gemini",
+		DefaultURL:  "https://generativelanguage.googleapis.com/v1beta",
+		Models:      []string{"gemini-pro", "gemini-pro-vision", "gemini-ultra"},
+		AuthScheme:  AuthSchemeQueryKey,
+		Description: "Google Gemini系列模型",
+		Tools:       true,
+		Vision:      true,
+	},
+	{
+		Kind:        APIKindAzureOpenAI,
+		Label:       "Azure OpenAI",
+		DefaultURL:  "https://{your-resource}.openai.azure.com",
+		Models:      []string{"gpt-4o", "gpt-4", "gpt-35-turbo"},
+		AuthScheme:  AuthSchemeAPIKeyHeader,
+		KeyHint:     "模型名称填写部署名，API版本填写api-version",
+		Description: "微软Azure托管的OpenAI模型",
+		Tools:       true,
+		Vision:      true,
+	},
+	{
+		Kind:        APIKindAnthropic,
+		Label:       "Anthropic Claude",
+		DefaultURL:  "https://api.anthropic.com/v1",
+		Models:      []string{"claude-3-opus", "claude-3-sonnet", "claude-3-haiku"},
+		AuthScheme:  AuthSchemeXAPIKey,
+		KeyHint:     "sk-ant-xxxxxx",
+		Description: "Anthropic Claude系列模型",
+		Tools:       true,
+		Vision:      true,
+	},
+	{
+		Kind:        APIKindDeepSeek,
+		Label:       "DeepSeek",
+		DefaultURL:  "https://api.deepseek.com",
+		Models:      []string{"deepseek-chat", "deepseek-reasoner"},
+		AuthScheme:  AuthSchemeBearer,
+		KeyHint:     "sk-xxxxxx",
+		Description: "国产大模型，性价比高",
+		StreamUsage: true,
+		Tools:       true,
+	},
+	{
+		Kind:            APIKindOllama,
+		Label:           "Ollama",
+		DefaultURL:      "http://localhost:11434",
+		Models:          []string{"llama3", "qwen2", "mistral", "codellama"},
+		AuthScheme:      AuthSchemeNone,
+		KeyHint:         "原生模式无需填写；地址以/v1结尾时使用OpenAI兼容模式",
+		Description:     "本地部署的大模型服务",
+		Tools:           true,
+		Vision:          true,
+		EmbeddingModels: []string{"nomic-embed-text", "bge-m3", "mxbai-embed-large"},
+	},
+	{
+		Kind:            APIKindQwen,
+		Label:           "阿里千问（DashScope）",
+		DefaultURL:      "https://dashscope.aliyuncs.com/compatible-mode/v1",
+		Models:          []string{"qwen-turbo", "qwen-plus", "qwen-max", "qwen-long"},
+		AuthScheme:      AuthSchemeBearer,
+		KeyHint:         "sk-xxxxxx（DashScope API Key）",
+		Description:     "阿里云百炼通义千问，OpenAI兼容模式",
+		StreamUsage:     true,
+		Tools:           true,
+		Vision:          true,
+		EmbeddingModels: []string{"text-embedding-v3", "text-embedding-v2"},
+	},
+	{
+		Kind:            APIKindZhipu,
+		Label:           "智谱 GLM",
+		DefaultURL:      "https://open.bigmodel.cn/api/paas/v4",
+		Models:          []string{"glm-4-plus", "glm-4", "glm-4-flash", "glm-4-air"},
+		AuthScheme:      AuthSchemeZhipuJWT,
+		KeyHint:         "{id}.{secret}，请求时自动签发JWT",
+		Description:     "智谱AI GLM系列模型",
+		Tools:           true,
+		Vision:          true,
+		EmbeddingModels: []string{"embedding-3", "embedding-2"},
+	},
+	{
+		Kind:        APIKindQianfan,
+		Label:       "百度千帆（文心）",
+		DefaultURL:  "https://aip.baidubce.com/rpc/2.0/ai_custom/v1",
+		Models:      []string{"completions_pro", "completions", "ernie-speed-128k", "ernie-lite-8k"},
+		AuthScheme:  AuthSchemeQianfanToken,
+		KeyHint:     "{API Key}:{Secret Key}，自动换取access_token",
+		Description: "百度智能云千帆大模型平台，模型名称填写服务路径",
+	},
+	{
+		Kind:        APIKindMoonshot,
+		Label:       "Moonshot（Kimi）",
+		DefaultURL:  "https://api.moonshot.cn/v1",
+		Models:      []string{"moonshot-v1-8k", "moonshot-v1-32k", "moonshot-v1-128k"},
+		AuthScheme:  AuthSchemeBearer,
+		KeyHint:     "sk-xxxxxx",
+		Description: "月之暗面Kimi大模型",
+		Tools:       true,
+		Vision:      true,
+	},
+	{
+		Kind:            APIKindDoubao,
+		Label:           "豆包（火山方舟）",
+		DefaultURL:      "https://ark.cn-beijing.volces.com/api/v3",
+		Models:          []string{"doubao-pro-32k", "doubao-lite-32k"},
+		AuthScheme:      AuthSchemeBearer,
+		KeyHint:         "火山方舟API Key，模型名称可填写推理接入点ID（ep-xxx）",
+		Description:     "字节跳动豆包大模型",
+		StreamUsage:     true,
+		Tools:           true,
+		Vision:          true,
+		EmbeddingModels: []string{"doubao-embedding", "doubao-embedding-large"},
+	},
+	{
+		Kind:        APIKindCoze,
+		Label:       "Coze 智能体",
+		DefaultURL:  "https://api.coze.cn",
+		Models:      []string{},
+		AuthScheme:  AuthSchemeBearer,
+		KeyHint:     "个人访问令牌 pat_xxx，模型名称填写Bot ID",
+		Description: "扣子智能体对话API，可直接使用已发布的六要素生成Bot",
+	},
+	{
+		Kind:        APIKindSpark,
+		Label:       "讯飞星火",
+		DefaultURL:  "https://spark-api-open.xf-yun.com/v1",
+		Models:      []string{"4.0Ultra", "generalv3.5", "lite"},
+		AuthScheme:  AuthSchemeBearer,
+		KeyHint:     "APIPassword",
+		Description: "科大讯飞星火大模型，OpenAI兼容接口",
+	},
+	{
+		Kind:            APIKindHunyuan,
+		Label:           "腾讯混元",
+		DefaultURL:      "https://api.hunyuan.cloud.tencent.com/v1",
+		Models:          []string{"hunyuan-pro", "hunyuan-standard", "hunyuan-lite"},
+		AuthScheme:      AuthSchemeBearer,
+		KeyHint:         "sk-xxxxxx",
+		Description:     "腾讯混元大模型，OpenAI兼容接口",
+		Tools:           true,
+		Vision:          true,
+		EmbeddingModels: []string{"hunyuan-embedding"},
+	},
+	{
+		Kind:        APIKindBedrock,
+		Label:       "Amazon Bedrock",
+		DefaultURL:  "https://bedrock-runtime.us-east-1.amazonaws.com",
+		Models:      []string{"anthropic.claude-3", "amazon.titan-text-express"},
+		AuthScheme:  AuthSchemeBearer,
+		Description: "AWS Bedrock托管模型",
+	},
+	{
+		Kind:        APIKindClaudeCode,
+		Label:       "Claude Code",
+		DefaultURL:  "https://api.anthropic.com/v1",
+		Models:      []string{"claude-code"},
+		AuthScheme:  AuthSchemeBearer,
+		Description: "Claude Code",
+	},
+}
+
+// GetAPIKindInfo 根据模型类型获取目录项
+func GetAPIKindInfo(kind string) (*APIKindInfo, bool) {
+	for i := range APIKinds {
+		if APIKinds[i].Kind == kind {
+			return &APIKinds[i], true
+		}
+	}
+	return nil, false
+}
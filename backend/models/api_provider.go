@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // APIProvider API Provider配置模型
@@ -19,6 +21,8 @@ type APIProvider struct {
 	APIRemark  string    `json:"api_remark,omitempty" gorm:"type:text"`
 	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // 移入回收站的时间，超出保留期后彻底删除
 }
 
 // TableName 指定表名
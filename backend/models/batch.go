@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// 批量生成状态
+const (
+	BatchStatusPending   = "pending"   // 等待生成
+	BatchStatusRunning   = "running"   // 生成中
+	BatchStatusCompleted = "completed" // 已完成（可能包含失败的行）
+	BatchStatusFailed    = "failed"    // 失败
+)
+
+// Batch 批量生成六要素模板的记录
+type Batch struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile      string     `json:"mobile" gorm:"type:varchar(32);not null;index"`
+	ProviderID  uint       `json:"provider_id" gorm:"not null"`
+	FileName    string     `json:"file_name" gorm:"type:varchar(255)"`
+	Format      string     `json:"format" gorm:"type:varchar(10);not null"` // csv / jsonl
+	Status      string     `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	Concurrency int        `json:"concurrency"`
+	Total       int        `json:"total"`
+	Succeeded   int        `json:"succeeded"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	Rows        []BatchRow `json:"rows,omitempty" gorm:"foreignKey:BatchID"`
+}
+
+// TableName 指定表名
+func (Batch) TableName() string {
+	return "cese_batch"
+}
+
+// BatchRow 批量生成中单行的结果
+type BatchRow struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	BatchID    uint      `json:"batch_id" gorm:"not null;index"`
+	RowNo      int       `json:"row_no"` // 在上传文件中的行号
+	Topic      string    `json:"topic" gorm:"type:varchar(255)"`
+	Status     string    `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	TemplateID *uint64   `json:"template_id,omitempty"` // 生成成功后保存的模板
+	Error      string    `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (BatchRow) TableName() string {
+	return "cese_batch_row"
+}
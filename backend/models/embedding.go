@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// 向量回填状态
+const (
+	EmbeddingStatusIdle      = "idle"      // 未执行回填
+	EmbeddingStatusRunning   = "running"   // 回填中
+	EmbeddingStatusCompleted = "completed" // 回填完成
+	EmbeddingStatusFailed    = "failed"    // 回填失败，Error 为失败原因
+)
+
+// EmbeddingSetting 用户的向量化设置：使用哪个API Provider的哪个向量模型
+type EmbeddingSetting struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile     string     `json:"-" gorm:"type:varchar(32);not null;uniqueIndex"`
+	ProviderID uint       `json:"provider_id" gorm:"not null"`
+	Model      string     `json:"model" gorm:"type:varchar(100);not null"`
+	Status     string     `json:"status" gorm:"type:varchar(20);not null;default:'idle';index"` // 回填状态
+	Error      string     `json:"error,omitempty" gorm:"type:text"`
+	BackfillAt *time.Time `json:"backfill_at,omitempty"` // 最近一次回填完成时间
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (EmbeddingSetting) TableName() string {
+	return "cese_embedding_setting"
+}
+
+// TemplateEmbedding 模板内容的向量，Vector 为归一化后的 float32 小端序数组
+// ContentHash 为向量化内容与模型的摘要，内容未变化时无需重新向量化
+type TemplateEmbedding struct {
+	TemplateID  uint64    `json:"template_id" gorm:"primaryKey"`
+	Mobile      string    `json:"mobile" gorm:"type:varchar(32);not null;index:idx_mobile_model"`
+	Model       string    `json:"model" gorm:"type:varchar(100);not null;index:idx_mobile_model"`
+	ContentHash string    `json:"content_hash" gorm:"type:char(64);not null"`
+	Dimensions  int       `json:"dimensions" gorm:"not null"`
+	Vector      []byte    `json:"-" gorm:"type:mediumblob;not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (TemplateEmbedding) TableName() string {
+	return "cese_template_embedding"
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Evaluation 提示词评测记录：同一提示词在多个API Provider上的对比结果
+type Evaluation struct {
+	ID              uint               `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile          string             `json:"mobile" gorm:"type:varchar(32);not null;index"`
+	TemplateID      *uint64            `json:"template_id,omitempty" gorm:"index"`
+	Prompt          string             `json:"prompt" gorm:"type:text;not null"`
+	System          string             `json:"system,omitempty" gorm:"type:text"`
+	Criteria        string             `json:"criteria,omitempty" gorm:"type:text"` // 评分标准，默认取模板的交付格式
+	JudgeProviderID *uint              `json:"judge_provider_id,omitempty"`
+	CreatedAt       time.Time          `json:"created_at" gorm:"autoCreateTime;index"`
+	Results         []EvaluationResult `json:"results,omitempty" gorm:"foreignKey:EvaluationID"`
+}
+
+// TableName 指定表名
+func (Evaluation) TableName() string {
+	return "cese_evaluation"
+}
+
+// EvaluationResult 单个API Provider的评测结果
+type EvaluationResult struct {
+	ID               uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	EvaluationID     uint      `json:"evaluation_id" gorm:"not null;index"`
+	ProviderID       uint      `json:"provider_id" gorm:"not null"`
+	ProviderName     string    `json:"provider_name" gorm:"type:varchar(100)"`
+	Model            string    `json:"model" gorm:"type:varchar(100)"`
+	Content          string    `json:"content" gorm:"type:mediumtext"`
+	LatencyMs        int64     `json:"latency_ms"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Error            string    `json:"error,omitempty" gorm:"type:text"`
+	Score            *float64  `json:"score,omitempty"` // 评审模型打分（0-10），未评审时为空
+	ScoreReason      string    `json:"score_reason,omitempty" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (EvaluationResult) TableName() string {
+	return "cese_evaluation_result"
+}
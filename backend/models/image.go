@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Image 生成请求附带的图片
+type Image struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile      string    `json:"mobile" gorm:"type:varchar(32);not null;index"`
+	FileName    string    `json:"file_name" gorm:"type:varchar(255);not null"`
+	MimeType    string    `json:"mime_type" gorm:"type:varchar(50);not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	StoragePath string    `json:"-" gorm:"type:varchar(500);not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (Image) TableName() string {
+	return "cese_image"
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 异步生成任务状态
+const (
+	JobStatusPending   = "pending"   // 排队中
+	JobStatusRunning   = "running"   // 执行中
+	JobStatusCompleted = "completed" // 已完成
+	JobStatusFailed    = "failed"    // 失败
+	JobStatusCanceled  = "canceled"  // 已取消
+)
+
+// Job 异步生成任务
+type Job struct {
+	ID               uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile           string          `json:"mobile" gorm:"type:varchar(32);not null;index"`
+	ProviderID       uint            `json:"provider_id" gorm:"not null"`
+	Status           string          `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	Request          json.RawMessage `json:"-" gorm:"type:mediumtext;not null"` // 生成请求（JSON）
+	Progress         int             `json:"progress"`                          // 已生成的字符数
+	Content          string          `json:"content,omitempty" gorm:"type:mediumtext"`
+	ToolCalls        json.RawMessage `json:"tool_calls,omitempty" gorm:"type:text"`
+	FinishReason     string          `json:"finish_reason,omitempty" gorm:"type:varchar(50)"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	TotalTokens      int             `json:"total_tokens"`
+	Error            string          `json:"error,omitempty" gorm:"type:text"`
+	StartedAt        *time.Time      `json:"started_at,omitempty"`
+	FinishedAt       *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt        time.Time       `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt        time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Job) TableName() string {
+	return "cese_job"
+}
+
+// Finished 任务是否已结束
+func (j *Job) Finished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCanceled
+}
@@ -0,0 +1,113 @@
+package models
+
+import "strings"
+
+// OpenAI BPE编码名称，对应 tiktoken 的词表文件 {encoding}.tiktoken
+const (
+	EncodingCL100K = "cl100k_base" // GPT-4、GPT-3.5
+	EncodingO200K  = "o200k_base"  // GPT-4o、GPT-4.1、o系列
+)
+
+// ModelInfo 模型目录项
+type ModelInfo struct {
+	Prefix        string `json:"prefix"`             // 模型名称前缀（小写），按最长前缀匹配
+	ContextWindow int    `json:"context_window"`     // 上下文窗口（token数），含输入与输出
+	MaxOutput     int    `json:"max_output"`         // 单次最大输出token数，0表示不单独限制
+	Encoding      string `json:"encoding,omitempty"` // OpenAI BPE编码，为空时按字符估算
+}
+
+// ModelCatalog 常用模型的上下文窗口（按厂商分组）
+var ModelCatalog = []ModelInfo{
+	// OpenAI
+	{Prefix: "gpt-3.5-turbo", ContextWindow: 16385, MaxOutput: 4096, Encoding: EncodingCL100K},
+	{Prefix: "gpt-35-turbo", ContextWindow: 16385, MaxOutput: 4096, Encoding: EncodingCL100K},
+	{Prefix: "gpt-4", ContextWindow: 8192, MaxOutput: 8192, Encoding: EncodingCL100K},
+	{Prefix: "gpt-4-32k", ContextWindow: 32768, MaxOutput: 32768, Encoding: EncodingCL100K},
+	{Prefix: "gpt-4-turbo", ContextWindow: 128000, MaxOutput: 4096, Encoding: EncodingCL100K},
+	{Prefix: "gpt-4o", ContextWindow: 128000, MaxOutput: 16384, Encoding: EncodingO200K},
+	{Prefix: "gpt-4.1", ContextWindow: 1047576, MaxOutput: 32768, Encoding: EncodingO200K},
+	{Prefix: "o1", ContextWindow: 200000, MaxOutput: 100000, Encoding: EncodingO200K},
+	{Prefix: "o3", ContextWindow: 200000, MaxOutput: 100000, Encoding: EncodingO200K},
+	{Prefix: "o4-mini", ContextWindow: 200000, MaxOutput: 100000, Encoding: EncodingO200K},
+
+	// Anthropic
+	{Prefix: "claude-3", ContextWindow: 200000, MaxOutput: 4096},
+	{Prefix: "claude-3-5", ContextWindow: 200000, MaxOutput: 8192},
+	{Prefix: "claude-3-7", ContextWindow: 200000, MaxOutput: 64000},
+	{Prefix: "claude-sonnet-4", ContextWindow: 200000, MaxOutput: 64000},
+	{Prefix: "claude-opus-4", ContextWindow: 200000, MaxOutput: 32000},
+
+	// Google
+	{Prefix: "gemini-pro", ContextWindow: 32760, MaxOutput: 8192},
+	{Prefix: "gemini-1.5-pro", ContextWindow: 2097152, MaxOutput: 8192},
+	{Prefix: "gemini-1.5-flash", ContextWindow: 1048576, MaxOutput: 8192},
+	{Prefix: "gemini-2.0", ContextWindow: 1048576, MaxOutput: 8192},
+	{Prefix: "gemini-2.5", ContextWindow: 1048576, MaxOutput: 65536},
+
+	// DeepSeek
+	{Prefix: "deepseek-chat", ContextWindow: 65536, MaxOutput: 8192},
+	{Prefix: "deepseek-reasoner", ContextWindow: 65536, MaxOutput: 8192},
+
+	// 阿里千问
+	{Prefix: "qwen-turbo", ContextWindow: 1000000, MaxOutput: 8192},
+	{Prefix: "qwen-plus", ContextWindow: 131072, MaxOutput: 8192},
+	{Prefix: "qwen-max", ContextWindow: 32768, MaxOutput: 8192},
+	{Prefix: "qwen-long", ContextWindow: 10000000, MaxOutput: 8192},
+
+	// 智谱
+	{Prefix: "glm-4", ContextWindow: 128000, MaxOutput: 4096},
+	{Prefix: "glm-4-flash", ContextWindow: 128000, MaxOutput: 4096},
+
+	// Moonshot
+	{Prefix: "moonshot-v1-8k", ContextWindow: 8192},
+	{Prefix: "moonshot-v1-32k", ContextWindow: 32768},
+	{Prefix: "moonshot-v1-128k", ContextWindow: 131072},
+
+	// 百度千帆
+	{Prefix: "ernie-speed-128k", ContextWindow: 131072, MaxOutput: 4096},
+	{Prefix: "ernie-lite-8k", ContextWindow: 8192, MaxOutput: 2048},
+
+	// 豆包
+	{Prefix: "doubao-pro-32k", ContextWindow: 32768, MaxOutput: 4096},
+	{Prefix: "doubao-lite-32k", ContextWindow: 32768, MaxOutput: 4096},
+	{Prefix: "doubao-pro-128k", ContextWindow: 131072, MaxOutput: 4096},
+
+	// 腾讯混元
+	{Prefix: "hunyuan-pro", ContextWindow: 32768, MaxOutput: 4096},
+	{Prefix: "hunyuan-standard", ContextWindow: 32768, MaxOutput: 2048},
+	{Prefix: "hunyuan-lite", ContextWindow: 262144, MaxOutput: 6144},
+
+	// Ollama 常用模型（默认值，实际以 num_ctx 为准）
+	{Prefix: "llama3", ContextWindow: 8192},
+	{Prefix: "llama3.1", ContextWindow: 131072},
+	{Prefix: "llama3.2", ContextWindow: 131072},
+	{Prefix: "qwen2", ContextWindow: 32768},
+	{Prefix: "mistral", ContextWindow: 32768},
+	{Prefix: "codellama", ContextWindow: 16384},
+}
+
+// LookupModel 按最长前缀在目录中查找模型，extra 中的项优先于内置目录
+// 模型名称忽略大小写，并去除 OpenRouter 的厂商前缀（如 openai/gpt-4o）
+func LookupModel(model string, extra []ModelInfo) (*ModelInfo, bool) {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return nil, false
+	}
+
+	for _, catalog := range [][]ModelInfo{extra, ModelCatalog} {
+		var best *ModelInfo
+		for i := range catalog {
+			prefix := strings.ToLower(catalog[i].Prefix)
+			if prefix != "" && strings.HasPrefix(name, prefix) && (best == nil || len(prefix) > len(best.Prefix)) {
+				best = &catalog[i]
+			}
+		}
+		if best != nil {
+			return best, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 输出过滤器，按以下顺序依次处理生成内容
+const (
+	OutputFilterSensitiveWord = "sensitive_word" // 敏感词替换
+	OutputFilterPIIMask       = "pii_mask"       // 个人信息脱敏（手机号、身份证号、邮箱）
+	OutputFilterMarkdown      = "markdown"       // Markdown规范化
+	OutputFilterTruncate      = "truncate"       // 超长截断
+)
+
+// OutputFilters 支持的输出过滤器
+var OutputFilters = []string{
+	OutputFilterSensitiveWord,
+	OutputFilterPIIMask,
+	OutputFilterMarkdown,
+	OutputFilterTruncate,
+}
+
+// 输出过滤设置来源
+const (
+	OutputFilterSourceDefault  = "default"  // 系统默认配置
+	OutputFilterSourceUser     = "user"     // 用户设置
+	OutputFilterSourceTemplate = "template" // 模板设置
+)
+
+// OutputFilterSetting 输出过滤设置，TemplateID 为0时为用户默认设置
+type OutputFilterSetting struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile     string    `json:"mobile" gorm:"type:varchar(32);not null;uniqueIndex:uk_mobile_template"`
+	TemplateID uint64    `json:"template_id" gorm:"not null;default:0;uniqueIndex:uk_mobile_template"`
+	Filters    string    `json:"filters" gorm:"type:varchar(255);not null"` // 启用的过滤器，逗号分隔
+	MaxLength  int       `json:"max_length"`                                // 截断长度（字符数），0表示使用系统默认
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (OutputFilterSetting) TableName() string {
+	return "cese_output_filter"
+}
+
+// FilterList 启用的过滤器列表
+func (s *OutputFilterSetting) FilterList() []string {
+	if s.Filters == "" {
+		return []string{}
+	}
+	return strings.Split(s.Filters, ",")
+}
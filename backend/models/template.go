@@ -1,25 +1,169 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// 模板可见范围
+const (
+	TemplateVisibilityPrivate = "private" // 仅自己可见
+	TemplateVisibilityShared  = "shared"  // 指定用户可见
+	TemplateVisibilityPublic  = "public"  // 所有人可见，展示在模板广场
 )
 
 // Template 六要素模板模型
 type Template struct {
-	ID             uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
-	Mobile         string    `gorm:"type:varchar(32);not null;index" json:"mobile"`
-	Topic          string    `gorm:"type:varchar(255);not null;index" json:"topic"`
-	TaskObjective  string    `gorm:"type:text" json:"task_objective"`
-	AIRole         string    `gorm:"type:text" json:"ai_role"`
-	MyRole         string    `gorm:"type:text" json:"my_role"`
-	KeyInformation string    `gorm:"type:text" json:"key_information"`
-	BehaviorRule   string    `gorm:"type:text" json:"behavior_rule"`
-	DeliveryFormat string    `gorm:"type:text" json:"delivery_format"`
-	CreatedAt      time.Time `gorm:"autoCreateTime;index" json:"created_at"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID               uint64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Mobile           string            `gorm:"type:varchar(32);not null;index" json:"mobile"`
+	Topic            string            `gorm:"type:varchar(255);not null;index" json:"topic"`
+	TaskObjective    string            `gorm:"type:text" json:"task_objective"`
+	AIRole           string            `gorm:"type:text" json:"ai_role"`
+	MyRole           string            `gorm:"type:text" json:"my_role"`
+	KeyInformation   string            `gorm:"type:text" json:"key_information"`
+	BehaviorRule     string            `gorm:"type:text" json:"behavior_rule"`
+	DeliveryFormat   string            `gorm:"type:text" json:"delivery_format"`
+	Version          int               `gorm:"not null;default:1" json:"version"` // 当前版本号，对应最新的修订记录
+	Visibility       string            `gorm:"type:varchar(16);not null;default:'private';index:idx_visibility_forks" json:"visibility"`
+	ForkCount        int               `gorm:"not null;default:0;index:idx_visibility_forks" json:"fork_count"`     // 被复制次数
+	SourceTemplateID *uint64           `gorm:"index" json:"source_template_id,omitempty"`                           // 复制来源模板
+	SourceAuthor     string            `gorm:"type:varchar(32);not null;default:''" json:"source_author,omitempty"` // 原作者（脱敏手机号）
+	FolderID         *uint64           `gorm:"index" json:"folder_id,omitempty"`                                    // 所在文件夹，为空表示未分类
+	Favorite         bool              `gorm:"not null;default:false" json:"favorite"`                              // 收藏
+	Pinned           bool              `gorm:"not null;default:false" json:"pinned"`                                // 置顶，列表中排在最前
+	Variables        TemplateVariables `gorm:"type:text" json:"variables,omitempty"`                                // 变量定义，要素中以 {{变量名}} 引用
+	CreatedAt        time.Time         `gorm:"autoCreateTime;index" json:"created_at"`
+	UpdatedAt        time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt    `gorm:"index" json:"-"` // 移入回收站的时间，超出保留期后彻底删除
+
+	Tags   []string             `gorm:"-" json:"tags,omitempty"`   // 标签名称，查询自己的模板时由标签关联表填充
+	Search *TemplateSearchMatch `gorm:"-" json:"search,omitempty"` // 全文检索时的相关度与高亮片段
+}
+
+// 模板变量类型
+const (
+	TemplateVariableString  = "string"  // 文本
+	TemplateVariableNumber  = "number"  // 数字
+	TemplateVariableBoolean = "boolean" // 是/否
+	TemplateVariableEnum    = "enum"    // 从 Options 中选择
+)
+
+// TemplateVariable 模板变量定义，Default 为对应类型的默认值
+type TemplateVariable struct {
+	Name        string      `json:"name" yaml:"name"`
+	Type        string      `json:"type" yaml:"type"`
+	Default     interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool        `json:"required" yaml:"required"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Options     []string    `json:"options,omitempty" yaml:"options,omitempty"` // 枚举类型的可选值
+}
+
+// TemplateVariables 模板变量定义列表，以JSON保存，为空时保存为NULL
+type TemplateVariables []TemplateVariable
+
+// Value 实现 driver.Valuer
+func (v TemplateVariables) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner
+func (v *TemplateVariables) Scan(value interface{}) error {
+	var data []byte
+	switch value := value.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("模板变量格式错误")
+	}
+	if len(data) == 0 {
+		*v = nil
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// TemplateSearchMatch 模板的全文检索结果
+type TemplateSearchMatch struct {
+	Score    float64           `json:"score"`
+	Snippets []TemplateSnippet `json:"snippets"`
+}
+
+// TemplateSnippet 命中字段的内容片段，Highlights 为命中词在 Text 中的字符区间 [起始, 结束)
+type TemplateSnippet struct {
+	Field      string   `json:"field"`
+	Label      string   `json:"label"`
+	Text       string   `json:"text"`
+	Highlights [][2]int `json:"highlights"`
 }
 
 // TableName 指定表名
 func (Template) TableName() string {
 	return "cese_template"
 }
+
+// TemplateShare 模板共享记录，可见范围为 shared 时指定的用户可查看与复制
+type TemplateShare struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID uint64    `gorm:"not null;uniqueIndex:uk_template_mobile" json:"template_id"`
+	Mobile     string    `gorm:"type:varchar(32);not null;uniqueIndex:uk_template_mobile;index" json:"mobile"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (TemplateShare) TableName() string {
+	return "cese_template_share"
+}
+
+// TemplateTag 用户的模板标签，同一用户下名称唯一
+type TemplateTag struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Mobile    string    `gorm:"type:varchar(32);not null;uniqueIndex:uk_mobile_name" json:"-"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_mobile_name" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (TemplateTag) TableName() string {
+	return "cese_template_tag"
+}
+
+// TemplateTagLink 模板与标签的多对多关联
+type TemplateTagLink struct {
+	TemplateID uint64 `gorm:"primaryKey"`
+	TagID      uint64 `gorm:"primaryKey;index"`
+}
+
+// TableName 指定表名
+func (TemplateTagLink) TableName() string {
+	return "cese_template_tag_link"
+}
+
+// TemplateFolder 用户的模板文件夹（分类），同一用户下名称唯一
+type TemplateFolder struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Mobile    string    `gorm:"type:varchar(32);not null;uniqueIndex:uk_mobile_name" json:"-"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_mobile_name" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TemplateFolder) TableName() string {
+	return "cese_template_folder"
+}
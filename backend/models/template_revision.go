@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// TemplateRevision 模板修订记录，每次创建、更新或恢复模板时保存一份完整快照，不可修改
+type TemplateRevision struct {
+	ID             uint64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID     uint64            `gorm:"not null;uniqueIndex:uk_template_version" json:"template_id"`
+	Version        int               `gorm:"not null;uniqueIndex:uk_template_version" json:"version"`
+	Mobile         string            `gorm:"type:varchar(32);not null" json:"mobile"` // 修改人
+	Topic          string            `gorm:"type:varchar(255);not null" json:"topic"`
+	TaskObjective  string            `gorm:"type:text" json:"task_objective"`
+	AIRole         string            `gorm:"type:text" json:"ai_role"`
+	MyRole         string            `gorm:"type:text" json:"my_role"`
+	KeyInformation string            `gorm:"type:text" json:"key_information"`
+	BehaviorRule   string            `gorm:"type:text" json:"behavior_rule"`
+	DeliveryFormat string            `gorm:"type:text" json:"delivery_format"`
+	Variables      TemplateVariables `gorm:"type:text" json:"variables,omitempty"`           // 变量定义
+	ChangeNote     string            `gorm:"type:varchar(255)" json:"change_note,omitempty"` // 修改说明
+	CreatedAt      time.Time         `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (TemplateRevision) TableName() string {
+	return "cese_template_revision"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// ModelPrice API Provider的模型价格，按每1K token计价
+// Model 为模型名称前缀，按最长前缀匹配；为空时作为该Provider的默认价格
+type ModelPrice struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProviderID  uint      `json:"provider_id" gorm:"not null;uniqueIndex:uk_provider_model"`
+	Model       string    `json:"model" gorm:"type:varchar(100);not null;default:'';uniqueIndex:uk_provider_model"`
+	InputPrice  float64   `json:"input_price" gorm:"type:decimal(12,6);not null;default:0"`  // 输入价格（每1K token）
+	OutputPrice float64   `json:"output_price" gorm:"type:decimal(12,6);not null;default:0"` // 输出价格（每1K token）
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (ModelPrice) TableName() string {
+	return "cese_model_price"
+}
+
+// GenerationUsage 每次生成的token用量与费用
+type GenerationUsage struct {
+	ID               uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile           string    `json:"mobile" gorm:"type:varchar(32);not null;index:idx_mobile_created"`
+	ProviderID       uint      `json:"provider_id" gorm:"not null"`
+	Model            string    `json:"model" gorm:"type:varchar(100);not null"`
+	TemplateID       uint64    `json:"template_id" gorm:"not null;default:0"` // 0表示未指定模板
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Estimated        bool      `json:"estimated"`                                         // Provider未返回用量时为估算值
+	InputPrice       float64   `json:"input_price" gorm:"type:decimal(12,6);not null"`    // 计费时的输入价格
+	OutputPrice      float64   `json:"output_price" gorm:"type:decimal(12,6);not null"`   // 计费时的输出价格
+	Cost             float64   `json:"cost" gorm:"type:decimal(16,6);not null;default:0"` // 费用，未设置价格时为0
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_mobile_created"`
+}
+
+// TableName 指定表名
+func (GenerationUsage) TableName() string {
+	return "cese_generation_usage"
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventGenerationCompleted = "generation.completed" // 生成完成
+	WebhookEventGenerationFailed    = "generation.failed"    // 生成失败
+	WebhookEventTemplateCreated     = "template.created"     // 模板创建
+	WebhookEventTemplateUpdated     = "template.updated"     // 模板更新
+)
+
+// WebhookEvents 支持订阅的事件类型
+var WebhookEvents = []string{
+	WebhookEventGenerationCompleted,
+	WebhookEventGenerationFailed,
+	WebhookEventTemplateCreated,
+	WebhookEventTemplateUpdated,
+}
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或重试
+	WebhookDeliverySucceeded = "succeeded" // 投递成功
+	WebhookDeliveryFailed    = "failed"    // 重试次数用尽
+)
+
+// Webhook 用户注册的回调地址
+type Webhook struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Mobile      string    `json:"mobile" gorm:"type:varchar(32);not null;index"`
+	URL         string    `json:"url" gorm:"type:varchar(500);not null"`
+	Secret      string    `json:"-" gorm:"type:varchar(255);not null"`      // 签名密钥（加密存储）
+	Events      string    `json:"events" gorm:"type:varchar(255);not null"` // 订阅的事件，逗号分隔
+	Status      int8      `json:"status" gorm:"type:tinyint(1);default:1"`
+	Description string    `json:"description,omitempty" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Webhook) TableName() string {
+	return "cese_webhook"
+}
+
+// EventList 订阅的事件列表
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes 是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookResponse Webhook响应（隐藏签名密钥）
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	Mobile      string    `json:"mobile"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"` // 仅创建或重置密钥时返回明文
+	Events      []string  `json:"events"`
+	Status      int8      `json:"status"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToResponse 转换为响应格式
+func (w *Webhook) ToResponse() *WebhookResponse {
+	return &WebhookResponse{
+		ID:          w.ID,
+		Mobile:      w.Mobile,
+		URL:         w.URL,
+		Events:      w.EventList(),
+		Status:      w.Status,
+		Description: w.Description,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+// WebhookDelivery Webhook投递记录
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	EventID        string     `json:"event_id" gorm:"type:varchar(64);not null"` // 事件ID，重新投递时不变
+	Event          string     `json:"event" gorm:"type:varchar(50);not null"`
+	Payload        string     `json:"payload,omitempty" gorm:"type:mediumtext;not null"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty" gorm:"type:text"`
+	Error          string     `json:"error,omitempty" gorm:"type:text"`
+	DurationMs     int64      `json:"duration_ms"`
+	NextRetryAt    *time.Time `json:"next_retry_at,omitempty" gorm:"index"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "cese_webhook_delivery"
+}
@@ -175,15 +175,15 @@ func UpdateAPIProvider(userMobile string, providerID uint, req *models.APIProvid
 	return db.Model(provider).Updates(updates).Error
 }
 
-// DeleteAPIProvider 删除API Provider
+// DeleteAPIProvider 删除API Provider，移入回收站，保留期内可恢复
 func DeleteAPIProvider(userMobile string, providerID uint) error {
 	provider, err := GetAPIProvider(userMobile, providerID)
 	if err != nil {
 		return err
 	}
 
-	db := config.GetDB()
-	return db.Delete(provider).Error
+	// 移入回收站，模型价格保留到彻底删除时
+	return config.GetDB().Delete(provider).Error
 }
 
 // GetDecryptedAPIKey 获取解密后的API Key（内部使用）
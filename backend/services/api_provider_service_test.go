@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/zsy619/cese-qoder/backend/config"
@@ -235,6 +236,25 @@ func TestDeleteAPIProvider(t *testing.T) {
 	if err == nil {
 		t.Error("GetAPIProvider() after delete should return error")
 	}
+
+	// 从回收站恢复
+	if _, err := RestoreAPIProvider(testMobile, providerID); err != nil {
+		t.Fatalf("RestoreAPIProvider() error = %v", err)
+	}
+	if _, err := GetAPIProvider(testMobile, providerID); err != nil {
+		t.Errorf("GetAPIProvider() after restore error = %v", err)
+	}
+
+	// 再次删除后彻底删除
+	if err := DeleteAPIProvider(testMobile, providerID); err != nil {
+		t.Fatalf("DeleteAPIProvider() error = %v", err)
+	}
+	if err := PurgeAPIProvider(testMobile, providerID); err != nil {
+		t.Fatalf("PurgeAPIProvider() error = %v", err)
+	}
+	if _, err := RestoreAPIProvider(testMobile, providerID); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Errorf("RestoreAPIProvider() after purge error = %v, want ErrTrashItemNotFound", err)
+	}
 }
 
 func TestEncryptionDecryption(t *testing.T) {
@@ -0,0 +1,575 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 批量生成文件格式
+const (
+	BatchFormatCSV   = "csv"
+	BatchFormatJSONL = "jsonl"
+)
+
+// maxTopicLength 主题最大字符数，与 cese_template.topic 一致
+const maxTopicLength = 255
+
+// batchInterruptedMessage 服务重启时未完成批量生成的错误信息
+const batchInterruptedMessage = "服务重启，批量生成中断"
+
+// 批量生成相关错误
+var (
+	ErrBatchNotFound = errors.New("批量生成记录不存在")
+	ErrBatchFormat   = errors.New("仅支持CSV或JSONL文件")
+)
+
+// BatchRequest 批量生成参数
+type BatchRequest struct {
+	ProviderID  uint    `form:"provider_id"`
+	Format      string  `form:"format"`      // 可选：csv / jsonl，默认按文件扩展名判断
+	Concurrency int     `form:"concurrency"` // 可选：并发生成的行数，默认取配置
+	Temperature float32 `form:"temperature"`
+	MaxTokens   int     `form:"max_tokens"`
+}
+
+// batchInput 文件中的一行，Error 非空表示该行无法解析
+type batchInput struct {
+	RowNo    int
+	Template TemplateRequest
+	Error    string
+}
+
+// sixElementStep 六要素中的一个要素及其提示词模板
+type sixElementStep struct {
+	Name   string
+	File   string
+	Column string
+	Field  func(*TemplateRequest) *string
+}
+
+// sixElementSteps 六要素生成顺序，后生成的要素以前面的要素作为上下文
+var sixElementSteps = []sixElementStep{
+	{"任务目标", "提示词-任务目标.md", "task_objective", func(r *TemplateRequest) *string { return &r.TaskObjective }},
+	{"AI的角色", "提示词-AI的角色.md", "ai_role", func(r *TemplateRequest) *string { return &r.AIRole }},
+	{"我的角色", "提示词-我的角色.md", "my_role", func(r *TemplateRequest) *string { return &r.MyRole }},
+	{"关键信息", "提示词-关键信息.md", "key_information", func(r *TemplateRequest) *string { return &r.KeyInformation }},
+	{"行为规则", "提示词-行为规则.md", "behavior_rule", func(r *TemplateRequest) *string { return &r.BehaviorRule }},
+	{"交付格式", "提示词-交付格式.md", "delivery_format", func(r *TemplateRequest) *string { return &r.DeliveryFormat }},
+}
+
+// batchColumn 解析CSV表头对应的字段，支持英文字段名与中文要素名
+func batchColumn(header string) func(*TemplateRequest) *string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	if header == "topic" || header == "主题" {
+		return func(r *TemplateRequest) *string { return &r.Topic }
+	}
+	for _, step := range sixElementSteps {
+		if header == step.Column || header == strings.ToLower(step.Name) {
+			return step.Field
+		}
+	}
+	return nil
+}
+
+// detectBatchFormat 确定文件格式：优先使用指定的格式，否则按扩展名判断
+func detectBatchFormat(fileName, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+	switch strings.ToLower(format) {
+	case BatchFormatCSV:
+		return BatchFormatCSV, nil
+	case BatchFormatJSONL:
+		return BatchFormatJSONL, nil
+	}
+	return "", ErrBatchFormat
+}
+
+// parseBatchFile 解析批量生成文件，单行格式错误记录在该行中，不影响其他行
+func parseBatchFile(format string, data []byte) ([]batchInput, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // 兼容Excel导出的UTF-8 BOM
+
+	var inputs []batchInput
+	var err error
+	if format == BatchFormatCSV {
+		inputs, err = parseBatchCSV(data)
+	} else {
+		inputs, err = parseBatchJSONL(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("文件中没有数据")
+	}
+
+	for i := range inputs {
+		if inputs[i].Error != "" {
+			continue
+		}
+		inputs[i].Error = validateBatchTemplate(&inputs[i].Template)
+	}
+	return inputs, nil
+}
+
+// parseBatchCSV 解析CSV文件，第一行为表头，必须包含主题列
+func parseBatchCSV(data []byte) ([]batchInput, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV文件缺少表头")
+	}
+	columns := make([]func(*TemplateRequest) *string, len(header))
+	hasTopic := false
+	for i, name := range header {
+		columns[i] = batchColumn(name)
+		if name := strings.ToLower(strings.TrimSpace(name)); name == "topic" || name == "主题" {
+			hasTopic = true
+		}
+	}
+	if !hasTopic {
+		return nil, errors.New("CSV文件缺少主题列（topic）")
+	}
+
+	var inputs []batchInput
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			inputs = append(inputs, batchInput{RowNo: parseErr.StartLine, Error: "CSV格式错误"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		input := batchInput{RowNo: line}
+		for i, value := range record {
+			if i < len(columns) && columns[i] != nil {
+				*columns[i](&input.Template) = strings.TrimSpace(value)
+			}
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// isBlankRecord 判断CSV行是否为空行
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBatchJSONL 解析JSONL文件，每行一个JSON对象，字段与模板接口一致，空行忽略
+func parseBatchJSONL(data []byte) ([]batchInput, error) {
+	var inputs []batchInput
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		input := batchInput{RowNo: lineNo}
+		if err := json.Unmarshal([]byte(line), &input.Template); err != nil {
+			input.Error = "JSON格式错误"
+		} else {
+			trimTemplateRequest(&input.Template)
+		}
+		inputs = append(inputs, input)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// trimTemplateRequest 去除主题与各要素首尾空白
+func trimTemplateRequest(r *TemplateRequest) {
+	r.Topic = strings.TrimSpace(r.Topic)
+	for _, step := range sixElementSteps {
+		field := step.Field(r)
+		*field = strings.TrimSpace(*field)
+	}
+}
+
+// validateBatchTemplate 校验单行数据，返回错误信息
+func validateBatchTemplate(r *TemplateRequest) string {
+	if r.Topic == "" {
+		return "主题不能为空"
+	}
+	if utf8.RuneCountInString(r.Topic) > maxTopicLength {
+		return fmt.Sprintf("主题不能超过%d个字符", maxTopicLength)
+	}
+	if err := ScreenTemplateInput(r); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// loadSixElementPrompts 读取六要素提示词模板
+func loadSixElementPrompts(dir string) (map[string]string, error) {
+	prompts := make(map[string]string, len(sixElementSteps))
+	for _, step := range sixElementSteps {
+		data, err := os.ReadFile(filepath.Join(dir, step.File))
+		if err != nil {
+			return nil, fmt.Errorf("读取提示词模板失败: %s", step.File)
+		}
+		prompts[step.File] = string(data)
+	}
+	return prompts, nil
+}
+
+// buildSixElementPrompt 用主题及已有要素替换提示词模板中的占位符
+// 占位符与前端 promptTemplates.ts 一致
+func buildSixElementPrompt(prompt string, r *TemplateRequest) string {
+	return strings.NewReplacer(
+		"{{topic}}", r.Topic,
+		"{{task}}", r.TaskObjective,
+		"{{ai_role}}", r.AIRole,
+		"{{my_role}}", r.MyRole,
+		"{{key_info}}", r.KeyInformation,
+		"{{behavior}}", r.BehaviorRule,
+	).Replace(prompt)
+}
+
+// generateSixElements 依次生成未填写的要素
+func generateSixElements(ctx context.Context, provider *models.APIProvider, prompts map[string]string, r *TemplateRequest, opts GenerateRequest) error {
+	for _, step := range sixElementSteps {
+		field := step.Field(r)
+		if *field != "" {
+			continue
+		}
+
+		req := opts
+		req.Prompt = buildSixElementPrompt(prompts[step.File], r)
+		result, err := Generate(ctx, provider, req)
+		if err != nil {
+			return fmt.Errorf("生成%s失败: %v", step.Name, err)
+		}
+		*field = strings.TrimSpace(FilterOutput(req.User, 0, result.Content))
+		if *field == "" {
+			return fmt.Errorf("生成%s失败: 返回内容为空", step.Name)
+		}
+	}
+	return nil
+}
+
+// CreateBatch 解析上传的文件并创建批量生成记录，在后台逐行生成六要素并保存为模板
+func CreateBatch(userMobile, fileName string, data []byte, req *BatchRequest) (*models.Batch, error) {
+	cfg := config.GetConfig().Batch
+	if len(data) > cfg.MaxFileSize<<20 {
+		return nil, fmt.Errorf("文件大小不能超过%dMB", cfg.MaxFileSize)
+	}
+
+	format, err := detectBatchFormat(fileName, req.Format)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := parseBatchFile(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) > cfg.MaxRows {
+		return nil, fmt.Errorf("单个文件最多%d行", cfg.MaxRows)
+	}
+
+	provider, err := GetAPIProvider(userMobile, req.ProviderID)
+	if err != nil {
+		return nil, errors.New("API Provider不存在")
+	}
+	if err := CheckGenerateProvider(provider, GenerateRequest{}); err != nil {
+		return nil, err
+	}
+	prompts, err := loadSixElementPrompts(cfg.PromptDir)
+	if err != nil {
+		utils.Error("加载六要素提示词模板失败", zap.String("dir", cfg.PromptDir), zap.Error(err))
+		return nil, err
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = cfg.Concurrency
+	}
+	concurrency = min(max(concurrency, 1), max(cfg.MaxConcurrency, 1))
+
+	batch := &models.Batch{
+		Mobile:      userMobile,
+		ProviderID:  provider.ID,
+		FileName:    filepath.Base(fileName),
+		Format:      format,
+		Status:      models.BatchStatusPending,
+		Concurrency: concurrency,
+		Total:       len(inputs),
+	}
+	for _, input := range inputs {
+		row := models.BatchRow{RowNo: input.RowNo, Topic: truncateRunes(input.Template.Topic, maxTopicLength), Status: models.BatchStatusPending}
+		if input.Error != "" {
+			row.Status = models.BatchStatusFailed
+			row.Error = input.Error
+			batch.Failed++
+		}
+		batch.Rows = append(batch.Rows, row)
+	}
+	if err := config.GetDB().Create(batch).Error; err != nil {
+		return nil, err
+	}
+
+	utils.Info("开始批量生成",
+		zap.Uint("batch_id", batch.ID),
+		zap.Uint("provider_id", provider.ID),
+		zap.Int("total", batch.Total),
+		zap.Int("concurrency", concurrency))
+
+	opts := GenerateRequest{Temperature: req.Temperature, MaxTokens: req.MaxTokens, User: userMobile}
+	go runBatch(*batch, provider, prompts, inputs, opts)
+	return batch, nil
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// runBatch 按并发数逐行生成六要素并保存为模板，单行失败不影响其他行
+func runBatch(batch models.Batch, provider *models.APIProvider, prompts map[string]string, inputs []batchInput, opts GenerateRequest) {
+	db := config.GetDB()
+	db.Model(&models.Batch{}).Where("id = ?", batch.ID).Update("status", models.BatchStatusRunning)
+
+	ctx := context.Background()
+	templateService := &TemplateService{}
+	var (
+		mu        sync.Mutex
+		succeeded int
+		failed    = batch.Failed
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, batch.Concurrency)
+	)
+	for i, input := range inputs {
+		if input.Error != "" {
+			continue
+		}
+		row := batch.Rows[i]
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(input batchInput) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			fields := map[string]interface{}{"status": models.BatchStatusCompleted}
+			template := input.Template
+			err := generateSixElements(ctx, provider, prompts, &template, opts)
+			if err == nil {
+				var saved *models.Template
+				if saved, err = templateService.CreateTemplate(batch.Mobile, &template); err == nil {
+					fields["template_id"] = saved.ID
+				}
+			}
+			if err != nil {
+				utils.Warn("批量生成单行失败", zap.Uint("batch_id", batch.ID), zap.Int("row_no", input.RowNo), zap.Error(err))
+				fields = map[string]interface{}{"status": models.BatchStatusFailed, "error": err.Error()}
+			}
+			if dbErr := db.Model(&models.BatchRow{}).Where("id = ?", row.ID).Updates(fields).Error; dbErr != nil {
+				utils.Error("更新批量生成结果失败", zap.Uint("row_id", row.ID), zap.Error(dbErr))
+			}
+
+			mu.Lock()
+			if err != nil {
+				failed++
+			} else {
+				succeeded++
+			}
+			mu.Unlock()
+		}(input)
+	}
+	wg.Wait()
+
+	if err := db.Model(&models.Batch{}).Where("id = ?", batch.ID).Updates(map[string]interface{}{
+		"status":      models.BatchStatusCompleted,
+		"succeeded":   succeeded,
+		"failed":      failed,
+		"finished_at": time.Now(),
+	}).Error; err != nil {
+		utils.Error("更新批量生成状态失败", zap.Uint("batch_id", batch.ID), zap.Error(err))
+	}
+	utils.Info("批量生成完成", zap.Uint("batch_id", batch.ID), zap.Int("succeeded", succeeded), zap.Int("failed", failed))
+}
+
+// RecoverBatches 服务启动时将上次未完成的批量生成标记为失败
+// 已保存的模板保留，未生成的行标记为失败
+func RecoverBatches() error {
+	return config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.Batch{}).Where("status IN ?", []string{models.BatchStatusPending, models.BatchStatusRunning}).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		utils.Warn("批量生成因服务重启中断", zap.Int("count", len(ids)))
+
+		if err := tx.Model(&models.BatchRow{}).Where("batch_id IN ? AND status = ?", ids, models.BatchStatusPending).Updates(map[string]interface{}{
+			"status": models.BatchStatusFailed,
+			"error":  batchInterruptedMessage,
+		}).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			var succeeded, failed int64
+			tx.Model(&models.BatchRow{}).Where("batch_id = ? AND status = ?", id, models.BatchStatusCompleted).Count(&succeeded)
+			tx.Model(&models.BatchRow{}).Where("batch_id = ? AND status = ?", id, models.BatchStatusFailed).Count(&failed)
+			if err := tx.Model(&models.Batch{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"status":      models.BatchStatusFailed,
+				"error":       batchInterruptedMessage,
+				"succeeded":   succeeded,
+				"failed":      failed,
+				"finished_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetBatch 获取批量生成记录及各行结果
+func GetBatch(userMobile string, batchID uint) (*models.Batch, error) {
+	var batch models.Batch
+	if err := config.GetDB().Preload("Rows", func(db *gorm.DB) *gorm.DB {
+		return db.Order("row_no ASC")
+	}).Where("id = ? AND mobile = ?", batchID, userMobile).First(&batch).Error; err != nil {
+		return nil, ErrBatchNotFound
+	}
+
+	// 生成中的记录实时统计已完成的行数
+	if batch.Status == models.BatchStatusRunning {
+		batch.Succeeded, batch.Failed = 0, 0
+		for _, row := range batch.Rows {
+			switch row.Status {
+			case models.BatchStatusCompleted:
+				batch.Succeeded++
+			case models.BatchStatusFailed:
+				batch.Failed++
+			}
+		}
+	}
+	return &batch, nil
+}
+
+// ListBatches 分页查询用户的批量生成记录（不含各行结果）
+func ListBatches(userMobile string, page, pageSize int) ([]models.Batch, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.GetDB().Model(&models.Batch{}).Where("mobile = ?", userMobile)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var batches []models.Batch
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&batches).Error; err != nil {
+		return nil, 0, err
+	}
+	return batches, total, nil
+}
+
+// batchReportRow 报告中的单行
+type batchReportRow struct {
+	RowNo      int     `json:"row_no"`
+	Topic      string  `json:"topic"`
+	Status     string  `json:"status"`
+	TemplateID *uint64 `json:"template_id,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// BuildBatchReport 生成批量生成结果报告，返回文件内容、Content-Type 与文件名
+// CSV 带 UTF-8 BOM 以便 Excel 正确识别中文
+func BuildBatchReport(batch *models.Batch, format string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	fileName := fmt.Sprintf("batch-%d-report.%s", batch.ID, format)
+
+	switch format {
+	case BatchFormatCSV:
+		buf.WriteString("\xef\xbb\xbf")
+		writer := csv.NewWriter(&buf)
+		writer.Write([]string{"row_no", "topic", "status", "template_id", "error"})
+		for _, row := range batch.Rows {
+			templateID := ""
+			if row.TemplateID != nil {
+				templateID = strconv.FormatUint(*row.TemplateID, 10)
+			}
+			writer.Write([]string{strconv.Itoa(row.RowNo), row.Topic, row.Status, templateID, row.Error})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "text/csv; charset=utf-8", fileName, nil
+	case BatchFormatJSONL:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		for _, row := range batch.Rows {
+			if err := encoder.Encode(batchReportRow{
+				RowNo:      row.RowNo,
+				Topic:      row.Topic,
+				Status:     row.Status,
+				TemplateID: row.TemplateID,
+				Error:      row.Error,
+			}); err != nil {
+				return nil, "", "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson; charset=utf-8", fileName, nil
+	}
+	return nil, "", "", ErrBatchFormat
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestDetectBatchFormat(t *testing.T) {
+	tests := []struct {
+		fileName string
+		format   string
+		want     string
+		wantErr  bool
+	}{
+		{"topics.csv", "", BatchFormatCSV, false},
+		{"topics.JSONL", "", BatchFormatJSONL, false},
+		{"topics.txt", "jsonl", BatchFormatJSONL, false},
+		{"topics.xlsx", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := detectBatchFormat(tt.fileName, tt.format)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("detectBatchFormat(%q, %q) = %q, %v", tt.fileName, tt.format, got, err)
+		}
+	}
+}
+
+func TestParseBatchCSV(t *testing.T) {
+	data := "\xef\xbb\xbf主题,task_objective,交付格式,备注\n" +
+		"产品发布会策划,,,忽略\n" +
+		",,,\n" +
+		"  ,目标,,\n" +
+		"周报生成, 总结本周工作 ,Markdown表格\n"
+
+	inputs, err := parseBatchFile(BatchFormatCSV, []byte(data))
+	if err != nil {
+		t.Fatalf("parseBatchFile() error = %v", err)
+	}
+	if len(inputs) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(inputs), inputs)
+	}
+	if inputs[0].RowNo != 2 || inputs[0].Template.Topic != "产品发布会策划" || inputs[0].Error != "" {
+		t.Errorf("unexpected first row: %+v", inputs[0])
+	}
+	if inputs[1].RowNo != 4 || inputs[1].Error != "主题不能为空" {
+		t.Errorf("unexpected second row: %+v", inputs[1])
+	}
+	second := inputs[2].Template
+	if inputs[2].RowNo != 5 || second.TaskObjective != "总结本周工作" || second.DeliveryFormat != "Markdown表格" {
+		t.Errorf("unexpected third row: %+v", inputs[2])
+	}
+
+	if _, err := parseBatchFile(BatchFormatCSV, []byte("name\n产品\n")); err == nil {
+		t.Error("parseBatchFile() expected error without topic column")
+	}
+	if _, err := parseBatchFile(BatchFormatCSV, []byte("topic\n")); err == nil {
+		t.Error("parseBatchFile() expected error for empty file")
+	}
+}
+
+func TestParseBatchJSONL(t *testing.T) {
+	data := `{"topic": "产品发布会策划"}
+
+{"topic": "周报生成", "ai_role": " 项目经理 "}
+not json
+{"topic": "` + strings.Repeat("长", maxTopicLength+1) + `"}
+`
+	inputs, err := parseBatchFile(BatchFormatJSONL, []byte(data))
+	if err != nil {
+		t.Fatalf("parseBatchFile() error = %v", err)
+	}
+	if len(inputs) != 4 {
+		t.Fatalf("got %d rows, want 4", len(inputs))
+	}
+	if inputs[1].RowNo != 3 || inputs[1].Template.AIRole != "项目经理" {
+		t.Errorf("unexpected second row: %+v", inputs[1])
+	}
+	if inputs[2].RowNo != 4 || inputs[2].Error != "JSON格式错误" {
+		t.Errorf("unexpected third row: %+v", inputs[2])
+	}
+	if inputs[3].Error == "" {
+		t.Error("expected error for topic exceeding max length")
+	}
+}
+
+func TestBuildSixElementPrompt(t *testing.T) {
+	prompt := "- 主题：{{topic}}\n- 任务目标：{{task}}\n- 关键信息：{{key_info}}"
+	got := buildSixElementPrompt(prompt, &TemplateRequest{Topic: "周报", TaskObjective: "总结", KeyInformation: "本周"})
+	if got != "- 主题：周报\n- 任务目标：总结\n- 关键信息：本周" {
+		t.Errorf("buildSixElementPrompt() = %q", got)
+	}
+}
+
+func TestLoadSixElementPrompts(t *testing.T) {
+	prompts, err := loadSixElementPrompts("../../frontend/public/docs")
+	if err != nil {
+		t.Fatalf("loadSixElementPrompts() error = %v", err)
+	}
+	for _, step := range sixElementSteps {
+		if !strings.Contains(prompts[step.File], "{{topic}}") {
+			t.Errorf("prompt %s missing topic placeholder", step.File)
+		}
+	}
+
+	if _, err := loadSixElementPrompts(t.TempDir()); err == nil {
+		t.Error("loadSixElementPrompts() expected error for missing files")
+	}
+}
+
+func TestGenerateSixElements(t *testing.T) {
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":" 生成%d "},"finish_reason":"stop"}]}`, len(prompts))
+	}))
+	defer server.Close()
+
+	templates := map[string]string{}
+	for _, step := range sixElementSteps {
+		templates[step.File] = step.Name + ":{{topic}}|{{task}}"
+	}
+	provider := &models.APIProvider{APIKind: models.APIKindOpenAICompatible, APIURL: server.URL, APIModel: "gpt-4o", APIStatus: 1}
+	r := &TemplateRequest{Topic: "周报", AIRole: "项目经理"}
+
+	if err := generateSixElements(context.Background(), provider, templates, r, GenerateRequest{}); err != nil {
+		t.Fatalf("generateSixElements() error = %v", err)
+	}
+	if len(prompts) != 5 {
+		t.Fatalf("got %d calls, want 5 (pre-filled ai_role skipped)", len(prompts))
+	}
+	if prompts[0] != "任务目标:周报|" || prompts[1] != "我的角色:周报|生成1" {
+		t.Errorf("unexpected prompts: %q", prompts[:2])
+	}
+	if r.TaskObjective != "生成1" || r.AIRole != "项目经理" || r.DeliveryFormat != "生成5" {
+		t.Errorf("unexpected template: %+v", r)
+	}
+}
+
+func TestBuildBatchReport(t *testing.T) {
+	templateID := uint64(9)
+	batch := &models.Batch{ID: 3, Rows: []models.BatchRow{
+		{RowNo: 2, Topic: "周报", Status: models.BatchStatusCompleted, TemplateID: &templateID},
+		{RowNo: 3, Topic: "", Status: models.BatchStatusFailed, Error: "主题不能为空"},
+	}}
+
+	data, contentType, fileName, err := BuildBatchReport(batch, BatchFormatCSV)
+	if err != nil {
+		t.Fatalf("BuildBatchReport() error = %v", err)
+	}
+	want := "\xef\xbb\xbfrow_no,topic,status,template_id,error\n2,周报,completed,9,\n3,,failed,,主题不能为空\n"
+	if string(data) != want || !strings.HasPrefix(contentType, "text/csv") || fileName != "batch-3-report.csv" {
+		t.Errorf("unexpected csv report: %q %s %s", data, contentType, fileName)
+	}
+
+	data, _, _, err = BuildBatchReport(batch, BatchFormatJSONL)
+	if err != nil {
+		t.Fatalf("BuildBatchReport() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || lines[0] != `{"row_no":2,"topic":"周报","status":"completed","template_id":9}` {
+		t.Errorf("unexpected jsonl report: %q", data)
+	}
+
+	if _, _, _, err := BuildBatchReport(batch, "xlsx"); err == nil {
+		t.Error("BuildBatchReport() expected error for unsupported format")
+	}
+}
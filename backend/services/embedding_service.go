@@ -0,0 +1,659 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 相似模板查询的返回条数
+const (
+	defaultSimilarTemplates = 10
+	maxSimilarTemplates     = 50
+)
+
+var (
+	ErrEmbeddingNotConfigured   = errors.New("尚未配置向量模型")
+	ErrEmbeddingUnsupported     = errors.New("该模型类型不支持向量化")
+	ErrEmbeddingBackfillRunning = errors.New("向量回填正在进行中")
+	ErrEmbeddingQuery           = fmt.Errorf("检索内容不能为空且不超过%d个字符", maxSearchQueryLength)
+)
+
+// EmbeddingSettingRequest 设置向量模型请求，模型为空时使用该模型类型的第一个常用向量模型
+type EmbeddingSettingRequest struct {
+	ProviderID uint   `json:"provider_id" binding:"required"`
+	Model      string `json:"model"`
+}
+
+// EmbeddingStatus 向量设置及回填进度
+type EmbeddingStatus struct {
+	models.EmbeddingSetting
+	TemplateCount int64 `json:"template_count"` // 模板总数
+	EmbeddedCount int64 `json:"embedded_count"` // 已按当前模型向量化的模板数
+}
+
+// SimilarTemplate 语义相似的模板，Similarity 为余弦相似度
+type SimilarTemplate struct {
+	models.Template
+	Similarity float64 `json:"similarity"`
+}
+
+// similarHit 相似度排序结果
+type similarHit struct {
+	TemplateID uint64
+	Similarity float64
+}
+
+var (
+	// embeddingBackfills 正在回填的用户，同一用户同时只运行一个回填
+	embeddingBackfills   = make(map[string]bool)
+	embeddingBackfillsMu sync.Mutex
+
+	embeddingSemOnce sync.Once
+	embeddingSem     chan struct{}
+)
+
+// embeddingConfig 返回向量化配置，未配置的项使用默认值
+func embeddingConfig() config.EmbeddingConfig {
+	cfg := config.GetConfig().Embedding
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 16
+	}
+	if cfg.MaxInputLength <= 0 {
+		cfg.MaxInputLength = 6000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	return cfg
+}
+
+// supportsEmbedding 判断模型类型是否支持向量化
+func supportsEmbedding(kind string) bool {
+	info, ok := models.GetAPIKindInfo(kind)
+	return ok && len(info.EmbeddingModels) > 0
+}
+
+// GetEmbeddingStatus 获取用户的向量设置及回填进度
+func GetEmbeddingStatus(userMobile string) (*EmbeddingStatus, error) {
+	setting, err := getEmbeddingSetting(userMobile)
+	if err != nil {
+		return nil, err
+	}
+	status := &EmbeddingStatus{EmbeddingSetting: *setting}
+	if err := config.DB.Model(&models.Template{}).Where("mobile = ?", userMobile).Count(&status.TemplateCount).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Model(&models.TemplateEmbedding{}).Where("mobile = ? AND model = ?", userMobile, setting.Model).Count(&status.EmbeddedCount).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SaveEmbeddingSetting 设置向量模型，更换模型时删除旧模型生成的向量
+func SaveEmbeddingSetting(userMobile string, req *EmbeddingSettingRequest) (*models.EmbeddingSetting, error) {
+	if isEmbeddingBackfillRunning(userMobile) {
+		return nil, ErrEmbeddingBackfillRunning
+	}
+	provider, err := GetAPIProvider(userMobile, req.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := models.GetAPIKindInfo(provider.APIKind)
+	if !ok || len(info.EmbeddingModels) == 0 {
+		return nil, ErrEmbeddingUnsupported
+	}
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		model = info.EmbeddingModels[0]
+	}
+
+	var setting models.EmbeddingSetting
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mobile = ?", userMobile).First(&setting).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			setting = models.EmbeddingSetting{Mobile: userMobile, Status: models.EmbeddingStatusIdle}
+		}
+		if setting.Model != "" && setting.Model != model {
+			if err := tx.Where("mobile = ?", userMobile).Delete(&models.TemplateEmbedding{}).Error; err != nil {
+				return err
+			}
+			setting.BackfillAt = nil
+		}
+		setting.ProviderID = provider.ID
+		setting.Model = model
+		return tx.Save(&setting).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存失败: %w", err)
+	}
+	return &setting, nil
+}
+
+// DeleteEmbeddingSetting 关闭向量化并删除已生成的向量
+func DeleteEmbeddingSetting(userMobile string) error {
+	if isEmbeddingBackfillRunning(userMobile) {
+		return ErrEmbeddingBackfillRunning
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mobile = ?", userMobile).Delete(&models.TemplateEmbedding{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("mobile = ?", userMobile).Delete(&models.EmbeddingSetting{}).Error
+	})
+}
+
+// getEmbeddingSetting 查询用户的向量设置
+func getEmbeddingSetting(userMobile string) (*models.EmbeddingSetting, error) {
+	var setting models.EmbeddingSetting
+	if err := config.DB.Where("mobile = ?", userMobile).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmbeddingNotConfigured
+		}
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// embeddingProvider 查询向量设置及其对应的Provider
+func embeddingProvider(userMobile string) (*models.EmbeddingSetting, *models.APIProvider, error) {
+	setting, err := getEmbeddingSetting(userMobile)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider, err := GetAPIProvider(userMobile, setting.ProviderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !supportsEmbedding(provider.APIKind) {
+		return nil, nil, ErrEmbeddingUnsupported
+	}
+	return setting, provider, nil
+}
+
+// scheduleTemplateEmbedding 模板保存后在后台重新向量化，未配置向量模型时忽略
+func scheduleTemplateEmbedding(template *models.Template) {
+	embeddingSemOnce.Do(func() {
+		embeddingSem = make(chan struct{}, embeddingConfig().Concurrency)
+	})
+	snapshot := *template
+	go func() {
+		embeddingSem <- struct{}{}
+		defer func() { <-embeddingSem }()
+
+		setting, provider, err := embeddingProvider(snapshot.Mobile)
+		if err != nil {
+			if !errors.Is(err, ErrEmbeddingNotConfigured) {
+				utils.Warn("模板向量化跳过", zap.Uint64("template_id", snapshot.ID), zap.Error(err))
+			}
+			return
+		}
+		if _, err := embedTemplates(context.Background(), setting.Model, provider, []models.Template{snapshot}); err != nil {
+			utils.Warn("模板向量化失败", zap.Uint64("template_id", snapshot.ID), zap.Error(err))
+		}
+	}()
+}
+
+// embedTemplates 向量化模板并保存，内容与模型均未变化的模板跳过，返回实际向量化的数量
+func embedTemplates(ctx context.Context, model string, provider *models.APIProvider, templates []models.Template) (int, error) {
+	if len(templates) == 0 {
+		return 0, nil
+	}
+	cfg := embeddingConfig()
+
+	ids := make([]uint64, len(templates))
+	for i := range templates {
+		ids[i] = templates[i].ID
+	}
+	var existing []models.TemplateEmbedding
+	if err := config.DB.Select("template_id", "content_hash").Where("template_id IN ?", ids).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	hashes := make(map[uint64]string, len(existing))
+	for _, e := range existing {
+		hashes[e.TemplateID] = e.ContentHash
+	}
+
+	var pending []models.TemplateEmbedding
+	var texts []string
+	for i := range templates {
+		text := embeddingContent(&templates[i], cfg.MaxInputLength)
+		hash := embeddingHash(model, text)
+		if hashes[templates[i].ID] == hash {
+			continue
+		}
+		pending = append(pending, models.TemplateEmbedding{
+			TemplateID:  templates[i].ID,
+			Mobile:      templates[i].Mobile,
+			Model:       model,
+			ContentHash: hash,
+		})
+		texts = append(texts, text)
+	}
+
+	for start := 0; start < len(pending); start += cfg.BatchSize {
+		end := min(start+cfg.BatchSize, len(pending))
+		vectors, err := embedTexts(ctx, provider, model, texts[start:end])
+		if err != nil {
+			return start, err
+		}
+		for i, vector := range vectors {
+			row := &pending[start+i]
+			normalizeVector(vector)
+			row.Dimensions = len(vector)
+			row.Vector = encodeVector(vector)
+			// 模板可能已被删除，只为仍存在的模板保存向量
+			var count int64
+			if err := config.DB.Model(&models.Template{}).Where("id = ?", row.TemplateID).Count(&count).Error; err != nil {
+				return start, err
+			}
+			if count == 0 {
+				continue
+			}
+			if err := config.DB.Save(row).Error; err != nil {
+				return start, err
+			}
+		}
+	}
+	return len(pending), nil
+}
+
+// embedQuery 向量化检索内容
+func embedQuery(ctx context.Context, provider *models.APIProvider, model, q string) ([]float32, error) {
+	vectors, err := embedTexts(ctx, provider, model, []string{q})
+	if err != nil {
+		return nil, err
+	}
+	normalizeVector(vectors[0])
+	return vectors[0], nil
+}
+
+// embedTexts 调用Provider的向量接口，返回与 texts 一一对应的向量
+// Ollama原生模式调用 /api/embeddings（每次一条），其余使用OpenAI格式的 /embeddings
+func embedTexts(ctx context.Context, provider *models.APIProvider, model string, texts []string) ([][]float32, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(embeddingConfig().Timeout)*time.Second)
+	defer cancel()
+
+	apiKey := strings.TrimSpace(provider.APIKey)
+	apiURL := buildEmbeddingURL(provider)
+	if isOllamaNative(provider) {
+		vectors := make([][]float32, 0, len(texts))
+		for _, text := range texts {
+			body, err := postEmbedding(ctx, provider, apiKey, apiURL, map[string]string{"model": model, "prompt": text})
+			if err != nil {
+				return nil, err
+			}
+			vector, err := parseOllamaEmbedding(body)
+			if err != nil {
+				return nil, err
+			}
+			vectors = append(vectors, vector)
+		}
+		return vectors, nil
+	}
+
+	body, err := postEmbedding(ctx, provider, apiKey, apiURL, map[string]interface{}{"model": model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	return parseOpenAIEmbeddings(body, len(texts))
+}
+
+// isOllamaNative 判断是否为Ollama原生模式（api_url 不含 /v1）
+func isOllamaNative(provider *models.APIProvider) bool {
+	return provider.APIKind == models.APIKindOllama && !strings.Contains(provider.APIURL, "/v1")
+}
+
+// buildEmbeddingURL 构建向量接口URL
+func buildEmbeddingURL(provider *models.APIProvider) string {
+	baseURL := strings.TrimRight(provider.APIURL, "/")
+	if isOllamaNative(provider) {
+		return baseURL + "/api/embeddings"
+	}
+	return baseURL + "/embeddings"
+}
+
+// postEmbedding 发送向量请求并返回响应体
+func postEmbedding(ctx context.Context, provider *models.APIProvider, apiKey, apiURL string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("创建请求失败")
+	}
+	if err := setRequestHeaders(ctx, httpReq, provider, apiKey); err != nil {
+		return nil, fmt.Errorf("认证失败: %v", err)
+	}
+
+	resp, err := newGenerateClient(ctx).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("API调用失败: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Warn("关闭响应体失败", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New("读取响应失败")
+	}
+	if resp.StatusCode != http.StatusOK {
+		utils.Error("向量接口返回错误状态码", zap.Int("status", resp.StatusCode), zap.String("body", string(body)), zap.String("api_url", apiURL))
+		return nil, errors.New(describeAPIError(provider, resp.StatusCode, body, apiURL))
+	}
+	return body, nil
+}
+
+// parseOpenAIEmbeddings 解析OpenAI格式的向量响应，按 index 还原输入顺序
+func parseOpenAIEmbeddings(body []byte, n int) ([][]float32, error) {
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析向量响应失败: %v", err)
+	}
+	if len(resp.Data) != n {
+		return nil, fmt.Errorf("向量数量不匹配: 期望%d个，返回%d个", n, len(resp.Data))
+	}
+	vectors := make([][]float32, n)
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= n || len(item.Embedding) == 0 {
+			return nil, errors.New("向量响应格式错误")
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for _, vector := range vectors {
+		if vector == nil {
+			return nil, errors.New("向量响应格式错误")
+		}
+	}
+	return vectors, nil
+}
+
+// parseOllamaEmbedding 解析Ollama原生 /api/embeddings 响应
+func parseOllamaEmbedding(body []byte) ([]float32, error) {
+	var resp struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析向量响应失败: %v", err)
+	}
+	if len(resp.Embedding) == 0 {
+		return nil, errors.New("向量响应为空，请确认模型支持向量化")
+	}
+	return resp.Embedding, nil
+}
+
+// embeddingContent 参与向量化的模板内容：标准Markdown格式，超出长度时截断
+func embeddingContent(template *models.Template, maxLength int) string {
+	content := BuildTemplateMarkdown(template)
+	if utf8.RuneCountInString(content) > maxLength {
+		content = string([]rune(content)[:maxLength])
+	}
+	return content
+}
+
+// embeddingHash 向量化内容与模型的摘要
+func embeddingHash(model, content string) string {
+	sum := sha256.Sum256([]byte(model + "\n" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeVector 将向量归一化为单位长度，归一化后余弦相似度即点积
+func normalizeVector(vector []float32) {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+}
+
+// encodeVector 以 float32 小端序编码向量
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+// decodeVector 解码 encodeVector 编码的向量
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
+
+// dotProduct 计算两个等长向量的点积，长度不同时返回 0
+func dotProduct(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// rankBySimilarity 按与 query 的相似度降序排列，排除 excludeID，返回前 limit 条
+func rankBySimilarity(query []float32, rows []models.TemplateEmbedding, excludeID uint64, limit int) []similarHit {
+	hits := make([]similarHit, 0, len(rows))
+	for _, row := range rows {
+		if row.TemplateID == excludeID || row.Dimensions != len(query) {
+			continue
+		}
+		hits = append(hits, similarHit{TemplateID: row.TemplateID, Similarity: dotProduct(query, decodeVector(row.Vector))})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Similarity != hits[j].Similarity {
+			return hits[i].Similarity > hits[j].Similarity
+		}
+		return hits[i].TemplateID > hits[j].TemplateID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// similarLimit 规范化相似模板返回条数
+func similarLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSimilarTemplates
+	}
+	return min(limit, maxSimilarTemplates)
+}
+
+// SearchSimilarTemplates 按语义检索用户自己的模板
+func (s *TemplateService) SearchSimilarTemplates(ctx context.Context, userMobile, q string, limit int) ([]SimilarTemplate, error) {
+	q = strings.TrimSpace(q)
+	if q == "" || utf8.RuneCountInString(q) > maxSearchQueryLength {
+		return nil, ErrEmbeddingQuery
+	}
+	setting, provider, err := embeddingProvider(userMobile)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := embedQuery(ctx, provider, setting.Model, q)
+	if err != nil {
+		return nil, err
+	}
+	return similarTemplates(userMobile, setting.Model, vector, 0, similarLimit(limit))
+}
+
+// GetSimilarTemplates 查询与指定模板语义相似的其他模板，模板尚未向量化时先向量化
+func (s *TemplateService) GetSimilarTemplates(ctx context.Context, userMobile string, templateID uint64, limit int) ([]SimilarTemplate, error) {
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	setting, provider, err := embeddingProvider(userMobile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := embedTemplates(ctx, setting.Model, provider, []models.Template{*template}); err != nil {
+		return nil, err
+	}
+
+	var embedding models.TemplateEmbedding
+	if err := config.DB.Where("template_id = ?", templateID).First(&embedding).Error; err != nil {
+		return nil, err
+	}
+	return similarTemplates(userMobile, setting.Model, decodeVector(embedding.Vector), templateID, similarLimit(limit))
+}
+
+// similarTemplates 在用户当前模型的全部向量中按相似度排序并加载模板
+func similarTemplates(userMobile, model string, vector []float32, excludeID uint64, limit int) ([]SimilarTemplate, error) {
+	var rows []models.TemplateEmbedding
+	if err := config.DB.Where("mobile = ? AND model = ?", userMobile, model).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	hits := rankBySimilarity(vector, rows, excludeID, limit)
+	if len(hits) == 0 {
+		return []SimilarTemplate{}, nil
+	}
+
+	ids := make([]uint64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.TemplateID
+	}
+	var templates []models.Template
+	if err := config.DB.Where("id IN ? AND mobile = ?", ids, userMobile).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	if err := loadTemplateTags(templates); err != nil {
+		return nil, err
+	}
+
+	results := make([]SimilarTemplate, 0, len(templates))
+	for _, hit := range hits {
+		i := slices.IndexFunc(templates, func(t models.Template) bool { return t.ID == hit.TemplateID })
+		if i >= 0 {
+			results = append(results, SimilarTemplate{Template: templates[i], Similarity: hit.Similarity})
+		}
+	}
+	return results, nil
+}
+
+// StartEmbeddingBackfill 在后台为用户已有的模板生成向量
+func StartEmbeddingBackfill(userMobile string) (*models.EmbeddingSetting, error) {
+	setting, provider, err := embeddingProvider(userMobile)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddingBackfillsMu.Lock()
+	if embeddingBackfills[userMobile] {
+		embeddingBackfillsMu.Unlock()
+		return nil, ErrEmbeddingBackfillRunning
+	}
+	embeddingBackfills[userMobile] = true
+	embeddingBackfillsMu.Unlock()
+
+	setting.Status = models.EmbeddingStatusRunning
+	setting.Error = ""
+	if err := config.DB.Model(setting).Updates(map[string]interface{}{"status": setting.Status, "error": ""}).Error; err != nil {
+		finishEmbeddingBackfill(userMobile)
+		return nil, err
+	}
+	go runEmbeddingBackfill(setting, provider)
+	return setting, nil
+}
+
+// runEmbeddingBackfill 分批向量化用户的全部模板，结束后记录状态
+func runEmbeddingBackfill(setting *models.EmbeddingSetting, provider *models.APIProvider) {
+	defer finishEmbeddingBackfill(setting.Mobile)
+	utils.Info("开始回填模板向量", zap.String("mobile", setting.Mobile), zap.String("model", setting.Model))
+
+	embedded := 0
+	var templates []models.Template
+	err := config.DB.Where("mobile = ?", setting.Mobile).FindInBatches(&templates, embeddingConfig().BatchSize, func(tx *gorm.DB, batch int) error {
+		n, err := embedTemplates(context.Background(), setting.Model, provider, templates)
+		embedded += n
+		return err
+	}).Error
+
+	updates := map[string]interface{}{"status": models.EmbeddingStatusCompleted, "error": "", "backfill_at": time.Now()}
+	if err != nil {
+		utils.Error("回填模板向量失败", zap.String("mobile", setting.Mobile), zap.Error(err))
+		updates = map[string]interface{}{"status": models.EmbeddingStatusFailed, "error": err.Error()}
+	} else {
+		utils.Info("回填模板向量完成", zap.String("mobile", setting.Mobile), zap.Int("embedded", embedded))
+	}
+	if err := config.DB.Model(&models.EmbeddingSetting{}).Where("id = ?", setting.ID).Updates(updates).Error; err != nil {
+		utils.Error("保存向量回填状态失败", zap.String("mobile", setting.Mobile), zap.Error(err))
+	}
+}
+
+// finishEmbeddingBackfill 释放用户的回填标记
+func finishEmbeddingBackfill(userMobile string) {
+	embeddingBackfillsMu.Lock()
+	delete(embeddingBackfills, userMobile)
+	embeddingBackfillsMu.Unlock()
+}
+
+// isEmbeddingBackfillRunning 判断用户是否正在回填
+func isEmbeddingBackfillRunning(userMobile string) bool {
+	embeddingBackfillsMu.Lock()
+	defer embeddingBackfillsMu.Unlock()
+	return embeddingBackfills[userMobile]
+}
+
+// RecoverEmbeddingBackfills 服务启动时重新执行上次因重启中断的回填，已向量化的模板会被跳过
+func RecoverEmbeddingBackfills() error {
+	var settings []models.EmbeddingSetting
+	if err := config.DB.Where("status = ?", models.EmbeddingStatusRunning).Find(&settings).Error; err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		if _, err := StartEmbeddingBackfill(setting.Mobile); err != nil {
+			utils.Warn("恢复向量回填失败", zap.String("mobile", setting.Mobile), zap.Error(err))
+			config.DB.Model(&models.EmbeddingSetting{}).Where("id = ?", setting.ID).Updates(map[string]interface{}{
+				"status": models.EmbeddingStatusFailed,
+				"error":  err.Error(),
+			})
+		}
+	}
+	return nil
+}
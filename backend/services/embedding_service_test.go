@@ -0,0 +1,121 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestVectorEncoding(t *testing.T) {
+	vector := []float32{3, 4}
+	normalizeVector(vector)
+	if !reflect.DeepEqual(vector, []float32{0.6, 0.8}) {
+		t.Fatalf("normalizeVector() = %v", vector)
+	}
+	if got := decodeVector(encodeVector(vector)); !reflect.DeepEqual(got, vector) {
+		t.Errorf("decodeVector(encodeVector()) = %v, want %v", got, vector)
+	}
+
+	zero := []float32{0, 0}
+	normalizeVector(zero)
+	if !reflect.DeepEqual(zero, []float32{0, 0}) {
+		t.Errorf("normalizeVector(zero) = %v", zero)
+	}
+}
+
+func TestRankBySimilarity(t *testing.T) {
+	row := func(id uint64, v ...float32) models.TemplateEmbedding {
+		normalizeVector(v)
+		return models.TemplateEmbedding{TemplateID: id, Dimensions: len(v), Vector: encodeVector(v)}
+	}
+	rows := []models.TemplateEmbedding{
+		row(1, 1, 0),
+		row(2, 1, 1),
+		row(3, 0, 1),
+		row(4, 1, 0, 0), // 维度不同，忽略
+	}
+
+	hits := rankBySimilarity([]float32{1, 0}, rows, 0, 2)
+	if len(hits) != 2 || hits[0].TemplateID != 1 || hits[1].TemplateID != 2 {
+		t.Fatalf("rankBySimilarity() = %+v", hits)
+	}
+	if math.Abs(hits[0].Similarity-1) > 1e-6 || math.Abs(hits[1].Similarity-math.Sqrt2/2) > 1e-6 {
+		t.Errorf("rankBySimilarity() similarity = %+v", hits)
+	}
+
+	hits = rankBySimilarity([]float32{1, 0}, rows, 1, 10)
+	if len(hits) != 2 || hits[0].TemplateID != 2 || hits[1].TemplateID != 3 {
+		t.Errorf("rankBySimilarity() exclude = %+v", hits)
+	}
+}
+
+func TestParseOpenAIEmbeddings(t *testing.T) {
+	body := `{"object":"list","data":[{"index":1,"embedding":[0.3,0.4]},{"index":0,"embedding":[0.1,0.2]}],"model":"text-embedding-3-small"}`
+	vectors, err := parseOpenAIEmbeddings([]byte(body), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vectors, [][]float32{{0.1, 0.2}, {0.3, 0.4}}) {
+		t.Errorf("parseOpenAIEmbeddings() = %v", vectors)
+	}
+
+	for _, body := range []string{
+		`{"data":[{"index":0,"embedding":[0.1]}]}`,
+		`{"data":[{"index":0,"embedding":[0.1]},{"index":0,"embedding":[0.2]}]}`,
+		`{"data":[{"index":0,"embedding":[]},{"index":1,"embedding":[0.2]}]}`,
+		`not json`,
+	} {
+		if _, err := parseOpenAIEmbeddings([]byte(body), 2); err == nil {
+			t.Errorf("parseOpenAIEmbeddings(%s) expected error", body)
+		}
+	}
+}
+
+func TestParseOllamaEmbedding(t *testing.T) {
+	vector, err := parseOllamaEmbedding([]byte(`{"embedding":[0.5,-0.5]}`))
+	if err != nil || !reflect.DeepEqual(vector, []float32{0.5, -0.5}) {
+		t.Errorf("parseOllamaEmbedding() = %v, %v", vector, err)
+	}
+	if _, err := parseOllamaEmbedding([]byte(`{"embedding":[]}`)); err == nil {
+		t.Error("parseOllamaEmbedding(empty) expected error")
+	}
+}
+
+func TestBuildEmbeddingURL(t *testing.T) {
+	tests := []struct {
+		provider models.APIProvider
+		want     string
+	}{
+		{models.APIProvider{APIKind: models.APIKindOllama, APIURL: "http://localhost:11434/"}, "http://localhost:11434/api/embeddings"},
+		{models.APIProvider{APIKind: models.APIKindOllama, APIURL: "http://localhost:11434/v1"}, "http://localhost:11434/v1/embeddings"},
+		{models.APIProvider{APIKind: models.APIKindOpenAICompatible, APIURL: "https://api.openai.com/v1"}, "https://api.openai.com/v1/embeddings"},
+	}
+	for _, tt := range tests {
+		if got := buildEmbeddingURL(&tt.provider); got != tt.want {
+			t.Errorf("buildEmbeddingURL(%s) = %s, want %s", tt.provider.APIURL, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddingContent(t *testing.T) {
+	template := &models.Template{Topic: "周报助手", TaskObjective: strings.Repeat("总结", 100)}
+	content := embeddingContent(template, 20)
+	if got := len([]rune(content)); got != 20 || !strings.HasPrefix(content, "# 周报助手") {
+		t.Errorf("embeddingContent() = %q (%d runes)", content, got)
+	}
+	if embeddingHash("a", content) == embeddingHash("b", content) {
+		t.Error("embeddingHash() should depend on model")
+	}
+}
+
+func TestSupportsEmbedding(t *testing.T) {
+	if !supportsEmbedding(models.APIKindOllama) || !supportsEmbedding(models.APIKindOpenAICompatible) {
+		t.Error("supportsEmbedding() should support Ollama and OpenAI Compatible")
+	}
+	if supportsEmbedding(models.APIKindAnthropic) {
+		t.Error("supportsEmbedding(anthropic) = true")
+	}
+}
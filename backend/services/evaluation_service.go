@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxEvaluationProviders 单次评测最多对比的Provider数量
+const maxEvaluationProviders = 8
+
+// 评测相关错误
+var (
+	ErrEvaluationNotFound = errors.New("评测记录不存在")
+)
+
+// EvaluationRequest 创建评测请求，template_id 与 prompt 至少指定一个
+type EvaluationRequest struct {
+	TemplateID      uint64  `json:"template_id,omitempty"`       // 可选：评测的模板，prompt为空时由模板六要素组装
+	Prompt          string  `json:"prompt,omitempty"`            // 可选：评测的提示词
+	System          string  `json:"system,omitempty"`            // 可选：系统消息
+	ProviderIDs     []uint  `json:"provider_ids"`                // 参与对比的API Provider
+	JudgeProviderID uint    `json:"judge_provider_id,omitempty"` // 可选：评审模型，为空时不打分
+	Criteria        string  `json:"criteria,omitempty"`          // 可选：评分标准，默认取模板的交付格式
+	Temperature     float32 `json:"temperature,omitempty"`
+	MaxTokens       int     `json:"max_tokens,omitempty"`
+}
+
+// judgeVerdict 评审模型返回的评分
+type judgeVerdict struct {
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// judgeSystemPrompt 评审模型的系统消息
+const judgeSystemPrompt = `你是一名严格的大模型输出评审员。请根据用户提示词和评分标准，评估待评审输出的质量，重点检查是否满足交付格式要求、是否完成任务目标、内容是否准确完整。
+只输出一个JSON对象，不要输出其他内容，格式为：{"score": 0到10之间的数字, "reason": "简要评分理由"}`
+
+// normalizeProviderIDs 去除重复与无效的Provider ID，保持原有顺序
+func normalizeProviderIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// CreateEvaluation 创建评测：并发调用各Provider生成内容，可选由评审模型打分，保存并返回评测记录
+func CreateEvaluation(ctx context.Context, userMobile string, req *EvaluationRequest) (*models.Evaluation, error) {
+	req.ProviderIDs = normalizeProviderIDs(req.ProviderIDs)
+	if len(req.ProviderIDs) == 0 {
+		return nil, errors.New("请选择参与评测的API Provider")
+	}
+	if len(req.ProviderIDs) > maxEvaluationProviders {
+		return nil, fmt.Errorf("单次评测最多对比%d个API Provider", maxEvaluationProviders)
+	}
+
+	evaluation := &models.Evaluation{
+		Mobile:   userMobile,
+		Prompt:   strings.TrimSpace(req.Prompt),
+		System:   req.System,
+		Criteria: strings.TrimSpace(req.Criteria),
+	}
+
+	// 使用模板时，由六要素组装提示词，交付格式作为默认评分标准；可使用公开或共享给自己的模板
+	if req.TemplateID != 0 {
+		template, err := (&TemplateService{}).GetVisibleTemplate(userMobile, req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		evaluation.TemplateID = &template.ID
+		if evaluation.Prompt == "" {
+			evaluation.Prompt = BuildTemplatePrompt(template)
+		}
+		if evaluation.Criteria == "" {
+			evaluation.Criteria = strings.TrimSpace(template.DeliveryFormat)
+		}
+	}
+	if evaluation.Prompt == "" {
+		return nil, errors.New("请指定评测的模板或提示词")
+	}
+	if err := ScreenGenerateRequest(&GenerateRequest{Prompt: evaluation.Prompt, System: evaluation.System, TemplateID: req.TemplateID, User: userMobile}); err != nil {
+		return nil, err
+	}
+
+	providers := make([]*models.APIProvider, 0, len(req.ProviderIDs))
+	for _, id := range req.ProviderIDs {
+		provider, err := GetAPIProvider(userMobile, id)
+		if err != nil {
+			return nil, fmt.Errorf("API Provider %d 不存在", id)
+		}
+		providers = append(providers, provider)
+	}
+
+	var judge *models.APIProvider
+	if req.JudgeProviderID != 0 {
+		provider, err := GetAPIProvider(userMobile, req.JudgeProviderID)
+		if err != nil {
+			return nil, errors.New("评审模型不存在")
+		}
+		if provider.APIStatus != 1 {
+			return nil, errors.New("评审模型未启用")
+		}
+		judge = provider
+		evaluation.JudgeProviderID = &provider.ID
+	}
+
+	genReq := GenerateRequest{
+		Prompt:      evaluation.Prompt,
+		System:      evaluation.System,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		User:        userMobile,
+	}
+	utils.Info("开始评测", zap.String("user_mobile", userMobile), zap.Int("provider_count", len(providers)), zap.Bool("judge", judge != nil))
+
+	evaluation.Results = runEvaluation(ctx, providers, genReq)
+	if judge != nil {
+		judgeEvaluationResults(ctx, judge, evaluation.Prompt, evaluation.Criteria, evaluation.Results)
+	}
+
+	if err := config.GetDB().Create(evaluation).Error; err != nil {
+		return nil, err
+	}
+	return evaluation, nil
+}
+
+// runEvaluation 并发调用各Provider生成内容，记录输出、耗时、token用量与错误
+// 结果顺序与 providers 一致，单个Provider失败不影响其他Provider
+func runEvaluation(ctx context.Context, providers []*models.APIProvider, req GenerateRequest) []models.EvaluationResult {
+	results := make([]models.EvaluationResult, len(providers))
+
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider *models.APIProvider) {
+			defer wg.Done()
+
+			model := req.Model
+			if model == "" {
+				model = provider.APIModel
+			}
+			result := models.EvaluationResult{
+				ProviderID:   provider.ID,
+				ProviderName: provider.Name,
+				Model:        model,
+			}
+
+			start := time.Now()
+			generated, err := evaluateProvider(ctx, provider, req)
+			result.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				utils.Warn("评测生成失败", zap.Uint("provider_id", provider.ID), zap.Error(err))
+				result.Error = err.Error()
+			} else {
+				result.Content = generated.Content
+				if generated.Usage != nil {
+					result.PromptTokens = generated.Usage.PromptTokens
+					result.CompletionTokens = generated.Usage.CompletionTokens
+					result.TotalTokens = generated.Usage.TotalTokens
+				}
+			}
+			results[i] = result
+		}(i, provider)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// evaluateProvider 检查Provider状态后以非流式方式生成内容
+func evaluateProvider(ctx context.Context, provider *models.APIProvider, req GenerateRequest) (*GenerateResult, error) {
+	if err := PrepareGenerateRequest(ctx, provider, &req); err != nil {
+		return nil, err
+	}
+	return Generate(ctx, provider, req)
+}
+
+// judgeEvaluationResults 使用评审模型并发为生成成功的结果打分，评审失败时保留空分数并记录原因
+func judgeEvaluationResults(ctx context.Context, judge *models.APIProvider, prompt, criteria string, results []models.EvaluationResult) {
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Error != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(result *models.EvaluationResult) {
+			defer wg.Done()
+
+			generated, err := Generate(ctx, judge, GenerateRequest{
+				System:      judgeSystemPrompt,
+				Prompt:      buildJudgePrompt(prompt, criteria, result.Content),
+				Temperature: 0.1,
+				MaxTokens:   500,
+			})
+			if err != nil {
+				utils.Warn("评审打分失败", zap.Uint("provider_id", result.ProviderID), zap.Error(err))
+				result.ScoreReason = "评审失败: " + err.Error()
+				return
+			}
+			verdict, err := parseJudgeVerdict(generated.Content)
+			if err != nil {
+				utils.Warn("解析评审结果失败", zap.Uint("provider_id", result.ProviderID), zap.String("content", generated.Content))
+				result.ScoreReason = "评审失败: " + err.Error()
+				return
+			}
+			result.Score = &verdict.Score
+			result.ScoreReason = verdict.Reason
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// buildJudgePrompt 构建评审提示词
+func buildJudgePrompt(prompt, criteria, output string) string {
+	if criteria == "" {
+		criteria = "无特定格式要求，按任务完成度与内容质量评分"
+	}
+	return fmt.Sprintf("## 用户提示词\n%s\n\n## 评分标准（交付格式）\n%s\n\n## 待评审输出\n%s\n", prompt, criteria, output)
+}
+
+// parseJudgeVerdict 从评审模型输出中提取JSON评分，兼容Markdown代码块等包裹内容
+// 分数限制在 0-10 之间
+func parseJudgeVerdict(content string) (*judgeVerdict, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("评审结果不是JSON格式")
+	}
+
+	var verdict judgeVerdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		return nil, errors.New("评审结果不是JSON格式")
+	}
+	verdict.Score = min(max(verdict.Score, 0), 10)
+	verdict.Reason = strings.TrimSpace(verdict.Reason)
+	return &verdict, nil
+}
+
+// GetEvaluation 获取评测记录及各Provider的结果
+func GetEvaluation(userMobile string, evaluationID uint) (*models.Evaluation, error) {
+	var evaluation models.Evaluation
+	if err := config.GetDB().Preload("Results", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Where("id = ? AND mobile = ?", evaluationID, userMobile).First(&evaluation).Error; err != nil {
+		return nil, ErrEvaluationNotFound
+	}
+	return &evaluation, nil
+}
+
+// ListEvaluations 分页查询用户的评测记录（不含结果详情），可按模板过滤
+func ListEvaluations(userMobile string, templateID uint64, page, pageSize int) ([]models.Evaluation, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.GetDB().Model(&models.Evaluation{}).Where("mobile = ?", userMobile)
+	if templateID != 0 {
+		query = query.Where("template_id = ?", templateID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var evaluations []models.Evaluation
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&evaluations).Error; err != nil {
+		return nil, 0, err
+	}
+	return evaluations, total, nil
+}
+
+// DeleteEvaluation 删除评测记录及其结果
+func DeleteEvaluation(userMobile string, evaluationID uint) error {
+	return config.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND mobile = ?", evaluationID, userMobile).Delete(&models.Evaluation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEvaluationNotFound
+		}
+		return tx.Where("evaluation_id = ?", evaluationID).Delete(&models.EvaluationResult{}).Error
+	})
+}
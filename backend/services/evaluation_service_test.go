@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestRunEvaluation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"结果"},"finish_reason":"stop"}],"usage":{"prompt_tokens":4,"completion_tokens":6,"total_tokens":10}}`)
+	}))
+	defer server.Close()
+
+	providers := []*models.APIProvider{
+		{ID: 1, Name: "ok", APIKind: models.APIKindOpenAICompatible, APIURL: server.URL, APIModel: "m1", APIStatus: 1},
+		{ID: 2, Name: "fail", APIKind: models.APIKindOpenAICompatible, APIURL: server.URL + "/fail", APIModel: "m2", APIStatus: 1},
+		{ID: 3, Name: "disabled", APIKind: models.APIKindOpenAICompatible, APIURL: server.URL, APIModel: "m3", APIStatus: 0},
+	}
+	results := runEvaluation(context.Background(), providers, GenerateRequest{Prompt: "hi"})
+
+	if len(results) != 3 {
+		t.Fatalf("results = %d, want 3", len(results))
+	}
+	if r := results[0]; r.ProviderID != 1 || r.Content != "结果" || r.TotalTokens != 10 || r.Error != "" || r.Model != "m1" {
+		t.Errorf("unexpected success result: %+v", r)
+	}
+	if r := results[1]; r.Error == "" || r.Content != "" {
+		t.Errorf("expected upstream error: %+v", r)
+	}
+	if r := results[2]; r.Error != ErrProviderDisabled.Error() {
+		t.Errorf("expected disabled error: %+v", r)
+	}
+}
+
+func TestJudgeEvaluationResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"评审如下：\n`+"```json"+`\n{\"score\": 12, \"reason\": \"格式正确\"}\n`+"```"+`"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	judge := &models.APIProvider{ID: 9, APIKind: models.APIKindOpenAICompatible, APIURL: server.URL, APIModel: "judge", APIStatus: 1}
+	results := []models.EvaluationResult{
+		{ProviderID: 1, Content: "输出"},
+		{ProviderID: 2, Error: "API返回错误: 500"},
+	}
+	judgeEvaluationResults(context.Background(), judge, "提示词", "JSON", results)
+
+	if results[0].Score == nil || *results[0].Score != 10 || results[0].ScoreReason != "格式正确" {
+		t.Errorf("unexpected judged result: %+v", results[0])
+	}
+	if results[1].Score != nil {
+		t.Errorf("failed result should not be judged: %+v", results[1])
+	}
+}
+
+func TestParseJudgeVerdict(t *testing.T) {
+	verdict, err := parseJudgeVerdict(`{"score": 7.5, "reason": " 基本满足 "}`)
+	if err != nil || verdict.Score != 7.5 || verdict.Reason != "基本满足" {
+		t.Errorf("parseJudgeVerdict() = %+v, %v", verdict, err)
+	}
+	if verdict, _ := parseJudgeVerdict(`{"score": -3}`); verdict == nil || verdict.Score != 0 {
+		t.Errorf("score should be clamped to 0: %+v", verdict)
+	}
+	for _, content := range []string{"无法评分", `{"score": "高"}`} {
+		if _, err := parseJudgeVerdict(content); err == nil {
+			t.Errorf("parseJudgeVerdict(%q) expected error", content)
+		}
+	}
+}
+
+func TestNormalizeProviderIDs(t *testing.T) {
+	got := normalizeProviderIDs([]uint{3, 0, 1, 3, 2, 1})
+	if len(got) != 3 || got[0] != 3 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("normalizeProviderIDs() = %v", got)
+	}
+}
+
+func TestBuildTemplatePrompt(t *testing.T) {
+	got := BuildTemplatePrompt(&models.Template{
+		TaskObjective:  "写一篇文章",
+		AIRole:         " 专业作者 ",
+		DeliveryFormat: "Markdown",
+	})
+	want := "## 任务目标\n写一篇文章\n\n## AI的角色\n专业作者\n\n## 交付格式\nMarkdown\n"
+	if got != want {
+		t.Errorf("BuildTemplatePrompt() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// AnthropicContentBlock Anthropic消息内容块
+//   - text: 文本
+//   - image: Base64编码的图片
+//   - tool_use: 模型发起的工具调用，input 为参数对象
+//   - tool_result: 工具执行结果，tool_use_id 对应调用ID
+type AnthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Source    *AnthropicImage `json:"source,omitempty"`
+}
+
+// AnthropicImage Anthropic图片来源
+type AnthropicImage struct {
+	Type      string `json:"type"` // base64
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// AnthropicMessage Anthropic消息格式，角色只有 user/assistant
+type AnthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []AnthropicContentBlock `json:"content"`
+}
+
+// AnthropicTool Anthropic工具定义
+type AnthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// AnthropicToolChoice Anthropic工具选择策略：auto/any/tool/none
+type AnthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// AnthropicRequest Anthropic Messages API 请求格式
+type AnthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []AnthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   float32              `json:"temperature,omitempty"`
+	TopP          float32              `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream"`
+	Tools         []AnthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *AnthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// AnthropicUsage Anthropic token用量
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// AnthropicResponse Anthropic Messages API 非流式响应格式
+type AnthropicResponse struct {
+	ID         string                  `json:"id"`
+	Model      string                  `json:"model"`
+	Content    []AnthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      AnthropicUsage          `json:"usage"`
+}
+
+// anthropicStreamEvent Anthropic流式事件，不同 type 使用不同字段
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message *struct {
+		Usage AnthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	ContentBlock *AnthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *AnthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// buildAnthropicRequest 构建Anthropic Messages API 请求
+// system 单独传递；tool 消息转换为 user 角色的 tool_result 内容块，连续的工具结果合并为一条消息
+func buildAnthropicRequest(model string, req GenerateRequest, stream bool) AnthropicRequest {
+	anthropicReq := AnthropicRequest{
+		Model:         model,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        stream,
+	}
+	if anthropicReq.MaxTokens == 0 {
+		anthropicReq.MaxTokens = 2000
+	}
+
+	var systems []string
+	for _, msg := range buildMessages(req) {
+		switch msg.Role {
+		case RoleSystem:
+			systems = append(systems, msg.Content)
+			continue
+		case RoleTool:
+			block := AnthropicContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			last := len(anthropicReq.Messages) - 1
+			if last >= 0 && anthropicReq.Messages[last].Role == RoleUser && anthropicReq.Messages[last].Content[0].Type == "tool_result" {
+				anthropicReq.Messages[last].Content = append(anthropicReq.Messages[last].Content, block)
+			} else {
+				anthropicReq.Messages = append(anthropicReq.Messages, AnthropicMessage{Role: RoleUser, Content: []AnthropicContentBlock{block}})
+			}
+			continue
+		}
+
+		anthropicMsg := AnthropicMessage{Role: msg.Role}
+		for _, image := range msg.Images {
+			anthropicMsg.Content = append(anthropicMsg.Content, AnthropicContentBlock{
+				Type:   "image",
+				Source: &AnthropicImage{Type: "base64", MediaType: image.MimeType, Data: image.base64Data()},
+			})
+		}
+		if msg.Content != "" {
+			anthropicMsg.Content = append(anthropicMsg.Content, AnthropicContentBlock{Type: "text", Text: msg.Content})
+		}
+		for _, call := range msg.ToolCalls {
+			anthropicMsg.Content = append(anthropicMsg.Content, AnthropicContentBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: toolArgumentsObject(call.Function.Arguments),
+			})
+		}
+		if len(anthropicMsg.Content) == 0 {
+			continue
+		}
+		anthropicReq.Messages = append(anthropicReq.Messages, anthropicMsg)
+	}
+	anthropicReq.System = strings.Join(systems, "\n\n")
+
+	for _, tool := range req.Tools {
+		schema := tool.Function.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		anthropicReq.Tools = append(anthropicReq.Tools, AnthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	if len(anthropicReq.Tools) > 0 {
+		switch choice := parseToolChoice(req.ToolChoice); choice.Mode {
+		case "auto":
+			anthropicReq.ToolChoice = &AnthropicToolChoice{Type: "auto"}
+		case "none":
+			anthropicReq.ToolChoice = &AnthropicToolChoice{Type: "none"}
+		case "required":
+			anthropicReq.ToolChoice = &AnthropicToolChoice{Type: "any"}
+		case "function":
+			anthropicReq.ToolChoice = &AnthropicToolChoice{Type: "tool", Name: choice.Name}
+		}
+	}
+	return anthropicReq
+}
+
+// normalizeAnthropicToolUse 将 tool_use 内容块转换为OpenAI格式的工具调用
+func normalizeAnthropicToolUse(blocks []AnthropicContentBlock) []ToolCall {
+	var calls []ToolCall
+	for _, block := range blocks {
+		if block.Type != "tool_use" {
+			continue
+		}
+		calls = append(calls, ToolCall{
+			ID:   block.ID,
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      block.Name,
+				Arguments: toolArgumentsString(block.Input),
+			},
+		})
+	}
+	return calls
+}
+
+// handleAnthropicStreamResponse 处理Anthropic格式的流式响应
+// tool_use 内容块开始时发送调用ID与函数名，input_json_delta 作为参数片段转发
+func handleAnthropicStreamResponse(resp *http.Response, sink StreamSink) {
+	utils.Info("开始处理Anthropic格式流式响应")
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	re := regexp.MustCompile(`<[^>]*>`)
+	var contentLength int
+	var usage Usage
+	var toolCalls toolCallAccumulator
+	toolIndexes := make(map[int]int) // 内容块序号 -> 工具调用序号
+	doneSent := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			utils.Error("解析Anthropic流响应失败", zap.Error(err), zap.String("data", data))
+			continue
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+			}
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+				index := len(toolIndexes)
+				toolIndexes[event.Index] = index
+				delta := ToolCall{
+					Index:    &index,
+					ID:       event.ContentBlock.ID,
+					Type:     "function",
+					Function: ToolCallFunction{Name: event.ContentBlock.Name},
+				}
+				toolCalls.add(delta)
+				sink.ToolCalls([]ToolCall{delta})
+			}
+		case "content_block_delta":
+			if event.Delta == nil {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				cleanContent := re.ReplaceAllString(event.Delta.Text, "")
+				if cleanContent == "" {
+					continue
+				}
+				contentLength += len(cleanContent)
+				sink.Content(cleanContent)
+			case "input_json_delta":
+				index, ok := toolIndexes[event.Index]
+				if !ok || event.Delta.PartialJSON == "" {
+					continue
+				}
+				delta := ToolCall{Index: &index, Function: ToolCallFunction{Arguments: event.Delta.PartialJSON}}
+				toolCalls.add(delta)
+				sink.ToolCalls([]ToolCall{delta})
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			sink.Done(newGenerateResult("", &usage, toolCalls.result()))
+			doneSent = true
+		case "error":
+			msg := "Anthropic返回错误"
+			if event.Error != nil {
+				msg = fmt.Sprintf("Anthropic返回错误: %s %s", event.Error.Type, event.Error.Message)
+			}
+			utils.Error("Anthropic流响应错误", zap.String("error", msg))
+			sink.Error(msg)
+			doneSent = true
+		}
+		if doneSent {
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		utils.Error("读取Anthropic流数据失败", zap.Error(err))
+		sink.Error("读取流数据失败")
+	} else if !doneSent {
+		sink.Done(newGenerateResult("", nil, toolCalls.result()))
+	}
+
+	utils.Info("Anthropic格式流式生成处理完成", zap.Int("content_length", contentLength))
+}
+
+// parseAnthropicResponse 解析Anthropic格式的响应
+func parseAnthropicResponse(body []byte) (*GenerateResult, error) {
+	utils.Info("开始解析Anthropic格式响应")
+
+	var anthropicResp AnthropicResponse
+	if err := json.Unmarshal(body, &anthropicResp); err != nil {
+		utils.Error("解析Anthropic响应失败", zap.Error(err), zap.String("body", string(body)))
+		return nil, errors.New("解析响应失败")
+	}
+
+	var content strings.Builder
+	for _, block := range anthropicResp.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	toolCalls := normalizeAnthropicToolUse(anthropicResp.Content)
+	if content.Len() == 0 && len(toolCalls) == 0 {
+		utils.Warn("Anthropic API未返回内容")
+		return nil, errors.New("API未返回内容")
+	}
+
+	re := regexp.MustCompile(`<[^>]*>`)
+	usage := &Usage{
+		PromptTokens:     anthropicResp.Usage.InputTokens,
+		CompletionTokens: anthropicResp.Usage.OutputTokens,
+		TotalTokens:      anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
+	}
+	return newGenerateResult(re.ReplaceAllString(content.String(), ""), usage, toolCalls), nil
+}
+
+// parseAnthropicError 解析Anthropic错误响应：{"type":"error","error":{"type":"...","message":"..."}}
+func parseAnthropicError(body []byte) string {
+	var errResp struct {
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error == nil || errResp.Error.Message == "" {
+		return ""
+	}
+	return fmt.Sprintf("Anthropic返回错误: %s %s", errResp.Error.Type, errResp.Error.Message)
+}
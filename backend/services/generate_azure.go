@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// defaultAzureAPIVersion Azure OpenAI默认api-version
+// Provider的api_version为空或沿用通用默认值v1时使用
+const defaultAzureAPIVersion = "2024-06-01"
+
+// AzurePromptFilterResult Azure提示词过滤结果
+type AzurePromptFilterResult struct {
+	PromptIndex          int                        `json:"prompt_index"`
+	ContentFilterResults map[string]json.RawMessage `json:"content_filter_results"`
+}
+
+// azureErrorResponse Azure错误响应格式
+type azureErrorResponse struct {
+	Error struct {
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+		InnerError struct {
+			Code                 string                     `json:"code"`
+			ContentFilterResult  map[string]json.RawMessage `json:"content_filter_result"`
+			ContentFilterResults map[string]json.RawMessage `json:"content_filter_results"`
+		} `json:"innererror"`
+	} `json:"error"`
+}
+
+// buildAzureURL 构建Azure OpenAI对话接口地址
+// 格式：{endpoint}/openai/deployments/{deployment}/chat/completions?api-version={version}
+func buildAzureURL(baseURL, deployment, apiVersion string) string {
+	version := strings.TrimSpace(apiVersion)
+	if version == "" || version == "v1" {
+		version = defaultAzureAPIVersion
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		baseURL, url.PathEscape(deployment), url.QueryEscape(version))
+}
+
+// parseAzureError 解析Azure错误响应，内容过滤错误返回拦截的类别
+func parseAzureError(body []byte) string {
+	var errResp azureErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Code == "" {
+		return ""
+	}
+
+	if errResp.Error.Code == "content_filter" || errResp.Error.InnerError.Code == "ResponsibleAIPolicyViolation" {
+		results := errResp.Error.InnerError.ContentFilterResult
+		if len(results) == 0 {
+			results = errResp.Error.InnerError.ContentFilterResults
+		}
+		return describeContentFilter(results)
+	}
+
+	return fmt.Sprintf("API返回错误: %s %s", errResp.Error.Code, errResp.Error.Message)
+}
+
+// describePromptFilter 检查提示词过滤结果，被拦截时返回错误信息，否则返回空字符串
+func describePromptFilter(results []AzurePromptFilterResult) string {
+	for _, r := range results {
+		if categories := filteredCategories(r.ContentFilterResults); len(categories) > 0 {
+			return "提示词触发内容安全策略被拦截: " + strings.Join(categories, ", ")
+		}
+	}
+	return ""
+}
+
+// describeContentFilter 生成内容过滤错误信息
+func describeContentFilter(results map[string]json.RawMessage) string {
+	categories := filteredCategories(results)
+	if len(categories) == 0 {
+		return "内容触发内容安全策略被拦截"
+	}
+	return "内容触发内容安全策略被拦截: " + strings.Join(categories, ", ")
+}
+
+// filteredCategories 提取被拦截的过滤类别
+// 各类别结构不完全一致（如 custom_blocklists 为数组），无法解析的类别直接忽略
+func filteredCategories(results map[string]json.RawMessage) []string {
+	categories := make([]string, 0)
+	for name, raw := range results {
+		var result struct {
+			Filtered bool   `json:"filtered"`
+			Severity string `json:"severity"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil || !result.Filtered {
+			continue
+		}
+		if result.Severity != "" {
+			categories = append(categories, fmt.Sprintf("%s(%s)", name, result.Severity))
+		} else {
+			categories = append(categories, name)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
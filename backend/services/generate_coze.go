@@ -0,0 +1,281 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// Coze流式事件类型
+const (
+	cozeEventChatCreated      = "conversation.chat.created"
+	cozeEventChatInProgress   = "conversation.chat.in_progress"
+	cozeEventMessageDelta     = "conversation.message.delta"
+	cozeEventMessageCompleted = "conversation.message.completed"
+	cozeEventChatCompleted    = "conversation.chat.completed"
+	cozeEventChatFailed       = "conversation.chat.failed"
+	cozeEventError            = "error"
+	cozeEventDone             = "done"
+)
+
+// cozeDefaultUserID 未识别用户时使用的Coze用户标识
+const cozeDefaultUserID = "cese-user"
+
+// CozeMessage Coze消息格式
+type CozeMessage struct {
+	Role        string `json:"role"`
+	Type        string `json:"type,omitempty"`
+	Content     string `json:"content"`
+	ContentType string `json:"content_type"`
+}
+
+// CozeChatRequest Coze v3 对话请求格式
+type CozeChatRequest struct {
+	BotID              string        `json:"bot_id"`
+	UserID             string        `json:"user_id"`
+	Stream             bool          `json:"stream"`
+	AutoSaveHistory    bool          `json:"auto_save_history"`
+	AdditionalMessages []CozeMessage `json:"additional_messages"`
+}
+
+// cozeChat Coze对话对象（chat.* 事件的数据）
+type cozeChat struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	Status         string `json:"status"`
+	Usage          *struct {
+		TokenCount  int `json:"token_count"`
+		OutputCount int `json:"output_count"`
+		InputCount  int `json:"input_count"`
+	} `json:"usage,omitempty"`
+	LastError *struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"last_error,omitempty"`
+}
+
+// cozeStreamResult Coze流式响应的汇总结果
+type cozeStreamResult struct {
+	ConversationID string
+	ChatID         string
+	Usage          *Usage
+	FollowUps      []string // Bot给出的推荐追问
+}
+
+// buildCozeURL 构建Coze对话接口地址
+func buildCozeURL(baseURL, conversationID string) string {
+	apiURL := fmt.Sprintf("%s/v3/chat", baseURL)
+	if conversationID != "" {
+		apiURL += "?conversation_id=" + url.QueryEscape(conversationID)
+	}
+	return apiURL
+}
+
+// buildCozeChatRequest 构建Coze对话请求
+// 模型名称即Bot ID；Coze非流式接口需轮询结果，因此始终以流式方式调用
+func buildCozeChatRequest(botID string, req GenerateRequest) CozeChatRequest {
+	userID := req.User
+	if userID == "" {
+		userID = cozeDefaultUserID
+	}
+
+	// Coze的人设由Bot配置决定，系统消息作为用户消息补充；历史中的助手回复对应 answer 类型
+	openaiMessages := buildMessages(req)
+	messages := make([]CozeMessage, 0, len(openaiMessages))
+	for _, msg := range openaiMessages {
+		if msg.Role == RoleAssistant {
+			messages = append(messages, CozeMessage{Role: RoleAssistant, Type: "answer", Content: msg.Content, ContentType: "text"})
+			continue
+		}
+		messages = append(messages, CozeMessage{Role: RoleUser, Type: "question", Content: msg.Content, ContentType: "text"})
+	}
+
+	return CozeChatRequest{
+		BotID:              botID,
+		UserID:             userID,
+		Stream:             true,
+		AutoSaveHistory:    true,
+		AdditionalMessages: messages,
+	}
+}
+
+// readCozeStream 读取Coze SSE事件流
+// 每个回答片段调用一次 onDelta；对话失败或返回错误事件时返回错误
+func readCozeStream(r io.Reader, onDelta func(content string)) (*cozeStreamResult, error) {
+	result := &cozeStreamResult{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var event string
+	var data strings.Builder
+
+	// dispatch 处理一个完整事件，返回 true 表示流结束
+	dispatch := func() (bool, error) {
+		defer func() {
+			event = ""
+			data.Reset()
+		}()
+		payload := data.String()
+
+		switch event {
+		case cozeEventChatCreated, cozeEventChatInProgress, cozeEventChatCompleted, cozeEventChatFailed:
+			var chat cozeChat
+			if err := json.Unmarshal([]byte(payload), &chat); err != nil {
+				utils.Error("解析Coze对话事件失败", zap.Error(err), zap.String("event", event))
+				return false, nil
+			}
+			if chat.ConversationID != "" {
+				result.ConversationID = chat.ConversationID
+			}
+			if chat.ID != "" {
+				result.ChatID = chat.ID
+			}
+			if event == cozeEventChatFailed {
+				if chat.LastError != nil {
+					return true, fmt.Errorf("Coze对话失败: %d %s", chat.LastError.Code, chat.LastError.Msg)
+				}
+				return true, errors.New("Coze对话失败")
+			}
+			if event == cozeEventChatCompleted && chat.Usage != nil {
+				result.Usage = &Usage{
+					PromptTokens:     chat.Usage.InputCount,
+					CompletionTokens: chat.Usage.OutputCount,
+					TotalTokens:      chat.Usage.TokenCount,
+				}
+			}
+		case cozeEventMessageDelta, cozeEventMessageCompleted:
+			var msg CozeMessage
+			if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+				utils.Error("解析Coze消息事件失败", zap.Error(err), zap.String("event", event))
+				return false, nil
+			}
+			if event == cozeEventMessageDelta && msg.Type == "answer" && msg.Content != "" {
+				onDelta(msg.Content)
+			}
+			if event == cozeEventMessageCompleted && msg.Type == "follow_up" {
+				result.FollowUps = append(result.FollowUps, msg.Content)
+			}
+		case cozeEventError:
+			if msg := parseCozeError([]byte(payload)); msg != "" {
+				return true, errors.New(msg)
+			}
+			return true, errors.New("Coze返回错误")
+		case cozeEventDone:
+			return true, nil
+		}
+		return false, nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if event == "" {
+				continue
+			}
+			if finished, err := dispatch(); finished || err != nil {
+				return result, err
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case strings.HasPrefix(line, "{"):
+			// 鉴权失败等错误以普通JSON返回（HTTP 200）
+			if msg := parseCozeError([]byte(line)); msg != "" {
+				return result, errors.New(msg)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	// 流结束但最后一个事件后没有空行
+	if event != "" {
+		if _, err := dispatch(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// handleCozeStreamResponse 处理Coze格式的流式响应，将 conversation.message.delta 转换为统一的SSE数据
+func handleCozeStreamResponse(resp *http.Response, sink StreamSink) {
+	utils.Info("开始处理Coze格式流式响应")
+
+	re := regexp.MustCompile(`<[^>]*>`)
+	var contentLength int
+	result, err := readCozeStream(resp.Body, func(content string) {
+		cleanContent := re.ReplaceAllString(content, "")
+		contentLength += len(cleanContent)
+		sink.Content(cleanContent)
+	})
+	if err != nil {
+		utils.Error("Coze流式响应失败", zap.Error(err))
+		sink.Error(err.Error())
+		return
+	}
+
+	done := newGenerateResult("", result.Usage, nil)
+	done.ConversationID = result.ConversationID
+	done.FollowUps = result.FollowUps
+	sink.Done(done)
+
+	utils.Info("Coze格式流式生成处理完成",
+		zap.String("conversation_id", result.ConversationID),
+		zap.Int("content_length", contentLength))
+}
+
+// parseCozeResponse 汇总Coze流式响应并以非流式格式返回
+func parseCozeResponse(body []byte) (*GenerateResult, error) {
+	utils.Info("开始解析Coze格式响应")
+
+	var content strings.Builder
+	result, err := readCozeStream(bytes.NewReader(body), func(delta string) {
+		content.WriteString(delta)
+	})
+	if err != nil {
+		utils.Error("Coze响应失败", zap.Error(err))
+		return nil, err
+	}
+
+	if content.Len() == 0 {
+		utils.Warn("Coze API未返回内容")
+		return nil, errors.New("API未返回内容")
+	}
+
+	re := regexp.MustCompile(`<[^>]*>`)
+	usage := result.Usage
+	if usage == nil {
+		usage = &Usage{}
+	}
+
+	generated := newGenerateResult(re.ReplaceAllString(content.String(), ""), usage, nil)
+	generated.ConversationID = result.ConversationID
+	generated.FollowUps = result.FollowUps
+	return generated, nil
+}
+
+// parseCozeError 解析Coze错误响应：{"code":4100,"msg":"..."}
+func parseCozeError(body []byte) string {
+	var errResp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Code == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Coze返回错误: %d %s", errResp.Code, errResp.Msg)
+}
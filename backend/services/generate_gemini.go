@@ -0,0 +1,368 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// GeminiPart Gemini内容片段：文本、图片、函数调用或函数结果
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *GeminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiInlineData 内联的二进制数据（Base64编码）
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// GeminiFunctionCall 模型发起的函数调用，args 为参数对象
+type GeminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse 函数执行结果，response 必须为对象
+type GeminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+// GeminiContent Gemini对话内容，角色只有 user/model
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiGenerationConfig Gemini生成参数
+type GeminiGenerationConfig struct {
+	Temperature     float32  `json:"temperature,omitempty"`
+	TopP            float32  `json:"topP,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+}
+
+// GeminiFunctionDeclaration Gemini函数声明
+type GeminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// GeminiTool Gemini工具定义
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiToolConfig Gemini工具选择策略，mode 取值 AUTO/NONE/ANY
+type GeminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"`
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+// GeminiRequest Gemini generateContent 请求格式
+type GeminiRequest struct {
+	Contents          []GeminiContent        `json:"contents"`
+	SystemInstruction *GeminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  GeminiGenerationConfig `json:"generationConfig"`
+	Tools             []GeminiTool           `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig      `json:"toolConfig,omitempty"`
+}
+
+// GeminiResponse Gemini generateContent 响应格式（流式每个数据块格式相同）
+type GeminiResponse struct {
+	Candidates []struct {
+		Content      GeminiContent `json:"content"`
+		FinishReason string        `json:"finishReason,omitempty"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+}
+
+// usage 转换为统一的token用量
+func (r *GeminiResponse) usage() *Usage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+}
+
+// parts 返回第一个候选的内容片段
+func (r *GeminiResponse) parts() []GeminiPart {
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	return r.Candidates[0].Content.Parts
+}
+
+// buildGeminiRequest 构建Gemini generateContent 请求
+// assistant 对应 model 角色；tool 消息转换为 functionResponse，函数名从历史调用中查找
+func buildGeminiRequest(req GenerateRequest) GeminiRequest {
+	geminiReq := GeminiRequest{
+		GenerationConfig: GeminiGenerationConfig{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			MaxOutputTokens: req.MaxTokens,
+			StopSequences:   req.Stop,
+			Seed:            req.Seed,
+		},
+	}
+
+	messages := buildMessages(req)
+	var systemParts []GeminiPart
+	for _, msg := range messages {
+		switch msg.Role {
+		case RoleSystem:
+			systemParts = append(systemParts, GeminiPart{Text: msg.Content})
+		case RoleTool:
+			name := msg.Name
+			if name == "" {
+				name = toolCallName(messages, msg.ToolCallID)
+			}
+			part := GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				ID:       msg.ToolCallID,
+				Name:     name,
+				Response: geminiFunctionResponse(msg.Content),
+			}}
+			last := len(geminiReq.Contents) - 1
+			if last >= 0 && geminiReq.Contents[last].Role == RoleUser && geminiReq.Contents[last].Parts[0].FunctionResponse != nil {
+				geminiReq.Contents[last].Parts = append(geminiReq.Contents[last].Parts, part)
+			} else {
+				geminiReq.Contents = append(geminiReq.Contents, GeminiContent{Role: RoleUser, Parts: []GeminiPart{part}})
+			}
+		default:
+			content := GeminiContent{Role: RoleUser}
+			if msg.Role == RoleAssistant {
+				content.Role = "model"
+			}
+			if msg.Content != "" {
+				content.Parts = append(content.Parts, GeminiPart{Text: msg.Content})
+			}
+			for _, image := range msg.Images {
+				content.Parts = append(content.Parts, GeminiPart{InlineData: &GeminiInlineData{
+					MimeType: image.MimeType,
+					Data:     image.base64Data(),
+				}})
+			}
+			for _, call := range msg.ToolCalls {
+				content.Parts = append(content.Parts, GeminiPart{FunctionCall: &GeminiFunctionCall{
+					ID:   call.ID,
+					Name: call.Function.Name,
+					Args: toolArgumentsObject(call.Function.Arguments),
+				}})
+			}
+			if len(content.Parts) > 0 {
+				geminiReq.Contents = append(geminiReq.Contents, content)
+			}
+		}
+	}
+	if len(systemParts) > 0 {
+		geminiReq.SystemInstruction = &GeminiContent{Parts: systemParts}
+	}
+
+	if len(req.Tools) > 0 {
+		declarations := make([]GeminiFunctionDeclaration, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			declarations = append(declarations, GeminiFunctionDeclaration{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			})
+		}
+		geminiReq.Tools = []GeminiTool{{FunctionDeclarations: declarations}}
+
+		choice := parseToolChoice(req.ToolChoice)
+		mode := map[string]string{"auto": "AUTO", "none": "NONE", "required": "ANY", "function": "ANY"}[choice.Mode]
+		if mode != "" {
+			geminiReq.ToolConfig = &GeminiToolConfig{}
+			geminiReq.ToolConfig.FunctionCallingConfig.Mode = mode
+			if choice.Name != "" {
+				geminiReq.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{choice.Name}
+			}
+		}
+	}
+	return geminiReq
+}
+
+// geminiFunctionResponse 工具结果为JSON对象时直接使用，否则包装为 {"content": "..."}
+func geminiFunctionResponse(content string) json.RawMessage {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	data, _ := json.Marshal(map[string]string{"content": content})
+	return data
+}
+
+// normalizeGeminiFunctionCalls 将 functionCall 片段转换为OpenAI格式的工具调用
+// 早期模型不返回调用ID，按序号生成
+func normalizeGeminiFunctionCalls(parts []GeminiPart, offset int) []ToolCall {
+	var calls []ToolCall
+	for _, part := range parts {
+		if part.FunctionCall == nil {
+			continue
+		}
+		id := part.FunctionCall.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", offset+len(calls))
+		}
+		calls = append(calls, ToolCall{
+			ID:   id,
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      part.FunctionCall.Name,
+				Arguments: toolArgumentsString(part.FunctionCall.Args),
+			},
+		})
+	}
+	return calls
+}
+
+// geminiText 拼接文本片段
+func geminiText(parts []GeminiPart) string {
+	var text strings.Builder
+	for _, part := range parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// handleGeminiStreamResponse 处理Gemini streamGenerateContent?alt=sse 格式的流式响应
+func handleGeminiStreamResponse(resp *http.Response, sink StreamSink) {
+	utils.Info("开始处理Gemini格式流式响应")
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	re := regexp.MustCompile(`<[^>]*>`)
+	var contentLength int
+	var usage *Usage
+	var toolCalls []ToolCall
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var geminiResp GeminiResponse
+		if err := json.Unmarshal([]byte(data), &geminiResp); err != nil {
+			utils.Error("解析Gemini流响应失败", zap.Error(err), zap.String("data", data))
+			continue
+		}
+
+		if geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+			msg := fmt.Sprintf("提示词被Gemini安全策略拦截: %s", geminiResp.PromptFeedback.BlockReason)
+			utils.Warn("Gemini提示词被拦截", zap.String("reason", msg))
+			sink.Error(msg)
+			return
+		}
+		if u := geminiResp.usage(); u != nil {
+			usage = u
+		}
+
+		parts := geminiResp.parts()
+		if cleanContent := re.ReplaceAllString(geminiText(parts), ""); cleanContent != "" {
+			contentLength += len(cleanContent)
+			sink.Content(cleanContent)
+		}
+
+		// Gemini一次返回完整的函数调用
+		if calls := normalizeGeminiFunctionCalls(parts, len(toolCalls)); len(calls) > 0 {
+			deltas := make([]ToolCall, 0, len(calls))
+			for _, call := range calls {
+				deltas = append(deltas, indexedToolCall(len(toolCalls), call))
+				toolCalls = append(toolCalls, call)
+			}
+			sink.ToolCalls(deltas)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		utils.Error("读取Gemini流数据失败", zap.Error(err))
+		sink.Error("读取流数据失败")
+		return
+	}
+	sink.Done(newGenerateResult("", usage, toolCalls))
+
+	utils.Info("Gemini格式流式生成处理完成", zap.Int("content_length", contentLength))
+}
+
+// parseGeminiResponse 解析Gemini格式的响应
+func parseGeminiResponse(body []byte) (*GenerateResult, error) {
+	utils.Info("开始解析Gemini格式响应")
+
+	var geminiResp GeminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		utils.Error("解析Gemini响应失败", zap.Error(err), zap.String("body", string(body)))
+		return nil, errors.New("解析响应失败")
+	}
+
+	if geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+		msg := fmt.Sprintf("提示词被Gemini安全策略拦截: %s", geminiResp.PromptFeedback.BlockReason)
+		utils.Warn("Gemini提示词被拦截", zap.String("reason", msg))
+		return nil, errors.New(msg)
+	}
+
+	parts := geminiResp.parts()
+	content := geminiText(parts)
+	toolCalls := normalizeGeminiFunctionCalls(parts, 0)
+	if content == "" && len(toolCalls) == 0 {
+		utils.Warn("Gemini API未返回内容")
+		return nil, errors.New("API未返回内容")
+	}
+
+	re := regexp.MustCompile(`<[^>]*>`)
+	usage := geminiResp.usage()
+	if usage == nil {
+		usage = &Usage{}
+	}
+	return newGenerateResult(re.ReplaceAllString(content, ""), usage, toolCalls), nil
+}
+
+// parseGeminiError 解析Gemini错误响应：{"error":{"code":400,"message":"...","status":"INVALID_ARGUMENT"}}
+func parseGeminiError(body []byte) string {
+	var errResp []struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	// 流式接口出错时返回数组
+	trimmed := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(trimmed, "[") {
+		trimmed = "[" + trimmed + "]"
+	}
+	if err := json.Unmarshal([]byte(trimmed), &errResp); err != nil || len(errResp) == 0 || errResp[0].Error == nil {
+		return ""
+	}
+	e := errResp[0].Error
+	return fmt.Sprintf("Gemini返回错误: %s %s", e.Status, e.Message)
+}
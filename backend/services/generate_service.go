@@ -0,0 +1,1236 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// truncateString 截断字符串，用于日志输出
+func truncateString(s string, maxLen int) string {
+	if utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+
+	// 转换为rune切片以正确处理中文字符
+	runes := []rune(s)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen]) + "..."
+	}
+	return s
+}
+
+// GenerateRequest AI生成请求
+type GenerateRequest struct {
+	ProviderID  uint     `json:"provider_id" binding:"required"` // API Provider ID
+	Prompt      string   `json:"prompt" binding:"required"`      // 提示词
+	System      string   `json:"system,omitempty"`               // 可选：系统消息
+	Temperature float32  `json:"temperature,omitempty"`          // 温度参数，默认0.7
+	MaxTokens   int      `json:"max_tokens,omitempty"`           // 最大token数，默认2000，已知模型会限制在剩余上下文之内
+	TopP        float32  `json:"top_p,omitempty"`                // 可选：核采样参数
+	Stop        []string `json:"stop,omitempty"`                 // 可选：停止词
+	Seed        *int     `json:"seed,omitempty"`                 // 可选：随机种子
+	Stream      bool     `json:"stream,omitempty"`               // 是否流式响应，默认true
+	Model       string   `json:"model,omitempty"`                // 可选：覆盖Provider配置的模型
+	NumCtx      int      `json:"num_ctx,omitempty"`              // 可选：上下文窗口大小（仅Ollama原生模式）
+	KeepAlive   string   `json:"keep_alive,omitempty"`           // 可选：模型驻留时间，如 5m（仅Ollama原生模式）
+	Format      string   `json:"format,omitempty"`               // 可选：输出格式，目前支持 json（仅Ollama原生模式）
+
+	ConversationID string `json:"conversation_id,omitempty"` // 可选：会话ID，用于延续Coze会话
+	TemplateID     uint64 `json:"template_id,omitempty"`     // 可选：生成所用的模板ID，用于读取模板的输出过滤设置
+	User           string `json:"-"`                         // 当前用户标识，由服务端填充
+
+	Messages   []OpenAIMessage `json:"messages,omitempty"`    // 可选：历史消息，位于system之后、prompt之前，可包含tool角色的工具结果
+	Tools      []Tool          `json:"tools,omitempty"`       // 可选：工具定义
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"` // 可选：工具选择策略，auto/none/required或指定函数
+
+	Images      []GenerateImage   `json:"images,omitempty"` // 可选：随提示词附带的图片
+	Attachments []ImageAttachment `json:"-"`                // 解析后的图片，由服务端填充
+}
+
+// OpenAIMessage OpenAI格式的消息
+type OpenAIMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant消息中的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool消息对应的工具调用ID
+
+	Images []ImageAttachment `json:"-"` // 附带的图片，序列化时转换为 content 数组
+}
+
+// OpenAIContentPart OpenAI多模态消息内容片段
+type OpenAIContentPart struct {
+	Type     string `json:"type"` // text / image_url
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// MarshalJSON 带图片的消息将 content 序列化为文本与 image_url 片段数组
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	type message OpenAIMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(message(m))
+	}
+
+	parts := make([]OpenAIContentPart, 0, len(m.Images)+1)
+	if m.Content != "" {
+		parts = append(parts, OpenAIContentPart{Type: "text", Text: m.Content})
+	}
+	for _, image := range m.Images {
+		part := OpenAIContentPart{Type: "image_url"}
+		part.ImageURL = &struct {
+			URL string `json:"url"`
+		}{URL: image.dataURL()}
+		parts = append(parts, part)
+	}
+	return json.Marshal(struct {
+		message
+		Content []OpenAIContentPart `json:"content"`
+	}{message: message(m), Content: parts})
+}
+
+// OpenAIRequest OpenAI API请求格式
+type OpenAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []OpenAIMessage      `json:"messages"`
+	Temperature   float32              `json:"temperature,omitempty"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	TopP          float32              `json:"top_p,omitempty"`
+	Stop          []string             `json:"stop,omitempty"`
+	Seed          *int                 `json:"seed,omitempty"`
+	Stream        bool                 `json:"stream"`
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"`
+	Tools         []Tool               `json:"tools,omitempty"`
+	ToolChoice    json.RawMessage      `json:"tool_choice,omitempty"`
+}
+
+// OpenAIStreamOptions 流式选项，include_usage 使最后一个数据块返回token用量
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// Usage token用量，各Provider的用量统一转换为OpenAI格式
+// Ollama原生模式额外返回耗时统计
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+
+	TotalDurationMs int64   `json:"total_duration_ms,omitempty"`
+	LoadDurationMs  int64   `json:"load_duration_ms,omitempty"`
+	EvalDurationMs  int64   `json:"eval_duration_ms,omitempty"`
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+}
+
+// OllamaOptions Ollama原生模式的模型参数
+type OllamaOptions struct {
+	Temperature float32  `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	TopP        float32  `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+// OllamaMessage Ollama原生格式的消息，工具调用参数为对象而非字符串
+type OllamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"` // Base64编码的图片
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+}
+
+// OllamaToolCall Ollama原生格式的工具调用
+type OllamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// OllamaChatRequest Ollama原生 /api/chat 请求格式
+type OllamaChatRequest struct {
+	Model     string          `json:"model"`
+	Messages  []OllamaMessage `json:"messages"`
+	Stream    bool            `json:"stream"`
+	Format    string          `json:"format,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+	Options   OllamaOptions   `json:"options"`
+	Tools     []Tool          `json:"tools,omitempty"`
+}
+
+// OllamaChatResponse Ollama原生 /api/chat 响应格式（流式每行一个，非流式为一个）
+type OllamaChatResponse struct {
+	Model              string        `json:"model"`
+	Message            OllamaMessage `json:"message"`
+	Done               bool          `json:"done"`
+	DoneReason         string        `json:"done_reason,omitempty"`
+	Error              string        `json:"error,omitempty"`
+	TotalDuration      int64         `json:"total_duration"`       // 纳秒
+	LoadDuration       int64         `json:"load_duration"`        // 纳秒
+	PromptEvalCount    int           `json:"prompt_eval_count"`    // 提示词token数
+	PromptEvalDuration int64         `json:"prompt_eval_duration"` // 纳秒
+	EvalCount          int           `json:"eval_count"`           // 生成token数
+	EvalDuration       int64         `json:"eval_duration"`        // 纳秒
+}
+
+// OpenAIStreamResponse OpenAI流式响应格式
+type OpenAIStreamResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content   string     `json:"content"`
+			Role      string     `json:"role,omitempty"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+		} `json:"delta"`
+		FinishReason         string                     `json:"finish_reason,omitempty"`
+		ContentFilterResults map[string]json.RawMessage `json:"content_filter_results,omitempty"` // Azure内容过滤结果
+		Usage                *Usage                     `json:"usage,omitempty"`                  // Moonshot在最后一个choice中返回用量
+	} `json:"choices"`
+	PromptFilterResults []AzurePromptFilterResult `json:"prompt_filter_results,omitempty"` // Azure提示词过滤结果
+	Usage               *Usage                    `json:"usage,omitempty"`                 // include_usage/智谱在最后一个数据块返回用量
+}
+
+// OpenAIResponse OpenAI非流式响应格式
+type OpenAIResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+		} `json:"message"`
+		FinishReason         string                     `json:"finish_reason"`
+		ContentFilterResults map[string]json.RawMessage `json:"content_filter_results,omitempty"` // Azure内容过滤结果
+	} `json:"choices"`
+	PromptFilterResults []AzurePromptFilterResult `json:"prompt_filter_results,omitempty"` // Azure提示词过滤结果
+	Usage               Usage                     `json:"usage"`
+}
+
+// GenerateResult 生成结果，流式与非流式调用统一使用
+type GenerateResult struct {
+	Content        string     `json:"content"`
+	Usage          *Usage     `json:"usage,omitempty"`
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason   string     `json:"finish_reason,omitempty"`
+	ConversationID string     `json:"conversation_id,omitempty"` // Coze会话ID
+	FollowUps      []string   `json:"follow_ups,omitempty"`      // Coze推荐问题
+}
+
+// newGenerateResult 构建生成结果，包含工具调用时结束原因为 tool_calls
+func newGenerateResult(content string, usage *Usage, toolCalls []ToolCall) *GenerateResult {
+	result := &GenerateResult{
+		Content:   content,
+		Usage:     usage,
+		ToolCalls: toolCalls,
+	}
+	if len(toolCalls) > 0 {
+		result.FinishReason = finishReasonToolCalls
+	}
+	return result
+}
+
+// StreamSink 流式生成的输出目标，由调用方决定如何转发（SSE、任务进度等）
+// Done 与 Error 二者只会调用其一，且只调用一次
+type StreamSink interface {
+	Content(content string)      // 生成内容片段
+	ToolCalls(deltas []ToolCall) // 工具调用增量
+	Done(result *GenerateResult) // 生成完成，Content 为完整内容
+	Error(message string)        // 生成失败
+}
+
+// collectSink 累积内容片段，完成时将完整内容写入结果后转发
+type collectSink struct {
+	StreamSink
+	content strings.Builder
+}
+
+func (s *collectSink) Content(content string) {
+	s.content.WriteString(content)
+	s.StreamSink.Content(content)
+}
+
+func (s *collectSink) Done(result *GenerateResult) {
+	if result.Content == "" {
+		result.Content = s.content.String()
+	}
+	s.StreamSink.Done(result)
+}
+
+// ErrProviderDisabled API Provider未启用
+var ErrProviderDisabled = errors.New("该API Provider未启用")
+
+// ValidateGenerateRequest 校验生成请求的消息、工具与图片参数
+func ValidateGenerateRequest(req GenerateRequest) error {
+	if err := validateGenerateMessages(req); err != nil {
+		return err
+	}
+	return validateGenerateImages(req)
+}
+
+// CheckGenerateProvider 检查Provider状态及是否支持请求中的工具调用与图片输入
+// 返回的错误均可直接作为参数错误提示给用户
+func CheckGenerateProvider(provider *models.APIProvider, req GenerateRequest) error {
+	if provider.APIStatus != 1 {
+		utils.Warn("API Provider未启用", zap.Uint("provider_id", provider.ID), zap.Int8("status", provider.APIStatus))
+		return ErrProviderDisabled
+	}
+
+	// 检查Provider是否支持工具调用
+	if len(req.Tools) > 0 && !supportsTools(provider.APIKind) {
+		utils.Warn("API Provider不支持工具调用", zap.String("provider_kind", provider.APIKind))
+		return errors.New("该模型类型不支持工具调用")
+	}
+
+	// 不支持工具调用的Provider也无法接收历史中的工具调用与工具结果
+	if !supportsTools(provider.APIKind) && hasToolMessages(req.Messages) {
+		utils.Warn("API Provider不支持工具消息", zap.String("provider_kind", provider.APIKind))
+		return errors.New("该模型类型不支持工具调用，历史消息中不能包含工具调用或工具结果")
+	}
+
+	// 检查Provider是否支持图片输入
+	if len(req.Images) > 0 && !supportsVision(provider.APIKind) {
+		utils.Warn("API Provider不支持图片输入", zap.String("provider_kind", provider.APIKind))
+		return errors.New("该模型类型不支持图片输入")
+	}
+	return nil
+}
+
+// PrepareGenerateRequest 检查Provider状态及能力、输入是否超出模型上下文窗口，并读取请求附带的图片
+// 返回的错误均可直接作为参数错误提示给用户
+func PrepareGenerateRequest(ctx context.Context, provider *models.APIProvider, req *GenerateRequest) error {
+	if err := CheckGenerateProvider(provider, *req); err != nil {
+		return err
+	}
+	if err := checkContextWindow(provider, req); err != nil {
+		return err
+	}
+
+	if len(req.Images) > 0 && len(req.Attachments) == 0 {
+		attachments, err := resolveGenerateImages(ctx, req.User, provider, req.Images)
+		if err != nil {
+			utils.Warn("读取生成图片失败", zap.Error(err))
+			return err
+		}
+		req.Attachments = attachments
+		utils.Info("生成请求附带图片", zap.Int("image_count", len(attachments)))
+	}
+	return nil
+}
+
+// applyGenerateDefaults 设置默认参数，返回实际使用的模型
+func applyGenerateDefaults(provider *models.APIProvider, req *GenerateRequest) string {
+	if req.Temperature == 0 {
+		req.Temperature = 0.7
+		utils.Info("设置默认温度参数", zap.Float32("temperature", req.Temperature))
+	}
+
+	// 使用请求中的模型或Provider配置的模型
+	if req.Model == "" {
+		utils.Info("使用Provider配置的模型", zap.String("model", provider.APIModel), zap.String("provider_kind", provider.APIKind))
+	} else {
+		utils.Info("使用请求中指定的模型", zap.String("model", req.Model), zap.String("provider_kind", provider.APIKind))
+	}
+
+	// 未指定时使用默认最大token数，已知模型限制在最大输出与剩余上下文之内
+	estimate := EstimateGenerateTokens(provider, req)
+	if req.MaxTokens == 0 {
+		utils.Info("设置默认最大token数", zap.Int("max_tokens", estimate.RequestedMaxTokens))
+	}
+	if estimate.Clamped {
+		utils.Info("最大token数超出模型可用范围，已自动调整",
+			zap.String("model", estimate.Model),
+			zap.Int("requested", estimate.RequestedMaxTokens),
+			zap.Int("max_tokens", estimate.MaxTokens),
+			zap.Int("prompt_tokens", estimate.PromptTokens),
+			zap.Int("context_window", estimate.ContextWindow))
+	}
+	req.MaxTokens = estimate.MaxTokens
+	return estimate.Model
+}
+
+// defaultGenerateTimeout 未指定截止时间时调用Provider的超时时间
+const defaultGenerateTimeout = 60 * time.Second
+
+// newGenerateClient 创建调用Provider的HTTP客户端
+// ctx 已设置截止时间时（如异步任务）由 ctx 控制超时，否则使用默认超时
+func newGenerateClient(ctx context.Context) *http.Client {
+	if _, ok := ctx.Deadline(); ok {
+		return &http.Client{}
+	}
+	return &http.Client{Timeout: defaultGenerateTimeout}
+}
+
+// GenerateStream 以流式方式调用Provider生成内容，结果通过 sink 输出，完成时记录用量与费用
+func GenerateStream(ctx context.Context, provider *models.APIProvider, req GenerateRequest, sink StreamSink) {
+	req.Stream = true
+	model := applyGenerateDefaults(provider, &req)
+	apiKey := strings.TrimSpace(provider.APIKey)
+	sink = &usageSink{StreamSink: sink, provider: provider, req: &req, model: model}
+	handleStreamGeneration(ctx, provider, apiKey, model, req, &collectSink{StreamSink: sink})
+}
+
+// Generate 以非流式方式调用Provider生成内容，成功时记录用量与费用
+func Generate(ctx context.Context, provider *models.APIProvider, req GenerateRequest) (*GenerateResult, error) {
+	req.Stream = false
+	model := applyGenerateDefaults(provider, &req)
+	apiKey := strings.TrimSpace(provider.APIKey)
+	result, err := handleNonStreamGeneration(ctx, provider, apiKey, model, req)
+	if err == nil {
+		RecordGenerationUsage(provider, &req, model, result)
+	}
+	return result, err
+}
+
+// handleStreamGeneration 处理流式生成
+func handleStreamGeneration(ctx context.Context, provider *models.APIProvider, apiKey, model string, req GenerateRequest, sink StreamSink) {
+	utils.Info("开始处理流式生成请求",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("provider_name", provider.Name),
+		zap.String("model", model),
+		zap.String("provider_kind", provider.APIKind))
+
+	// 构建请求体
+	reqBody, err := buildRequestBody(provider, model, req, true)
+	if err != nil {
+		utils.Error("请求体构建失败", zap.Error(err))
+		sink.Error("请求体构建失败")
+		return
+	}
+
+	utils.Debug("构建请求体成功", zap.String("request_body", string(reqBody)))
+
+	// 构建API URL
+	apiURL := buildAPIURL(provider, model, req)
+	utils.Info("构建API URL", zap.String("api_url", apiURL))
+
+	// 创建HTTP请求
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		utils.Error("创建HTTP请求失败", zap.Error(err))
+		sink.Error("创建请求失败")
+		return
+	}
+
+	// 设置请求头
+	if err := setRequestHeaders(ctx, httpReq, provider, apiKey); err != nil {
+		utils.Error("设置认证信息失败", zap.Error(err), zap.String("provider_kind", provider.APIKind))
+		sink.Error(fmt.Sprintf("认证失败: %v", err))
+		return
+	}
+	utils.Info("设置请求头完成", zap.String("provider_kind", provider.APIKind))
+
+	// 发送请求
+	client := newGenerateClient(ctx)
+
+	utils.Info("开始发送API请求")
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		utils.Error("API请求失败", zap.Error(err), zap.String("provider", provider.Name))
+		sink.Error(fmt.Sprintf("API调用失败: %v", err))
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Warn("关闭响应体失败", zap.Error(err))
+		}
+	}()
+
+	utils.Info("收到API响应", zap.Int("status_code", resp.StatusCode))
+
+	// 检查响应状态码
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		utils.Error("API返回错误状态码", zap.Int("status", resp.StatusCode), zap.String("body", string(body)), zap.String("api_url", apiURL))
+		sink.Error(describeAPIError(provider, resp.StatusCode, body, apiURL))
+		return
+	}
+
+	utils.Info("开始处理流式响应", zap.String("provider_kind", provider.APIKind))
+
+	// 根据Provider类型处理不同的流式响应格式
+	switch provider.APIKind {
+	case models.APIKindAnthropic:
+		utils.Debug("使用Anthropic格式处理流式响应")
+		handleAnthropicStreamResponse(resp, sink)
+	case models.APIKindGemini:
+		utils.Debug("使用Gemini格式处理流式响应")
+		handleGeminiStreamResponse(resp, sink)
+	case models.APIKindCoze:
+		utils.Debug("使用Coze格式处理流式响应")
+		handleCozeStreamResponse(resp, sink)
+	case models.APIKindQianfan:
+		utils.Debug("使用百度千帆格式处理流式响应")
+		handleQianfanStreamResponse(resp, sink)
+	case models.APIKindOllama:
+		// 如果是Ollama且URL包含/v1，使用OpenAI格式处理
+		if strings.Contains(provider.APIURL, "/v1") {
+			utils.Debug("使用OpenAI格式处理Ollama流式响应")
+			handleOpenAIStreamResponse(resp, sink)
+		} else {
+			// Ollama原生格式处理
+			utils.Debug("使用Ollama原生格式处理流式响应")
+			handleOllamaStreamResponse(resp, sink)
+		}
+	default:
+		// OpenAI兼容格式处理
+		utils.Debug("使用OpenAI兼容格式处理流式响应")
+		handleOpenAIStreamResponse(resp, sink)
+	}
+}
+
+// handleOpenAIStreamResponse 处理OpenAI格式的流式响应
+// 收到finish_reason后继续读取，以便接收最后一个数据块中的token用量（include_usage、智谱、Moonshot）
+func handleOpenAIStreamResponse(resp *http.Response, sink StreamSink) {
+	utils.Info("开始处理OpenAI格式流式响应")
+
+	// 读取流式响应
+	scanner := bufio.NewScanner(resp.Body)
+	var contentLength int
+	messageCount := 0
+	var usage *Usage
+	var toolCalls toolCallAccumulator
+	doneSent := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// 跳过空行
+		if line == "" {
+			continue
+		}
+
+		// SSE格式：data: {...}
+		if strings.HasPrefix(line, "data: ") {
+			data := strings.TrimPrefix(line, "data: ")
+
+			// OpenAI在流结束时发送 [DONE]
+			if data == "[DONE]" {
+				utils.Info("收到流结束信号[DONE]", zap.Int("message_count", messageCount), zap.Int("content_length", contentLength))
+				sink.Done(newGenerateResult("", usage, toolCalls.result()))
+				doneSent = true
+				break
+			}
+
+			// 解析并转发流式数据
+			var streamResp OpenAIStreamResponse
+			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
+				utils.Error("解析流响应失败", zap.Error(err), zap.String("data", data))
+				continue
+			}
+
+			messageCount++
+
+			// Azure在首个数据块中返回提示词过滤结果
+			if msg := describePromptFilter(streamResp.PromptFilterResults); msg != "" {
+				utils.Warn("提示词被内容过滤拦截", zap.String("reason", msg))
+				sink.Error(msg)
+				doneSent = true
+				break
+			}
+
+			if streamResp.Usage != nil {
+				usage = streamResp.Usage
+			}
+
+			// 提取内容并发送
+			if len(streamResp.Choices) > 0 {
+				choice := streamResp.Choices[0]
+				if choice.Usage != nil {
+					usage = choice.Usage
+				}
+
+				content := choice.Delta.Content
+				if content != "" {
+					// 清理HTML标签，防止前端显示问题
+					cleanContent := content
+					// 移除HTML标签
+					re := regexp.MustCompile(`<[^>]*>`)
+					cleanContent = re.ReplaceAllString(cleanContent, "")
+					contentLength += len(cleanContent)
+					sink.Content(cleanContent)
+					utils.Debug("发送流数据片段", zap.Int("length", len(cleanContent)))
+				}
+
+				// 工具调用增量单独作为 tool_calls 事件转发
+				if len(choice.Delta.ToolCalls) > 0 {
+					for _, delta := range choice.Delta.ToolCalls {
+						toolCalls.add(delta)
+					}
+					sink.ToolCalls(choice.Delta.ToolCalls)
+				}
+
+				// 生成内容被内容过滤拦截
+				if choice.FinishReason == "content_filter" {
+					msg := describeContentFilter(choice.ContentFilterResults)
+					utils.Warn("生成内容被内容过滤拦截", zap.String("reason", msg))
+					sink.Error(msg)
+					doneSent = true
+					break
+				}
+
+				if choice.FinishReason != "" {
+					utils.Info("流响应完成",
+						zap.String("finish_reason", choice.FinishReason),
+						zap.Int("message_count", messageCount),
+						zap.Int("content_length", contentLength))
+				}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		utils.Error("读取流数据失败", zap.Error(err))
+		sink.Error("读取流数据失败")
+	} else if !doneSent {
+		// 部分Provider不发送[DONE]，读取结束后补发完成信号
+		sink.Done(newGenerateResult("", usage, toolCalls.result()))
+	}
+
+	utils.Info("OpenAI格式流式生成处理完成",
+		zap.Int("message_count", messageCount),
+		zap.Int("content_length", contentLength))
+}
+
+// handleOllamaStreamResponse 处理Ollama原生 /api/chat 格式的流式响应
+func handleOllamaStreamResponse(resp *http.Response, sink StreamSink) {
+	utils.Info("开始处理Ollama原生格式流式响应")
+
+	// 读取流式响应
+	scanner := bufio.NewScanner(resp.Body)
+	var contentLength int
+	messageCount := 0
+	var toolCalls []ToolCall
+	doneSent := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// 跳过空行
+		if line == "" {
+			continue
+		}
+
+		// Ollama原生格式：每行都是一个独立的JSON对象
+		var ollamaResp OllamaChatResponse
+		if err := json.Unmarshal([]byte(line), &ollamaResp); err != nil {
+			utils.Error("解析Ollama流响应失败", zap.Error(err), zap.String("data", line))
+			continue
+		}
+
+		messageCount++
+
+		if ollamaResp.Error != "" {
+			utils.Error("Ollama返回错误", zap.String("error", ollamaResp.Error))
+			sink.Error(fmt.Sprintf("API返回错误: %s", ollamaResp.Error))
+			doneSent = true
+			break
+		}
+
+		// 提取内容并发送
+		if response := ollamaResp.Message.Content; response != "" {
+			// 清理HTML标签，防止前端显示问题
+			re := regexp.MustCompile(`<[^>]*>`)
+			cleanResponse := re.ReplaceAllString(response, "")
+			contentLength += len(cleanResponse)
+			sink.Content(cleanResponse)
+			utils.Debug("发送Ollama流数据片段", zap.Int("length", len(cleanResponse)))
+		}
+
+		// Ollama一次返回完整的工具调用
+		if calls := normalizeOllamaToolCalls(ollamaResp.Message.ToolCalls, len(toolCalls)); len(calls) > 0 {
+			deltas := make([]ToolCall, 0, len(calls))
+			for _, call := range calls {
+				deltas = append(deltas, indexedToolCall(len(toolCalls), call))
+				toolCalls = append(toolCalls, call)
+			}
+			sink.ToolCalls(deltas)
+		}
+
+		// 检查是否完成，最后一帧携带耗时统计
+		if ollamaResp.Done {
+			usage := buildOllamaUsage(&ollamaResp)
+			utils.Info("Ollama流响应完成",
+				zap.Int("message_count", messageCount),
+				zap.Int("content_length", contentLength),
+				zap.Any("usage", usage))
+			sink.Done(newGenerateResult("", usage, toolCalls))
+			doneSent = true
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		utils.Error("读取Ollama流数据失败", zap.Error(err))
+		sink.Error("读取流数据失败")
+	} else if !doneSent {
+		// 连接提前关闭时未收到完成帧，补发完成信号
+		sink.Done(newGenerateResult("", nil, toolCalls))
+	}
+
+	utils.Info("Ollama原生格式流式生成处理完成",
+		zap.Int("message_count", messageCount),
+		zap.Int("content_length", contentLength))
+}
+
+// handleNonStreamGeneration 处理非流式生成
+func handleNonStreamGeneration(ctx context.Context, provider *models.APIProvider, apiKey, model string, req GenerateRequest) (*GenerateResult, error) {
+	// 构建API URL
+	apiURL := buildAPIURL(provider, model, req)
+	utils.Info("开始处理非流式生成请求",
+		zap.Uint("provider_id", provider.ID),
+		zap.String("provider_name", provider.Name),
+		zap.String("model", model),
+		zap.String("provider_kind", provider.APIKind),
+		zap.String("api_url", apiURL))
+
+	// 构建请求体
+	reqBody, err := buildRequestBody(provider, model, req, false)
+	if err != nil {
+		utils.Error("请求体构建失败", zap.Error(err))
+		return nil, errors.New("请求体构建失败")
+	}
+
+	utils.Debug("构建请求体成功", zap.String("request_body", string(reqBody)))
+	utils.Info("构建API URL", zap.String("api_url", apiURL))
+
+	// 创建HTTP请求
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		utils.Error("创建HTTP请求失败", zap.Error(err))
+		return nil, errors.New("创建请求失败")
+	}
+
+	// 设置请求头
+	if err := setRequestHeaders(ctx, httpReq, provider, apiKey); err != nil {
+		utils.Error("设置认证信息失败", zap.Error(err), zap.String("provider_kind", provider.APIKind))
+		return nil, fmt.Errorf("认证失败: %v", err)
+	}
+	utils.Info("设置请求头完成", zap.String("provider_kind", provider.APIKind))
+
+	// 发送请求
+	client := newGenerateClient(ctx)
+
+	utils.Info("开始发送API请求")
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		utils.Error("API请求失败", zap.Error(err), zap.String("provider", provider.Name))
+		return nil, fmt.Errorf("API调用失败: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Warn("关闭响应体失败", zap.Error(err))
+		}
+	}()
+
+	utils.Info("收到API响应", zap.Int("status_code", resp.StatusCode))
+
+	// 读取响应
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		utils.Error("读取响应失败", zap.Error(err))
+		return nil, errors.New("读取响应失败")
+	}
+
+	utils.Debug("读取响应体成功", zap.Int("body_length", len(body)))
+
+	// 检查响应状态码
+	if resp.StatusCode != http.StatusOK {
+		utils.Error("API返回错误状态码", zap.Int("status", resp.StatusCode), zap.String("body", string(body)), zap.String("api_url", apiURL))
+		return nil, errors.New(describeAPIError(provider, resp.StatusCode, body, apiURL))
+	}
+
+	// 根据Provider类型解析不同的响应格式
+	switch provider.APIKind {
+	case models.APIKindAnthropic:
+		return parseAnthropicResponse(body)
+	case models.APIKindGemini:
+		return parseGeminiResponse(body)
+	case models.APIKindCoze:
+		return parseCozeResponse(body)
+	case models.APIKindQianfan:
+		return parseQianfanResponse(body)
+	case models.APIKindOllama:
+		// 如果是Ollama且URL包含/v1，使用OpenAI格式解析
+		if strings.Contains(provider.APIURL, "/v1") {
+			return parseOpenAIResponse(body)
+		}
+		// Ollama原生格式解析
+		return parseOllamaResponse(body)
+	default:
+		// OpenAI兼容格式解析
+		return parseOpenAIResponse(body)
+	}
+}
+
+// parseOpenAIResponse 解析OpenAI格式的响应
+func parseOpenAIResponse(body []byte) (*GenerateResult, error) {
+	utils.Info("开始解析OpenAI格式响应")
+
+	// 解析响应
+	var openaiResp OpenAIResponse
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		utils.Error("解析OpenAI响应失败", zap.Error(err), zap.String("body", string(body)))
+		return nil, errors.New("解析响应失败")
+	}
+
+	utils.Info("解析OpenAI响应成功",
+		zap.String("response_id", openaiResp.ID),
+		zap.String("response_model", openaiResp.Model))
+
+	if msg := describePromptFilter(openaiResp.PromptFilterResults); msg != "" {
+		utils.Warn("提示词被内容过滤拦截", zap.String("reason", msg))
+		return nil, errors.New(msg)
+	}
+
+	// 提取生成的内容
+	if len(openaiResp.Choices) == 0 {
+		utils.Warn("OpenAI API未返回内容")
+		return nil, errors.New("API未返回内容")
+	}
+
+	if openaiResp.Choices[0].FinishReason == "content_filter" {
+		msg := describeContentFilter(openaiResp.Choices[0].ContentFilterResults)
+		utils.Warn("生成内容被内容过滤拦截", zap.String("reason", msg))
+		return nil, errors.New(msg)
+	}
+
+	message := openaiResp.Choices[0].Message
+	content := message.Content
+	// 清理HTML标签，防止前端显示问题
+	re := regexp.MustCompile(`<[^>]*>`)
+	cleanContent := re.ReplaceAllString(content, "")
+	utils.Info("提取OpenAI生成内容成功",
+		zap.Int("content_length", len(cleanContent)),
+		zap.Int("prompt_tokens", openaiResp.Usage.PromptTokens),
+		zap.Int("completion_tokens", openaiResp.Usage.CompletionTokens),
+		zap.Int("total_tokens", openaiResp.Usage.TotalTokens))
+
+	return newGenerateResult(cleanContent, &openaiResp.Usage, message.ToolCalls), nil
+}
+
+// parseOllamaResponse 解析Ollama原生 /api/chat 格式的响应
+func parseOllamaResponse(body []byte) (*GenerateResult, error) {
+	utils.Info("开始解析Ollama原生格式响应")
+
+	// 解析响应
+	var ollamaResp OllamaChatResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		utils.Error("解析Ollama响应失败", zap.Error(err), zap.String("body", string(body)))
+		return nil, errors.New("解析响应失败")
+	}
+
+	utils.Info("解析Ollama响应成功",
+		zap.String("response_model", ollamaResp.Model),
+		zap.String("done_reason", ollamaResp.DoneReason))
+
+	if ollamaResp.Error != "" {
+		utils.Error("Ollama返回错误", zap.String("error", ollamaResp.Error))
+		return nil, fmt.Errorf("API返回错误: %s", ollamaResp.Error)
+	}
+
+	// 提取生成的内容
+	response := ollamaResp.Message.Content
+	toolCalls := normalizeOllamaToolCalls(ollamaResp.Message.ToolCalls, 0)
+	if response == "" && len(toolCalls) == 0 {
+		utils.Warn("Ollama API未返回内容")
+		return nil, errors.New("API未返回内容")
+	}
+
+	// 清理HTML标签，防止前端显示问题
+	re := regexp.MustCompile(`<[^>]*>`)
+	content := re.ReplaceAllString(response, "")
+	usage := buildOllamaUsage(&ollamaResp)
+	utils.Info("提取Ollama生成内容成功", zap.Int("content_length", len(content)), zap.Any("usage", usage))
+
+	return newGenerateResult(content, usage, toolCalls), nil
+}
+
+// buildOllamaUsage 将Ollama的计数与耗时统计转换为usage信息
+// Ollama的耗时字段单位为纳秒，tokens_per_second = eval_count / eval_duration
+func buildOllamaUsage(resp *OllamaChatResponse) *Usage {
+	usage := &Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		TotalDurationMs:  resp.TotalDuration / int64(time.Millisecond),
+		LoadDurationMs:   resp.LoadDuration / int64(time.Millisecond),
+	}
+	if resp.EvalDuration > 0 {
+		usage.EvalDurationMs = resp.EvalDuration / int64(time.Millisecond)
+		tokensPerSecond := float64(resp.EvalCount) / (float64(resp.EvalDuration) / float64(time.Second))
+		// 保留两位小数
+		usage.TokensPerSecond = float64(int64(tokensPerSecond*100+0.5)) / 100
+	}
+	return usage
+}
+
+// describeAPIError 根据Provider类型将上游错误响应转换为可读的错误信息
+func describeAPIError(provider *models.APIProvider, statusCode int, body []byte, apiURL string) string {
+	switch provider.APIKind {
+	case models.APIKindAzureOpenAI:
+		if msg := parseAzureError(body); msg != "" {
+			return msg
+		}
+	case models.APIKindAnthropic:
+		if msg := parseAnthropicError(body); msg != "" {
+			return msg
+		}
+	case models.APIKindGemini:
+		if msg := parseGeminiError(body); msg != "" {
+			return msg
+		}
+	case models.APIKindCoze:
+		if msg := parseCozeError(body); msg != "" {
+			return msg
+		}
+	case models.APIKindQwen, models.APIKindZhipu, models.APIKindQianfan, models.APIKindMoonshot, models.APIKindDoubao:
+		if msg := parseVendorError(provider.APIKind, body); msg != "" {
+			return msg
+		}
+	}
+
+	// 如果是404错误，提供更详细的错误信息
+	if statusCode == http.StatusNotFound {
+		return fmt.Sprintf("API返回404错误，%s。请求URL: %s", notFoundHint(provider), apiURL)
+	}
+	return fmt.Sprintf("API返回错误: %d", statusCode)
+}
+
+// notFoundHint 根据Provider类型给出404错误的排查提示
+func notFoundHint(provider *models.APIProvider) string {
+	switch provider.APIKind {
+	case models.APIKindOllama:
+		return "请检查Ollama服务是否运行、API地址是否正确（OpenAI兼容模式需以 /v1 结尾），以及模型是否已拉取"
+	case models.APIKindAzureOpenAI:
+		return "请检查资源地址、部署名称（模型名称）与api-version是否正确"
+	case models.APIKindCoze:
+		return "请检查API地址，以及Bot ID（模型名称）是否正确且已发布为API服务"
+	default:
+		return "请检查API地址与模型名称是否正确"
+	}
+}
+
+// buildAPIURL 根据Provider类型构建API URL
+func buildAPIURL(provider *models.APIProvider, model string, req GenerateRequest) string {
+	baseURL := strings.TrimRight(provider.APIURL, "/")
+
+	// 根据Provider类型和URL格式选择合适的端点
+	switch provider.APIKind {
+	case models.APIKindGemini:
+		// Google Gemini使用不同的API端点，流式接口以SSE格式返回
+		url := fmt.Sprintf("%s/models/%s:generateContent", baseURL, model)
+		if req.Stream {
+			url = fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", baseURL, model)
+		}
+		utils.Debug("构建Google Gemini API URL", zap.String("url", url))
+		return url
+	case models.APIKindAnthropic:
+		// Anthropic使用原生 Messages API；已配置完整 /messages 地址的Provider直接使用
+		url := baseURL
+		if !strings.HasSuffix(baseURL, "/messages") {
+			url = fmt.Sprintf("%s/messages", baseURL)
+		}
+		utils.Debug("构建Anthropic API URL", zap.String("url", url))
+		return url
+	case models.APIKindAzureOpenAI:
+		// Azure OpenAI按部署名路由，模型名即部署名，api-version作为查询参数
+		url := buildAzureURL(baseURL, model, provider.APIVersion)
+		utils.Debug("构建Azure OpenAI API URL", zap.String("url", url))
+		return url
+	case models.APIKindCoze:
+		// Coze对话接口，会话ID作为查询参数
+		url := buildCozeURL(baseURL, req.ConversationID)
+		utils.Debug("构建Coze API URL", zap.String("url", url))
+		return url
+	case models.APIKindQianfan:
+		// 百度千帆按服务路径路由，模型名称即服务路径，access_token在认证阶段追加
+		url := fmt.Sprintf("%s/wenxinworkshop/chat/%s", baseURL, model)
+		utils.Debug("构建百度千帆API URL", zap.String("url", url))
+		return url
+	case models.APIKindOllama:
+		// Ollama 也支持 OpenAI 兼容格式
+		// 如果 api_url 包含 /v1，使用 OpenAI 格式
+		if strings.Contains(baseURL, "/v1") {
+			url := fmt.Sprintf("%s/chat/completions", baseURL)
+			utils.Debug("构建Ollama OpenAI兼容模式URL", zap.String("url", url))
+			return url
+		}
+		// 否则使用 Ollama 原生 chat 接口
+		url := fmt.Sprintf("%s/api/chat", baseURL)
+		utils.Debug("构建Ollama原生模式URL", zap.String("url", url))
+		return url
+	default:
+		// OpenAI兼容格式（适用于大部分Provider）
+		url := fmt.Sprintf("%s/chat/completions", baseURL)
+		utils.Debug("构建OpenAI兼容模式URL", zap.String("url", url))
+		return url
+	}
+}
+
+// buildMessages 构建对话消息列表（可选的系统消息 + 历史消息 + 用户提示词）
+// 历史消息以工具结果结尾时，提示词可以为空
+func buildMessages(req GenerateRequest) []OpenAIMessage {
+	messages := make([]OpenAIMessage, 0, len(req.Messages)+2)
+	if strings.TrimSpace(req.System) != "" {
+		messages = append(messages, OpenAIMessage{
+			Role:    RoleSystem,
+			Content: req.System,
+		})
+	}
+	messages = append(messages, req.Messages...)
+	if strings.TrimSpace(req.Prompt) != "" || len(req.Messages) == 0 {
+		messages = append(messages, OpenAIMessage{
+			Role:    RoleUser,
+			Content: req.Prompt,
+			Images:  req.Attachments,
+		})
+	}
+	return messages
+}
+
+// buildOllamaMessages 将消息转换为Ollama原生格式，工具调用参数转换为对象
+func buildOllamaMessages(req GenerateRequest) []OllamaMessage {
+	openaiMessages := buildMessages(req)
+	messages := make([]OllamaMessage, 0, len(openaiMessages))
+	for _, msg := range openaiMessages {
+		ollamaMsg := OllamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, image := range msg.Images {
+			ollamaMsg.Images = append(ollamaMsg.Images, image.base64Data())
+		}
+		for _, call := range msg.ToolCalls {
+			var toolCall OllamaToolCall
+			toolCall.Function.Name = call.Function.Name
+			toolCall.Function.Arguments = toolArgumentsObject(call.Function.Arguments)
+			ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, toolCall)
+		}
+		messages = append(messages, ollamaMsg)
+	}
+	return messages
+}
+
+// normalizeOllamaToolCalls 将Ollama原生格式的工具调用转换为OpenAI格式
+// Ollama不返回调用ID，按序号生成
+func normalizeOllamaToolCalls(calls []OllamaToolCall, offset int) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	result := make([]ToolCall, 0, len(calls))
+	for i, call := range calls {
+		result = append(result, ToolCall{
+			ID:   fmt.Sprintf("call_%d", offset+i),
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: toolArgumentsString(call.Function.Arguments),
+			},
+		})
+	}
+	return result
+}
+
+// buildOpenAIRequest 构建OpenAI兼容格式请求
+func buildOpenAIRequest(provider *models.APIProvider, model string, req GenerateRequest, stream bool) OpenAIRequest {
+	openaiReq := OpenAIRequest{
+		Model:       model,
+		Messages:    buildMessages(req),
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
+		Stream:      stream,
+		Tools:       req.Tools,
+	}
+	if len(req.Tools) > 0 {
+		openaiReq.ToolChoice = req.ToolChoice
+	}
+	// 支持的Provider在流式响应末尾返回token用量
+	if info, ok := models.GetAPIKindInfo(provider.APIKind); ok && info.StreamUsage && stream {
+		openaiReq.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
+	}
+	return openaiReq
+}
+
+// buildOllamaChatRequest 构建Ollama原生 /api/chat 格式请求
+// 模型参数统一放入 options，max_tokens 对应 num_predict
+func buildOllamaChatRequest(model string, req GenerateRequest, stream bool) OllamaChatRequest {
+	ollamaReq := OllamaChatRequest{
+		Model:     model,
+		Messages:  buildOllamaMessages(req),
+		Stream:    stream,
+		KeepAlive: req.KeepAlive,
+		Tools:     req.Tools,
+		Options: OllamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+			NumCtx:      req.NumCtx,
+			TopP:        req.TopP,
+			Stop:        req.Stop,
+			Seed:        req.Seed,
+		},
+	}
+	if strings.EqualFold(req.Format, "json") {
+		ollamaReq.Format = "json"
+	}
+	return ollamaReq
+}
+
+// buildRequestBody 构建请求体
+func buildRequestBody(provider *models.APIProvider, model string, req GenerateRequest, stream bool) ([]byte, error) {
+	utils.Debug("开始构建请求体", zap.String("provider_kind", provider.APIKind), zap.String("model", model), zap.String("prompt_preview", truncateString(req.Prompt, 50)))
+
+	// 根据Provider类型构建不同的请求体
+	switch provider.APIKind {
+	case models.APIKindAnthropic:
+		utils.Debug("构建Anthropic格式请求体")
+		data, err := json.Marshal(buildAnthropicRequest(model, req, stream))
+		if err != nil {
+			utils.Error("序列化Anthropic请求体失败", zap.Error(err))
+			return nil, err
+		}
+		utils.Debug("构建Anthropic请求体成功", zap.String("request_body", string(data)))
+		return data, nil
+	case models.APIKindGemini:
+		utils.Debug("构建Gemini格式请求体")
+		data, err := json.Marshal(buildGeminiRequest(req))
+		if err != nil {
+			utils.Error("序列化Gemini请求体失败", zap.Error(err))
+			return nil, err
+		}
+		utils.Debug("构建Gemini请求体成功", zap.String("request_body", string(data)))
+		return data, nil
+	case models.APIKindCoze:
+		utils.Debug("构建Coze格式请求体")
+		data, err := json.Marshal(buildCozeChatRequest(model, req))
+		if err != nil {
+			utils.Error("序列化Coze请求体失败", zap.Error(err))
+			return nil, err
+		}
+		utils.Debug("构建Coze请求体成功", zap.String("request_body", string(data)))
+		return data, nil
+	case models.APIKindQianfan:
+		utils.Debug("构建百度千帆格式请求体")
+		data, err := json.Marshal(buildQianfanRequest(req, stream))
+		if err != nil {
+			utils.Error("序列化百度千帆请求体失败", zap.Error(err))
+			return nil, err
+		}
+		utils.Debug("构建百度千帆请求体成功", zap.String("request_body", string(data)))
+		return data, nil
+	case models.APIKindOllama:
+		// 如果URL包含/v1，使用OpenAI格式
+		if strings.Contains(provider.APIURL, "/v1") {
+			utils.Debug("构建Ollama OpenAI兼容格式请求体")
+			data, err := json.Marshal(buildOpenAIRequest(provider, model, req, stream))
+			if err != nil {
+				utils.Error("序列化OpenAI请求体失败", zap.Error(err))
+				return nil, err
+			}
+			utils.Debug("构建OpenAI请求体成功", zap.String("request_body", string(data)))
+			return data, nil
+		}
+
+		utils.Debug("构建Ollama原生格式请求体")
+		data, err := json.Marshal(buildOllamaChatRequest(model, req, stream))
+		if err != nil {
+			utils.Error("序列化Ollama请求体失败", zap.Error(err))
+			return nil, err
+		}
+		utils.Debug("构建Ollama请求体成功", zap.String("request_body", string(data)))
+		return data, nil
+	default:
+		utils.Debug("构建OpenAI兼容格式请求体")
+		// OpenAI兼容格式（适用于大部分Provider）
+		data, err := json.Marshal(buildOpenAIRequest(provider, model, req, stream))
+		if err != nil {
+			utils.Error("序列化OpenAI请求体失败", zap.Error(err))
+			return nil, err
+		}
+		utils.Debug("构建OpenAI请求体成功", zap.String("request_body", string(data)))
+		return data, nil
+	}
+}
+
+// setRequestHeaders 设置请求头及认证信息
+// 智谱需要签发JWT，百度千帆需要换取access_token，因此可能返回错误
+func setRequestHeaders(ctx context.Context, req *http.Request, provider *models.APIProvider, apiKey string) error {
+	utils.Debug("开始设置请求头", zap.String("provider_kind", provider.APIKind), zap.String("api_url", req.URL.String()))
+	req.Header.Set("Content-Type", "application/json")
+
+	// 根据Provider类型设置认证头
+	switch provider.APIKind {
+	case models.APIKindGemini:
+		// Google Gemini使用API Key作为查询参数
+		q := req.URL.Query()
+		q.Set("key", apiKey)
+		req.URL.RawQuery = q.Encode()
+		utils.Debug("设置Google Gemini请求头", zap.String("query", req.URL.RawQuery))
+	case models.APIKindAnthropic:
+		// Anthropic使用x-api-key头
+		req.Header.Set("x-api-key", apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		utils.Debug("设置Anthropic请求头", zap.String("x-api-key", apiKey))
+	case models.APIKindAzureOpenAI:
+		// Azure OpenAI使用api-key头
+		req.Header.Set("api-key", apiKey)
+		utils.Debug("设置Azure OpenAI请求头")
+	case models.APIKindZhipu:
+		// 智谱使用API Key签发的JWT作为Bearer Token
+		token, err := zhipuToken(apiKey)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		utils.Debug("设置智谱请求头")
+	case models.APIKindQianfan:
+		// 百度千帆使用AK/SK换取的access_token作为查询参数
+		token, err := qianfanAccessToken(ctx, apiKey)
+		if err != nil {
+			return err
+		}
+		q := req.URL.Query()
+		q.Set("access_token", token)
+		req.URL.RawQuery = q.Encode()
+		utils.Debug("设置百度千帆请求参数")
+	case models.APIKindOllama:
+		// Ollama通常不需要认证头，除非有特殊配置
+		// 如果URL包含/v1，使用OpenAI格式的认证
+		if strings.Contains(provider.APIURL, "/v1") {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+			utils.Debug("设置Ollama OpenAI兼容模式请求头", zap.String("Authorization", fmt.Sprintf("Bearer %s", apiKey)))
+		} else {
+			utils.Debug("Ollama原生模式无需认证头")
+		}
+		// 否则Ollama原生格式通常不需要认证头
+	default:
+		// OpenAI兼容格式（适用于大部分Provider）
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+		utils.Debug("设置OpenAI兼容模式请求头", zap.String("Authorization", fmt.Sprintf("Bearer %s", apiKey)))
+	}
+	return nil
+}
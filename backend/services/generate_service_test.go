@@ -0,0 +1,895 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestBuildAPIURLOllama(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiURL string
+		want   string
+	}{
+		{"原生模式", "http://localhost:11434", "http://localhost:11434/api/chat"},
+		{"原生模式-末尾斜杠", "http://localhost:11434/", "http://localhost:11434/api/chat"},
+		{"OpenAI兼容模式", "http://localhost:11434/v1", "http://localhost:11434/v1/chat/completions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &models.APIProvider{APIKind: "Ollama", APIURL: tt.apiURL}
+			if got := buildAPIURL(provider, "qwen2", GenerateRequest{}); got != tt.want {
+				t.Errorf("buildAPIURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRequestBodyOllamaChat(t *testing.T) {
+	seed := 42
+	provider := &models.APIProvider{APIKind: "Ollama", APIURL: "http://localhost:11434"}
+	req := GenerateRequest{
+		Prompt:      "写一段介绍",
+		System:      "你是写作助手",
+		Temperature: 0.5,
+		MaxTokens:   512,
+		NumCtx:      4096,
+		TopP:        0.9,
+		Stop:        []string{"###"},
+		Seed:        &seed,
+		KeepAlive:   "10m",
+		Format:      "JSON",
+	}
+
+	data, err := buildRequestBody(provider, "qwen2", req, true)
+	if err != nil {
+		t.Fatalf("buildRequestBody() error = %v", err)
+	}
+
+	var got OllamaChatRequest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal request body error = %v", err)
+	}
+
+	if got.Model != "qwen2" || !got.Stream {
+		t.Errorf("unexpected model/stream: %s/%v", got.Model, got.Stream)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Role != "user" {
+		t.Fatalf("unexpected messages: %+v", got.Messages)
+	}
+	if got.Options.NumPredict != 512 || got.Options.NumCtx != 4096 || got.Options.TopP != 0.9 {
+		t.Errorf("unexpected options: %+v", got.Options)
+	}
+	if got.Options.Seed == nil || *got.Options.Seed != 42 || len(got.Options.Stop) != 1 {
+		t.Errorf("unexpected seed/stop: %+v", got.Options)
+	}
+	if got.KeepAlive != "10m" || got.Format != "json" {
+		t.Errorf("unexpected keep_alive/format: %s/%s", got.KeepAlive, got.Format)
+	}
+}
+
+func TestBuildRequestBodyOpenAIWithoutSystem(t *testing.T) {
+	provider := &models.APIProvider{APIKind: "DeepSeek", APIURL: "https://api.deepseek.com"}
+	req := GenerateRequest{Prompt: "你好", MaxTokens: 100}
+
+	data, err := buildRequestBody(provider, "deepseek-chat", req, false)
+	if err != nil {
+		t.Fatalf("buildRequestBody() error = %v", err)
+	}
+
+	var got OpenAIRequest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal request body error = %v", err)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+	if got.MaxTokens != 100 {
+		t.Errorf("max_tokens = %d, want 100", got.MaxTokens)
+	}
+}
+
+func TestBuildOllamaUsage(t *testing.T) {
+	resp := &OllamaChatResponse{
+		PromptEvalCount: 20,
+		EvalCount:       100,
+		TotalDuration:   int64(3 * time.Second),
+		EvalDuration:    int64(2 * time.Second),
+	}
+
+	usage := buildOllamaUsage(resp)
+	if usage.TotalTokens != 120 {
+		t.Errorf("total_tokens = %v, want 120", usage.TotalTokens)
+	}
+	if usage.TokensPerSecond != 50.0 {
+		t.Errorf("tokens_per_second = %v, want 50", usage.TokensPerSecond)
+	}
+	if usage.TotalDurationMs != 3000 {
+		t.Errorf("total_duration_ms = %v, want 3000", usage.TotalDurationMs)
+	}
+	data, _ := json.Marshal(usage)
+	if strings.Contains(string(data), "load_duration_ms") {
+		t.Errorf("load_duration_ms should be omitted when zero")
+	}
+}
+
+func TestBuildAPIURLAnthropic(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiURL string
+		want   string
+	}{
+		{"基础地址", "https://api.anthropic.com/v1", "https://api.anthropic.com/v1/messages"},
+		{"基础地址-末尾斜杠", "https://api.anthropic.com/v1/", "https://api.anthropic.com/v1/messages"},
+		{"完整Messages地址", "https://api.anthropic.com/v1/messages", "https://api.anthropic.com/v1/messages"},
+		{"完整Messages地址-末尾斜杠", "https://proxy.example.com/anthropic/v1/messages/", "https://proxy.example.com/anthropic/v1/messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &models.APIProvider{APIKind: models.APIKindAnthropic, APIURL: tt.apiURL}
+			if got := buildAPIURL(provider, "claude-3-haiku", GenerateRequest{}); got != tt.want {
+				t.Errorf("buildAPIURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeAPIErrorNotFound(t *testing.T) {
+	tests := []struct {
+		kind string
+		want string
+	}{
+		{models.APIKindOllama, "请检查Ollama服务是否运行"},
+		{models.APIKindAzureOpenAI, "部署名称"},
+		{models.APIKindCoze, "Bot ID"},
+		{models.APIKindAnthropic, "请检查API地址与模型名称是否正确"},
+		{models.APIKindZhipu, "请检查API地址与模型名称是否正确"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			got := describeAPIError(&models.APIProvider{APIKind: tt.kind}, http.StatusNotFound, nil, "https://example.com/x")
+			if !strings.Contains(got, tt.want) || !strings.Contains(got, "https://example.com/x") {
+				t.Errorf("describeAPIError() = %q, want hint %q", got, tt.want)
+			}
+			if tt.kind != models.APIKindOllama && strings.Contains(got, "Ollama") {
+				t.Errorf("describeAPIError() = %q, should not mention Ollama", got)
+			}
+		})
+	}
+}
+
+func TestBuildAPIURLAzure(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		want       string
+	}{
+		{"默认版本", "v1", "https://demo.openai.azure.com/openai/deployments/gpt4o-prod/chat/completions?api-version=2024-06-01"},
+		{"指定版本", "2024-10-21", "https://demo.openai.azure.com/openai/deployments/gpt4o-prod/chat/completions?api-version=2024-10-21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &models.APIProvider{APIKind: "Azure OpenAI", APIURL: "https://demo.openai.azure.com/", APIVersion: tt.apiVersion}
+			if got := buildAPIURL(provider, "gpt4o-prod", GenerateRequest{}); got != tt.want {
+				t.Errorf("buildAPIURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAzureContentFilterError(t *testing.T) {
+	body := []byte(`{"error":{"code":"content_filter","message":"The response was filtered","status":400,
+		"innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{
+		"hate":{"filtered":false,"severity":"safe"},
+		"violence":{"filtered":true,"severity":"high"},
+		"jailbreak":{"filtered":true,"detected":true},
+		"custom_blocklists":[]}}}}`)
+
+	got := parseAzureError(body)
+	want := "内容触发内容安全策略被拦截: jailbreak, violence(high)"
+	if got != want {
+		t.Errorf("parseAzureError() = %s, want %s", got, want)
+	}
+}
+
+func TestDescribePromptFilter(t *testing.T) {
+	var resp OpenAIStreamResponse
+	data := `{"choices":[],"prompt_filter_results":[{"prompt_index":0,"content_filter_results":{"sexual":{"filtered":false,"severity":"safe"}}}]}`
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	if msg := describePromptFilter(resp.PromptFilterResults); msg != "" {
+		t.Errorf("describePromptFilter() = %s, want empty", msg)
+	}
+}
+
+func TestZhipuToken(t *testing.T) {
+	token, err := zhipuToken("abc123.secretvalue")
+	if err != nil {
+		t.Fatalf("zhipuToken() error = %v", err)
+	}
+
+	parsed, err := jwt.Parse(token, func(tk *jwt.Token) (interface{}, error) {
+		return []byte("secretvalue"), nil
+	})
+	if err != nil || !parsed.Valid {
+		t.Fatalf("token should be valid: %v", err)
+	}
+	if parsed.Header["sign_type"] != "SIGN" {
+		t.Errorf("sign_type header = %v, want SIGN", parsed.Header["sign_type"])
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+	if claims["api_key"] != "abc123" {
+		t.Errorf("api_key claim = %v, want abc123", claims["api_key"])
+	}
+
+	// 不符合 {id}.{secret} 格式时原样返回
+	if raw, _ := zhipuToken("plain-key"); raw != "plain-key" {
+		t.Errorf("zhipuToken(plain-key) = %s", raw)
+	}
+}
+
+func TestQianfanAccessTokenCached(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("client_id") != "ak" || r.URL.Query().Get("client_secret") != "sk" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"access_token":"24.token","expires_in":2592000}`))
+	}))
+	defer server.Close()
+
+	original := qianfanTokenURL
+	qianfanTokenURL = server.URL
+	defer func() { qianfanTokenURL = original }()
+
+	for i := 0; i < 2; i++ {
+		token, err := qianfanAccessToken(context.Background(), "ak:sk")
+		if err != nil {
+			t.Fatalf("qianfanAccessToken() error = %v", err)
+		}
+		if token != "24.token" {
+			t.Errorf("token = %s, want 24.token", token)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", calls)
+	}
+}
+
+func TestQianfanAccessTokenNotBlockedByRefresh(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(`{"access_token":"24.slow","expires_in":2592000}`))
+	}))
+	defer server.Close()
+
+	original := qianfanTokenURL
+	qianfanTokenURL = server.URL
+	var refresh sync.WaitGroup
+	defer func() {
+		close(release)
+		refresh.Wait()
+		qianfanTokenURL = original
+	}()
+
+	qianfanTokenCache.Lock()
+	qianfanTokenCache.tokens["cached:sk"] = qianfanCachedToken{token: "24.cached", expiresAt: time.Now().Add(time.Hour)}
+	qianfanTokenCache.Unlock()
+
+	// 另一个Key换取token时，已缓存的Key不应等待
+	refresh.Add(1)
+	go func() {
+		defer refresh.Done()
+		_, _ = qianfanAccessToken(context.Background(), "slow:sk")
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan string, 1)
+	go func() {
+		token, _ := qianfanAccessToken(context.Background(), "cached:sk")
+		done <- token
+	}()
+	select {
+	case token := <-done:
+		if token != "24.cached" {
+			t.Errorf("token = %s, want 24.cached", token)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached token lookup blocked by another key's refresh")
+	}
+}
+
+func TestParseVendorError(t *testing.T) {
+	tests := []struct {
+		name string
+		kind string
+		body string
+		want string
+	}{
+		{"智谱", models.APIKindZhipu, `{"error":{"code":"1261","message":"Prompt 超长"}}`, "智谱 GLM返回错误: 1261 Prompt 超长"},
+		{"Moonshot", models.APIKindMoonshot, `{"error":{"type":"invalid_authentication_error","message":"Invalid Authentication"}}`, "Moonshot（Kimi）返回错误: invalid_authentication_error Invalid Authentication"},
+		{"千帆", models.APIKindQianfan, `{"error_code":110,"error_msg":"Access token invalid"}`, "百度千帆（文心）返回错误: 110 Access token invalid"},
+		{"DashScope原生", models.APIKindQwen, `{"code":"InvalidApiKey","message":"Invalid API-key provided.","request_id":"x"}`, "阿里千问（DashScope）返回错误: InvalidApiKey Invalid API-key provided."},
+		{"无法解析", models.APIKindDoubao, `upstream timeout`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseVendorError(tt.kind, []byte(tt.body)); got != tt.want {
+				t.Errorf("parseVendorError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRequestBodyQianfan(t *testing.T) {
+	provider := &models.APIProvider{APIKind: models.APIKindQianfan, APIURL: "https://aip.baidubce.com/rpc/2.0/ai_custom/v1"}
+	req := GenerateRequest{Prompt: "你好", System: "你是助手", MaxTokens: 800}
+
+	if got := buildAPIURL(provider, "completions_pro", req); got != "https://aip.baidubce.com/rpc/2.0/ai_custom/v1/wenxinworkshop/chat/completions_pro" {
+		t.Errorf("buildAPIURL() = %s", got)
+	}
+
+	data, err := buildRequestBody(provider, "completions_pro", req, true)
+	if err != nil {
+		t.Fatalf("buildRequestBody() error = %v", err)
+	}
+	var got QianfanRequest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	if got.System != "你是助手" || len(got.Messages) != 1 || got.MaxOutputTokens != 800 {
+		t.Errorf("unexpected qianfan request: %+v", got)
+	}
+}
+
+func TestBuildQianfanRequestMessages(t *testing.T) {
+	req := GenerateRequest{
+		System: "你是助手",
+		Messages: []OpenAIMessage{
+			{Role: RoleSystem, Content: "用中文回答"},
+			{Role: RoleUser, Content: "第一问"},
+			{Role: RoleAssistant, Content: "第一答"},
+			{Role: RoleUser, Content: "补充"},
+		},
+		Prompt: "第二问",
+	}
+
+	got := buildQianfanRequest(req, false)
+	if got.System != "你是助手\n\n用中文回答" {
+		t.Errorf("System = %q", got.System)
+	}
+	want := []OpenAIMessage{
+		{Role: RoleUser, Content: "第一问"},
+		{Role: RoleAssistant, Content: "第一答"},
+		{Role: RoleUser, Content: "补充\n\n第二问"},
+	}
+	if !reflect.DeepEqual(got.Messages, want) {
+		t.Errorf("Messages = %+v, want %+v", got.Messages, want)
+	}
+
+	// 只有历史消息、没有提示词时不追加空的用户消息
+	got = buildQianfanRequest(GenerateRequest{Messages: []OpenAIMessage{{Role: RoleUser, Content: "你好"}}}, false)
+	if len(got.Messages) != 1 || got.Messages[0].Content != "你好" {
+		t.Errorf("Messages = %+v", got.Messages)
+	}
+}
+
+func TestBuildOpenAIRequestStreamUsage(t *testing.T) {
+	qwen := &models.APIProvider{APIKind: models.APIKindQwen}
+	if got := buildOpenAIRequest(qwen, "qwen-plus", GenerateRequest{Prompt: "hi"}, true); got.StreamOptions == nil || !got.StreamOptions.IncludeUsage {
+		t.Errorf("qwen stream request should include usage")
+	}
+	if got := buildOpenAIRequest(qwen, "qwen-plus", GenerateRequest{Prompt: "hi"}, false); got.StreamOptions != nil {
+		t.Errorf("non-stream request should not set stream_options")
+	}
+	moonshot := &models.APIProvider{APIKind: models.APIKindMoonshot}
+	if got := buildOpenAIRequest(moonshot, "moonshot-v1-8k", GenerateRequest{Prompt: "hi"}, true); got.StreamOptions != nil {
+		t.Errorf("moonshot request should not set stream_options")
+	}
+}
+
+func TestBuildCozeRequest(t *testing.T) {
+	provider := &models.APIProvider{APIKind: models.APIKindCoze, APIURL: "https://api.coze.cn"}
+	req := GenerateRequest{Prompt: "生成六要素", ConversationID: "7350", User: "13800000000"}
+
+	if got := buildAPIURL(provider, "bot-1", req); got != "https://api.coze.cn/v3/chat?conversation_id=7350" {
+		t.Errorf("buildAPIURL() = %s", got)
+	}
+
+	data, err := buildRequestBody(provider, "bot-1", req, false)
+	if err != nil {
+		t.Fatalf("buildRequestBody() error = %v", err)
+	}
+	var got CozeChatRequest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	if got.BotID != "bot-1" || got.UserID != "13800000000" || !got.Stream || len(got.AdditionalMessages) != 1 {
+		t.Errorf("unexpected coze request: %+v", got)
+	}
+	if got := buildCozeChatRequest("bot-1", GenerateRequest{Prompt: "hi"}); got.UserID != cozeDefaultUserID {
+		t.Errorf("UserID = %s, want default", got.UserID)
+	}
+}
+
+func TestBuildCozeChatRequestMessages(t *testing.T) {
+	req := GenerateRequest{
+		System: "语气正式",
+		Messages: []OpenAIMessage{
+			{Role: RoleUser, Content: "写一个周报模板"},
+			{Role: RoleAssistant, Content: "好的，请提供主题"},
+			{Role: RoleUser, Content: "研发周报"},
+		},
+	}
+
+	got := buildCozeChatRequest("bot-1", req).AdditionalMessages
+	want := []CozeMessage{
+		{Role: RoleUser, Type: "question", Content: "语气正式", ContentType: "text"},
+		{Role: RoleUser, Type: "question", Content: "写一个周报模板", ContentType: "text"},
+		{Role: RoleAssistant, Type: "answer", Content: "好的，请提供主题", ContentType: "text"},
+		{Role: RoleUser, Type: "question", Content: "研发周报", ContentType: "text"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AdditionalMessages = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckGenerateProviderToolMessages(t *testing.T) {
+	messages := []OpenAIMessage{
+		{Role: RoleUser, Content: "查天气"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: "{}"}}}},
+		{Role: RoleTool, ToolCallID: "call_1", Content: "晴"},
+	}
+
+	for _, kind := range []string{models.APIKindQianfan, models.APIKindCoze} {
+		provider := &models.APIProvider{APIKind: kind, APIStatus: 1}
+		if err := CheckGenerateProvider(provider, GenerateRequest{Messages: messages}); err == nil {
+			t.Errorf("CheckGenerateProvider(%s) expected error for tool messages", kind)
+		}
+		if err := CheckGenerateProvider(provider, GenerateRequest{Messages: messages[:1]}); err != nil {
+			t.Errorf("CheckGenerateProvider(%s) error = %v", kind, err)
+		}
+	}
+	openai := &models.APIProvider{APIKind: models.APIKindOpenAICompatible, APIStatus: 1}
+	if err := CheckGenerateProvider(openai, GenerateRequest{Messages: messages}); err != nil {
+		t.Errorf("CheckGenerateProvider(OpenAI Compatible) error = %v", err)
+	}
+}
+
+func TestReadCozeStream(t *testing.T) {
+	stream := "event:conversation.chat.created\n" +
+		"data:{\"id\":\"chat-1\",\"conversation_id\":\"conv-1\",\"status\":\"created\"}\n\n" +
+		"event:conversation.message.delta\n" +
+		"data:{\"role\":\"assistant\",\"type\":\"answer\",\"content\":\"你\",\"content_type\":\"text\"}\n\n" +
+		"event:conversation.message.delta\n" +
+		"data:{\"role\":\"assistant\",\"type\":\"answer\",\"content\":\"好\",\"content_type\":\"text\"}\n\n" +
+		"event:conversation.message.completed\n" +
+		"data:{\"role\":\"assistant\",\"type\":\"follow_up\",\"content\":\"还有吗？\",\"content_type\":\"text\"}\n\n" +
+		"event:conversation.chat.completed\n" +
+		"data:{\"id\":\"chat-1\",\"conversation_id\":\"conv-1\",\"status\":\"completed\",\"usage\":{\"token_count\":30,\"output_count\":10,\"input_count\":20}}\n\n" +
+		"event:done\n" +
+		"data:\"[DONE]\"\n\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/chat" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(stream))
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(buildCozeURL(server.URL, ""), "application/json", nil)
+	if err != nil {
+		t.Fatalf("post error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var content string
+	result, err := readCozeStream(resp.Body, func(delta string) { content += delta })
+	if err != nil {
+		t.Fatalf("readCozeStream() error = %v", err)
+	}
+	if content != "你好" {
+		t.Errorf("content = %q, want 你好", content)
+	}
+	if result.ConversationID != "conv-1" || result.ChatID != "chat-1" {
+		t.Errorf("unexpected ids: %+v", result)
+	}
+	if result.Usage == nil || result.Usage.PromptTokens != 20 || result.Usage.CompletionTokens != 10 || result.Usage.TotalTokens != 30 {
+		t.Errorf("unexpected usage: %+v", result.Usage)
+	}
+	if len(result.FollowUps) != 1 {
+		t.Errorf("FollowUps = %v", result.FollowUps)
+	}
+}
+
+func TestReadCozeStreamErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+	}{
+		{
+			name:   "鉴权失败",
+			stream: `{"code":4100,"msg":"authentication is invalid"}`,
+		},
+		{
+			name: "对话失败",
+			stream: "event:conversation.chat.failed\n" +
+				"data:{\"id\":\"chat-1\",\"status\":\"failed\",\"last_error\":{\"code\":4011,\"msg\":\"quota exceeded\"}}\n\n",
+		},
+		{
+			name:   "错误事件",
+			stream: "event:error\ndata:{\"code\":4000,\"msg\":\"invalid bot_id\"}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readCozeStream(strings.NewReader(tt.stream), func(string) {
+				t.Errorf("unexpected delta")
+			})
+			if err == nil {
+				t.Errorf("readCozeStream() expected error")
+			}
+		})
+	}
+}
+
+// recordingSink 记录流式生成输出，用于测试
+type recordingSink struct {
+	contents []string
+	deltas   [][]ToolCall
+	result   *GenerateResult
+	err      string
+}
+
+func (s *recordingSink) Content(content string)      { s.contents = append(s.contents, content) }
+func (s *recordingSink) ToolCalls(deltas []ToolCall) { s.deltas = append(s.deltas, deltas) }
+func (s *recordingSink) Done(result *GenerateResult) { s.result = result }
+func (s *recordingSink) Error(message string)        { s.err = message }
+
+func TestBuildOpenAIRequestWithTools(t *testing.T) {
+	provider := &models.APIProvider{APIKind: models.APIKindOpenAICompatible}
+	req := GenerateRequest{
+		Messages: []OpenAIMessage{
+			{Role: RoleUser, Content: "北京天气"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"北京"}`}}}},
+			{Role: RoleTool, ToolCallID: "call_1", Content: `{"temp":20}`},
+		},
+		Tools:      []Tool{{Type: "function", Function: ToolFunction{Name: "get_weather", Parameters: json.RawMessage(`{"type":"object"}`)}}},
+		ToolChoice: json.RawMessage(`"auto"`),
+	}
+	if err := validateGenerateMessages(req); err != nil {
+		t.Fatalf("validateGenerateMessages() error = %v", err)
+	}
+
+	got := buildOpenAIRequest(provider, "gpt-4o", req, false)
+	if len(got.Messages) != 3 || got.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+	if len(got.Tools) != 1 || string(got.ToolChoice) != `"auto"` {
+		t.Errorf("unexpected tools: %+v %s", got.Tools, got.ToolChoice)
+	}
+}
+
+func TestValidateGenerateMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		req  GenerateRequest
+	}{
+		{name: "空提示词", req: GenerateRequest{}},
+		{name: "缺少tool_call_id", req: GenerateRequest{Messages: []OpenAIMessage{{Role: RoleTool, Content: "x"}}}},
+		{name: "未知角色", req: GenerateRequest{Prompt: "hi", Messages: []OpenAIMessage{{Role: "bot"}}}},
+		{name: "无效工具", req: GenerateRequest{Prompt: "hi", Tools: []Tool{{Type: "retrieval"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGenerateMessages(tt.req); err == nil {
+				t.Errorf("validateGenerateMessages() expected error")
+			}
+		})
+	}
+}
+
+func TestHandleOpenAIStreamToolCalls(t *testing.T) {
+	stream := `data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}
+data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}
+data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"北京\"}"}}]},"finish_reason":"tool_calls"}]}
+data: [DONE]
+`
+	sink := &recordingSink{}
+	handleOpenAIStreamResponse(&http.Response{Body: io.NopCloser(strings.NewReader(stream))}, sink)
+
+	if len(sink.deltas) != 3 || len(sink.contents) != 0 {
+		t.Fatalf("deltas = %d, contents = %d", len(sink.deltas), len(sink.contents))
+	}
+	if sink.result == nil || sink.result.FinishReason != finishReasonToolCalls {
+		t.Fatalf("unexpected result: %+v", sink.result)
+	}
+	fn := sink.result.ToolCalls[0].Function
+	if fn.Name != "get_weather" || fn.Arguments != `{"city":"北京"}` {
+		t.Errorf("unexpected tool call: %+v", fn)
+	}
+}
+
+func TestBuildAnthropicRequest(t *testing.T) {
+	provider := &models.APIProvider{APIKind: models.APIKindAnthropic, APIURL: "https://api.anthropic.com/v1"}
+	req := GenerateRequest{
+		System: "你是助手",
+		Messages: []OpenAIMessage{
+			{Role: RoleUser, Content: "北京和上海天气"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{
+				{ID: "toolu_1", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"北京"}`}},
+				{ID: "toolu_2", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"上海"}`}},
+			}},
+			{Role: RoleTool, ToolCallID: "toolu_1", Content: "20度"},
+			{Role: RoleTool, ToolCallID: "toolu_2", Content: "25度"},
+		},
+		MaxTokens:  500,
+		Tools:      []Tool{{Type: "function", Function: ToolFunction{Name: "get_weather"}}},
+		ToolChoice: json.RawMessage(`"required"`),
+	}
+
+	if got := buildAPIURL(provider, "claude-3-haiku", req); got != "https://api.anthropic.com/v1/messages" {
+		t.Errorf("buildAPIURL() = %s", got)
+	}
+
+	got := buildAnthropicRequest("claude-3-haiku", req, true)
+	if got.System != "你是助手" || len(got.Messages) != 3 {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if blocks := got.Messages[1].Content; len(blocks) != 2 || blocks[0].Type != "tool_use" || string(blocks[0].Input) != `{"city":"北京"}` {
+		t.Errorf("unexpected assistant blocks: %+v", blocks)
+	}
+	if blocks := got.Messages[2].Content; got.Messages[2].Role != RoleUser || len(blocks) != 2 || blocks[1].ToolUseID != "toolu_2" {
+		t.Errorf("tool results should be merged: %+v", got.Messages[2])
+	}
+	if got.ToolChoice == nil || got.ToolChoice.Type != "any" || string(got.Tools[0].InputSchema) == "" {
+		t.Errorf("unexpected tools: %+v %+v", got.Tools, got.ToolChoice)
+	}
+}
+
+func TestHandleAnthropicStreamToolUse(t *testing.T) {
+	stream := `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":12,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"查询中"}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\": \"北京\"}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+	sink := &recordingSink{}
+	handleAnthropicStreamResponse(&http.Response{Body: io.NopCloser(strings.NewReader(stream))}, sink)
+
+	if len(sink.contents) != 1 || sink.contents[0] != "查询中" || len(sink.deltas) != 2 {
+		t.Fatalf("unexpected output: %v %d", sink.contents, len(sink.deltas))
+	}
+	if sink.result == nil || sink.result.Usage == nil || sink.result.Usage.TotalTokens != 32 {
+		t.Fatalf("unexpected result: %+v", sink.result)
+	}
+	call := sink.result.ToolCalls[0]
+	if call.ID != "toolu_1" || call.Function.Arguments != `{"city": "北京"}` {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+}
+
+func TestBuildGeminiRequest(t *testing.T) {
+	provider := &models.APIProvider{APIKind: models.APIKindGemini, APIURL: "https://generativelanguage.googleapis.com/v1beta"}
+	req := GenerateRequest{
+		System: "你是助手",
+		Messages: []OpenAIMessage{
+			{Role: RoleUser, Content: "北京天气"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_0", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"北京"}`}}}},
+			{Role: RoleTool, ToolCallID: "call_0", Content: "20度"},
+		},
+		Stream:     true,
+		Tools:      []Tool{{Type: "function", Function: ToolFunction{Name: "get_weather"}}},
+		ToolChoice: json.RawMessage(`{"type":"function","function":{"name":"get_weather"}}`),
+	}
+
+	if got := buildAPIURL(provider, "gemini-pro", req); got != "https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:streamGenerateContent?alt=sse" {
+		t.Errorf("buildAPIURL() = %s", got)
+	}
+
+	got := buildGeminiRequest(req)
+	if got.SystemInstruction == nil || len(got.Contents) != 3 {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if got.Contents[1].Role != "model" || got.Contents[1].Parts[0].FunctionCall.Name != "get_weather" {
+		t.Errorf("unexpected model content: %+v", got.Contents[1])
+	}
+	fr := got.Contents[2].Parts[0].FunctionResponse
+	if fr == nil || fr.Name != "get_weather" || string(fr.Response) != `{"content":"20度"}` {
+		t.Errorf("unexpected function response: %+v", fr)
+	}
+	if got.ToolConfig == nil || got.ToolConfig.FunctionCallingConfig.Mode != "ANY" {
+		t.Errorf("unexpected tool config: %+v", got.ToolConfig)
+	}
+}
+
+func TestNormalizeToolCalls(t *testing.T) {
+	gemini := normalizeGeminiFunctionCalls([]GeminiPart{
+		{Text: "好的"},
+		{FunctionCall: &GeminiFunctionCall{Name: "get_weather", Args: json.RawMessage(`{"city":"北京"}`)}},
+	}, 0)
+	if len(gemini) != 1 || gemini[0].ID != "call_0" || gemini[0].Function.Arguments != `{"city":"北京"}` {
+		t.Errorf("unexpected gemini calls: %+v", gemini)
+	}
+
+	anthropic := normalizeAnthropicToolUse([]AnthropicContentBlock{
+		{Type: "text", Text: "好的"},
+		{Type: "tool_use", ID: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"city":"北京"}`)},
+	})
+	if len(anthropic) != 1 || anthropic[0].ID != "toolu_1" || anthropic[0].Type != "function" {
+		t.Errorf("unexpected anthropic calls: %+v", anthropic)
+	}
+
+	var ollamaCall OllamaToolCall
+	ollamaCall.Function.Name = "get_weather"
+	ollamaCall.Function.Arguments = json.RawMessage(`{"city":"北京"}`)
+	ollama := normalizeOllamaToolCalls([]OllamaToolCall{ollamaCall}, 2)
+	if len(ollama) != 1 || ollama[0].ID != "call_2" || ollama[0].Function.Arguments != `{"city":"北京"}` {
+		t.Errorf("unexpected ollama calls: %+v", ollama)
+	}
+}
+
+func TestBuildRequestBodyWithImages(t *testing.T) {
+	png := ImageAttachment{MimeType: "image/png", Data: []byte("png-bytes")}
+	remote := ImageAttachment{URL: "https://example.com/a.jpg"}
+	req := GenerateRequest{Prompt: "描述图片", Attachments: []ImageAttachment{png, remote}}
+
+	openai := &models.APIProvider{APIKind: models.APIKindOpenAICompatible}
+	data, err := buildRequestBody(openai, "gpt-4o", req, false)
+	if err != nil {
+		t.Fatalf("buildRequestBody() error = %v", err)
+	}
+	var openaiReq struct {
+		Messages []struct {
+			Content []OpenAIContentPart `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &openaiReq); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	parts := openaiReq.Messages[0].Content
+	if len(parts) != 3 || parts[0].Text != "描述图片" || parts[1].ImageURL.URL != "data:image/png;base64,cG5nLWJ5dGVz" || parts[2].ImageURL.URL != remote.URL {
+		t.Errorf("unexpected content parts: %+v", parts)
+	}
+
+	ollamaReq := buildOllamaChatRequest("llava", GenerateRequest{Prompt: "描述图片", Attachments: []ImageAttachment{png}}, false)
+	if images := ollamaReq.Messages[0].Images; len(images) != 1 || images[0] != "cG5nLWJ5dGVz" {
+		t.Errorf("unexpected ollama images: %v", images)
+	}
+
+	geminiReq := buildGeminiRequest(GenerateRequest{Prompt: "描述图片", Attachments: []ImageAttachment{png}})
+	if parts := geminiReq.Contents[0].Parts; len(parts) != 2 || parts[1].InlineData == nil || parts[1].InlineData.MimeType != "image/png" {
+		t.Errorf("unexpected gemini parts: %+v", parts)
+	}
+
+	anthropicReq := buildAnthropicRequest("claude-3-haiku", GenerateRequest{Prompt: "描述图片", Attachments: []ImageAttachment{png}}, false)
+	if blocks := anthropicReq.Messages[0].Content; len(blocks) != 2 || blocks[0].Type != "image" || blocks[0].Source.Data != "cG5nLWJ5dGVz" {
+		t.Errorf("unexpected anthropic blocks: %+v", blocks)
+	}
+}
+
+func TestValidateGenerateImages(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     GenerateRequest
+		wantErr bool
+	}{
+		{name: "无图片", req: GenerateRequest{Prompt: "hi"}},
+		{name: "上传图片", req: GenerateRequest{Prompt: "hi", Images: []GenerateImage{{ID: 1}}}},
+		{name: "图片URL", req: GenerateRequest{Prompt: "hi", Images: []GenerateImage{{URL: "https://example.com/a.png"}}}},
+		{name: "缺少提示词", req: GenerateRequest{Images: []GenerateImage{{ID: 1}}}, wantErr: true},
+		{name: "同时指定", req: GenerateRequest{Prompt: "hi", Images: []GenerateImage{{ID: 1, URL: "https://example.com/a.png"}}}, wantErr: true},
+		{name: "超出数量", req: GenerateRequest{Prompt: "hi", Images: []GenerateImage{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGenerateImages(tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("validateGenerateImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsImageData(t *testing.T) {
+	tests := []struct {
+		provider *models.APIProvider
+		want     bool
+	}{
+		{&models.APIProvider{APIKind: models.APIKindOpenAICompatible}, false},
+		{&models.APIProvider{APIKind: models.APIKindGemini}, true},
+		{&models.APIProvider{APIKind: models.APIKindAnthropic}, true},
+		{&models.APIProvider{APIKind: models.APIKindOllama, APIURL: "http://localhost:11434"}, true},
+		{&models.APIProvider{APIKind: models.APIKindOllama, APIURL: "http://localhost:11434/v1"}, false},
+	}
+	for _, tt := range tests {
+		if got := needsImageData(tt.provider); got != tt.want {
+			t.Errorf("needsImageData(%s %s) = %v, want %v", tt.provider.APIKind, tt.provider.APIURL, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateStreamCollectsContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.Stream || body.MaxTokens != 2000 {
+			t.Errorf("unexpected request: %+v %v", body, err)
+		}
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"你好\"}}]}\n\n")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"世界\"},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := &models.APIProvider{APIKind: models.APIKindOpenAICompatible, APIURL: server.URL, APIModel: "gpt-4o", APIStatus: 1}
+	sink := &recordingSink{}
+	GenerateStream(context.Background(), provider, GenerateRequest{Prompt: "hi"}, sink)
+
+	if sink.err != "" || len(sink.contents) != 2 {
+		t.Fatalf("unexpected output: %v %s", sink.contents, sink.err)
+	}
+	if sink.result == nil || sink.result.Content != "你好世界" || sink.result.Usage.TotalTokens != 5 {
+		t.Errorf("unexpected result: %+v", sink.result)
+	}
+}
+
+func TestGenerateNonStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"<b>答案</b>"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	provider := &models.APIProvider{APIKind: models.APIKindOpenAICompatible, APIURL: server.URL, APIModel: "gpt-4o", APIStatus: 1}
+	result, err := Generate(context.Background(), provider, GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Content != "答案" || result.Usage.TotalTokens != 5 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	provider.APIURL = server.URL + "/missing"
+	if _, err := Generate(context.Background(), provider, GenerateRequest{Prompt: "hi"}); err == nil {
+		t.Errorf("Generate() expected error for 404")
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+// 对话消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// finishReasonToolCalls 模型请求调用工具时统一返回的结束原因
+const finishReasonToolCalls = "tool_calls"
+
+// Tool 工具定义（OpenAI格式，其他Provider在构建请求时转换）
+type Tool struct {
+	Type     string       `json:"type"` // 目前仅支持 function
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction 函数定义，parameters 为JSON Schema
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall 模型发起的工具调用（OpenAI格式）
+// 流式增量中 Index 标识所属的调用，Arguments 为参数片段；完整调用不含 Index
+type ToolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction 工具调用的函数名与参数（JSON字符串）
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// toolChoice 解析后的工具选择策略
+type toolChoice struct {
+	Mode string // auto / none / required / function
+	Name string // Mode 为 function 时指定的函数名
+}
+
+// parseToolChoice 解析OpenAI格式的 tool_choice
+// 支持 "auto"、"none"、"required" 以及 {"type":"function","function":{"name":"..."}}
+func parseToolChoice(raw json.RawMessage) toolChoice {
+	if len(raw) == 0 {
+		return toolChoice{}
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		return toolChoice{Mode: mode}
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err == nil && named.Function.Name != "" {
+		return toolChoice{Mode: "function", Name: named.Function.Name}
+	}
+	return toolChoice{}
+}
+
+// supportsTools 判断模型类型是否支持工具调用
+func supportsTools(kind string) bool {
+	info, ok := models.GetAPIKindInfo(kind)
+	return ok && info.Tools
+}
+
+// validateGenerateMessages 校验生成请求中的历史消息
+func validateGenerateMessages(req GenerateRequest) error {
+	if strings.TrimSpace(req.Prompt) == "" && len(req.Messages) == 0 {
+		return errors.New("提示词不能为空")
+	}
+	for i, msg := range req.Messages {
+		switch msg.Role {
+		case RoleSystem, RoleUser, RoleAssistant:
+		case RoleTool:
+			if msg.ToolCallID == "" {
+				return fmt.Errorf("第%d条消息缺少tool_call_id", i+1)
+			}
+		default:
+			return fmt.Errorf("第%d条消息角色不支持: %s", i+1, msg.Role)
+		}
+	}
+	for _, tool := range req.Tools {
+		if tool.Type != "function" || tool.Function.Name == "" {
+			return errors.New("工具定义无效，仅支持带名称的function")
+		}
+	}
+	return nil
+}
+
+// hasToolMessages 判断历史消息中是否包含工具调用或工具结果
+func hasToolMessages(messages []OpenAIMessage) bool {
+	for _, msg := range messages {
+		if msg.Role == RoleTool || len(msg.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCallName 在历史消息中根据工具调用ID查找函数名（Gemini的函数结果需要函数名）
+func toolCallName(messages []OpenAIMessage, id string) string {
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			if call.ID == id {
+				return call.Function.Name
+			}
+		}
+	}
+	return ""
+}
+
+// toolArgumentsObject 将参数JSON字符串转换为对象，供原生格式（Anthropic/Gemini/Ollama）使用
+func toolArgumentsObject(arguments string) json.RawMessage {
+	trimmed := strings.TrimSpace(arguments)
+	if trimmed == "" || !json.Valid([]byte(trimmed)) || !strings.HasPrefix(trimmed, "{") {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(trimmed)
+}
+
+// toolArgumentsString 将原生格式的参数对象转换为JSON字符串
+func toolArgumentsString(args json.RawMessage) string {
+	if len(args) == 0 || string(args) == "null" {
+		return "{}"
+	}
+	return string(args)
+}
+
+// toolCallAccumulator 合并流式工具调用增量
+type toolCallAccumulator struct {
+	calls []ToolCall
+}
+
+// add 按 Index 合并一个增量，函数名与参数按片段拼接
+func (a *toolCallAccumulator) add(delta ToolCall) {
+	index := 0
+	if delta.Index != nil {
+		index = *delta.Index
+	}
+	for len(a.calls) <= index {
+		a.calls = append(a.calls, ToolCall{Type: "function"})
+	}
+	call := &a.calls[index]
+	if delta.ID != "" {
+		call.ID = delta.ID
+	}
+	if delta.Type != "" {
+		call.Type = delta.Type
+	}
+	call.Function.Name += delta.Function.Name
+	call.Function.Arguments += delta.Function.Arguments
+}
+
+// result 返回合并后的完整工具调用
+func (a *toolCallAccumulator) result() []ToolCall {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	return a.calls
+}
+
+// indexedToolCall 为完整的工具调用补充流式 Index，原生格式一次返回完整调用时使用
+func indexedToolCall(index int, call ToolCall) ToolCall {
+	call.Index = &index
+	return call
+}
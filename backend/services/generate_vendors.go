@@ -0,0 +1,318 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// ===== 智谱 GLM =====
+
+// zhipuTokenTTL 智谱JWT有效期
+const zhipuTokenTTL = 30 * time.Minute
+
+// zhipuToken 使用智谱API Key签发JWT
+// API Key格式为 {id}.{secret}；不符合该格式时原样返回，兼容直接使用API Key的调用方式
+func zhipuToken(apiKey string) (string, error) {
+	id, secret, ok := strings.Cut(apiKey, ".")
+	if !ok || id == "" || secret == "" {
+		return apiKey, nil
+	}
+
+	now := time.Now().UnixMilli()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"api_key":   id,
+		"exp":       now + zhipuTokenTTL.Milliseconds(),
+		"timestamp": now,
+	})
+	token.Header["sign_type"] = "SIGN"
+
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("智谱JWT签发失败: %w", err)
+	}
+	return signed, nil
+}
+
+// ===== 百度千帆 =====
+
+// qianfanTokenURL 百度千帆access_token获取地址
+var qianfanTokenURL = "https://aip.baidubce.com/oauth/2.0/token"
+
+// qianfanTokenCache access_token缓存，key为API Key
+var qianfanTokenCache = struct {
+	sync.Mutex
+	tokens map[string]qianfanCachedToken
+}{tokens: make(map[string]qianfanCachedToken)}
+
+// qianfanCachedToken 缓存的access_token
+type qianfanCachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// QianfanRequest 百度千帆对话请求格式
+// system 单独传递，messages 只包含 user/assistant
+type QianfanRequest struct {
+	Messages        []OpenAIMessage `json:"messages"`
+	System          string          `json:"system,omitempty"`
+	Temperature     float32         `json:"temperature,omitempty"`
+	TopP            float32         `json:"top_p,omitempty"`
+	MaxOutputTokens int             `json:"max_output_tokens,omitempty"`
+	Stop            []string        `json:"stop,omitempty"`
+	Stream          bool            `json:"stream"`
+}
+
+// QianfanResponse 百度千帆对话响应格式（流式每个数据块格式相同）
+type QianfanResponse struct {
+	ID               string `json:"id"`
+	Result           string `json:"result"`
+	IsEnd            bool   `json:"is_end"`
+	IsTruncated      bool   `json:"is_truncated"`
+	NeedClearHistory bool   `json:"need_clear_history"`
+	Usage            *Usage `json:"usage,omitempty"`
+	ErrorCode        int    `json:"error_code,omitempty"`
+	ErrorMsg         string `json:"error_msg,omitempty"`
+}
+
+// qianfanAccessToken 获取百度千帆access_token
+// API Key格式为 {API Key}:{Secret Key}；不含冒号时视为已获取的access_token直接使用
+func qianfanAccessToken(ctx context.Context, apiKey string) (string, error) {
+	ak, sk, ok := strings.Cut(apiKey, ":")
+	if !ok {
+		if apiKey == "" {
+			return "", errors.New("百度千帆需要配置 {API Key}:{Secret Key}")
+		}
+		return apiKey, nil
+	}
+
+	qianfanTokenCache.Lock()
+	cached, exists := qianfanTokenCache.tokens[apiKey]
+	qianfanTokenCache.Unlock()
+	if exists && time.Now().Before(cached.expiresAt) {
+		return cached.token, nil
+	}
+
+	// 换取token期间不持有锁，避免一次慢请求阻塞其他Key的千帆调用
+	token, expiresAt, err := fetchQianfanAccessToken(ctx, ak, sk)
+	if err != nil {
+		return "", err
+	}
+
+	qianfanTokenCache.Lock()
+	qianfanTokenCache.tokens[apiKey] = qianfanCachedToken{token: token, expiresAt: expiresAt}
+	qianfanTokenCache.Unlock()
+	utils.Info("获取百度千帆access_token成功", zap.Time("expires_at", expiresAt))
+
+	return token, nil
+}
+
+// fetchQianfanAccessToken 使用 API Key 与 Secret Key 换取access_token，返回token及缓存过期时间
+func fetchQianfanAccessToken(ctx context.Context, ak, sk string) (string, time.Time, error) {
+	query := url.Values{}
+	query.Set("grant_type", "client_credentials")
+	query.Set("client_id", ak)
+	query.Set("client_secret", sk)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", qianfanTokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("获取百度千帆access_token失败: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Warn("关闭响应体失败", zap.Error(err))
+		}
+	}()
+
+	var tokenResp struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", time.Time{}, fmt.Errorf("解析百度千帆access_token失败: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("获取百度千帆access_token失败: %s %s", tokenResp.Error, tokenResp.ErrorDescription)
+	}
+
+	// 提前一小时过期，避免临界时刻失效
+	expiresAt := time.Now().Add(time.Duration(tokenResp.ExpiresIn)*time.Second - time.Hour)
+	return tokenResp.AccessToken, expiresAt, nil
+}
+
+// buildQianfanRequest 构建百度千帆请求
+// 系统消息合并到 system 字段；千帆要求 user/assistant 交替出现，连续的同角色消息合并为一条
+func buildQianfanRequest(req GenerateRequest, stream bool) QianfanRequest {
+	qianfanReq := QianfanRequest{
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		MaxOutputTokens: req.MaxTokens,
+		Stop:            req.Stop,
+		Stream:          stream,
+	}
+
+	var systems []string
+	for _, msg := range buildMessages(req) {
+		if msg.Role == RoleSystem {
+			systems = append(systems, msg.Content)
+			continue
+		}
+		last := len(qianfanReq.Messages) - 1
+		if last >= 0 && qianfanReq.Messages[last].Role == msg.Role {
+			qianfanReq.Messages[last].Content += "\n\n" + msg.Content
+			continue
+		}
+		qianfanReq.Messages = append(qianfanReq.Messages, OpenAIMessage{Role: msg.Role, Content: msg.Content})
+	}
+	qianfanReq.System = strings.Join(systems, "\n\n")
+	return qianfanReq
+}
+
+// handleQianfanStreamResponse 处理百度千帆格式的流式响应
+// 千帆出错时仍返回200，响应体为不带 data: 前缀的错误JSON
+func handleQianfanStreamResponse(resp *http.Response, sink StreamSink) {
+	utils.Info("开始处理百度千帆格式流式响应")
+
+	scanner := bufio.NewScanner(resp.Body)
+	var contentLength int
+	messageCount := 0
+	doneSent := false
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		data := strings.TrimPrefix(line, "data: ")
+		var qianfanResp QianfanResponse
+		if err := json.Unmarshal([]byte(data), &qianfanResp); err != nil {
+			utils.Error("解析百度千帆流响应失败", zap.Error(err), zap.String("data", data))
+			continue
+		}
+
+		messageCount++
+
+		if qianfanResp.ErrorCode != 0 {
+			utils.Error("百度千帆返回错误", zap.Int("error_code", qianfanResp.ErrorCode), zap.String("error_msg", qianfanResp.ErrorMsg))
+			sink.Error(fmt.Sprintf("API返回错误: %d %s", qianfanResp.ErrorCode, qianfanResp.ErrorMsg))
+			doneSent = true
+			break
+		}
+
+		if qianfanResp.Result != "" {
+			re := regexp.MustCompile(`<[^>]*>`)
+			cleanContent := re.ReplaceAllString(qianfanResp.Result, "")
+			contentLength += len(cleanContent)
+			sink.Content(cleanContent)
+		}
+
+		if qianfanResp.IsEnd {
+			utils.Info("百度千帆流响应完成", zap.Int("message_count", messageCount), zap.Int("content_length", contentLength))
+			sink.Done(newGenerateResult("", qianfanResp.Usage, nil))
+			doneSent = true
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		utils.Error("读取百度千帆流数据失败", zap.Error(err))
+		sink.Error("读取流数据失败")
+	} else if !doneSent {
+		sink.Done(newGenerateResult("", nil, nil))
+	}
+}
+
+// parseQianfanResponse 解析百度千帆格式的响应
+func parseQianfanResponse(body []byte) (*GenerateResult, error) {
+	utils.Info("开始解析百度千帆格式响应")
+
+	var qianfanResp QianfanResponse
+	if err := json.Unmarshal(body, &qianfanResp); err != nil {
+		utils.Error("解析百度千帆响应失败", zap.Error(err), zap.String("body", string(body)))
+		return nil, errors.New("解析响应失败")
+	}
+
+	if qianfanResp.ErrorCode != 0 {
+		utils.Error("百度千帆返回错误", zap.Int("error_code", qianfanResp.ErrorCode), zap.String("error_msg", qianfanResp.ErrorMsg))
+		return nil, fmt.Errorf("API返回错误: %d %s", qianfanResp.ErrorCode, qianfanResp.ErrorMsg)
+	}
+
+	if qianfanResp.Result == "" {
+		utils.Warn("百度千帆API未返回内容")
+		return nil, errors.New("API未返回内容")
+	}
+
+	re := regexp.MustCompile(`<[^>]*>`)
+	content := re.ReplaceAllString(qianfanResp.Result, "")
+	usage := qianfanResp.Usage
+	if usage == nil {
+		usage = &Usage{}
+	}
+
+	return newGenerateResult(content, usage, nil), nil
+}
+
+// ===== 国内厂商错误解析 =====
+
+// parseVendorError 解析国内厂商的错误响应，无法解析时返回空字符串
+//   - 通义千问兼容模式/智谱/Moonshot/豆包: {"error":{"code":"...","message":"...","type":"..."}}
+//   - 通义千问DashScope原生: {"code":"InvalidApiKey","message":"...","request_id":"..."}
+//   - 百度千帆: {"error_code":110,"error_msg":"Access token invalid or no longer valid"}
+func parseVendorError(kind string, body []byte) string {
+	var errResp struct {
+		Error *struct {
+			Code    json.RawMessage `json:"code"`
+			Type    string          `json:"type"`
+			Message string          `json:"message"`
+		} `json:"error"`
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		ErrorCode int    `json:"error_code"`
+		ErrorMsg  string `json:"error_msg"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return ""
+	}
+
+	label := kind
+	if info, ok := models.GetAPIKindInfo(kind); ok {
+		label = info.Label
+	}
+
+	switch {
+	case errResp.Error != nil && errResp.Error.Message != "":
+		// 智谱的code为字符串数字，豆包/千问为字符串，Moonshot只有type
+		code := strings.Trim(string(errResp.Error.Code), `"`)
+		if code == "" || code == "null" {
+			code = errResp.Error.Type
+		}
+		return fmt.Sprintf("%s返回错误: %s %s", label, code, errResp.Error.Message)
+	case errResp.ErrorCode != 0:
+		return fmt.Sprintf("%s返回错误: %d %s", label, errResp.ErrorCode, errResp.ErrorMsg)
+	case errResp.Code != "" && errResp.Message != "":
+		return fmt.Sprintf("%s返回错误: %s %s", label, errResp.Code, errResp.Message)
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+// 图片相关错误
+var (
+	ErrImageTooLarge    = errors.New("图片大小超出限制")
+	ErrImageTypeInvalid = errors.New("不支持的图片类型")
+	ErrImageNotFound    = errors.New("图片不存在")
+)
+
+// imageExtensions 图片类型对应的文件扩展名
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// maxImageBytes 单张图片最大字节数
+func maxImageBytes() int64 {
+	return int64(config.GetConfig().Upload.MaxImageSize) << 20
+}
+
+// ValidateImageData 校验图片大小与类型，返回根据内容识别的MIME类型
+func ValidateImageData(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", ErrImageTypeInvalid
+	}
+	if int64(len(data)) > maxImageBytes() {
+		return "", ErrImageTooLarge
+	}
+
+	mimeType := http.DetectContentType(data)
+	for _, allowed := range config.GetConfig().Upload.ImageTypes {
+		if mimeType == allowed {
+			return mimeType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrImageTypeInvalid, mimeType)
+}
+
+// SaveImage 保存用户上传的图片
+func SaveImage(userMobile, fileName string, data []byte) (*models.Image, error) {
+	mimeType, err := ValidateImageData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按用户与月份分目录存储，文件名随机生成
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	ext := imageExtensions[mimeType]
+	relPath := filepath.Join(userMobile, time.Now().Format("200601"), hex.EncodeToString(random)+ext)
+	absPath := filepath.Join(config.GetConfig().Upload.Dir, relPath)
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(absPath, data, 0o644); err != nil {
+		return nil, err
+	}
+
+	image := &models.Image{
+		Mobile:      userMobile,
+		FileName:    filepath.Base(fileName),
+		MimeType:    mimeType,
+		Size:        int64(len(data)),
+		StoragePath: relPath,
+	}
+	if err := config.GetDB().Create(image).Error; err != nil {
+		_ = os.Remove(absPath)
+		return nil, err
+	}
+	return image, nil
+}
+
+// GetImage 获取用户的图片
+func GetImage(userMobile string, imageID uint) (*models.Image, error) {
+	var image models.Image
+	if err := config.GetDB().Where("id = ? AND mobile = ?", imageID, userMobile).First(&image).Error; err != nil {
+		return nil, ErrImageNotFound
+	}
+	return &image, nil
+}
+
+// ReadImageData 读取图片内容
+func ReadImageData(image *models.Image) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(config.GetConfig().Upload.Dir, image.StoragePath))
+	if err != nil {
+		return nil, ErrImageNotFound
+	}
+	return data, nil
+}
+
+// FetchImage 下载图片URL并校验大小与类型
+func FetchImage(ctx context.Context, imageURL string) ([]byte, string, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", errors.New("图片URL仅支持http/https")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("下载图片失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("下载图片失败: HTTP %d", resp.StatusCode)
+	}
+
+	// 多读一个字节用于判断是否超出限制
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes()+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("下载图片失败: %w", err)
+	}
+	mimeType, err := ValidateImageData(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, mimeType, nil
+}
+
+// ===== 生成请求中的图片 =====
+
+// GenerateImage 生成请求中引用的图片：已上传图片的ID或图片URL，二选一
+type GenerateImage struct {
+	ID  uint   `json:"id,omitempty"`
+	URL string `json:"url,omitempty"`
+}
+
+// ImageAttachment 解析后的图片，Data 为空时表示直接传递URL
+type ImageAttachment struct {
+	MimeType string
+	Data     []byte
+	URL      string
+}
+
+// base64Data 返回图片内容的Base64编码
+func (a ImageAttachment) base64Data() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// dataURL 返回图片地址，已读取内容时使用 data URL
+func (a ImageAttachment) dataURL() string {
+	if len(a.Data) == 0 {
+		return a.URL
+	}
+	return fmt.Sprintf("data:%s;base64,%s", a.MimeType, a.base64Data())
+}
+
+// supportsVision 判断模型类型是否支持图片输入
+func supportsVision(kind string) bool {
+	info, ok := models.GetAPIKindInfo(kind)
+	return ok && info.Vision
+}
+
+// needsImageData 判断Provider是否需要图片内容（无法直接传递URL）
+// Gemini使用inlineData、Anthropic使用base64图片块、Ollama原生模式使用images字段
+func needsImageData(provider *models.APIProvider) bool {
+	switch provider.APIKind {
+	case models.APIKindGemini, models.APIKindAnthropic:
+		return true
+	case models.APIKindOllama:
+		return !strings.Contains(provider.APIURL, "/v1")
+	}
+	return false
+}
+
+// validateGenerateImages 校验生成请求中的图片引用
+func validateGenerateImages(req GenerateRequest) error {
+	if len(req.Images) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return errors.New("附带图片时提示词不能为空")
+	}
+	if maxImages := config.GetConfig().Upload.MaxImages; len(req.Images) > maxImages {
+		return fmt.Errorf("单次最多附带%d张图片", maxImages)
+	}
+	for i, image := range req.Images {
+		if (image.ID == 0) == (image.URL == "") {
+			return fmt.Errorf("第%d张图片需要指定id或url其中之一", i+1)
+		}
+	}
+	return nil
+}
+
+// resolveGenerateImages 读取已上传的图片，并按Provider需要下载图片URL
+func resolveGenerateImages(ctx context.Context, userMobile string, provider *models.APIProvider, images []GenerateImage) ([]ImageAttachment, error) {
+	attachments := make([]ImageAttachment, 0, len(images))
+	for _, image := range images {
+		if image.URL != "" {
+			if !needsImageData(provider) {
+				attachments = append(attachments, ImageAttachment{URL: image.URL})
+				continue
+			}
+			data, mimeType, err := FetchImage(ctx, image.URL)
+			if err != nil {
+				return nil, err
+			}
+			attachments = append(attachments, ImageAttachment{MimeType: mimeType, Data: data, URL: image.URL})
+			continue
+		}
+
+		stored, err := GetImage(userMobile, image.ID)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ReadImageData(stored)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, ImageAttachment{MimeType: stored.MimeType, Data: data})
+	}
+	return attachments, nil
+}
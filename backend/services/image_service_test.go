@@ -0,0 +1,67 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+)
+
+// pngHeader PNG文件头，足以被 http.DetectContentType 识别
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestValidateImageData(t *testing.T) {
+	maxSize := config.GetConfig().Upload.MaxImageSize << 20
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{name: "PNG", data: pngHeader, want: "image/png"},
+		{name: "文本", data: []byte("hello world"), wantErr: ErrImageTypeInvalid},
+		{name: "空文件", data: nil, wantErr: ErrImageTypeInvalid},
+		{name: "超出大小", data: append(pngHeader, bytes.Repeat([]byte{0}, maxSize)...), wantErr: ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateImageData(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ValidateImageData() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ValidateImageData() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a.png" {
+			_, _ = w.Write(pngHeader)
+			return
+		}
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	data, mimeType, err := FetchImage(t.Context(), server.URL+"/a.png")
+	if err != nil || mimeType != "image/png" || !bytes.Equal(data, pngHeader) {
+		t.Errorf("FetchImage() = %q, %v", mimeType, err)
+	}
+	if _, _, err := FetchImage(t.Context(), server.URL+"/page"); !errors.Is(err, ErrImageTypeInvalid) {
+		t.Errorf("FetchImage() error = %v, want ErrImageTypeInvalid", err)
+	}
+	if _, _, err := FetchImage(t.Context(), "file:///etc/passwd"); err == nil {
+		t.Errorf("FetchImage() should reject non-http url")
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// 输入检查规则
+const (
+	InputRulePromptLength    = "prompt_length"    // 提示词超长
+	InputRuleTokenLimit      = "token_limit"      // 预估token数超限
+	InputRuleBlockedPattern  = "blocked_pattern"  // 命中禁止内容
+	InputRulePromptInjection = "prompt_injection" // 疑似提示词注入
+)
+
+// maxRejectionMatch 拒绝原因中返回的命中内容最大字符数
+const maxRejectionMatch = 50
+
+// InputRejection 输入检查未通过的原因，作为错误返回，可直接作为响应数据
+type InputRejection struct {
+	Rule   string `json:"rule"`             // 命中的规则
+	Field  string `json:"field"`            // 命中的字段：prompt/system/messages/key_information等
+	Reason string `json:"reason"`           // 可读的拒绝原因
+	Match  string `json:"match,omitempty"`  // 命中的内容或注入特征
+	Limit  int    `json:"limit,omitempty"`  // 长度类规则的上限
+	Actual int    `json:"actual,omitempty"` // 长度类规则的实际值
+}
+
+func (r *InputRejection) Error() string {
+	return r.Reason
+}
+
+// injectionSignature 提示词注入特征
+type injectionSignature struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// injectionSignatures 常见的提示词注入特征：要求忽略既有指令、套取系统提示词、越狱角色与伪造的对话角色标记
+var injectionSignatures = []injectionSignature{
+	{"忽略既有指令", regexp.MustCompile(`(?i)(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|preceding|system|all)\s+(instructions?|prompts?|rules|directions)`)},
+	{"忽略既有指令", regexp.MustCompile(`(忽略|无视|忘记|忘掉|不要理会|不要遵守)(掉)?(你)?(之前|以上|上面|前面|先前|上述|此前|所有|全部)(的)?(所有|全部|一切)?(的)?(指令|指示|规则|要求|提示词|设定|限制)`)},
+	{"套取系统提示词", regexp.MustCompile(`(?i)(reveal|show|print|repeat|output|leak)\s+(me\s+)?(your|the)\s+(system\s+prompt|initial\s+instructions|hidden\s+instructions)`)},
+	{"套取系统提示词", regexp.MustCompile(`(输出|打印|泄露|告诉我|显示|重复|透露)(一下)?(你的|你收到的)?(系统提示词|系统指令|初始指令|system\s*prompt)`)},
+	{"越狱角色", regexp.MustCompile(`(?i)(you\s+are\s+now|act\s+as|pretend\s+to\s+be)\s+(an?\s+)?(DAN|unrestricted|jailbroken|developer\s+mode)`)},
+	{"越狱角色", regexp.MustCompile(`(?i)(你现在是|你现在扮演|扮演|进入)(一个)?(不受任何?限制|没有任何?限制|DAN|开发者模式|越狱模式)`)},
+	{"伪造对话角色", regexp.MustCompile(`(?i)<\|im_(start|end)\|>|\[/?INST\]|<</?SYS>>|</?system>`)},
+}
+
+var (
+	blockedPatternsOnce sync.Once
+	blockedPatterns     []*regexp.Regexp
+)
+
+// compileBlockedPatterns 编译禁止内容的正则表达式，无效的表达式记录日志后忽略
+func compileBlockedPatterns(patterns []string) []*regexp.Regexp {
+	var result []*regexp.Regexp
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			utils.Warn("禁止内容的正则表达式无效", zap.String("pattern", p), zap.Error(err))
+			continue
+		}
+		result = append(result, re)
+	}
+	return result
+}
+
+// getBlockedPatterns 返回配置的禁止内容，首次调用时编译
+func getBlockedPatterns() []*regexp.Regexp {
+	blockedPatternsOnce.Do(func() {
+		blockedPatterns = compileBlockedPatterns(config.GetConfig().Input.BlockedPatterns)
+	})
+	return blockedPatterns
+}
+
+// truncateMatch 截断命中内容，避免拒绝原因过长
+func truncateMatch(s string) string {
+	return truncateRunes(strings.TrimSpace(s), maxRejectionMatch)
+}
+
+// matchBlockedPatterns 检查文本是否命中禁止内容
+func matchBlockedPatterns(patterns []*regexp.Regexp, field, text string) *InputRejection {
+	for _, re := range patterns {
+		if m := re.FindString(text); m != "" {
+			return &InputRejection{
+				Rule:   InputRuleBlockedPattern,
+				Field:  field,
+				Reason: "输入包含不允许的内容",
+				Match:  truncateMatch(m),
+			}
+		}
+	}
+	return nil
+}
+
+// detectPromptInjection 检查文本是否包含提示词注入特征
+func detectPromptInjection(field, text string) *InputRejection {
+	for _, sig := range injectionSignatures {
+		if m := sig.Pattern.FindString(text); m != "" {
+			return &InputRejection{
+				Rule:   InputRulePromptInjection,
+				Field:  field,
+				Reason: fmt.Sprintf("关键信息中疑似包含提示词注入（%s），请检查粘贴的内容", sig.Name),
+				Match:  truncateMatch(m),
+			}
+		}
+	}
+	return nil
+}
+
+// extractKeyInformation 从六要素提示词中提取“关键信息”章节的内容
+// 提示词格式见 BuildTemplatePrompt 与前端预览，没有该章节时返回空字符串
+func extractKeyInformation(prompt string) string {
+	var b strings.Builder
+	inSection := false
+	for _, line := range strings.Split(prompt, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			inSection = strings.TrimSpace(strings.TrimLeft(trimmed, "#")) == "关键信息"
+			continue
+		}
+		if inSection {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ScreenGenerateRequest 调用Provider前检查输入：提示词长度、预估token数、禁止内容，
+// 以及关键信息中的提示词注入（指定模板时检查模板的关键信息，否则检查提示词中的关键信息章节）
+// 未通过时返回 *InputRejection
+func ScreenGenerateRequest(req *GenerateRequest) error {
+	cfg := config.GetConfig().Input
+
+	if n := utf8.RuneCountInString(req.Prompt); cfg.MaxPromptLength > 0 && n > cfg.MaxPromptLength {
+		return reject(req.User, &InputRejection{
+			Rule:   InputRulePromptLength,
+			Field:  "prompt",
+			Reason: fmt.Sprintf("提示词不能超过%d个字符", cfg.MaxPromptLength),
+			Limit:  cfg.MaxPromptLength,
+			Actual: n,
+		})
+	}
+
+	fields := []struct{ name, text string }{{"system", req.System}, {"prompt", req.Prompt}}
+	for _, m := range req.Messages {
+		fields = append(fields, struct{ name, text string }{"messages", m.Content})
+	}
+
+	tokens := 0
+	for _, f := range fields {
+		tokens += EstimateTokens(f.text)
+	}
+	if cfg.MaxInputTokens > 0 && tokens > cfg.MaxInputTokens {
+		return reject(req.User, &InputRejection{
+			Rule:   InputRuleTokenLimit,
+			Field:  "prompt",
+			Reason: fmt.Sprintf("输入内容预估约%d个token，超过上限%d，请精简提示词或历史消息", tokens, cfg.MaxInputTokens),
+			Limit:  cfg.MaxInputTokens,
+			Actual: tokens,
+		})
+	}
+
+	patterns := getBlockedPatterns()
+	for _, f := range fields {
+		if rejection := matchBlockedPatterns(patterns, f.name, f.text); rejection != nil {
+			return reject(req.User, rejection)
+		}
+	}
+
+	if !cfg.InjectionCheck {
+		return nil
+	}
+	keyInformation := extractKeyInformation(req.Prompt)
+	if req.TemplateID > 0 && config.GetDB() != nil {
+		var template models.Template
+		if err := config.GetDB().Where("id = ? AND mobile = ?", req.TemplateID, req.User).First(&template).Error; err == nil {
+			keyInformation = template.KeyInformation
+		}
+	}
+	if rejection := detectPromptInjection("key_information", keyInformation); rejection != nil {
+		return reject(req.User, rejection)
+	}
+	return nil
+}
+
+// ScreenTemplateInput 检查六要素内容：各要素不能包含禁止内容，关键信息不能包含提示词注入
+// 未通过时返回 *InputRejection
+func ScreenTemplateInput(r *TemplateRequest) error {
+	fields := []struct{ name, text string }{
+		{"topic", r.Topic},
+		{"task_objective", r.TaskObjective},
+		{"ai_role", r.AIRole},
+		{"my_role", r.MyRole},
+		{"key_information", r.KeyInformation},
+		{"behavior_rule", r.BehaviorRule},
+		{"delivery_format", r.DeliveryFormat},
+	}
+	patterns := getBlockedPatterns()
+	for _, f := range fields {
+		if rejection := matchBlockedPatterns(patterns, f.name, f.text); rejection != nil {
+			return rejection
+		}
+	}
+	if config.GetConfig().Input.InjectionCheck {
+		if rejection := detectPromptInjection("key_information", r.KeyInformation); rejection != nil {
+			return rejection
+		}
+	}
+	return nil
+}
+
+// reject 记录拒绝日志并返回拒绝原因
+func reject(userMobile string, rejection *InputRejection) error {
+	utils.Warn("输入检查未通过",
+		zap.String("user_mobile", userMobile),
+		zap.String("rule", rejection.Rule),
+		zap.String("field", rejection.Field),
+		zap.String("match", rejection.Match))
+	return rejection
+}
@@ -0,0 +1,610 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// 任务相关错误
+var (
+	ErrJobNotFound     = errors.New("任务不存在")
+	ErrJobQueueFull    = errors.New("任务队列已满，请稍后重试")
+	ErrJobNotAvailable = errors.New("任务服务未启动")
+	ErrJobFinished     = errors.New("任务已结束")
+)
+
+// 任务结束时推送给订阅者的错误信息
+const (
+	jobCanceledMessage = "任务已取消"
+	jobTimeoutMessage  = "任务执行超时"
+	jobRequeueMessage  = "服务重启，任务将重新执行"
+)
+
+// jobProgressInterval 执行中任务进度写入数据库的最小间隔
+const jobProgressInterval = 2 * time.Second
+
+// jobSubscriberBuffer 订阅者事件缓冲数量，消费过慢的订阅者会被断开
+const jobSubscriberBuffer = 256
+
+// JobEvent 任务执行事件，Result 与 Error 表示任务结束
+type JobEvent struct {
+	Content string
+	Result  *GenerateResult
+	Error   string
+}
+
+// jobRun 执行中任务的内存状态：缓存已生成的内容并向订阅者广播事件
+type jobRun struct {
+	mu          sync.Mutex
+	content     strings.Builder
+	subscribers map[chan JobEvent]struct{}
+	finished    bool
+	canceled    bool // 由用户取消
+	cancel      context.CancelFunc
+}
+
+func newJobRun(cancel context.CancelFunc) *jobRun {
+	return &jobRun{
+		subscribers: make(map[chan JobEvent]struct{}),
+		cancel:      cancel,
+	}
+}
+
+// publish 记录并广播事件，结束事件发送后关闭所有订阅
+func (r *jobRun) publish(event JobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finished {
+		return
+	}
+
+	r.content.WriteString(event.Content)
+	r.finished = event.Result != nil || event.Error != ""
+	for ch := range r.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者消费过慢，断开后由订阅者重新读取任务状态
+			delete(r.subscribers, ch)
+			close(ch)
+			continue
+		}
+		if r.finished {
+			delete(r.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe 订阅后续事件，返回订阅时已生成的内容；任务已结束时返回 nil 通道
+func (r *jobRun) subscribe() (string, chan JobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finished {
+		return "", nil
+	}
+	ch := make(chan JobEvent, jobSubscriberBuffer)
+	r.subscribers[ch] = struct{}{}
+	return r.content.String(), ch
+}
+
+// unsubscribe 取消订阅
+func (r *jobRun) unsubscribe(ch chan JobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscribers[ch]; ok {
+		delete(r.subscribers, ch)
+		close(ch)
+	}
+}
+
+// requestCancel 标记任务由用户取消并中止生成
+func (r *jobRun) requestCancel() {
+	r.mu.Lock()
+	r.canceled = true
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// isCanceled 任务是否由用户取消
+func (r *jobRun) isCanceled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.canceled
+}
+
+// jobSink 将流式生成结果写入任务：内容实时广播，进度按间隔写入数据库
+type jobSink struct {
+	jobID     uint
+	run       *jobRun
+	progress  int
+	savedAt   time.Time
+	toolCalls int
+	result    *GenerateResult
+	err       string
+}
+
+func (s *jobSink) Content(content string) {
+	s.progress += utf8.RuneCountInString(content)
+	s.run.publish(JobEvent{Content: content})
+	if time.Since(s.savedAt) >= jobProgressInterval {
+		s.savedAt = time.Now()
+		updateJob(s.jobID, map[string]interface{}{"progress": s.progress})
+	}
+}
+
+func (s *jobSink) ToolCalls(deltas []ToolCall) {
+	s.toolCalls += len(deltas)
+}
+
+func (s *jobSink) Done(result *GenerateResult) {
+	s.result = result
+}
+
+func (s *jobSink) Error(message string) {
+	s.err = message
+}
+
+// JobManager 异步生成任务执行器：有界队列 + 固定数量的工作协程
+type JobManager struct {
+	queue   chan uint
+	timeout time.Duration
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu   sync.Mutex
+	runs map[uint]*jobRun
+}
+
+// jobManager 全局任务执行器，未启动时为空
+var jobManager *JobManager
+
+// StartJobManager 启动任务执行器，并将未完成的任务从数据库重新入队
+func StartJobManager(cfg config.JobConfig) error {
+	workers := max(cfg.Workers, 1)
+	ctx, stop := context.WithCancel(context.Background())
+	m := &JobManager{
+		queue:   make(chan uint, max(cfg.QueueSize, 1)),
+		timeout: time.Duration(max(cfg.Timeout, 1)) * time.Second,
+		ctx:     ctx,
+		stop:    stop,
+		runs:    make(map[uint]*jobRun),
+	}
+
+	ids, err := recoverJobs()
+	if err != nil {
+		stop()
+		return err
+	}
+
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+
+	// 恢复的任务可能超过队列容量，异步入队
+	if len(ids) > 0 {
+		utils.Info("重新入队未完成的任务", zap.Int("count", len(ids)))
+		go func() {
+			for _, id := range ids {
+				select {
+				case m.queue <- id:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	jobManager = m
+	utils.Info("任务执行器已启动", zap.Int("workers", workers), zap.Int("queue_size", cap(m.queue)))
+	return nil
+}
+
+// StopJobManager 停止任务执行器，执行中的任务恢复为排队状态，下次启动时重新执行
+func StopJobManager() {
+	m := jobManager
+	if m == nil {
+		return
+	}
+	jobManager = nil
+	m.stop()
+	m.wg.Wait()
+	utils.Info("任务执行器已停止")
+}
+
+// recoverJobs 将上次未执行完的任务恢复为排队状态，返回需要入队的任务ID
+func recoverJobs() ([]uint, error) {
+	db := config.GetDB()
+	if err := db.Model(&models.Job{}).Where("status = ?", models.JobStatusRunning).Updates(map[string]interface{}{
+		"status":     models.JobStatusPending,
+		"progress":   0,
+		"started_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	if err := db.Model(&models.Job{}).Where("status = ?", models.JobStatusPending).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// worker 从队列中取出任务并执行
+func (m *JobManager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case id := <-m.queue:
+			m.execute(id)
+		}
+	}
+}
+
+// register 登记执行中的任务
+func (m *JobManager) register(id uint, cancel context.CancelFunc) *jobRun {
+	run := newJobRun(cancel)
+	m.mu.Lock()
+	m.runs[id] = run
+	m.mu.Unlock()
+	return run
+}
+
+// unregister 移除执行中的任务
+func (m *JobManager) unregister(id uint) {
+	m.mu.Lock()
+	delete(m.runs, id)
+	m.mu.Unlock()
+}
+
+// activeRun 获取执行中的任务
+func (m *JobManager) activeRun(id uint) *jobRun {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.runs[id]
+}
+
+// execute 执行任务并保存结果
+func (m *JobManager) execute(id uint) {
+	ctx, cancel := context.WithTimeout(m.ctx, m.timeout)
+	defer cancel()
+
+	// 先登记再更新状态，保证状态变为执行中后可以被取消
+	run := m.register(id, cancel)
+	defer m.unregister(id)
+
+	db := config.GetDB()
+	result := db.Model(&models.Job{}).Where("id = ? AND status = ?", id, models.JobStatusPending).Updates(map[string]interface{}{
+		"status":     models.JobStatusRunning,
+		"started_at": time.Now(),
+	})
+	if result.Error != nil {
+		utils.Error("更新任务状态失败", zap.Uint("job_id", id), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		// 任务已被取消或删除
+		return
+	}
+
+	var job models.Job
+	if err := db.First(&job, id).Error; err != nil {
+		utils.Error("读取任务失败", zap.Uint("job_id", id), zap.Error(err))
+		return
+	}
+	utils.Info("开始执行任务", zap.Uint("job_id", id), zap.Uint("provider_id", job.ProviderID))
+
+	var req GenerateRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		m.fail(&job, run, "任务参数无效")
+		return
+	}
+	req.User = job.Mobile
+
+	provider, err := GetAPIProvider(job.Mobile, job.ProviderID)
+	if err != nil {
+		m.fail(&job, run, "API Provider不存在")
+		return
+	}
+	if err := PrepareGenerateRequest(ctx, provider, &req); err != nil {
+		m.fail(&job, run, err.Error())
+		return
+	}
+
+	sink := &jobSink{jobID: id, run: run, savedAt: time.Now()}
+	GenerateStream(ctx, provider, req, WithOutputFilters(req.User, req.TemplateID, sink))
+
+	switch {
+	case run.isCanceled():
+		m.finish(job.ID, run, models.JobStatusCanceled, jobCanceledMessage)
+	case m.ctx.Err() != nil:
+		// 服务停止，恢复为排队状态等待下次启动
+		updateJob(id, map[string]interface{}{"status": models.JobStatusPending, "progress": 0, "started_at": nil})
+		run.publish(JobEvent{Error: jobRequeueMessage})
+	case sink.result != nil:
+		m.complete(&job, run, sink.result)
+	case ctx.Err() == context.DeadlineExceeded:
+		m.fail(&job, run, jobTimeoutMessage)
+	default:
+		m.fail(&job, run, sink.err)
+	}
+}
+
+// complete 保存任务结果并投递生成完成事件
+func (m *JobManager) complete(job *models.Job, run *jobRun, result *GenerateResult) {
+	fields := map[string]interface{}{
+		"status":        models.JobStatusCompleted,
+		"content":       result.Content,
+		"progress":      utf8.RuneCountInString(result.Content),
+		"finish_reason": result.FinishReason,
+		"finished_at":   time.Now(),
+	}
+	if result.Usage != nil {
+		fields["prompt_tokens"] = result.Usage.PromptTokens
+		fields["completion_tokens"] = result.Usage.CompletionTokens
+		fields["total_tokens"] = result.Usage.TotalTokens
+	}
+	if len(result.ToolCalls) > 0 {
+		if data, err := json.Marshal(result.ToolCalls); err == nil {
+			fields["tool_calls"] = string(data)
+		}
+	}
+	updateJob(job.ID, fields)
+	run.publish(JobEvent{Result: result})
+	EmitWebhookEvent(job.Mobile, models.WebhookEventGenerationCompleted, generationEventData("job", job.ID, job.ProviderID, result, ""))
+	utils.Info("任务执行完成", zap.Uint("job_id", job.ID), zap.Int("content_length", len(result.Content)))
+}
+
+// fail 标记任务失败并投递生成失败事件
+func (m *JobManager) fail(job *models.Job, run *jobRun, message string) {
+	utils.Warn("任务执行失败", zap.Uint("job_id", job.ID), zap.String("error", message))
+	m.finish(job.ID, run, models.JobStatusFailed, message)
+	EmitWebhookEvent(job.Mobile, models.WebhookEventGenerationFailed, generationEventData("job", job.ID, job.ProviderID, nil, message))
+}
+
+// finish 以失败或取消状态结束任务
+func (m *JobManager) finish(id uint, run *jobRun, status, message string) {
+	updateJob(id, map[string]interface{}{
+		"status":      status,
+		"error":       message,
+		"finished_at": time.Now(),
+	})
+	run.publish(JobEvent{Error: message})
+}
+
+// updateJob 更新任务字段，失败时仅记录日志
+func updateJob(id uint, fields map[string]interface{}) {
+	if err := config.GetDB().Model(&models.Job{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		utils.Error("更新任务失败", zap.Uint("job_id", id), zap.Error(err))
+	}
+}
+
+// SubmitJob 提交异步生成任务
+// 请求参数需已通过 ValidateGenerateRequest 校验，图片在执行时读取
+func SubmitJob(userMobile string, provider *models.APIProvider, req GenerateRequest) (*models.Job, error) {
+	m := jobManager
+	if m == nil {
+		return nil, ErrJobNotAvailable
+	}
+	if err := CheckGenerateProvider(provider, req); err != nil {
+		return nil, err
+	}
+	if len(m.queue) >= cap(m.queue) {
+		return nil, ErrJobQueueFull
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{
+		Mobile:     userMobile,
+		ProviderID: provider.ID,
+		Status:     models.JobStatusPending,
+		Request:    data,
+	}
+	if err := config.GetDB().Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	select {
+	case m.queue <- job.ID:
+	default:
+		updateJob(job.ID, map[string]interface{}{
+			"status":      models.JobStatusFailed,
+			"error":       ErrJobQueueFull.Error(),
+			"finished_at": time.Now(),
+		})
+		return nil, ErrJobQueueFull
+	}
+
+	utils.Info("任务已提交", zap.Uint("job_id", job.ID), zap.Uint("provider_id", provider.ID))
+	return job, nil
+}
+
+// GetJob 获取用户的任务
+func GetJob(userMobile string, jobID uint) (*models.Job, error) {
+	var job models.Job
+	if err := config.GetDB().Where("id = ? AND mobile = ?", jobID, userMobile).First(&job).Error; err != nil {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// ListJobs 分页查询用户的任务（不含生成内容），可按状态过滤
+func ListJobs(userMobile, status string, page, pageSize int) ([]models.Job, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.GetDB().Model(&models.Job{}).Where("mobile = ?", userMobile)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []models.Job
+	if err := query.Omit("content", "tool_calls").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// CancelJob 取消排队中或执行中的任务
+func CancelJob(userMobile string, jobID uint) error {
+	job, err := GetJob(userMobile, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Finished() {
+		return ErrJobFinished
+	}
+
+	// 排队中的任务直接标记为已取消，执行器取到后会跳过
+	result := config.GetDB().Model(&models.Job{}).Where("id = ? AND status = ?", jobID, models.JobStatusPending).Updates(map[string]interface{}{
+		"status":      models.JobStatusCanceled,
+		"error":       jobCanceledMessage,
+		"finished_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// 执行中的任务通知执行器中止
+	if m := jobManager; m != nil {
+		if run := m.activeRun(jobID); run != nil {
+			run.requestCancel()
+			return nil
+		}
+	}
+	return ErrJobFinished
+}
+
+// jobResult 将已完成任务转换为生成结果
+func jobResult(job *models.Job) *GenerateResult {
+	result := &GenerateResult{
+		Content:      job.Content,
+		FinishReason: job.FinishReason,
+		Usage: &Usage{
+			PromptTokens:     job.PromptTokens,
+			CompletionTokens: job.CompletionTokens,
+			TotalTokens:      job.TotalTokens,
+		},
+	}
+	if len(job.ToolCalls) > 0 {
+		_ = json.Unmarshal(job.ToolCalls, &result.ToolCalls)
+	}
+	return result
+}
+
+// StreamJob 推送任务的生成内容：执行中的任务先推送已生成内容再实时推送后续内容，
+// 已结束的任务直接推送结果，排队中的任务等待开始执行
+func StreamJob(ctx context.Context, userMobile string, jobID uint, sink StreamSink) error {
+	var streamed strings.Builder // 已推送的流式内容，重新订阅时避免重复推送
+	for {
+		job, err := GetJob(userMobile, jobID)
+		if err != nil {
+			return err
+		}
+
+		if job.Finished() {
+			if job.Status != models.JobStatusCompleted {
+				sink.Error(job.Error)
+				return nil
+			}
+			sendJobRemainder(jobID, job.Content, streamed.String(), sink)
+			sink.Done(jobResult(job))
+			return nil
+		}
+
+		if m := jobManager; m != nil {
+			if run := m.activeRun(jobID); run != nil && followJobRun(ctx, run, sink, &streamed) {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// sendJobRemainder 推送已完成任务中尚未推送的内容
+// 保存的结果与已推送的流式内容不一致时不再续传，避免重复或截断，完整结果可通过任务详情获取
+func sendJobRemainder(jobID uint, content, streamed string, sink StreamSink) {
+	rest, ok := strings.CutPrefix(content, streamed)
+	if !ok {
+		utils.Warn("任务结果与已推送内容不一致，不再续传", zap.Uint("job_id", jobID),
+			zap.Int("content_length", len(content)), zap.Int("streamed_length", len(streamed)))
+		return
+	}
+	if rest != "" {
+		sink.Content(rest)
+	}
+}
+
+// followJobRun 订阅执行中的任务并推送事件，收到结束事件时返回 true
+// streamed 记录已推送的内容，与任务的流式内容来自同一来源
+func followJobRun(ctx context.Context, run *jobRun, sink StreamSink, streamed *strings.Builder) bool {
+	content, ch := run.subscribe()
+	if ch == nil {
+		return false
+	}
+	defer run.unsubscribe(ch)
+
+	if rest, ok := strings.CutPrefix(content, streamed.String()); ok && rest != "" {
+		sink.Content(rest)
+		streamed.WriteString(rest)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if event.Content != "" {
+				sink.Content(event.Content)
+				streamed.WriteString(event.Content)
+			}
+			if event.Result != nil {
+				sink.Done(event.Result)
+				return true
+			}
+			if event.Error != "" {
+				sink.Error(event.Error)
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestJobRunPublishSubscribe(t *testing.T) {
+	run := newJobRun(func() {})
+	run.publish(JobEvent{Content: "你好"})
+
+	content, ch := run.subscribe()
+	if content != "你好" {
+		t.Errorf("subscribe() content = %q, want %q", content, "你好")
+	}
+
+	run.publish(JobEvent{Content: "，世界"})
+	run.publish(JobEvent{Result: &GenerateResult{Content: "你好，世界"}})
+	// 结束后的事件被忽略
+	run.publish(JobEvent{Content: "多余"})
+
+	var events []JobEvent
+	for event := range ch {
+		events = append(events, event)
+	}
+	if len(events) != 2 || events[0].Content != "，世界" || events[1].Result == nil {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if _, ch := run.subscribe(); ch != nil {
+		t.Error("subscribe() after finish should return nil channel")
+	}
+}
+
+func TestJobRunSlowSubscriber(t *testing.T) {
+	run := newJobRun(func() {})
+	_, ch := run.subscribe()
+	for i := 0; i <= jobSubscriberBuffer; i++ {
+		run.publish(JobEvent{Content: "x"})
+	}
+
+	count := 0
+	for range ch {
+		count++
+	}
+	if count != jobSubscriberBuffer {
+		t.Errorf("slow subscriber received %d events, want %d", count, jobSubscriberBuffer)
+	}
+	if len(run.subscribers) != 0 {
+		t.Error("slow subscriber should be removed")
+	}
+}
+
+func TestJobRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := newJobRun(cancel)
+	if run.isCanceled() {
+		t.Fatal("new run should not be canceled")
+	}
+	run.requestCancel()
+	if !run.isCanceled() || ctx.Err() == nil {
+		t.Error("requestCancel() should mark run canceled and cancel context")
+	}
+}
+
+func TestFollowJobRun(t *testing.T) {
+	run := newJobRun(func() {})
+	run.publish(JobEvent{Content: "第一段"})
+
+	sink := &recordingSink{}
+	var streamed strings.Builder
+	streamed.WriteString("第")
+	done := make(chan bool)
+	go func() {
+		done <- followJobRun(context.Background(), run, sink, &streamed)
+	}()
+
+	// 等待订阅完成后再推送后续内容
+	for {
+		run.mu.Lock()
+		n := len(run.subscribers)
+		run.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	run.publish(JobEvent{Content: "第二段"})
+	run.publish(JobEvent{Error: jobCanceledMessage})
+
+	if !<-done {
+		t.Fatal("followJobRun() should return true on terminal event")
+	}
+	if got := strings.Join(sink.contents, ""); got != "一段第二段" {
+		t.Errorf("contents = %q, want %q", got, "一段第二段")
+	}
+	if streamed.String() != "第一段第二段" {
+		t.Errorf("streamed = %q, want %q", streamed.String(), "第一段第二段")
+	}
+	if sink.err != jobCanceledMessage || sink.result != nil {
+		t.Errorf("unexpected terminal state: err=%q result=%+v", sink.err, sink.result)
+	}
+}
+
+func TestSendJobRemainder(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		streamed string
+		want     string
+	}{
+		{"未推送", "完整结果", "", "完整结果"},
+		{"续传剩余内容", "第一段第二段", "第一段", "第二段"},
+		{"已全部推送", "第一段", "第一段", ""},
+		{"结果与已推送内容不一致", "过滤后的结果", "原始的流式内容", ""},
+		{"结果短于已推送内容", "第一", "第一段", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			sendJobRemainder(1, tt.content, tt.streamed, sink)
+			if got := strings.Join(sink.contents, ""); got != tt.want {
+				t.Errorf("contents = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobResult(t *testing.T) {
+	toolCalls, _ := json.Marshal([]ToolCall{{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "get_weather"}}})
+	job := &models.Job{
+		Status:           models.JobStatusCompleted,
+		Content:          "完成",
+		ToolCalls:        toolCalls,
+		FinishReason:     "tool_calls",
+		PromptTokens:     10,
+		CompletionTokens: 5,
+		TotalTokens:      15,
+	}
+
+	result := jobResult(job)
+	if result.Content != "完成" || result.FinishReason != "tool_calls" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 15 {
+		t.Errorf("unexpected usage: %+v", result.Usage)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Function.Name != "get_weather" {
+		t.Errorf("unexpected tool calls: %+v", result.ToolCalls)
+	}
+}
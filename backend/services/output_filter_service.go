@@ -0,0 +1,577 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// maxOutputFilterLength 可设置的截断长度上限（字符数）
+const maxOutputFilterLength = 100000
+
+// 流式输出时，单行内容超过 outputStreamMaxPending 个字符仍未换行则提前输出，
+// 保留末尾 outputStreamHoldback 个字符，避免敏感词或个人信息被拆分到两次输出中
+const (
+	outputStreamMaxPending = 256
+	outputStreamHoldback   = 64
+)
+
+// OutputFilterRequest 设置输出过滤请求
+type OutputFilterRequest struct {
+	Filters   []string `json:"filters"`    // 启用的过滤器，空数组表示全部关闭
+	MaxLength int      `json:"max_length"` // 截断长度（字符数），0表示使用系统默认
+}
+
+// OutputFilterSettingResponse 生效的输出过滤设置
+type OutputFilterSettingResponse struct {
+	TemplateID uint64   `json:"template_id,omitempty"`
+	Filters    []string `json:"filters"`
+	MaxLength  int      `json:"max_length"`
+	Source     string   `json:"source"` // default：系统默认；user：用户设置；template：模板设置
+}
+
+// OutputFilter 输出过滤器，按顺序处理同一次生成的连续内容片段，可以保存片段间的状态
+type OutputFilter interface {
+	Apply(text string) string // 处理一个内容片段
+	Flush() string            // 生成结束，返回需要追加的内容
+}
+
+// ============ 敏感词 ============
+
+var (
+	sensitiveWordsOnce    sync.Once
+	sensitiveWordsPattern *regexp.Regexp
+)
+
+// loadSensitiveWords 合并配置与词库文件中的敏感词，长词优先匹配，忽略英文大小写
+func loadSensitiveWords(cfg config.OutputConfig) *regexp.Regexp {
+	words := append([]string{}, cfg.SensitiveWords...)
+	if cfg.LexiconFile != "" {
+		file, err := os.Open(cfg.LexiconFile)
+		if err != nil {
+			utils.Warn("读取敏感词词库失败", zap.String("file", cfg.LexiconFile), zap.Error(err))
+		} else {
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				words = append(words, scanner.Text())
+			}
+		}
+	}
+	return compileSensitiveWords(words)
+}
+
+// compileSensitiveWords 构建敏感词匹配正则，没有敏感词时返回 nil
+func compileSensitiveWords(words []string) *regexp.Regexp {
+	seen := make(map[string]bool, len(words))
+	var quoted []string
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" || strings.HasPrefix(w, "#") || seen[strings.ToLower(w)] {
+			continue
+		}
+		seen[strings.ToLower(w)] = true
+		quoted = append(quoted, regexp.QuoteMeta(w))
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	sort.SliceStable(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// sensitiveWords 返回全局敏感词匹配正则，首次调用时加载
+func sensitiveWords() *regexp.Regexp {
+	sensitiveWordsOnce.Do(func() {
+		sensitiveWordsPattern = loadSensitiveWords(config.GetConfig().Output)
+	})
+	return sensitiveWordsPattern
+}
+
+// sensitiveWordFilter 将敏感词替换为等长的替换字符
+type sensitiveWordFilter struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func (f *sensitiveWordFilter) Apply(text string) string {
+	if f.pattern == nil {
+		return text
+	}
+	return f.pattern.ReplaceAllStringFunc(text, func(w string) string {
+		return strings.Repeat(f.replacement, utf8.RuneCountInString(w))
+	})
+}
+
+func (f *sensitiveWordFilter) Flush() string { return "" }
+
+// ============ 个人信息脱敏 ============
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	digitsPattern = regexp.MustCompile(`[0-9]+[Xx]?`)
+	mobilePattern = regexp.MustCompile(`^1[3-9][0-9]{9}$`)
+	idCardPattern = regexp.MustCompile(`^[1-9][0-9]{16}[0-9Xx]$`)
+)
+
+// piiFilter 脱敏手机号（保留前3后4位）、身份证号（保留前6后4位）与邮箱（保留首字符与域名）
+type piiFilter struct{}
+
+func (piiFilter) Apply(text string) string {
+	text = emailPattern.ReplaceAllStringFunc(text, func(email string) string {
+		at := strings.LastIndexByte(email, '@')
+		return email[:1] + "***" + email[at:]
+	})
+	return digitsPattern.ReplaceAllStringFunc(text, func(s string) string {
+		switch {
+		case mobilePattern.MatchString(s):
+			return s[:3] + "****" + s[7:]
+		case idCardPattern.MatchString(s):
+			return s[:6] + strings.Repeat("*", 8) + s[14:]
+		}
+		return s
+	})
+}
+
+func (piiFilter) Flush() string { return "" }
+
+// isPIIByte 是否可能是手机号、身份证号或邮箱中的字符
+func isPIIByte(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || strings.IndexByte("._%+-@", b) >= 0
+}
+
+// ============ Markdown规范化 ============
+
+var (
+	headingPattern = regexp.MustCompile(`^(\s{0,3}#{1,6})([^#\s])`)
+	bulletPattern  = regexp.MustCompile(`^(\s*)[*+](\s+)`)
+)
+
+// markdownFilter 统一换行符、去除行尾空白、合并连续空行、补全标题#后的空格、
+// 统一无序列表标记为 -，并在结束时补全未闭合的代码块；代码块内的内容保持不变
+type markdownFilter struct {
+	started   bool   // 是否已输出非空内容，开头的空行会被去除
+	lineStart bool   // 下一个字符是否位于行首
+	inFence   bool   // 是否位于代码块内
+	newlines  int    // 末尾已输出的连续换行数
+	space     string // 暂缓输出的行尾空白，遇到换行时丢弃
+}
+
+func newMarkdownFilter() *markdownFilter {
+	return &markdownFilter{lineStart: true}
+}
+
+func (f *markdownFilter) Apply(text string) string {
+	text = strings.ReplaceAll(text, "\r", "")
+	var b strings.Builder
+	for text != "" {
+		line, rest, hasNewline := strings.Cut(text, "\n")
+		text = rest
+
+		verbatim := f.inFence
+		if f.lineStart && isFenceLine(line) {
+			f.inFence = !f.inFence
+			verbatim = false
+		}
+		if f.lineStart && !verbatim && !isThematicBreak(line) {
+			line = headingPattern.ReplaceAllString(line, "$1 $2")
+			line = bulletPattern.ReplaceAllString(line, "$1-$2")
+		}
+
+		if verbatim {
+			b.WriteString(f.space + line)
+			f.space = ""
+			if line != "" {
+				f.started = true
+				f.newlines = 0
+			}
+		} else {
+			trimmed := strings.TrimRight(line, " \t")
+			if trimmed != "" {
+				b.WriteString(f.space + trimmed)
+				f.space = ""
+				f.started = true
+				f.newlines = 0
+			}
+			f.space += line[len(trimmed):]
+		}
+
+		if hasNewline {
+			f.space = ""
+			if verbatim || (f.started && f.newlines < 2) {
+				b.WriteByte('\n')
+				f.newlines++
+			}
+			f.lineStart = true
+		} else if line != "" {
+			f.lineStart = false
+		}
+	}
+	return b.String()
+}
+
+func (f *markdownFilter) Flush() string {
+	if !f.inFence {
+		return ""
+	}
+	f.inFence = false
+	if f.newlines == 0 {
+		return "\n```"
+	}
+	return "```"
+}
+
+// isFenceLine 是否为代码块的起止行
+func isFenceLine(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) <= 3 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"))
+}
+
+// isThematicBreak 是否为分隔线（如 * * *），避免被当作列表处理
+func isThematicBreak(line string) bool {
+	trimmed := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(trimmed) < 3 {
+		return false
+	}
+	return strings.Count(trimmed, trimmed[:1]) == len(trimmed) && strings.ContainsAny(trimmed[:1], "*-_")
+}
+
+// ============ 截断 ============
+
+// truncateFilter 内容超过 limit 个字符时截断，追加 suffix 后丢弃之后的内容
+type truncateFilter struct {
+	limit     int
+	suffix    string
+	count     int
+	truncated bool
+}
+
+func (f *truncateFilter) Apply(text string) string {
+	if f.truncated {
+		return ""
+	}
+	n := utf8.RuneCountInString(text)
+	if f.count+n <= f.limit {
+		f.count += n
+		return text
+	}
+	f.truncated = true
+	runes := []rune(text)
+	return string(runes[:f.limit-f.count]) + f.suffix
+}
+
+func (f *truncateFilter) Flush() string { return "" }
+
+// ============ 过滤链 ============
+
+// OutputFilterChain 一次生成使用的过滤链，过滤器带有状态，不能在多次生成间复用
+type OutputFilterChain struct {
+	filters   []OutputFilter
+	sensitive *regexp.Regexp
+}
+
+// newOutputFilterChain 按启用的过滤器构建过滤链，maxLength 为0时使用系统默认截断长度
+func newOutputFilterChain(names []string, maxLength int) *OutputFilterChain {
+	cfg := config.GetConfig().Output
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled[name] = true
+	}
+
+	chain := &OutputFilterChain{}
+	if enabled[models.OutputFilterSensitiveWord] {
+		chain.sensitive = sensitiveWords()
+		if chain.sensitive != nil {
+			replacement := cfg.Replacement
+			if replacement == "" {
+				replacement = "*"
+			}
+			chain.filters = append(chain.filters, &sensitiveWordFilter{pattern: chain.sensitive, replacement: replacement})
+		}
+	}
+	if enabled[models.OutputFilterPIIMask] {
+		chain.filters = append(chain.filters, piiFilter{})
+	}
+	if enabled[models.OutputFilterMarkdown] {
+		chain.filters = append(chain.filters, newMarkdownFilter())
+	}
+	if enabled[models.OutputFilterTruncate] {
+		if maxLength <= 0 {
+			maxLength = cfg.MaxLength
+		}
+		if maxLength > 0 {
+			chain.filters = append(chain.filters, &truncateFilter{limit: maxLength, suffix: cfg.TruncateSuffix})
+		}
+	}
+	return chain
+}
+
+// Empty 是否未启用任何过滤器
+func (c *OutputFilterChain) Empty() bool {
+	return len(c.filters) == 0
+}
+
+// Apply 依次经过各过滤器处理内容片段
+func (c *OutputFilterChain) Apply(text string) string {
+	for _, f := range c.filters {
+		text = f.Apply(text)
+	}
+	return text
+}
+
+// Flush 生成结束，返回各过滤器需要追加的内容
+func (c *OutputFilterChain) Flush() string {
+	var tail string
+	for _, f := range c.filters {
+		tail = f.Apply(tail) + f.Flush()
+	}
+	return tail
+}
+
+// Filter 过滤完整内容
+func (c *OutputFilterChain) Filter(text string) string {
+	return c.Apply(text) + c.Flush()
+}
+
+// streamCut 返回流式缓冲内容中可以安全输出的前缀长度（字节）
+// 优先在最后一个换行处切分；单行过长时在末尾保留一段，并避开可能被拆分的敏感词与个人信息
+func (c *OutputFilterChain) streamCut(pending string) int {
+	if i := strings.LastIndexByte(pending, '\n'); i >= 0 {
+		return i + 1
+	}
+	n := utf8.RuneCountInString(pending)
+	if n <= outputStreamMaxPending {
+		return 0
+	}
+
+	cut := len(pending)
+	for i := 0; i < outputStreamHoldback; i++ {
+		_, size := utf8.DecodeLastRuneInString(pending[:cut])
+		cut -= size
+	}
+	for cut > 0 && isPIIByte(pending[cut-1]) && isPIIByte(pending[cut]) {
+		cut--
+	}
+	if c.sensitive != nil {
+		for _, loc := range c.sensitive.FindAllStringIndex(pending, -1) {
+			if loc[0] < cut && cut < loc[1] {
+				cut = loc[0]
+			}
+		}
+	}
+	return cut
+}
+
+// filterSink 过滤流式输出的内容片段，完成时以过滤后的完整内容作为结果
+type filterSink struct {
+	StreamSink
+	chain   *OutputFilterChain
+	pending string
+	content strings.Builder
+}
+
+func (s *filterSink) emit(text string) {
+	if text == "" {
+		return
+	}
+	s.content.WriteString(text)
+	s.StreamSink.Content(text)
+}
+
+func (s *filterSink) Content(content string) {
+	s.pending += content
+	if cut := s.chain.streamCut(s.pending); cut > 0 {
+		s.emit(s.chain.Apply(s.pending[:cut]))
+		s.pending = s.pending[cut:]
+	}
+}
+
+func (s *filterSink) flush() {
+	s.emit(s.chain.Apply(s.pending) + s.chain.Flush())
+	s.pending = ""
+}
+
+func (s *filterSink) Done(result *GenerateResult) {
+	s.flush()
+	filtered := *result
+	filtered.Content = s.content.String()
+	s.StreamSink.Done(&filtered)
+}
+
+func (s *filterSink) Error(message string) {
+	s.flush()
+	s.StreamSink.Error(message)
+}
+
+// WithOutputFilters 按用户或模板的输出过滤设置包装 sink，未启用过滤器时原样返回
+func WithOutputFilters(userMobile string, templateID uint64, sink StreamSink) StreamSink {
+	chain := NewOutputFilterChain(userMobile, templateID)
+	if chain.Empty() {
+		return sink
+	}
+	return &filterSink{StreamSink: sink, chain: chain}
+}
+
+// FilterOutput 按用户或模板的输出过滤设置过滤完整内容
+func FilterOutput(userMobile string, templateID uint64, text string) string {
+	return NewOutputFilterChain(userMobile, templateID).Filter(text)
+}
+
+// NewOutputFilterChain 按用户或模板生效的输出过滤设置构建过滤链
+func NewOutputFilterChain(userMobile string, templateID uint64) *OutputFilterChain {
+	setting := resolveOutputFilterSetting(userMobile, templateID)
+	return newOutputFilterChain(setting.Filters, setting.MaxLength)
+}
+
+// ============ 设置 ============
+
+// normalizeOutputFilters 校验并去重过滤器，按 models.OutputFilters 的顺序返回
+func normalizeOutputFilters(filters []string) (string, error) {
+	selected := make(map[string]bool, len(filters))
+	for _, name := range filters {
+		name = strings.TrimSpace(name)
+		valid := false
+		for _, f := range models.OutputFilters {
+			if f == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("不支持的过滤器: %s，可选: %s", name, strings.Join(models.OutputFilters, ", "))
+		}
+		selected[name] = true
+	}
+
+	var result []string
+	for _, f := range models.OutputFilters {
+		if selected[f] {
+			result = append(result, f)
+		}
+	}
+	return strings.Join(result, ","), nil
+}
+
+// defaultOutputFilterSetting 系统默认的输出过滤设置
+func defaultOutputFilterSetting() *OutputFilterSettingResponse {
+	cfg := config.GetConfig().Output
+	filters, err := normalizeOutputFilters(cfg.Filters)
+	if err != nil {
+		utils.Warn("输出过滤默认配置无效", zap.Error(err))
+		filters = ""
+	}
+	setting := &models.OutputFilterSetting{Filters: filters}
+	return &OutputFilterSettingResponse{Filters: setting.FilterList(), MaxLength: cfg.MaxLength, Source: models.OutputFilterSourceDefault}
+}
+
+// toOutputFilterResponse 转换为生效设置，MaxLength 为0时使用系统默认
+func toOutputFilterResponse(setting *models.OutputFilterSetting, source string) *OutputFilterSettingResponse {
+	maxLength := setting.MaxLength
+	if maxLength <= 0 {
+		maxLength = config.GetConfig().Output.MaxLength
+	}
+	return &OutputFilterSettingResponse{
+		TemplateID: setting.TemplateID,
+		Filters:    setting.FilterList(),
+		MaxLength:  maxLength,
+		Source:     source,
+	}
+}
+
+// findOutputFilterSetting 查询用户（templateID 为0）或模板的设置
+func findOutputFilterSetting(userMobile string, templateID uint64) (*models.OutputFilterSetting, bool) {
+	db := config.GetDB()
+	if db == nil || userMobile == "" {
+		return nil, false
+	}
+	var setting models.OutputFilterSetting
+	if err := db.Where("mobile = ? AND template_id = ?", userMobile, templateID).First(&setting).Error; err != nil {
+		return nil, false
+	}
+	return &setting, true
+}
+
+// resolveOutputFilterSetting 生效的设置，优先级：模板设置 > 用户设置 > 系统默认
+func resolveOutputFilterSetting(userMobile string, templateID uint64) *OutputFilterSettingResponse {
+	if templateID > 0 {
+		if setting, ok := findOutputFilterSetting(userMobile, templateID); ok {
+			return toOutputFilterResponse(setting, models.OutputFilterSourceTemplate)
+		}
+	}
+	if setting, ok := findOutputFilterSetting(userMobile, 0); ok {
+		resp := toOutputFilterResponse(setting, models.OutputFilterSourceUser)
+		resp.TemplateID = templateID
+		return resp
+	}
+	resp := defaultOutputFilterSetting()
+	resp.TemplateID = templateID
+	return resp
+}
+
+// GetOutputFilterSetting 获取用户或模板生效的输出过滤设置
+func GetOutputFilterSetting(userMobile string, templateID uint64) (*OutputFilterSettingResponse, error) {
+	if templateID > 0 {
+		if _, err := (&TemplateService{}).GetTemplateByID(userMobile, templateID); err != nil {
+			return nil, err
+		}
+	}
+	return resolveOutputFilterSetting(userMobile, templateID), nil
+}
+
+// SaveOutputFilterSetting 保存用户（templateID 为0）或模板的输出过滤设置
+func SaveOutputFilterSetting(userMobile string, templateID uint64, req *OutputFilterRequest) (*OutputFilterSettingResponse, error) {
+	if templateID > 0 {
+		if _, err := (&TemplateService{}).GetTemplateByID(userMobile, templateID); err != nil {
+			return nil, err
+		}
+	}
+	filters, err := normalizeOutputFilters(req.Filters)
+	if err != nil {
+		return nil, err
+	}
+	if req.MaxLength < 0 || req.MaxLength > maxOutputFilterLength {
+		return nil, fmt.Errorf("截断长度必须在0-%d之间", maxOutputFilterLength)
+	}
+
+	setting, ok := findOutputFilterSetting(userMobile, templateID)
+	if !ok {
+		setting = &models.OutputFilterSetting{Mobile: userMobile, TemplateID: templateID}
+	}
+	setting.Filters = filters
+	setting.MaxLength = req.MaxLength
+	if err := config.GetDB().Save(setting).Error; err != nil {
+		return nil, err
+	}
+
+	source := models.OutputFilterSourceUser
+	if templateID > 0 {
+		source = models.OutputFilterSourceTemplate
+	}
+	return toOutputFilterResponse(setting, source), nil
+}
+
+// ErrOutputFilterNotFound 模板未单独设置输出过滤
+var ErrOutputFilterNotFound = errors.New("该模板未单独设置输出过滤")
+
+// DeleteOutputFilterSetting 删除模板的输出过滤设置，恢复使用用户设置
+func DeleteOutputFilterSetting(userMobile string, templateID uint64) error {
+	result := config.GetDB().Where("mobile = ? AND template_id = ?", userMobile, templateID).Delete(&models.OutputFilterSetting{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOutputFilterNotFound
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+)
+
+func TestSensitiveWordFilter(t *testing.T) {
+	pattern := compileSensitiveWords([]string{"赌博", " # 注释", "", "Casino", "赌博网站", "casino"})
+	f := &sensitiveWordFilter{pattern: pattern, replacement: "*"}
+
+	got := f.Apply("请勿访问赌博网站或CASINO，赌博有害")
+	if got != "请勿访问****或******，**有害" {
+		t.Errorf("Apply() = %q", got)
+	}
+	if compileSensitiveWords([]string{" ", "#"}) != nil {
+		t.Error("compileSensitiveWords() expected nil for empty lexicon")
+	}
+}
+
+func TestPIIFilter(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"联系电话13800138000。", "联系电话138****8000。"},
+		{"身份证110101199003071234和11010119900307123X", "身份证110101********1234和110101********123X"},
+		{"邮箱 zhangsan@example.com", "邮箱 z***@example.com"},
+		{"订单号138001380001不是手机号", "订单号138001380001不是手机号"},
+		{"13800138000@qq.com", "1***@qq.com"},
+	}
+	for _, tt := range tests {
+		if got := (piiFilter{}).Apply(tt.in); got != tt.want {
+			t.Errorf("Apply(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownFilter(t *testing.T) {
+	in := "\n\n#标题  \r\n* 列表一\n+ 列表二\n\n\n\n* * *\n```go\n#include   \n\n\n\n* x\n```\n结尾\n```\ncode"
+	want := "# 标题\n- 列表一\n- 列表二\n\n* * *\n```go\n#include   \n\n\n\n* x\n```\n结尾\n```\ncode\n```"
+
+	f := newMarkdownFilter()
+	if got := f.Apply(in) + f.Flush(); got != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+
+	// 流式输出时按行输入，结果一致
+	f = newMarkdownFilter()
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(in, "\n") {
+		b.WriteString(f.Apply(line))
+	}
+	b.WriteString(f.Flush())
+	if b.String() != want {
+		t.Errorf("streamed Apply() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestTruncateFilter(t *testing.T) {
+	f := &truncateFilter{limit: 5, suffix: "…"}
+	got := f.Apply("你好") + f.Apply("世界你好") + f.Apply("不输出")
+	if got != "你好世界你…" {
+		t.Errorf("Apply() = %q", got)
+	}
+}
+
+func TestNormalizeOutputFilters(t *testing.T) {
+	got, err := normalizeOutputFilters([]string{"truncate", "pii_mask", "truncate"})
+	if err != nil || got != "pii_mask,truncate" {
+		t.Errorf("normalizeOutputFilters() = %q, %v", got, err)
+	}
+	if got, err := normalizeOutputFilters(nil); err != nil || got != "" {
+		t.Errorf("normalizeOutputFilters(nil) = %q, %v", got, err)
+	}
+	if _, err := normalizeOutputFilters([]string{"html"}); err == nil {
+		t.Error("normalizeOutputFilters() expected error for unknown filter")
+	}
+}
+
+func TestFilterSink(t *testing.T) {
+	pattern := compileSensitiveWords([]string{"敏感词"})
+	chain := &OutputFilterChain{
+		filters:   []OutputFilter{&sensitiveWordFilter{pattern: pattern, replacement: "*"}, piiFilter{}, newMarkdownFilter()},
+		sensitive: pattern,
+	}
+	inner := &recordingSink{}
+	sink := &collectSink{StreamSink: &filterSink{StreamSink: inner, chain: chain}}
+
+	// 敏感词与手机号被拆分到多个片段中
+	for _, chunk := range []string{"#标题\n电话138", "0013", "8000，含敏", "感词", "\n", strings.Repeat("长", 300), "敏感", "词结尾"} {
+		sink.Content(chunk)
+	}
+	sink.Done(&GenerateResult{FinishReason: "stop"})
+
+	want := "# 标题\n电话138****8000，含***\n" + strings.Repeat("长", 300) + "***结尾"
+	if got := strings.Join(inner.contents, ""); got != want {
+		t.Errorf("streamed content = %q, want %q", got, want)
+	}
+	if len(inner.contents) < 3 {
+		t.Errorf("expected content to be streamed in several chunks, got %d", len(inner.contents))
+	}
+	if inner.result == nil || inner.result.Content != want || inner.result.FinishReason != "stop" {
+		t.Errorf("unexpected result: %+v", inner.result)
+	}
+}
+
+func TestOutputFilterChainDefaults(t *testing.T) {
+	// 未连接数据库时使用系统默认设置（默认不启用过滤器）
+	db := config.DB
+	config.DB = nil
+	defer func() { config.DB = db }()
+
+	if chain := NewOutputFilterChain("13800138000", 1); !chain.Empty() {
+		t.Error("NewOutputFilterChain() expected empty chain by default")
+	}
+	inner := &recordingSink{}
+	if sink := WithOutputFilters("13800138000", 0, inner); sink != inner {
+		t.Error("WithOutputFilters() should return the sink unchanged without filters")
+	}
+
+	chain := newOutputFilterChain([]string{"pii_mask", "truncate"}, 10)
+	if got := chain.Filter("电话：13800138000，请尽快联系"); got != "电话：138****……（内容已截断）" {
+		t.Errorf("Filter() = %q", got)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"gopkg.in/yaml.v3"
+)
+
+// 模板导出格式
+const (
+	TemplateFormatMarkdown = "markdown" // 标题加六要素章节的Markdown
+	TemplateFormatCoze     = "coze"     // 仅六要素章节，可直接粘贴为Coze智能体提示词
+	TemplateFormatJSON     = "json"
+	TemplateFormatYAML     = "yaml"
+	TemplateFormatOpenAI   = "openai" // OpenAI messages（system + user）
+)
+
+// maxExportFileNameLength 导出文件名中主题部分的最大字符数
+const maxExportFileNameLength = 50
+
+// ErrTemplateExportFormat 不支持的导出格式
+var ErrTemplateExportFormat = errors.New("仅支持markdown、coze、json、yaml、openai格式")
+
+// templateExportFormats 各导出格式的文件扩展名与Content-Type
+var templateExportFormats = map[string]struct {
+	ext         string
+	contentType string
+}{
+	TemplateFormatMarkdown: {"md", "text/markdown; charset=utf-8"},
+	TemplateFormatCoze:     {"md", "text/markdown; charset=utf-8"},
+	TemplateFormatJSON:     {"json", "application/json; charset=utf-8"},
+	TemplateFormatYAML:     {"yaml", "application/x-yaml; charset=utf-8"},
+	TemplateFormatOpenAI:   {"json", "application/json; charset=utf-8"},
+}
+
+// TemplateDocument 模板的可移植表示，用于JSON/YAML导出与导入
+type TemplateDocument struct {
+	Topic          string `json:"topic" yaml:"topic"`
+	TaskObjective  string `json:"task_objective" yaml:"task_objective"`
+	AIRole         string `json:"ai_role" yaml:"ai_role"`
+	MyRole         string `json:"my_role" yaml:"my_role"`
+	KeyInformation string `json:"key_information" yaml:"key_information"`
+	BehaviorRule   string `json:"behavior_rule" yaml:"behavior_rule"`
+	DeliveryFormat string `json:"delivery_format" yaml:"delivery_format"`
+
+	Variables models.TemplateVariables `json:"variables,omitempty" yaml:"variables,omitempty"`
+}
+
+// TemplateExportFile 导出结果
+type TemplateExportFile struct {
+	Data        []byte
+	ContentType string
+	FileName    string
+}
+
+// ExportTemplate 按指定格式导出单个模板，可导出自己的、公开的或共享给自己的模板
+func (s *TemplateService) ExportTemplate(userMobile string, templateID uint64, format string) (*TemplateExportFile, error) {
+	if _, ok := templateExportFormats[format]; !ok {
+		return nil, ErrTemplateExportFormat
+	}
+	template, err := s.GetVisibleTemplate(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := renderTemplate(template, format)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateExportFile{
+		Data:        data,
+		ContentType: templateExportFormats[format].contentType,
+		FileName:    fmt.Sprintf("template-%d.%s", template.ID, templateExportFormats[format].ext),
+	}, nil
+}
+
+// ExportTemplates 将用户的全部模板按指定格式导出为zip压缩包，每个模板一个文件
+func (s *TemplateService) ExportTemplates(userMobile, format string) (*TemplateExportFile, error) {
+	if _, ok := templateExportFormats[format]; !ok {
+		return nil, ErrTemplateExportFormat
+	}
+
+	var templates []models.Template
+	if err := config.DB.Where("mobile = ?", userMobile).Order("id ASC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("查询失败: %w", err)
+	}
+
+	data, err := buildTemplateArchive(templates, format)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateExportFile{
+		Data:        data,
+		ContentType: "application/zip",
+		FileName:    fmt.Sprintf("templates-%s.zip", time.Now().Format("20060102")),
+	}, nil
+}
+
+// buildTemplateArchive 将模板逐个渲染并写入zip压缩包
+func buildTemplateArchive(templates []models.Template, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for i := range templates {
+		data, err := renderTemplate(&templates[i], format)
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("%d-%s.%s", templates[i].ID, exportFileName(templates[i].Topic), templateExportFormats[format].ext)
+		file, err := writer.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportFileName 将主题转换为安全的文件名
+func exportFileName(topic string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|`, r), r < 0x20:
+			return '_'
+		case r == ' ' || r == '\t':
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(topic))
+	if utf8.RuneCountInString(name) > maxExportFileNameLength {
+		name = string([]rune(name)[:maxExportFileNameLength])
+	}
+	if strings.Trim(name, "._-") == "" {
+		return "template"
+	}
+	return name
+}
+
+// renderTemplate 按格式渲染单个模板
+func renderTemplate(template *models.Template, format string) ([]byte, error) {
+	switch format {
+	case TemplateFormatMarkdown:
+		return []byte(BuildTemplateMarkdown(template)), nil
+	case TemplateFormatCoze:
+		var b strings.Builder
+		writeTemplateSections(&b, templateSections(template), false)
+		return []byte(b.String()), nil
+	case TemplateFormatJSON:
+		return marshalExportJSON(newTemplateDocument(template))
+	case TemplateFormatYAML:
+		return yaml.Marshal(newTemplateDocument(template))
+	case TemplateFormatOpenAI:
+		// OpenAIMessage 自定义的序列化会转义HTML字符，这里只输出 role 与 content
+		type message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}
+		var messages []message
+		for _, m := range BuildTemplateMessages(template) {
+			messages = append(messages, message{Role: m.Role, Content: m.Content})
+		}
+		return marshalExportJSON(map[string][]message{"messages": messages})
+	}
+	return nil, ErrTemplateExportFormat
+}
+
+// marshalExportJSON 输出缩进且不转义HTML字符的JSON
+func marshalExportJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newTemplateDocument 提取模板内容
+func newTemplateDocument(template *models.Template) TemplateDocument {
+	return TemplateDocument{
+		Topic:          template.Topic,
+		TaskObjective:  template.TaskObjective,
+		AIRole:         template.AIRole,
+		MyRole:         template.MyRole,
+		KeyInformation: template.KeyInformation,
+		BehaviorRule:   template.BehaviorRule,
+		DeliveryFormat: template.DeliveryFormat,
+		Variables:      template.Variables,
+	}
+}
+
+// BuildTemplateMarkdown 以 "# 主题" 加六个 "## 要素" 章节的标准格式输出模板，空要素保留标题
+// 章节格式与 coze智能体提示词.md 一致
+func BuildTemplateMarkdown(template *models.Template) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", strings.TrimSpace(template.Topic))
+	writeTemplateSections(&b, templateSections(template), false)
+	return b.String()
+}
+
+// BuildTemplateMessages 将模板转换为OpenAI messages：
+// AI的角色、行为规则、交付格式作为 system，任务目标、我的角色、关键信息作为 user
+// user 部分为空时以主题作为 user 消息
+func BuildTemplateMessages(template *models.Template) []OpenAIMessage {
+	sections := templateSections(template)
+	var system, user strings.Builder
+	writeTemplateSections(&system, []templateSection{sections[1], sections[4], sections[5]}, true)
+	writeTemplateSections(&user, []templateSection{sections[0], sections[2], sections[3]}, true)
+
+	messages := make([]OpenAIMessage, 0, 2)
+	if system.Len() > 0 {
+		messages = append(messages, OpenAIMessage{Role: RoleSystem, Content: system.String()})
+	}
+	content := user.String()
+	if content == "" {
+		content = strings.TrimSpace(template.Topic)
+	}
+	return append(messages, OpenAIMessage{Role: RoleUser, Content: content})
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+	"gopkg.in/yaml.v3"
+)
+
+var exportTestTemplate = models.Template{
+	ID:             7,
+	Topic:          "写作助手",
+	TaskObjective:  "写一篇文章",
+	AIRole:         "专业作者",
+	KeyInformation: "目标读者：<开发者>",
+	DeliveryFormat: "Markdown",
+}
+
+func TestBuildTemplateMarkdown(t *testing.T) {
+	got := BuildTemplateMarkdown(&exportTestTemplate)
+	want := "# 写作助手\n\n## 任务目标\n写一篇文章\n\n## AI的角色\n专业作者\n\n## 我的角色\n\n\n" +
+		"## 关键信息\n目标读者：<开发者>\n\n## 行为规则\n\n\n## 交付格式\nMarkdown\n"
+	if got != want {
+		t.Errorf("BuildTemplateMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildTemplateMessages(t *testing.T) {
+	messages := BuildTemplateMessages(&exportTestTemplate)
+	if len(messages) != 2 || messages[0].Role != RoleSystem || messages[1].Role != RoleUser {
+		t.Fatalf("BuildTemplateMessages() = %+v", messages)
+	}
+	if messages[0].Content != "## AI的角色\n专业作者\n\n## 交付格式\nMarkdown\n" {
+		t.Errorf("system = %q", messages[0].Content)
+	}
+	if !strings.HasPrefix(messages[1].Content, "## 任务目标\n写一篇文章\n") {
+		t.Errorf("user = %q", messages[1].Content)
+	}
+
+	messages = BuildTemplateMessages(&models.Template{Topic: "仅主题"})
+	if len(messages) != 1 || messages[0].Content != "仅主题" {
+		t.Errorf("BuildTemplateMessages(topic only) = %+v", messages)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data, err := renderTemplate(&exportTestTemplate, TemplateFormatJSON)
+	if err != nil || !bytes.Contains(data, []byte("<开发者>")) {
+		t.Fatalf("renderTemplate(json) = %s, %v", data, err)
+	}
+	var doc TemplateDocument
+	if err := json.Unmarshal(data, &doc); err != nil || !reflect.DeepEqual(doc, newTemplateDocument(&exportTestTemplate)) {
+		t.Errorf("json round trip = %+v, %v", doc, err)
+	}
+
+	data, err = renderTemplate(&exportTestTemplate, TemplateFormatYAML)
+	if err != nil {
+		t.Fatalf("renderTemplate(yaml) error = %v", err)
+	}
+	doc = TemplateDocument{}
+	if err := yaml.Unmarshal(data, &doc); err != nil || !reflect.DeepEqual(doc, newTemplateDocument(&exportTestTemplate)) {
+		t.Errorf("yaml round trip = %+v, %v", doc, err)
+	}
+
+	data, err = renderTemplate(&exportTestTemplate, TemplateFormatOpenAI)
+	var body struct {
+		Messages []OpenAIMessage `json:"messages"`
+	}
+	if err != nil || json.Unmarshal(data, &body) != nil || len(body.Messages) != 2 {
+		t.Errorf("renderTemplate(openai) = %s, %v", data, err)
+	}
+
+	if _, err := renderTemplate(&exportTestTemplate, "docx"); err != ErrTemplateExportFormat {
+		t.Errorf("renderTemplate(docx) error = %v", err)
+	}
+}
+
+func TestBuildTemplateArchive(t *testing.T) {
+	templates := []models.Template{exportTestTemplate, {ID: 8, Topic: "a/b: c"}}
+	data, err := buildTemplateArchive(templates, TemplateFormatCoze)
+	if err != nil {
+		t.Fatalf("buildTemplateArchive() error = %v", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	if len(reader.File) != 2 || reader.File[0].Name != "7-写作助手.md" || reader.File[1].Name != "8-a_b_-c.md" {
+		for _, f := range reader.File {
+			t.Log(f.Name)
+		}
+		t.Errorf("unexpected archive entries")
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	tests := map[string]string{
+		"写作 助手":                  "写作-助手",
+		"../..":                  "template",
+		"":                       "template",
+		strings.Repeat("长", 60):  strings.Repeat("长", maxExportFileNameLength),
+		"report<2025>|final?.md": "report_2025__final_.md",
+	}
+	for topic, want := range tests {
+		if got := exportFileName(topic); got != want {
+			t.Errorf("exportFileName(%q) = %q, want %q", topic, got, want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 模板导入限制
+const (
+	maxImportFiles       = 100     // 单次导入的文件数（含zip内的文件）
+	maxImportFileSize    = 1 << 20 // 单个文件最大1MB（zip内的文件按解压后大小计算）
+	maxImportArchiveSize = 8 << 20 // zip压缩包最大8MB
+)
+
+// MaxTemplateImportBodySize 导入请求的请求体大小上限
+const MaxTemplateImportBodySize = maxImportArchiveSize + 1<<20
+
+// ErrTemplateImportEmpty 没有可导入的文件
+var ErrTemplateImportEmpty = errors.New("请选择要导入的文件")
+
+// TemplateImportUpload 上传的待导入文件
+type TemplateImportUpload struct {
+	Name string
+	Data []byte
+}
+
+// TemplateImportItem 文件中解析出的一个模板
+type TemplateImportItem struct {
+	Template   TemplateRequest `json:"template"`
+	Missing    []string        `json:"missing,omitempty"`     // 缺少的要素
+	TemplateID uint64          `json:"template_id,omitempty"` // 导入成功后的模板ID
+	Error      string          `json:"error,omitempty"`
+}
+
+// TemplateImportFileResult 单个文件的导入结果，Error 非空表示整个文件无法解析
+type TemplateImportFileResult struct {
+	File      string               `json:"file"`
+	Error     string               `json:"error,omitempty"`
+	Templates []TemplateImportItem `json:"templates"`
+}
+
+// TemplateImportResult 导入结果
+type TemplateImportResult struct {
+	Preview  bool                       `json:"preview"`  // 预览时只解析不保存
+	Total    int                        `json:"total"`    // 解析出的模板数
+	Imported int                        `json:"imported"` // 导入成功（预览时为可导入）的模板数
+	Failed   int                        `json:"failed"`   // 失败的模板数与无法解析的文件数
+	Files    []TemplateImportFileResult `json:"files"`
+}
+
+// templateHeadingAliases 六要素章节标题及别名（小写、去除空白后比较）
+var templateHeadingAliases = []struct {
+	title   string
+	aliases []string
+	field   func(*TemplateRequest) *string
+}{
+	{"主题", []string{"主题", "topic", "title"}, func(r *TemplateRequest) *string { return &r.Topic }},
+	{"任务目标", []string{"任务目标", "目标", "任务", "taskobjective", "objective", "task", "goal"}, func(r *TemplateRequest) *string { return &r.TaskObjective }},
+	{"AI的角色", []string{"ai的角色", "ai角色", "airole", "role", "assistantrole"}, func(r *TemplateRequest) *string { return &r.AIRole }},
+	{"我的角色", []string{"我的角色", "用户角色", "myrole", "userrole"}, func(r *TemplateRequest) *string { return &r.MyRole }},
+	{"关键信息", []string{"关键信息", "背景信息", "keyinformation", "keyinfo", "context", "background"}, func(r *TemplateRequest) *string { return &r.KeyInformation }},
+	{"行为规则", []string{"行为规则", "规则", "behaviorrule", "behaviorrules", "behaviourrules", "rules", "constraints"}, func(r *TemplateRequest) *string { return &r.BehaviorRule }},
+	{"交付格式", []string{"交付格式", "输出格式", "deliveryformat", "outputformat", "format"}, func(r *TemplateRequest) *string { return &r.DeliveryFormat }},
+}
+
+var (
+	markdownHeadingPattern = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	headingNumberPattern   = regexp.MustCompile(`^(\d+[.、)]|[一二三四五六七八九十]+[、.]|[（(]\d+[)）])\s*`)
+)
+
+// templateHeadingField 匹配章节标题，返回对应的字段；忽略大小写、序号、强调符号与末尾冒号
+func templateHeadingField(heading string) (string, func(*TemplateRequest) *string) {
+	heading = strings.Trim(heading, "*_` ")
+	heading = headingNumberPattern.ReplaceAllString(heading, "")
+	heading = strings.TrimRight(heading, ":： ")
+	key := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(heading))
+
+	for _, h := range templateHeadingAliases {
+		for _, alias := range h.aliases {
+			if key == alias {
+				return h.title, h.field
+			}
+		}
+	}
+	return "", nil
+}
+
+// ParseTemplateMarkdown 解析六要素Markdown：按章节标题填充字段，章节顺序任意，缺少的章节为空
+// 第一个不属于六要素的一级标题作为主题，没有时由调用方决定主题；代码块中的标题不参与解析
+func ParseTemplateMarkdown(text string) (*TemplateRequest, error) {
+	req := &TemplateRequest{}
+	found := false
+	var current func(*TemplateRequest) *string
+	var content []string
+	inFence := false
+
+	flush := func() {
+		if current != nil {
+			value := strings.TrimSpace(strings.Join(content, "\n"))
+			if field := current(req); value != "" {
+				if *field != "" {
+					*field += "\n\n"
+				}
+				*field += value
+			}
+		}
+		content = content[:0]
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(strings.TrimPrefix(text, "\ufeff")))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportFileSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := markdownHeadingPattern.FindStringSubmatch(line); m != nil {
+				if title, field := templateHeadingField(m[2]); field != nil {
+					flush()
+					current = field
+					found = found || title != "主题"
+					continue
+				}
+				if len(m[1]) == 1 && req.Topic == "" && current == nil {
+					req.Topic = strings.TrimSpace(m[2])
+					continue
+				}
+			}
+		}
+		if current != nil {
+			content = append(content, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	flush()
+
+	if !found {
+		return nil, errors.New("未找到六要素章节（如 ## 任务目标）")
+	}
+	return req, nil
+}
+
+// parseTemplateDocuments 解析JSON或YAML：单个对象或对象数组，字段与导出格式一致
+func parseTemplateDocuments(data []byte, format string) ([]TemplateRequest, error) {
+	var docs []TemplateDocument
+	var err error
+	if format == TemplateFormatJSON {
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(trimmed, &docs)
+		} else {
+			var doc TemplateDocument
+			err = json.Unmarshal(trimmed, &doc)
+			docs = append(docs, doc)
+		}
+	} else {
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err == nil && len(node.Content) > 0 && node.Content[0].Kind == yaml.SequenceNode {
+			err = node.Decode(&docs)
+		} else if err == nil {
+			var doc TemplateDocument
+			err = node.Decode(&doc)
+			docs = append(docs, doc)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s格式错误", strings.ToUpper(format))
+	}
+
+	reqs := make([]TemplateRequest, 0, len(docs))
+	for _, doc := range docs {
+		req := TemplateRequest{
+			Topic:          doc.Topic,
+			TaskObjective:  doc.TaskObjective,
+			AIRole:         doc.AIRole,
+			MyRole:         doc.MyRole,
+			KeyInformation: doc.KeyInformation,
+			BehaviorRule:   doc.BehaviorRule,
+			DeliveryFormat: doc.DeliveryFormat,
+		}
+		if len(doc.Variables) > 0 {
+			req.Variables = &doc.Variables
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// importFileFormat 按扩展名判断导入文件格式
+func importFileFormat(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".txt":
+		return TemplateFormatMarkdown
+	case ".json":
+		return TemplateFormatJSON
+	case ".yaml", ".yml":
+		return TemplateFormatYAML
+	case ".zip":
+		return "zip"
+	}
+	return ""
+}
+
+// expandImportUploads 展开zip压缩包，跳过目录、隐藏文件与不支持的文件
+func expandImportUploads(uploads []TemplateImportUpload) ([]TemplateImportUpload, []TemplateImportFileResult) {
+	var files []TemplateImportUpload
+	var rejected []TemplateImportFileResult
+	reject := func(name, reason string) {
+		rejected = append(rejected, TemplateImportFileResult{File: name, Error: reason, Templates: []TemplateImportItem{}})
+	}
+
+	for _, upload := range uploads {
+		switch importFileFormat(upload.Name) {
+		case "":
+			reject(upload.Name, "仅支持Markdown、JSON、YAML或zip文件")
+		case "zip":
+			if len(upload.Data) > maxImportArchiveSize {
+				reject(upload.Name, fmt.Sprintf("压缩包不能超过%dMB", maxImportArchiveSize>>20))
+				continue
+			}
+			reader, err := zip.NewReader(bytes.NewReader(upload.Data), int64(len(upload.Data)))
+			if err != nil {
+				reject(upload.Name, "无法读取zip压缩包")
+				continue
+			}
+			for _, entry := range reader.File {
+				name := upload.Name + "/" + entry.Name
+				base := path.Base(entry.Name)
+				if entry.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.HasPrefix(entry.Name, "__MACOSX/") {
+					continue
+				}
+				if f := importFileFormat(base); f == "" || f == "zip" {
+					reject(name, "仅支持Markdown、JSON、YAML文件")
+					continue
+				}
+				data, err := readZipEntry(entry)
+				if err != nil {
+					reject(name, err.Error())
+					continue
+				}
+				files = append(files, TemplateImportUpload{Name: name, Data: data})
+			}
+		default:
+			if len(upload.Data) > maxImportFileSize {
+				reject(upload.Name, fmt.Sprintf("文件不能超过%dMB", maxImportFileSize>>20))
+				continue
+			}
+			files = append(files, upload)
+		}
+	}
+	return files, rejected
+}
+
+// readZipEntry 读取zip中的文件，按实际解压大小限制，防止压缩炸弹
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, errors.New("无法读取文件")
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+	if err != nil {
+		return nil, errors.New("无法读取文件")
+	}
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("文件不能超过%dMB", maxImportFileSize>>20)
+	}
+	return data, nil
+}
+
+// parseImportFile 解析单个文件中的模板，Markdown缺少主题时以文件名作为主题
+func parseImportFile(upload TemplateImportUpload) TemplateImportFileResult {
+	result := TemplateImportFileResult{File: upload.Name, Templates: []TemplateImportItem{}}
+	if !utf8.Valid(upload.Data) {
+		result.Error = "文件不是UTF-8编码"
+		return result
+	}
+
+	var reqs []TemplateRequest
+	format := importFileFormat(upload.Name)
+	if format == TemplateFormatMarkdown {
+		req, err := ParseTemplateMarkdown(string(upload.Data))
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if req.Topic == "" {
+			base := path.Base(upload.Name)
+			req.Topic = strings.TrimSuffix(base, path.Ext(base))
+		}
+		reqs = append(reqs, *req)
+	} else {
+		var err error
+		if reqs, err = parseTemplateDocuments(upload.Data, format); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	if len(reqs) == 0 {
+		result.Error = "文件中没有模板"
+		return result
+	}
+
+	for _, req := range reqs {
+		item := TemplateImportItem{Template: req}
+		trimTemplateRequest(&item.Template)
+		for _, h := range templateHeadingAliases[1:] {
+			if *h.field(&item.Template) == "" {
+				item.Missing = append(item.Missing, h.title)
+			}
+		}
+		item.Error = validateBatchTemplate(&item.Template)
+		result.Templates = append(result.Templates, item)
+	}
+	return result
+}
+
+// ImportTemplates 解析上传的Markdown/JSON/YAML文件（支持zip压缩包）并导入为模板
+// preview 为 true 时只返回解析结果；单个文件或模板的错误记录在结果中，不影响其他文件
+func (s *TemplateService) ImportTemplates(userMobile string, uploads []TemplateImportUpload, preview bool) (*TemplateImportResult, error) {
+	if len(uploads) == 0 {
+		return nil, ErrTemplateImportEmpty
+	}
+	files, rejected := expandImportUploads(uploads)
+	if len(files) > maxImportFiles {
+		return nil, fmt.Errorf("单次最多导入%d个文件", maxImportFiles)
+	}
+
+	result := &TemplateImportResult{Preview: preview, Files: make([]TemplateImportFileResult, 0, len(files)+len(rejected))}
+	for _, file := range files {
+		fileResult := parseImportFile(file)
+		for i := range fileResult.Templates {
+			item := &fileResult.Templates[i]
+			if item.Error != "" || preview {
+				continue
+			}
+			req := item.Template
+			req.ChangeNote = truncateRunes("从 "+path.Base(file.Name)+" 导入", maxChangeNoteLength)
+			template, err := s.CreateTemplate(userMobile, &req)
+			if err != nil {
+				item.Error = err.Error()
+				continue
+			}
+			item.TemplateID = template.ID
+		}
+		result.Files = append(result.Files, fileResult)
+	}
+	result.Files = append(result.Files, rejected...)
+
+	for _, file := range result.Files {
+		if file.Error != "" {
+			result.Failed++
+			continue
+		}
+		for _, item := range file.Templates {
+			result.Total++
+			if item.Error != "" {
+				result.Failed++
+			} else {
+				result.Imported++
+			}
+		}
+	}
+	return result, nil
+}
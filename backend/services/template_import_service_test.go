@@ -0,0 +1,147 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplateMarkdown(t *testing.T) {
+	text := "\ufeff# 写作助手\r\n\r\n前言不属于任何要素\n\n" +
+		"## 2. Delivery Format：\nMarkdown\n\n" +
+		"### **AI 的角色**\n专业作者\n\n" +
+		"## Task Objective\n写一篇文章\n```md\n## 我的角色\n代码块中的标题\n```\n\n" +
+		"## 行为规则\n- 规则1\n#### 小标题\n- 规则2\n"
+
+	got, err := ParseTemplateMarkdown(text)
+	if err != nil {
+		t.Fatalf("ParseTemplateMarkdown() error = %v", err)
+	}
+	want := &TemplateRequest{
+		Topic:          "写作助手",
+		TaskObjective:  "写一篇文章\n```md\n## 我的角色\n代码块中的标题\n```",
+		AIRole:         "专业作者",
+		BehaviorRule:   "- 规则1\n#### 小标题\n- 规则2",
+		DeliveryFormat: "Markdown",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTemplateMarkdown() = %+v, want %+v", got, want)
+	}
+
+	for _, text := range []string{"", "# 只有标题\n正文", "## 主题\n只有主题"} {
+		if _, err := ParseTemplateMarkdown(text); err == nil {
+			t.Errorf("ParseTemplateMarkdown(%q) expected error", text)
+		}
+	}
+}
+
+func TestParseTemplateMarkdownRoundTrip(t *testing.T) {
+	got, err := ParseTemplateMarkdown(BuildTemplateMarkdown(&exportTestTemplate))
+	if err != nil {
+		t.Fatalf("ParseTemplateMarkdown() error = %v", err)
+	}
+	if doc := newTemplateDocument(&exportTestTemplate); got.Topic != doc.Topic || got.TaskObjective != doc.TaskObjective ||
+		got.KeyInformation != doc.KeyInformation || got.MyRole != "" || got.DeliveryFormat != doc.DeliveryFormat {
+		t.Errorf("round trip = %+v", got)
+	}
+}
+
+func TestTemplateHeadingField(t *testing.T) {
+	tests := map[string]string{
+		"任务目标":            "任务目标",
+		"一、关键信息":          "关键信息",
+		"Key_Information": "关键信息",
+		"my role:":        "我的角色",
+		"Output Format":   "交付格式",
+		"备注":              "",
+	}
+	for heading, want := range tests {
+		if got, _ := templateHeadingField(heading); got != want {
+			t.Errorf("templateHeadingField(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
+
+func TestParseTemplateDocuments(t *testing.T) {
+	tests := []struct {
+		data, format string
+		topics       []string
+	}{
+		{`{"topic":"a","ai_role":"r"}`, TemplateFormatJSON, []string{"a"}},
+		{`[{"topic":"a"},{"topic":"b"}]`, TemplateFormatJSON, []string{"a", "b"}},
+		{"topic: a\nai_role: r\n", TemplateFormatYAML, []string{"a"}},
+		{"- topic: a\n- topic: b\n", TemplateFormatYAML, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		reqs, err := parseTemplateDocuments([]byte(tt.data), tt.format)
+		if err != nil || len(reqs) != len(tt.topics) {
+			t.Errorf("parseTemplateDocuments(%q) = %+v, %v", tt.data, reqs, err)
+			continue
+		}
+		for i, topic := range tt.topics {
+			if reqs[i].Topic != topic {
+				t.Errorf("parseTemplateDocuments(%q)[%d].Topic = %q", tt.data, i, reqs[i].Topic)
+			}
+		}
+	}
+
+	if _, err := parseTemplateDocuments([]byte(`{"topic":`), TemplateFormatJSON); err == nil {
+		t.Error("parseTemplateDocuments(invalid json) expected error")
+	}
+}
+
+func TestParseImportFile(t *testing.T) {
+	result := parseImportFile(TemplateImportUpload{Name: "notes/周报助手.md", Data: []byte("## 任务目标\n写周报\n")})
+	if result.Error != "" || len(result.Templates) != 1 {
+		t.Fatalf("parseImportFile() = %+v", result)
+	}
+	item := result.Templates[0]
+	if item.Template.Topic != "周报助手" || item.Error != "" || len(item.Missing) != 5 {
+		t.Errorf("parseImportFile() item = %+v", item)
+	}
+
+	result = parseImportFile(TemplateImportUpload{Name: "a.json", Data: []byte(`[{"topic":" "}]`)})
+	if len(result.Templates) != 1 || result.Templates[0].Error == "" {
+		t.Errorf("parseImportFile(empty topic) = %+v", result)
+	}
+
+	result = parseImportFile(TemplateImportUpload{Name: "a.md", Data: []byte{0xff, 0xfe}})
+	if result.Error == "" {
+		t.Errorf("parseImportFile(invalid utf8) = %+v", result)
+	}
+}
+
+func TestImportTemplatesPreview(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"templates/1-写作助手.md":  BuildTemplateMarkdown(&exportTestTemplate),
+		"templates/list.json":  `[{"topic":"a","task_objective":"t"},{"topic":""}]`,
+		"templates/.DS_Store":  "x",
+		"__MACOSX/._1-写作助手.md": "x",
+		"templates/image.png":  "x",
+	} {
+		f, _ := writer.Create(name)
+		f.Write([]byte(content))
+	}
+	writer.Close()
+
+	result, err := (&TemplateService{}).ImportTemplates("13800138000", []TemplateImportUpload{
+		{Name: "export.zip", Data: buf.Bytes()},
+		{Name: "readme.docx", Data: []byte("x")},
+		{Name: "plain.md", Data: []byte(strings.Repeat("正文\n", 3))},
+	}, true)
+	if err != nil {
+		t.Fatalf("ImportTemplates() error = %v", err)
+	}
+	// 3个模板（1个主题为空），plain.md 无要素章节，image.png 与 readme.docx 不支持
+	if !result.Preview || result.Total != 3 || result.Imported != 2 || result.Failed != 4 || len(result.Files) != 5 {
+		t.Errorf("ImportTemplates() = %+v", result)
+	}
+
+	if _, err := (&TemplateService{}).ImportTemplates("13800138000", nil, true); err != ErrTemplateImportEmpty {
+		t.Errorf("ImportTemplates(nil) error = %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// 检查结果的严重程度及对应的扣分
+const (
+	LintSeverityError   = "error"   // 必须修改
+	LintSeverityWarning = "warning" // 建议修改
+	LintSeverityInfo    = "info"    // 提示
+)
+
+var lintSeverityPenalty = map[string]int{
+	LintSeverityError:   15,
+	LintSeverityWarning: 6,
+	LintSeverityInfo:    2,
+}
+
+// 检查规则
+const (
+	LintRuleEmpty                 = "empty"                  // 要素为空
+	LintRuleTooShort              = "too_short"              // 要素过短
+	LintRuleVagueObjective        = "vague_objective"        // 任务目标含糊
+	LintRuleNoProhibition         = "no_prohibition"         // 行为规则没有禁止项
+	LintRuleVagueFormat           = "vague_format"           // 交付格式不具体
+	LintRuleRoleConflict          = "role_conflict"          // AI的角色与我的角色重复
+	LintRuleRolePerspective       = "role_perspective"       // 角色描述的视角错误
+	LintRuleUnresolvedPlaceholder = "unresolved_placeholder" // 残留 Coze 格式的 [...] 占位符
+	LintRuleUndeclaredVariable    = "undeclared_variable"    // 使用了未声明的 {{变量}}
+	LintRuleUnusedVariable        = "unused_variable"        // 声明的变量未被使用
+)
+
+// lintMinLength 各要素建议的最少字符数
+var lintMinLength = map[string]int{
+	"topic":           4,
+	"task_objective":  10,
+	"ai_role":         4,
+	"my_role":         2,
+	"key_information": 10,
+	"behavior_rule":   10,
+	"delivery_format": 4,
+}
+
+// lintVagueWords 任务目标中表示含糊的词
+var lintVagueWords = []string{"一些", "某些", "相关", "等等", "之类", "尽量", "适当", "更好", "优化一下", "看看", "随便", "差不多", "something", "stuff", "etc", "better"}
+
+// lintProhibitionWords 行为规则中表示禁止的词
+var lintProhibitionWords = []string{"不要", "不得", "禁止", "避免", "不能", "不可", "不允许", "切勿", "严禁", "勿", "never", "don't", "do not", "avoid", "must not"}
+
+// lintFormatWords 交付格式中表示具体格式的词，含数字时也视为具体（如字数、条数）
+var lintFormatWords = []string{
+	"markdown", "json", "yaml", "xml", "csv", "html", "table", "list", "bullet", "heading", "paragraph",
+	"表格", "列表", "清单", "段落", "标题", "要点", "代码块", "大纲", "邮件", "步骤", "字数", "章节", "格式",
+}
+
+// lintRolePrefixes 比较角色时去除的前缀
+var lintRolePrefixes = []string{"你是", "我是", "作为", "扮演", "一名", "一位", "一个"}
+
+// lintPlaceholderPattern Coze 提示词格式中的 [...] 占位符
+var lintPlaceholderPattern = regexp.MustCompile(`\[[^\[\]\n]{2,80}\]`)
+
+// ErrTemplateLintProvider 指定的评审Provider不存在
+var ErrTemplateLintProvider = errors.New("评审模型不存在")
+
+// TemplateLintFinding 一条检查结果，Field 为空表示针对整个模板
+type TemplateLintFinding struct {
+	Field    string `json:"field"`
+	Label    string `json:"label"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// TemplateLintReview 大模型评审结果，评审失败时 Error 为原因
+type TemplateLintReview struct {
+	ProviderID  uint                  `json:"provider_id"`
+	Score       *int                  `json:"score,omitempty"` // 0-100
+	Summary     string                `json:"summary,omitempty"`
+	Suggestions []TemplateLintFinding `json:"suggestions,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+// TemplateLintResult 模板质量检查结果，Score 为 0-100 的规则评分
+type TemplateLintResult struct {
+	Score    int                   `json:"score"`
+	Findings []TemplateLintFinding `json:"findings"`
+	Review   *TemplateLintReview   `json:"review,omitempty"`
+}
+
+// TemplateLintOptions 可选的大模型评审
+type TemplateLintOptions struct {
+	ProviderID uint   `json:"provider_id,omitempty"` // 评审模型，为空时只做规则检查
+	Model      string `json:"model,omitempty"`       // 可选：覆盖Provider配置的模型
+}
+
+// TemplateLintDraftRequest 检查未保存的模板内容
+type TemplateLintDraftRequest struct {
+	TemplateDocument
+	TemplateLintOptions
+}
+
+// templateReviewSystemPrompt 评审模型的系统消息
+const templateReviewSystemPrompt = `你是一名资深提示词工程师，负责评审"上下文六要素"提示词模板（主题、任务目标、AI的角色、我的角色、关键信息、行为规则、交付格式）。
+请评估模板是否清晰、具体、无矛盾，能否让大模型稳定产出符合要求的结果，并针对需要改进的要素给出具体修改建议。
+只输出一个JSON对象，不要输出其他内容，格式为：{"score": 0到100的整数, "summary": "总体评价", "suggestions": [{"field": "要素字段名", "message": "修改建议"}]}
+field 取值为 topic、task_objective、ai_role、my_role、key_information、behavior_rule、delivery_format，针对整体的建议 field 为空字符串。`
+
+// LintTemplate 按规则检查模板质量，返回评分与各要素的问题
+func LintTemplate(template *models.Template) *TemplateLintResult {
+	contents := templateContents(template)
+	var findings []TemplateLintFinding
+	add := func(i int, rule, severity, message string) {
+		field, label := "", "整体"
+		if i >= 0 {
+			field, label = templateFields[i].name, templateFields[i].label
+		}
+		findings = append(findings, TemplateLintFinding{Field: field, Label: label, Rule: rule, Severity: severity, Message: message})
+	}
+
+	for i, content := range contents {
+		field := templateFields[i]
+		content = strings.TrimSpace(content)
+		if content == "" {
+			add(i, LintRuleEmpty, LintSeverityError, fmt.Sprintf("%s为空", field.label))
+			continue
+		}
+		if n := utf8.RuneCountInString(content); n < lintMinLength[field.name] {
+			add(i, LintRuleTooShort, LintSeverityWarning, fmt.Sprintf("%s过短（%d个字符），建议至少%d个字符", field.label, n, lintMinLength[field.name]))
+		}
+		if placeholders := unresolvedPlaceholders(content); len(placeholders) > 0 {
+			add(i, LintRuleUnresolvedPlaceholder, LintSeverityError, fmt.Sprintf("存在未替换的占位符：%s", strings.Join(placeholders, "、")))
+		}
+	}
+
+	if objective := strings.TrimSpace(template.TaskObjective); objective != "" {
+		if words := containsAny(objective, lintVagueWords); len(words) > 0 && !strings.ContainsFunc(objective, unicode.IsDigit) {
+			add(1, LintRuleVagueObjective, LintSeverityWarning, fmt.Sprintf("任务目标含糊（%s），建议写明具体产出、数量或验收标准", strings.Join(words, "、")))
+		}
+	}
+	if rule := strings.TrimSpace(template.BehaviorRule); rule != "" && len(containsAny(rule, lintProhibitionWords)) == 0 {
+		add(5, LintRuleNoProhibition, LintSeverityWarning, "行为规则中没有禁止项，建议补充\"不要……\"\"避免……\"等约束")
+	}
+	if format := strings.TrimSpace(template.DeliveryFormat); format != "" && len(containsAny(format, lintFormatWords)) == 0 && !strings.ContainsFunc(format, unicode.IsDigit) {
+		add(6, LintRuleVagueFormat, LintSeverityWarning, "交付格式不具体，建议指定Markdown、JSON、表格、字数等具体格式")
+	}
+	lintRoles(template, add)
+	lintVariables(template, add)
+
+	score := 100
+	for _, finding := range findings {
+		score -= lintSeverityPenalty[finding.Severity]
+	}
+	if findings == nil {
+		findings = []TemplateLintFinding{}
+	}
+	return &TemplateLintResult{Score: max(score, 0), Findings: findings}
+}
+
+// lintRoles 检查AI的角色与我的角色是否重复或视角错误
+func lintRoles(template *models.Template, add func(int, string, string, string)) {
+	aiRole, myRole := strings.TrimSpace(template.AIRole), strings.TrimSpace(template.MyRole)
+	if strings.HasPrefix(aiRole, "我是") {
+		add(2, LintRuleRolePerspective, LintSeverityWarning, "AI的角色应描述AI扮演的身份，而不是\"我是……\"")
+	}
+	if strings.HasPrefix(myRole, "你是") {
+		add(3, LintRuleRolePerspective, LintSeverityWarning, "我的角色应描述你自己的身份，而不是\"你是……\"")
+	}
+
+	ai, my := normalizeRole(aiRole), normalizeRole(myRole)
+	if ai == "" || my == "" {
+		return
+	}
+	switch {
+	case ai == my:
+		add(3, LintRuleRoleConflict, LintSeverityError, "AI的角色与我的角色相同，双方身份应有区分")
+	case utf8.RuneCountInString(my) >= 2 && strings.Contains(ai, my), utf8.RuneCountInString(ai) >= 2 && strings.Contains(my, ai):
+		add(3, LintRuleRoleConflict, LintSeverityWarning, "AI的角色与我的角色相互包含，请确认双方身份没有混淆")
+	}
+}
+
+// lintVariables 检查 {{变量}} 是否均已声明、声明的变量是否被使用
+func lintVariables(template *models.Template, add func(int, string, string, string)) {
+	used := templatePlaceholders(template)
+	for _, name := range used {
+		if !slices.ContainsFunc(template.Variables, func(v models.TemplateVariable) bool { return v.Name == name }) {
+			add(-1, LintRuleUndeclaredVariable, LintSeverityWarning, fmt.Sprintf("使用了未声明的变量「%s」，渲染时不会被替换", name))
+		}
+	}
+	for _, v := range template.Variables {
+		if !slices.Contains(used, v.Name) {
+			add(-1, LintRuleUnusedVariable, LintSeverityInfo, fmt.Sprintf("变量「%s」已声明但未在任何要素中使用", v.Name))
+		}
+	}
+}
+
+// normalizeRole 去除空白、标点与常见前缀，用于比较两个角色
+func normalizeRole(role string) string {
+	role = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, role)
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, prefix := range lintRolePrefixes {
+			if strings.HasPrefix(role, prefix) {
+				role = strings.TrimPrefix(role, prefix)
+				trimmed = true
+			}
+		}
+	}
+	return role
+}
+
+// containsAny 返回文本中出现的词（不区分大小写）
+func containsAny(text string, words []string) []string {
+	lower := strings.ToLower(text)
+	var found []string
+	for _, word := range words {
+		if strings.Contains(lower, word) {
+			found = append(found, word)
+		}
+	}
+	return found
+}
+
+// unresolvedPlaceholders 查找 Coze 提示词格式中未替换的 [...] 占位符
+// Markdown 链接 [文字](url)、任务列表 [ ]/[x] 与引用编号 [1] 不视为占位符
+func unresolvedPlaceholders(text string) []string {
+	var found []string
+	for _, loc := range lintPlaceholderPattern.FindAllStringIndex(text, -1) {
+		match := text[loc[0]:loc[1]]
+		inner := strings.TrimSpace(match[1 : len(match)-1])
+		if strings.HasPrefix(text[loc[1]:], "(") || inner == "" || strings.EqualFold(inner, "x") {
+			continue
+		}
+		if !strings.ContainsFunc(inner, func(r rune) bool { return !unicode.IsDigit(r) && !unicode.IsSpace(r) && r != ',' && r != '-' }) {
+			continue
+		}
+		if !slices.Contains(found, match) {
+			found = append(found, match)
+		}
+	}
+	return found
+}
+
+// LintTemplateByID 检查自己的、公开的或共享给自己的模板，可选使用大模型评审
+func (s *TemplateService) LintTemplateByID(ctx context.Context, userMobile string, templateID uint64, opts TemplateLintOptions) (*TemplateLintResult, error) {
+	template, err := s.GetVisibleTemplate(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return lintTemplateWithReview(ctx, userMobile, template, opts)
+}
+
+// LintTemplateDraft 检查尚未保存的模板内容
+func (s *TemplateService) LintTemplateDraft(ctx context.Context, userMobile string, req *TemplateLintDraftRequest) (*TemplateLintResult, error) {
+	template := &models.Template{
+		Topic:          req.Topic,
+		TaskObjective:  req.TaskObjective,
+		AIRole:         req.AIRole,
+		MyRole:         req.MyRole,
+		KeyInformation: req.KeyInformation,
+		BehaviorRule:   req.BehaviorRule,
+		DeliveryFormat: req.DeliveryFormat,
+		Variables:      req.Variables,
+	}
+	return lintTemplateWithReview(ctx, userMobile, template, req.TemplateLintOptions)
+}
+
+// lintTemplateWithReview 规则检查后按需调用评审模型，评审失败不影响规则检查结果
+func lintTemplateWithReview(ctx context.Context, userMobile string, template *models.Template, opts TemplateLintOptions) (*TemplateLintResult, error) {
+	result := LintTemplate(template)
+	if opts.ProviderID == 0 {
+		return result, nil
+	}
+	provider, err := GetAPIProvider(userMobile, opts.ProviderID)
+	if err != nil {
+		return nil, ErrTemplateLintProvider
+	}
+
+	review := &TemplateLintReview{ProviderID: provider.ID}
+	result.Review = review
+	generated, err := Generate(ctx, provider, GenerateRequest{
+		System:      templateReviewSystemPrompt,
+		Prompt:      buildTemplateReviewPrompt(template, result.Findings),
+		Model:       opts.Model,
+		Temperature: 0.1,
+		MaxTokens:   1000,
+		User:        userMobile,
+	})
+	if err != nil {
+		utils.Warn("模板评审失败", zap.Uint("provider_id", provider.ID), zap.Error(err))
+		review.Error = "评审失败: " + err.Error()
+		return result, nil
+	}
+	if err := parseTemplateReview(generated.Content, review); err != nil {
+		utils.Warn("解析模板评审结果失败", zap.Uint("provider_id", provider.ID), zap.String("content", generated.Content))
+		review.Error = "评审失败: " + err.Error()
+	}
+	return result, nil
+}
+
+// buildTemplateReviewPrompt 构建评审提示词：模板内容及规则检查发现的问题
+func buildTemplateReviewPrompt(template *models.Template, findings []TemplateLintFinding) string {
+	var b strings.Builder
+	b.WriteString("## 待评审模板\n")
+	b.WriteString(BuildTemplateMarkdown(template))
+	b.WriteString("\n## 规则检查发现的问题\n")
+	if len(findings) == 0 {
+		b.WriteString("无\n")
+	}
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "- [%s] %s：%s\n", finding.Severity, finding.Label, finding.Message)
+	}
+	return b.String()
+}
+
+// parseTemplateReview 从评审模型输出中提取JSON结果，兼容Markdown代码块等包裹内容，分数限制在 0-100
+func parseTemplateReview(content string, review *TemplateLintReview) error {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return errors.New("评审结果不是JSON格式")
+	}
+
+	var verdict struct {
+		Score       float64 `json:"score"`
+		Summary     string  `json:"summary"`
+		Suggestions []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"suggestions"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		return errors.New("评审结果不是JSON格式")
+	}
+
+	score := int(min(max(verdict.Score, 0), 100) + 0.5)
+	review.Score = &score
+	review.Summary = strings.TrimSpace(verdict.Summary)
+	for _, suggestion := range verdict.Suggestions {
+		message := strings.TrimSpace(suggestion.Message)
+		if message == "" {
+			continue
+		}
+		finding := TemplateLintFinding{Label: "整体", Rule: "llm_review", Severity: LintSeverityInfo, Message: message}
+		for _, f := range templateFields {
+			if f.name == suggestion.Field {
+				finding.Field, finding.Label = f.name, f.label
+			}
+		}
+		review.Suggestions = append(review.Suggestions, finding)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+var lintGoodTemplate = models.Template{
+	Topic:          "产品周报撰写助手",
+	TaskObjective:  "根据本周的工作记录撰写一份产品周报，包含3个重点进展和下周计划",
+	AIRole:         "资深产品经理",
+	MyRole:         "产品团队负责人",
+	KeyInformation: "本周上线了搜索功能，修复了12个缺陷，用户留存提升2%",
+	BehaviorRule:   "使用客观的语气，不要编造数据，避免使用行业黑话",
+	DeliveryFormat: "Markdown格式，包含标题与要点列表，不超过500字",
+}
+
+// lintRules 返回检查结果中的 字段/规则 列表
+func lintRules(result *TemplateLintResult) []string {
+	var rules []string
+	for _, finding := range result.Findings {
+		rules = append(rules, finding.Field+"/"+finding.Rule)
+	}
+	return rules
+}
+
+func TestLintTemplateClean(t *testing.T) {
+	result := LintTemplate(&lintGoodTemplate)
+	if result.Score != 100 || len(result.Findings) != 0 {
+		t.Errorf("LintTemplate(good) = %d, %+v", result.Score, result.Findings)
+	}
+}
+
+func TestLintTemplateFindings(t *testing.T) {
+	template := lintGoodTemplate
+	template.TaskObjective = "帮我优化一下文案，让它更好"
+	template.AIRole = "你是产品经理"
+	template.MyRole = "我是产品经理"
+	template.KeyInformation = "[填写本周的工作记录]"
+	template.BehaviorRule = "使用客观的语气，保持简洁"
+	template.DeliveryFormat = "清晰易读"
+
+	want := []string{
+		"key_information/unresolved_placeholder",
+		"task_objective/vague_objective",
+		"behavior_rule/no_prohibition",
+		"delivery_format/vague_format",
+		"my_role/role_conflict",
+	}
+	result := LintTemplate(&template)
+	if got := lintRules(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("LintTemplate() rules = %v, want %v", got, want)
+	}
+	if result.Score != 100-15*2-6*3 {
+		t.Errorf("LintTemplate() score = %d", result.Score)
+	}
+}
+
+func TestLintTemplateEmptyAndShort(t *testing.T) {
+	result := LintTemplate(&models.Template{Topic: "周报", TaskObjective: "写周报"})
+	rules := lintRules(result)
+	for _, rule := range []string{"topic/too_short", "task_objective/too_short", "ai_role/empty", "delivery_format/empty"} {
+		found := false
+		for _, got := range rules {
+			found = found || got == rule
+		}
+		if !found {
+			t.Errorf("LintTemplate() missing %s in %v", rule, rules)
+		}
+	}
+	if result.Score != 100-15*5-6*2 {
+		t.Errorf("LintTemplate() score = %d", result.Score)
+	}
+}
+
+func TestLintTemplateRoles(t *testing.T) {
+	template := lintGoodTemplate
+	template.AIRole = "我是资深产品经理"
+	template.MyRole = "产品经理"
+	want := []string{"ai_role/role_perspective", "my_role/role_conflict"}
+	if got := lintRules(LintTemplate(&template)); !reflect.DeepEqual(got, want) {
+		t.Errorf("LintTemplate() rules = %v, want %v", got, want)
+	}
+}
+
+func TestLintTemplateVariables(t *testing.T) {
+	template := lintGoodTemplate
+	template.Topic = "{{产品}}周报撰写助手"
+	template.KeyInformation = "本周工作记录：{{记录}}，共修复了12个缺陷"
+	template.Variables = models.TemplateVariables{{Name: "产品"}, {Name: "团队"}}
+	want := []string{"/undeclared_variable", "/unused_variable"}
+	if got := lintRules(LintTemplate(&template)); !reflect.DeepEqual(got, want) {
+		t.Errorf("LintTemplate() rules = %v, want %v", got, want)
+	}
+}
+
+func TestUnresolvedPlaceholders(t *testing.T) {
+	text := "参见[文档](https://example.com)与引用[1][2, 3]，- [ ] 待办 - [x] 完成，[必须遵守的规则1]、[指定输出格式]、[必须遵守的规则1]"
+	want := []string{"[必须遵守的规则1]", "[指定输出格式]"}
+	if got := unresolvedPlaceholders(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("unresolvedPlaceholders() = %v, want %v", got, want)
+	}
+}
+
+func TestParseTemplateReview(t *testing.T) {
+	var review TemplateLintReview
+	content := "```json\n{\"score\": 120, \"summary\": \" 整体清晰 \", \"suggestions\": [{\"field\": \"behavior_rule\", \"message\": \"补充禁止项\"}, {\"field\": \"\", \"message\": \"增加示例\"}, {\"field\": \"ai_role\", \"message\": \"\"}]}\n```"
+	if err := parseTemplateReview(content, &review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Score == nil || *review.Score != 100 || review.Summary != "整体清晰" {
+		t.Errorf("parseTemplateReview() = %+v", review)
+	}
+	if len(review.Suggestions) != 2 || review.Suggestions[0].Label != "行为规则" || review.Suggestions[1].Label != "整体" {
+		t.Errorf("parseTemplateReview() suggestions = %+v", review.Suggestions)
+	}
+
+	if err := parseTemplateReview("无法评审", &TemplateLintReview{}); err == nil {
+		t.Error("parseTemplateReview(non-json) expected error")
+	}
+}
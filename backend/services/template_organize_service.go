@@ -0,0 +1,567 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 模板整理相关限制
+const (
+	maxTemplateTags      = 20  // 单个模板最多的标签数
+	maxTemplateFolders   = 100 // 每个用户最多的文件夹数
+	maxOrganizeNameLen   = 50  // 标签、文件夹名称的最大字符数
+	defaultTagSuggestMax = 10  // 标签联想默认返回数量
+	maxTagSuggestMax     = 100 // 标签联想最多返回数量
+)
+
+// 模板整理相关错误
+var (
+	ErrTemplateTagName        = fmt.Errorf("标签名称不能为空且不能超过%d个字符", maxOrganizeNameLen)
+	ErrTemplateTagLimit       = fmt.Errorf("每个模板最多%d个标签", maxTemplateTags)
+	ErrTemplateTagNotFound    = errors.New("标签不存在")
+	ErrTemplateFolderName     = fmt.Errorf("文件夹名称不能为空且不能超过%d个字符", maxOrganizeNameLen)
+	ErrTemplateFolderLimit    = fmt.Errorf("最多创建%d个文件夹", maxTemplateFolders)
+	ErrTemplateFolderExists   = errors.New("文件夹名称已存在")
+	ErrTemplateFolderNotFound = errors.New("文件夹不存在")
+	ErrTemplateFolderFilter   = errors.New("folder_id 格式错误")
+)
+
+// TemplateOrganizeRequest 整理模板请求，未提供的字段保持不变
+type TemplateOrganizeRequest struct {
+	Tags     *[]string `json:"tags"`      // 以该列表整体替换模板标签，不存在的标签自动创建
+	FolderID *uint64   `json:"folder_id"` // 移入的文件夹，0 表示移出文件夹
+	Favorite *bool     `json:"favorite"`
+	Pinned   *bool     `json:"pinned"`
+}
+
+// TemplateTagInfo 标签及使用该标签的模板数
+type TemplateTagInfo struct {
+	ID            uint64 `json:"id"`
+	Name          string `json:"name"`
+	TemplateCount int64  `json:"template_count"`
+}
+
+// TemplateTagRenameResult 重命名标签结果，新名称与已有标签相同时两者合并
+type TemplateTagRenameResult struct {
+	Tag    TemplateTagInfo `json:"tag"`
+	Merged bool            `json:"merged"`
+}
+
+// TemplateFolderInfo 文件夹及其中的模板数
+type TemplateFolderInfo struct {
+	models.TemplateFolder
+	TemplateCount int64 `json:"template_count"`
+}
+
+// normalizeOrganizeName 去除首尾空白及标签前的 #，并校验长度
+func normalizeOrganizeName(name string) (string, bool) {
+	name = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(name), "#"))
+	if name == "" || utf8.RuneCountInString(name) > maxOrganizeNameLen {
+		return "", false
+	}
+	return name, true
+}
+
+// normalizeTagNames 规范化标签名称，按不区分大小写去重并保留首次出现的顺序
+func normalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, raw := range names {
+		name, ok := normalizeOrganizeName(raw)
+		if !ok {
+			return nil, ErrTemplateTagName
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	if len(result) > maxTemplateTags {
+		return nil, ErrTemplateTagLimit
+	}
+	return result, nil
+}
+
+// parseFolderFilter 解析列表的 folder_id 过滤条件：空表示不过滤，0 表示未分类
+func parseFolderFilter(value string) (folderID uint64, filter bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false, nil
+	}
+	folderID, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, ErrTemplateFolderFilter
+	}
+	return folderID, true, nil
+}
+
+// splitTagFilter 拆分列表的 tag 过滤条件，多个标签以逗号分隔
+func splitTagFilter(value string) []string {
+	var tags []string
+	for _, part := range strings.Split(value, ",") {
+		if name, ok := normalizeOrganizeName(part); ok {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// applyTemplateOrganizeFilters 为模板列表添加标签、文件夹与收藏过滤条件
+func applyTemplateOrganizeFilters(query *gorm.DB, userMobile string, req *TemplateQueryRequest) (*gorm.DB, error) {
+	for _, tag := range splitTagFilter(req.Tag) {
+		query = query.Where("EXISTS (SELECT 1 FROM cese_template_tag_link l JOIN cese_template_tag t ON t.id = l.tag_id "+
+			"WHERE l.template_id = cese_template.id AND t.mobile = ? AND t.name = ?)", userMobile, tag)
+	}
+
+	folderID, filter, err := parseFolderFilter(req.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	if filter {
+		if folderID == 0 {
+			query = query.Where("folder_id IS NULL")
+		} else {
+			query = query.Where("folder_id = ?", folderID)
+		}
+	}
+
+	if req.Favorite {
+		query = query.Where("favorite = ?", true)
+	}
+	return query, nil
+}
+
+// loadTemplateTags 为模板填充标签名称
+func loadTemplateTags(templates []models.Template) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	ids := make([]uint64, len(templates))
+	for i := range templates {
+		ids[i] = templates[i].ID
+	}
+
+	var rows []struct {
+		TemplateID uint64
+		Name       string
+	}
+	if err := config.DB.Table("cese_template_tag_link l").
+		Select("l.template_id, t.name").
+		Joins("JOIN cese_template_tag t ON t.id = l.tag_id").
+		Where("l.template_id IN ?", ids).
+		Order("t.name ASC").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	tags := make(map[uint64][]string, len(templates))
+	for _, row := range rows {
+		tags[row.TemplateID] = append(tags[row.TemplateID], row.Name)
+	}
+	for i := range templates {
+		templates[i].Tags = tags[templates[i].ID]
+	}
+	return nil
+}
+
+// hideTemplateOrganization 清除模板所有者的文件夹、收藏与置顶信息，用于他人查看
+func hideTemplateOrganization(template *models.Template) {
+	template.FolderID = nil
+	template.Favorite = false
+	template.Pinned = false
+	template.Tags = nil
+}
+
+// OrganizeTemplate 设置模板的标签、文件夹、收藏与置顶，仅模板所有者可操作
+// 整理操作不产生修订版本，也不更新模板的更新时间
+func (s *TemplateService) OrganizeTemplate(userMobile string, templateID uint64, req *TemplateOrganizeRequest) (*models.Template, error) {
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return nil, errors.New("模板不存在或无权操作")
+	}
+
+	var tags []string
+	if req.Tags != nil {
+		if tags, err = normalizeTagNames(*req.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if req.FolderID != nil {
+		if *req.FolderID == 0 {
+			updates["folder_id"] = nil
+		} else {
+			if _, err := getTemplateFolder(userMobile, *req.FolderID); err != nil {
+				return nil, err
+			}
+			updates["folder_id"] = *req.FolderID
+		}
+	}
+	if req.Favorite != nil {
+		updates["favorite"] = *req.Favorite
+	}
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(template).UpdateColumns(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Tags != nil {
+			return setTemplateTags(tx, userMobile, templateID, tags)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存失败: %w", err)
+	}
+
+	if err := config.DB.First(template, templateID).Error; err != nil {
+		return nil, err
+	}
+	templates := []models.Template{*template}
+	if err := loadTemplateTags(templates); err != nil {
+		return nil, err
+	}
+	return &templates[0], nil
+}
+
+// setTemplateTags 以给定名称整体替换模板的标签，不存在的标签自动创建
+func setTemplateTags(tx *gorm.DB, userMobile string, templateID uint64, names []string) error {
+	if err := tx.Where("template_id = ?", templateID).Delete(&models.TemplateTagLink{}).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	// 数据库排序规则不区分大小写，已有标签沿用其原有写法
+	var existing []models.TemplateTag
+	if err := tx.Where("mobile = ? AND name IN ?", userMobile, names).Find(&existing).Error; err != nil {
+		return err
+	}
+	tagIDs := make(map[string]uint64, len(existing))
+	for _, tag := range existing {
+		tagIDs[strings.ToLower(tag.Name)] = tag.ID
+	}
+
+	links := make([]models.TemplateTagLink, 0, len(names))
+	for _, name := range names {
+		id, ok := tagIDs[strings.ToLower(name)]
+		if !ok {
+			tag := models.TemplateTag{Mobile: userMobile, Name: name}
+			if err := tx.Create(&tag).Error; err != nil {
+				return err
+			}
+			id = tag.ID
+		}
+		links = append(links, models.TemplateTagLink{TemplateID: templateID, TagID: id})
+	}
+	return tx.Create(&links).Error
+}
+
+// ListTemplateTags 查询用户的标签及使用次数，按使用次数降序；prefix 非空时按名称前缀联想
+func (s *TemplateService) ListTemplateTags(userMobile, prefix string, limit int) ([]TemplateTagInfo, error) {
+	if limit <= 0 {
+		limit = defaultTagSuggestMax
+	}
+	if limit > maxTagSuggestMax {
+		limit = maxTagSuggestMax
+	}
+
+	query := config.DB.Model(&models.TemplateTag{}).
+		Select("cese_template_tag.id, cese_template_tag.name, COUNT(tp.id) AS template_count").
+		Joins("LEFT JOIN cese_template_tag_link l ON l.tag_id = cese_template_tag.id").
+		Joins("LEFT JOIN cese_template tp ON tp.id = l.template_id AND tp.deleted_at IS NULL"). // 回收站中的模板不计数
+		Where("cese_template_tag.mobile = ?", userMobile)
+	if prefix = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(prefix), "#")); prefix != "" {
+		query = query.Where("cese_template_tag.name LIKE ?", prefix+"%")
+	}
+
+	tags := []TemplateTagInfo{}
+	if err := query.Group("cese_template_tag.id, cese_template_tag.name").
+		Order("template_count DESC, cese_template_tag.name ASC").
+		Limit(limit).Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// RenameTemplateTag 重命名标签，作用于用户的全部模板；新名称已被其他标签使用时合并到该标签
+func (s *TemplateService) RenameTemplateTag(userMobile string, tagID uint64, name string) (*TemplateTagRenameResult, error) {
+	name, ok := normalizeOrganizeName(name)
+	if !ok {
+		return nil, ErrTemplateTagName
+	}
+	tag, err := getTemplateTag(userMobile, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target models.TemplateTag
+	err = config.DB.Where("mobile = ? AND name = ? AND id <> ?", userMobile, name, tagID).First(&target).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		if err := mergeTemplateTags(userMobile, []uint64{tagID}, &target); err != nil {
+			return nil, err
+		}
+		info, err := templateTagInfo(&target)
+		if err != nil {
+			return nil, err
+		}
+		return &TemplateTagRenameResult{Tag: *info, Merged: true}, nil
+	}
+
+	if err := config.DB.Model(tag).Update("name", name).Error; err != nil {
+		return nil, fmt.Errorf("保存失败: %w", err)
+	}
+	info, err := templateTagInfo(tag)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateTagRenameResult{Tag: *info}, nil
+}
+
+// MergeTemplateTags 将多个标签合并到目标标签，原标签关联的模板改为关联目标标签，原标签删除
+func (s *TemplateService) MergeTemplateTags(userMobile string, sourceIDs []uint64, targetID uint64) (*TemplateTagInfo, error) {
+	target, err := getTemplateTag(userMobile, targetID)
+	if err != nil {
+		return nil, err
+	}
+	var sources []uint64
+	for _, id := range sourceIDs {
+		if id == targetID {
+			continue
+		}
+		if _, err := getTemplateTag(userMobile, id); err != nil {
+			return nil, err
+		}
+		sources = append(sources, id)
+	}
+	if err := mergeTemplateTags(userMobile, sources, target); err != nil {
+		return nil, err
+	}
+	return templateTagInfo(target)
+}
+
+// mergeTemplateTags 在事务中迁移标签关联并删除原标签
+func mergeTemplateTags(userMobile string, sourceIDs []uint64, target *models.TemplateTag) error {
+	if len(sourceIDs) == 0 {
+		return nil
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var linked []uint64
+		if err := tx.Model(&models.TemplateTagLink{}).Where("tag_id = ?", target.ID).Pluck("template_id", &linked).Error; err != nil {
+			return err
+		}
+		var moving []uint64
+		if err := tx.Model(&models.TemplateTagLink{}).Where("tag_id IN ?", sourceIDs).Distinct().Pluck("template_id", &moving).Error; err != nil {
+			return err
+		}
+
+		links := mergedTagLinks(linked, moving, target.ID)
+		if len(links) > 0 {
+			if err := tx.Create(&links).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("tag_id IN ?", sourceIDs).Delete(&models.TemplateTagLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ? AND mobile = ?", sourceIDs, userMobile).Delete(&models.TemplateTag{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("合并标签失败: %w", err)
+	}
+	utils.Info("标签已合并", zap.Uint64("target_id", target.ID), zap.Int("sources", len(sourceIDs)))
+	return nil
+}
+
+// mergedTagLinks 返回合并时需要新增的关联：原标签下尚未关联目标标签的模板
+func mergedTagLinks(linked, moving []uint64, targetID uint64) []models.TemplateTagLink {
+	exists := make(map[uint64]bool, len(linked))
+	for _, id := range linked {
+		exists[id] = true
+	}
+	var links []models.TemplateTagLink
+	for _, id := range moving {
+		if exists[id] {
+			continue
+		}
+		exists[id] = true
+		links = append(links, models.TemplateTagLink{TemplateID: id, TagID: targetID})
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].TemplateID < links[j].TemplateID })
+	return links
+}
+
+// DeleteTemplateTag 删除标签，并从所有模板上移除
+func (s *TemplateService) DeleteTemplateTag(userMobile string, tagID uint64) error {
+	tag, err := getTemplateTag(userMobile, tagID)
+	if err != nil {
+		return err
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tagID).Delete(&models.TemplateTagLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
+
+// getTemplateTag 查询用户自己的标签
+func getTemplateTag(userMobile string, tagID uint64) (*models.TemplateTag, error) {
+	var tag models.TemplateTag
+	if err := config.DB.Where("id = ? AND mobile = ?", tagID, userMobile).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateTagNotFound
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// templateTagInfo 统计标签的使用次数，回收站中的模板不计数
+func templateTagInfo(tag *models.TemplateTag) (*TemplateTagInfo, error) {
+	info := &TemplateTagInfo{ID: tag.ID, Name: tag.Name}
+	if err := config.DB.Model(&models.Template{}).
+		Where("id IN (?)", config.DB.Model(&models.TemplateTagLink{}).Select("template_id").Where("tag_id = ?", tag.ID)).
+		Count(&info.TemplateCount).Error; err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ListTemplateFolders 查询用户的文件夹及其中的模板数，按名称排序
+func (s *TemplateService) ListTemplateFolders(userMobile string) ([]TemplateFolderInfo, error) {
+	var folders []models.TemplateFolder
+	if err := config.DB.Where("mobile = ?", userMobile).Order("name ASC").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		FolderID uint64
+		Count    int64
+	}
+	if err := config.DB.Model(&models.Template{}).
+		Select("folder_id, COUNT(*) AS count").
+		Where("mobile = ? AND folder_id IS NOT NULL", userMobile).
+		Group("folder_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	countByFolder := make(map[uint64]int64, len(counts))
+	for _, c := range counts {
+		countByFolder[c.FolderID] = c.Count
+	}
+
+	result := make([]TemplateFolderInfo, len(folders))
+	for i, folder := range folders {
+		result[i] = TemplateFolderInfo{TemplateFolder: folder, TemplateCount: countByFolder[folder.ID]}
+	}
+	return result, nil
+}
+
+// CreateTemplateFolder 创建文件夹
+func (s *TemplateService) CreateTemplateFolder(userMobile, name string) (*models.TemplateFolder, error) {
+	name, ok := normalizeOrganizeName(name)
+	if !ok {
+		return nil, ErrTemplateFolderName
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.TemplateFolder{}).Where("mobile = ?", userMobile).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= maxTemplateFolders {
+		return nil, ErrTemplateFolderLimit
+	}
+	if err := checkTemplateFolderName(userMobile, name, 0); err != nil {
+		return nil, err
+	}
+
+	folder := &models.TemplateFolder{Mobile: userMobile, Name: name}
+	if err := config.DB.Create(folder).Error; err != nil {
+		return nil, fmt.Errorf("创建失败: %w", err)
+	}
+	return folder, nil
+}
+
+// RenameTemplateFolder 重命名文件夹
+func (s *TemplateService) RenameTemplateFolder(userMobile string, folderID uint64, name string) (*models.TemplateFolder, error) {
+	name, ok := normalizeOrganizeName(name)
+	if !ok {
+		return nil, ErrTemplateFolderName
+	}
+	folder, err := getTemplateFolder(userMobile, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplateFolderName(userMobile, name, folderID); err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(folder).Update("name", name).Error; err != nil {
+		return nil, fmt.Errorf("保存失败: %w", err)
+	}
+	return folder, nil
+}
+
+// DeleteTemplateFolder 删除文件夹，其中的模板移回未分类，模板本身不删除
+func (s *TemplateService) DeleteTemplateFolder(userMobile string, folderID uint64) error {
+	folder, err := getTemplateFolder(userMobile, folderID)
+	if err != nil {
+		return err
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// 回收站中的模板一并移出，恢复后为未分类
+		if err := tx.Unscoped().Model(&models.Template{}).Where("mobile = ? AND folder_id = ?", userMobile, folderID).
+			UpdateColumn("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(folder).Error
+	})
+}
+
+// getTemplateFolder 查询用户自己的文件夹
+func getTemplateFolder(userMobile string, folderID uint64) (*models.TemplateFolder, error) {
+	var folder models.TemplateFolder
+	if err := config.DB.Where("id = ? AND mobile = ?", folderID, userMobile).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateFolderNotFound
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// checkTemplateFolderName 检查文件夹名称是否已被用户的其他文件夹使用
+func checkTemplateFolderName(userMobile, name string, excludeID uint64) error {
+	var count int64
+	if err := config.DB.Model(&models.TemplateFolder{}).
+		Where("mobile = ? AND name = ? AND id <> ?", userMobile, name, excludeID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTemplateFolderExists
+	}
+	return nil
+}
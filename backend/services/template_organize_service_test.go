@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestNormalizeTagNames(t *testing.T) {
+	got, err := normalizeTagNames([]string{" 营销 ", "#短文", "营销", "Go", "go"})
+	if err != nil || !reflect.DeepEqual(got, []string{"营销", "短文", "Go"}) {
+		t.Errorf("normalizeTagNames() = %v, %v", got, err)
+	}
+
+	if got, err := normalizeTagNames(nil); err != nil || len(got) != 0 {
+		t.Errorf("normalizeTagNames(nil) = %v, %v", got, err)
+	}
+
+	for _, names := range [][]string{{""}, {"#"}, {strings.Repeat("标", maxOrganizeNameLen+1)}} {
+		if _, err := normalizeTagNames(names); !errors.Is(err, ErrTemplateTagName) {
+			t.Errorf("normalizeTagNames(%q) err = %v, want ErrTemplateTagName", names, err)
+		}
+	}
+
+	var many []string
+	for i := 0; i <= maxTemplateTags; i++ {
+		many = append(many, strings.Repeat("a", i+1))
+	}
+	if _, err := normalizeTagNames(many); !errors.Is(err, ErrTemplateTagLimit) {
+		t.Errorf("normalizeTagNames(%d tags) err = %v, want ErrTemplateTagLimit", len(many), err)
+	}
+}
+
+func TestParseFolderFilter(t *testing.T) {
+	tests := []struct {
+		value  string
+		id     uint64
+		filter bool
+	}{
+		{"", 0, false},
+		{" ", 0, false},
+		{"0", 0, true},
+		{"12", 12, true},
+	}
+	for _, tt := range tests {
+		id, filter, err := parseFolderFilter(tt.value)
+		if err != nil || id != tt.id || filter != tt.filter {
+			t.Errorf("parseFolderFilter(%q) = %d, %v, %v", tt.value, id, filter, err)
+		}
+	}
+
+	for _, value := range []string{"-1", "abc"} {
+		if _, _, err := parseFolderFilter(value); !errors.Is(err, ErrTemplateFolderFilter) {
+			t.Errorf("parseFolderFilter(%q) err = %v, want ErrTemplateFolderFilter", value, err)
+		}
+	}
+}
+
+func TestSplitTagFilter(t *testing.T) {
+	got := splitTagFilter("营销, #短文,, ")
+	if !reflect.DeepEqual(got, []string{"营销", "短文"}) {
+		t.Errorf("splitTagFilter() = %v", got)
+	}
+	if got := splitTagFilter(""); len(got) != 0 {
+		t.Errorf("splitTagFilter(\"\") = %v", got)
+	}
+}
+
+func TestMergedTagLinks(t *testing.T) {
+	got := mergedTagLinks([]uint64{1, 2}, []uint64{3, 2, 5, 3}, 9)
+	want := []models.TemplateTagLink{
+		{TemplateID: 3, TagID: 9},
+		{TemplateID: 5, TagID: 9},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergedTagLinks() = %v, want %v", got, want)
+	}
+	if got := mergedTagLinks([]uint64{1}, []uint64{1}, 9); len(got) != 0 {
+		t.Errorf("mergedTagLinks() = %v, want empty", got)
+	}
+}
+
+func TestHideTemplateOrganization(t *testing.T) {
+	folderID := uint64(3)
+	template := &models.Template{FolderID: &folderID, Favorite: true, Pinned: true, Tags: []string{"营销"}}
+	hideTemplateOrganization(template)
+	if template.FolderID != nil || template.Favorite || template.Pinned || template.Tags != nil {
+		t.Errorf("hideTemplateOrganization() = %+v", template)
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+// maxRefineInstructionLength 修改要求的最大字符数
+const maxRefineInstructionLength = 500
+
+// ErrTemplateRefine 要素优化参数错误
+var ErrTemplateRefine = errors.New("优化参数错误")
+
+// TemplateRefineRequest 使用大模型按要求修改模板的单个要素
+type TemplateRefineRequest struct {
+	ProviderID  uint    `json:"provider_id" binding:"required"`
+	Field       string  `json:"field" binding:"required"`       // 要修改的要素：task_objective、ai_role、my_role、key_information、behavior_rule、delivery_format
+	Instruction string  `json:"instruction" binding:"required"` // 修改要求，如"规则更严格""翻译成英文""精简"
+	Model       string  `json:"model,omitempty"`                // 可选：覆盖Provider配置的模型
+	Temperature float32 `json:"temperature,omitempty"`          // 可选：温度参数，默认0.7
+}
+
+// TemplateRefineSink 要素优化的输出目标，生成完成后先以 Refined 输出修改结果与当前值的对比，再调用 Done
+type TemplateRefineSink interface {
+	StreamSink
+	Refined(diff *TemplateFieldDiff)
+}
+
+// templateRefineSystemPrompt 要素优化的系统消息
+const templateRefineSystemPrompt = `你是一名资深提示词工程师，负责按用户的修改要求改写"上下文六要素"提示词模板中的单个要素。
+其他要素仅作为上下文参考，不要修改或输出。
+只输出修改后的要素内容本身，不要输出要素名称、标题、解释说明或代码块标记；保留原文中的 {{变量名}} 占位符。`
+
+// refineElementIndex 返回要素在 templateFields 中的位置，主题不支持优化
+func refineElementIndex(field string) int {
+	for i, f := range templateFields[1:] {
+		if f.name == field {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// buildTemplateRefinePrompt 构建要素优化的提示词：主题、其他要素、待修改要素的当前值与修改要求
+func buildTemplateRefinePrompt(template *models.Template, index int, instruction string) string {
+	contents := templateContents(template)
+	var b strings.Builder
+	fmt.Fprintf(&b, "## 主题\n%s\n\n## 其他要素（仅供参考）\n", contents[0])
+	for i := 1; i < len(templateFields); i++ {
+		if i == index || strings.TrimSpace(contents[i]) == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "### %s\n%s\n\n", templateFields[i].label, contents[i])
+	}
+
+	current := contents[index]
+	if strings.TrimSpace(current) == "" {
+		current = "（当前为空）"
+	}
+	fmt.Fprintf(&b, "## 待修改的要素：%s\n%s\n\n## 修改要求\n%s\n", templateFields[index].label, current, instruction)
+	return b.String()
+}
+
+// cleanRefinedValue 去除模型输出中多余的代码块标记与要素标题
+func cleanRefinedValue(content, label string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") && strings.HasSuffix(content, "```") && len(content) > 6 {
+		content = strings.TrimSuffix(content, "```")
+		if i := strings.Index(content, "\n"); i >= 0 {
+			content = content[i+1:]
+		} else {
+			content = ""
+		}
+		content = strings.TrimSpace(content)
+	}
+	if first, rest, found := strings.Cut(content, "\n"); found {
+		if heading := strings.TrimSpace(strings.TrimLeft(first, "#")); strings.HasPrefix(first, "#") && strings.TrimRight(heading, "：:") == label {
+			content = strings.TrimSpace(rest)
+		}
+	}
+	return content
+}
+
+// refineSink 生成完成时计算修改结果与当前值的对比
+type refineSink struct {
+	TemplateRefineSink
+	field   int
+	current string
+}
+
+func (s *refineSink) Done(result *GenerateResult) {
+	f := templateFields[s.field]
+	diff := &TemplateFieldDiff{
+		Field: f.name,
+		Label: f.label,
+		From:  s.current,
+		To:    cleanRefinedValue(result.Content, f.label),
+	}
+	if diff.From != diff.To {
+		diff.Changed = true
+		diff.Lines = diffLines(diff.From, diff.To)
+	}
+	s.TemplateRefineSink.Refined(diff)
+	s.TemplateRefineSink.Done(result)
+}
+
+// RefineTemplateElement 将其他要素作为上下文，按修改要求流式生成单个要素的新内容
+// 参数、模板、Provider 与输入检查的错误在开始生成前返回，生成过程中的错误通过 sink 输出
+// 修改结果不会自动保存，由调用方确认对比后通过更新模板保存为新的修订版本
+func (s *TemplateService) RefineTemplateElement(ctx context.Context, userMobile string, templateID uint64, req *TemplateRefineRequest, sink TemplateRefineSink) error {
+	index := refineElementIndex(req.Field)
+	if index < 0 {
+		return fmt.Errorf("%w: 不支持的要素「%s」", ErrTemplateRefine, req.Field)
+	}
+	instruction := strings.TrimSpace(req.Instruction)
+	if instruction == "" {
+		return fmt.Errorf("%w: 修改要求不能为空", ErrTemplateRefine)
+	}
+	if utf8.RuneCountInString(instruction) > maxRefineInstructionLength {
+		return fmt.Errorf("%w: 修改要求不能超过%d个字符", ErrTemplateRefine, maxRefineInstructionLength)
+	}
+
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return err
+	}
+	provider, err := GetAPIProvider(userMobile, req.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	generateReq := GenerateRequest{
+		ProviderID:  provider.ID,
+		System:      templateRefineSystemPrompt,
+		Prompt:      buildTemplateRefinePrompt(template, index, instruction),
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		TemplateID:  template.ID,
+		User:        userMobile,
+	}
+	if err := ScreenGenerateRequest(&generateReq); err != nil {
+		return err
+	}
+	if err := PrepareGenerateRequest(ctx, provider, &generateReq); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateRefine, err)
+	}
+
+	GenerateStream(ctx, provider, generateReq, &refineSink{
+		TemplateRefineSink: sink,
+		field:              index,
+		current:            templateContents(template)[index],
+	})
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestRefineElementIndex(t *testing.T) {
+	if got := refineElementIndex("behavior_rule"); templateFields[got].name != "behavior_rule" {
+		t.Errorf("refineElementIndex(behavior_rule) = %d", got)
+	}
+	for _, field := range []string{"topic", "unknown", ""} {
+		if got := refineElementIndex(field); got != -1 {
+			t.Errorf("refineElementIndex(%q) = %d, want -1", field, got)
+		}
+	}
+}
+
+func TestBuildTemplateRefinePrompt(t *testing.T) {
+	template := &models.Template{
+		Topic:         "周报助手",
+		TaskObjective: "撰写周报",
+		AIRole:        "产品经理",
+		BehaviorRule:  "简洁",
+	}
+	prompt := buildTemplateRefinePrompt(template, refineElementIndex("behavior_rule"), "规则更严格")
+	for _, part := range []string{"## 主题\n周报助手", "### 任务目标\n撰写周报", "### AI的角色\n产品经理", "## 待修改的要素：行为规则\n简洁", "## 修改要求\n规则更严格"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("prompt missing %q:\n%s", part, prompt)
+		}
+	}
+	if strings.Contains(prompt, "### 行为规则") || strings.Contains(prompt, "### 我的角色") {
+		t.Errorf("prompt should omit the refined and empty elements:\n%s", prompt)
+	}
+
+	prompt = buildTemplateRefinePrompt(template, refineElementIndex("delivery_format"), "补充格式")
+	if !strings.Contains(prompt, "## 待修改的要素：交付格式\n（当前为空）") {
+		t.Errorf("prompt = %s", prompt)
+	}
+}
+
+func TestCleanRefinedValue(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"  不要编造数据\n避免冗长  ", "不要编造数据\n避免冗长"},
+		{"```markdown\n不要编造数据\n```", "不要编造数据"},
+		{"## 行为规则：\n不要编造数据", "不要编造数据"},
+		{"## 注意事项\n不要编造数据", "## 注意事项\n不要编造数据"},
+		{"```\n```", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanRefinedValue(tt.content, "行为规则"); got != tt.want {
+			t.Errorf("cleanRefinedValue(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+// recordRefineSink 记录输出顺序的要素优化输出目标
+type recordRefineSink struct {
+	events []string
+	diff   *TemplateFieldDiff
+}
+
+func (s *recordRefineSink) Content(string)       { s.events = append(s.events, "content") }
+func (s *recordRefineSink) ToolCalls([]ToolCall) {}
+func (s *recordRefineSink) Error(string)         { s.events = append(s.events, "error") }
+func (s *recordRefineSink) Done(*GenerateResult) { s.events = append(s.events, "done") }
+func (s *recordRefineSink) Refined(diff *TemplateFieldDiff) {
+	s.events, s.diff = append(s.events, "diff"), diff
+}
+
+func TestRefineSinkDone(t *testing.T) {
+	record := &recordRefineSink{}
+	sink := &refineSink{TemplateRefineSink: record, field: refineElementIndex("behavior_rule"), current: "简洁\n礼貌"}
+	sink.Done(&GenerateResult{Content: "```\n简洁\n不要编造数据\n```"})
+
+	if strings.Join(record.events, ",") != "diff,done" {
+		t.Fatalf("events = %v", record.events)
+	}
+	diff := record.diff
+	if diff.Field != "behavior_rule" || diff.Label != "行为规则" || !diff.Changed || diff.To != "简洁\n不要编造数据" {
+		t.Errorf("diff = %+v", diff)
+	}
+	want := []DiffLine{{DiffOpEqual, "简洁"}, {DiffOpDelete, "礼貌"}, {DiffOpAdd, "不要编造数据"}}
+	if len(diff.Lines) != len(want) {
+		t.Fatalf("diff.Lines = %+v", diff.Lines)
+	}
+	for i := range want {
+		if diff.Lines[i] != want[i] {
+			t.Errorf("diff.Lines[%d] = %+v, want %+v", i, diff.Lines[i], want[i])
+		}
+	}
+}
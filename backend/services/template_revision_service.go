@@ -0,0 +1,342 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxChangeNoteLength 修改说明最大字符数
+const maxChangeNoteLength = 255
+
+// maxLineDiffCells 逐行对比的计算量上限（行数乘积），超出时按整段替换展示
+const maxLineDiffCells = 1000000
+
+// 模板修订相关错误
+var (
+	ErrTemplateRevisionNotFound = errors.New("修订版本不存在")
+	ErrTemplateConflict         = errors.New("模板已被修改，请刷新后重试")
+)
+
+// 逐行对比的操作类型
+const (
+	DiffOpEqual  = "equal"
+	DiffOpDelete = "delete"
+	DiffOpAdd    = "add"
+)
+
+// templateFields 参与修订对比的字段：主题与六要素
+var templateFields = []struct {
+	name  string
+	label string
+	get   func(*models.TemplateRevision) string
+}{
+	{"topic", "主题", func(r *models.TemplateRevision) string { return r.Topic }},
+	{"task_objective", "任务目标", func(r *models.TemplateRevision) string { return r.TaskObjective }},
+	{"ai_role", "AI的角色", func(r *models.TemplateRevision) string { return r.AIRole }},
+	{"my_role", "我的角色", func(r *models.TemplateRevision) string { return r.MyRole }},
+	{"key_information", "关键信息", func(r *models.TemplateRevision) string { return r.KeyInformation }},
+	{"behavior_rule", "行为规则", func(r *models.TemplateRevision) string { return r.BehaviorRule }},
+	{"delivery_format", "交付格式", func(r *models.TemplateRevision) string { return r.DeliveryFormat }},
+}
+
+// DiffLine 逐行对比结果中的一行
+type DiffLine struct {
+	Op   string `json:"op"` // equal / delete / add
+	Text string `json:"text"`
+}
+
+// TemplateFieldDiff 单个字段的对比结果
+type TemplateFieldDiff struct {
+	Field   string     `json:"field"`
+	Label   string     `json:"label"`
+	Changed bool       `json:"changed"`
+	From    string     `json:"from"`
+	To      string     `json:"to"`
+	Lines   []DiffLine `json:"lines,omitempty"` // 有变化时的逐行对比
+}
+
+// TemplateRevisionDiff 两个修订版本的对比结果
+type TemplateRevisionDiff struct {
+	TemplateID uint64              `json:"template_id"`
+	From       int                 `json:"from"`
+	To         int                 `json:"to"`
+	Fields     []TemplateFieldDiff `json:"fields"`
+}
+
+// validateChangeNote 校验修改说明长度
+func validateChangeNote(note string) error {
+	if utf8.RuneCountInString(note) > maxChangeNoteLength {
+		return fmt.Errorf("修改说明不能超过%d个字符", maxChangeNoteLength)
+	}
+	return nil
+}
+
+// newTemplateRevision 以模板当前内容生成修订快照
+func newTemplateRevision(template *models.Template, userMobile, note string) models.TemplateRevision {
+	return models.TemplateRevision{
+		TemplateID:     template.ID,
+		Version:        template.Version,
+		Mobile:         userMobile,
+		Topic:          template.Topic,
+		TaskObjective:  template.TaskObjective,
+		AIRole:         template.AIRole,
+		MyRole:         template.MyRole,
+		KeyInformation: template.KeyInformation,
+		BehaviorRule:   template.BehaviorRule,
+		DeliveryFormat: template.DeliveryFormat,
+		Variables:      template.Variables,
+		ChangeNote:     strings.TrimSpace(note),
+	}
+}
+
+// sameTemplateContent 模板内容与修订快照是否一致
+func sameTemplateContent(template *models.Template, revision *models.TemplateRevision) bool {
+	current := newTemplateRevision(template, "", "")
+	for _, f := range templateFields {
+		if f.get(&current) != f.get(revision) {
+			return false
+		}
+	}
+	return reflect.DeepEqual(current.Variables, revision.Variables)
+}
+
+// saveTemplateRevision 保存已修改的模板并生成新的修订版本，内容没有变化时不生成修订
+// 以版本号做乐观锁，并发修改时返回 ErrTemplateConflict
+func (s *TemplateService) saveTemplateRevision(userMobile string, template *models.Template, note string) (*models.Template, error) {
+	var latest models.TemplateRevision
+	err := config.DB.Where("template_id = ? AND version = ?", template.ID, template.Version).First(&latest).Error
+	if err == nil && sameTemplateContent(template, &latest) {
+		return template, nil
+	}
+
+	previous := template.Version
+	template.Version = previous + 1
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Template{}).
+			Where("id = ? AND version = ?", template.ID, previous).
+			Updates(map[string]interface{}{
+				"topic":           template.Topic,
+				"task_objective":  template.TaskObjective,
+				"ai_role":         template.AIRole,
+				"my_role":         template.MyRole,
+				"key_information": template.KeyInformation,
+				"behavior_rule":   template.BehaviorRule,
+				"delivery_format": template.DeliveryFormat,
+				"variables":       template.Variables,
+				"version":         template.Version,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTemplateConflict
+		}
+		revision := newTemplateRevision(template, userMobile, note)
+		return tx.Create(&revision).Error
+	})
+	if err != nil {
+		template.Version = previous
+		return nil, err
+	}
+
+	// 重新读取以获得最新的更新时间
+	config.DB.First(template, template.ID)
+	utils.Info("模板已生成新版本", zap.Uint64("template_id", template.ID), zap.Int("version", template.Version))
+	templateSearch().Index(template)
+	scheduleTemplateEmbedding(template)
+
+	EmitWebhookEvent(userMobile, models.WebhookEventTemplateUpdated, template)
+	return template, nil
+}
+
+// ListTemplateRevisions 分页查询模板的修订历史，按版本号倒序
+func (s *TemplateService) ListTemplateRevisions(userMobile string, templateID uint64, page, pageSize int) ([]models.TemplateRevision, int64, error) {
+	if _, err := s.GetTemplateByID(userMobile, templateID); err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.DB.Model(&models.TemplateRevision{}).Where("template_id = ?", templateID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []models.TemplateRevision
+	if err := query.Order("version DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+	return revisions, total, nil
+}
+
+// GetTemplateRevision 获取模板的指定修订版本
+func (s *TemplateService) GetTemplateRevision(userMobile string, templateID uint64, version int) (*models.TemplateRevision, error) {
+	if _, err := s.GetTemplateByID(userMobile, templateID); err != nil {
+		return nil, err
+	}
+	return getTemplateRevision(templateID, version)
+}
+
+func getTemplateRevision(templateID uint64, version int) (*models.TemplateRevision, error) {
+	var revision models.TemplateRevision
+	if err := config.DB.Where("template_id = ? AND version = ?", templateID, version).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateRevisionNotFound
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// DiffTemplateRevisions 逐字段对比两个修订版本，to 为0时与当前版本对比
+func (s *TemplateService) DiffTemplateRevisions(userMobile string, templateID uint64, from, to int) (*TemplateRevisionDiff, error) {
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		to = template.Version
+	}
+
+	fromRevision, err := getTemplateRevision(templateID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := getTemplateRevision(templateID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffTemplateRevisions(fromRevision, toRevision), nil
+}
+
+// diffTemplateRevisions 逐字段对比两个修订快照
+func diffTemplateRevisions(from, to *models.TemplateRevision) *TemplateRevisionDiff {
+	diff := &TemplateRevisionDiff{
+		TemplateID: to.TemplateID,
+		From:       from.Version,
+		To:         to.Version,
+		Fields:     make([]TemplateFieldDiff, 0, len(templateFields)),
+	}
+	for _, f := range templateFields {
+		field := TemplateFieldDiff{
+			Field: f.name,
+			Label: f.label,
+			From:  f.get(from),
+			To:    f.get(to),
+		}
+		if field.From != field.To {
+			field.Changed = true
+			field.Lines = diffLines(field.From, field.To)
+		}
+		diff.Fields = append(diff.Fields, field)
+	}
+	return diff
+}
+
+// splitDiffLines 按行拆分文本，空文本没有行
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// diffLines 基于最长公共子序列逐行对比，删除的行排在新增的行之前
+func diffLines(from, to string) []DiffLine {
+	a, b := splitDiffLines(from), splitDiffLines(to)
+	if len(a)*len(b) > maxLineDiffCells {
+		lines := make([]DiffLine, 0, len(a)+len(b))
+		for _, line := range a {
+			lines = append(lines, DiffLine{Op: DiffOpDelete, Text: line})
+		}
+		for _, line := range b {
+			lines = append(lines, DiffLine{Op: DiffOpAdd, Text: line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffOpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffOpDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffOpAdd, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffOpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffOpAdd, Text: b[j]})
+	}
+	return lines
+}
+
+// RestoreTemplateRevision 将指定修订版本恢复为当前内容，恢复本身生成新的修订版本
+func (s *TemplateService) RestoreTemplateRevision(userMobile string, templateID uint64, version int, note string) (*models.Template, error) {
+	if err := validateChangeNote(note); err != nil {
+		return nil, err
+	}
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := getTemplateRevision(templateID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Topic = revision.Topic
+	template.TaskObjective = revision.TaskObjective
+	template.AIRole = revision.AIRole
+	template.MyRole = revision.MyRole
+	template.KeyInformation = revision.KeyInformation
+	template.BehaviorRule = revision.BehaviorRule
+	template.DeliveryFormat = revision.DeliveryFormat
+	template.Variables = revision.Variables
+
+	if strings.TrimSpace(note) == "" {
+		note = fmt.Sprintf("恢复至版本%d", version)
+	}
+	return s.saveTemplateRevision(userMobile, template, note)
+}
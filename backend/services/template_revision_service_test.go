@@ -0,0 +1,70 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     []DiffLine
+	}{
+		{"", "a", []DiffLine{{DiffOpAdd, "a"}}},
+		{"a", "", []DiffLine{{DiffOpDelete, "a"}}},
+		{"a\nb\nc", "a\nx\nc", []DiffLine{
+			{DiffOpEqual, "a"}, {DiffOpDelete, "b"}, {DiffOpAdd, "x"}, {DiffOpEqual, "c"},
+		}},
+		{"a\r\nb", "a\nb\nc", []DiffLine{
+			{DiffOpEqual, "a"}, {DiffOpEqual, "b"}, {DiffOpAdd, "c"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := diffLines(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("diffLines(%q, %q) = %+v, want %+v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestDiffTemplateRevisions(t *testing.T) {
+	from := &models.TemplateRevision{TemplateID: 1, Version: 1, Topic: "写作助手", MyRole: "作者"}
+	to := &models.TemplateRevision{TemplateID: 1, Version: 3, Topic: "写作助手（更新版）", MyRole: "作者"}
+
+	diff := diffTemplateRevisions(from, to)
+	if diff.From != 1 || diff.To != 3 || len(diff.Fields) != len(templateFields) {
+		t.Fatalf("diffTemplateRevisions() = %+v", diff)
+	}
+	for _, f := range diff.Fields {
+		changed := f.Field == "topic"
+		if f.Changed != changed || (len(f.Lines) > 0) != changed {
+			t.Errorf("field %s: changed=%v lines=%+v", f.Field, f.Changed, f.Lines)
+		}
+	}
+}
+
+func TestSameTemplateContent(t *testing.T) {
+	template := &models.Template{ID: 1, Version: 2, Topic: "t", AIRole: "r"}
+	revision := newTemplateRevision(template, "13800138000", "  说明  ")
+	if revision.ChangeNote != "说明" || revision.Version != 2 {
+		t.Errorf("newTemplateRevision() = %+v", revision)
+	}
+	if !sameTemplateContent(template, &revision) {
+		t.Error("sameTemplateContent() = false, want true")
+	}
+	template.AIRole = "r2"
+	if sameTemplateContent(template, &revision) {
+		t.Error("sameTemplateContent() = true, want false")
+	}
+}
+
+func TestValidateChangeNote(t *testing.T) {
+	if err := validateChangeNote(strings.Repeat("改", maxChangeNoteLength)); err != nil {
+		t.Errorf("validateChangeNote() unexpected error: %v", err)
+	}
+	if err := validateChangeNote(strings.Repeat("改", maxChangeNoteLength+1)); err == nil {
+		t.Error("validateChangeNote() expected error")
+	}
+}
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 全文检索相关限制
+const (
+	maxSearchQueryLength = 100 // 检索内容的最大字符数
+	maxSearchSnippets    = 3   // 每个模板最多返回的高亮片段数
+)
+
+// 全文检索后端名称
+const (
+	SearchBackendMySQL  = "mysql"
+	SearchBackendMemory = "memory"
+)
+
+// templateFullTextColumns FULLTEXT 索引 ft_template_content 覆盖的列，顺序需与索引定义一致
+const templateFullTextColumns = "topic, task_objective, ai_role, my_role, key_information, behavior_rule, delivery_format"
+
+// ErrTemplateSearchQuery 检索内容过长
+var ErrTemplateSearchQuery = fmt.Errorf("检索内容不能超过%d个字符", maxSearchQueryLength)
+
+// TemplateSearchHit 检索命中的模板及相关度
+type TemplateSearchHit struct {
+	TemplateID uint64
+	Score      float64
+}
+
+// TemplateSearchBackend 模板全文检索后端
+type TemplateSearchBackend interface {
+	// Search 在用户自己的模板中检索，按相关度降序返回最多 limit 条
+	Search(userMobile, query string, limit int) ([]TemplateSearchHit, error)
+	// Index 模板新增或修改后更新索引
+	Index(template *models.Template)
+	// Remove 模板删除后移除索引
+	Remove(templateID uint64)
+}
+
+var (
+	templateSearchOnce    sync.Once
+	templateSearchBackend TemplateSearchBackend
+)
+
+// templateSearch 返回全局检索后端，首次调用时按配置创建
+func templateSearch() TemplateSearchBackend {
+	templateSearchOnce.Do(func() {
+		templateSearchBackend = newTemplateSearchBackend(config.GetConfig().Search.Backend)
+	})
+	return templateSearchBackend
+}
+
+// SetTemplateSearchBackend 替换全局检索后端，用于接入外部检索服务，需在处理请求前调用
+func SetTemplateSearchBackend(backend TemplateSearchBackend) {
+	templateSearchOnce.Do(func() {})
+	templateSearchBackend = backend
+}
+
+// newTemplateSearchBackend 按名称创建检索后端，未知名称使用 MySQL
+func newTemplateSearchBackend(name string) TemplateSearchBackend {
+	switch name {
+	case SearchBackendMemory:
+		return newMemorySearchBackend()
+	case SearchBackendMySQL, "":
+	default:
+		utils.Warn("未知的全文检索后端，使用mysql", zap.String("backend", name))
+	}
+	return mysqlSearchBackend{}
+}
+
+// ============ MySQL FULLTEXT ============
+
+// mysqlSearchBackend 基于 MySQL FULLTEXT（ngram 分词）索引检索，索引由数据库维护
+type mysqlSearchBackend struct{}
+
+func (mysqlSearchBackend) Search(userMobile, query string, limit int) ([]TemplateSearchHit, error) {
+	match := "MATCH(" + templateFullTextColumns + ") AGAINST(? IN NATURAL LANGUAGE MODE)"
+	var hits []TemplateSearchHit
+	err := config.DB.Model(&models.Template{}).
+		Select("id AS template_id, "+match+" AS score", query).
+		Where("mobile = ? AND "+match, userMobile, query).
+		Order("score DESC, id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+func (mysqlSearchBackend) Index(*models.Template) {}
+
+func (mysqlSearchBackend) Remove(uint64) {}
+
+// ============ 进程内索引 ============
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// searchDocument 进程内索引中的一个模板
+type searchDocument struct {
+	mobile string
+	terms  map[string]int // 词频，主题中的词计两次
+	length int
+}
+
+// memorySearchBackend 进程内倒排索引，首次检索时从数据库加载全部模板，适用于不支持 FULLTEXT 的数据库
+type memorySearchBackend struct {
+	mu       sync.RWMutex
+	loaded   bool
+	docs     map[uint64]*searchDocument
+	byMobile map[string]map[uint64]*searchDocument
+}
+
+func newMemorySearchBackend() *memorySearchBackend {
+	return &memorySearchBackend{
+		docs:     make(map[uint64]*searchDocument),
+		byMobile: make(map[string]map[uint64]*searchDocument),
+	}
+}
+
+// load 从数据库加载全部模板建立索引
+func (b *memorySearchBackend) load() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.loaded {
+		return nil
+	}
+
+	var batch []models.Template
+	err := config.DB.Model(&models.Template{}).
+		Select("id, mobile, "+templateFullTextColumns).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				b.add(&batch[i])
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+	b.loaded = true
+	utils.Info("模板全文索引已加载", zap.Int("templates", len(b.docs)))
+	return nil
+}
+
+// add 将模板加入索引，已存在时替换，调用方需持有写锁
+func (b *memorySearchBackend) add(template *models.Template) {
+	b.remove(template.ID)
+
+	doc := &searchDocument{mobile: template.Mobile, terms: make(map[string]int)}
+	topic := searchTokens(template.Topic)
+	for _, tokens := range [][]string{topic, topic, searchTokens(strings.Join(templateContents(template)[1:], "\n"))} {
+		for _, token := range tokens {
+			doc.terms[token]++
+			doc.length++
+		}
+	}
+
+	b.docs[template.ID] = doc
+	if b.byMobile[doc.mobile] == nil {
+		b.byMobile[doc.mobile] = make(map[uint64]*searchDocument)
+	}
+	b.byMobile[doc.mobile][template.ID] = doc
+}
+
+// remove 从索引中删除模板，调用方需持有写锁
+func (b *memorySearchBackend) remove(templateID uint64) {
+	doc, ok := b.docs[templateID]
+	if !ok {
+		return
+	}
+	delete(b.docs, templateID)
+	delete(b.byMobile[doc.mobile], templateID)
+	if len(b.byMobile[doc.mobile]) == 0 {
+		delete(b.byMobile, doc.mobile)
+	}
+}
+
+// Index 更新模板索引；索引尚未加载时忽略，加载时会从数据库读取最新内容
+func (b *memorySearchBackend) Index(template *models.Template) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.loaded {
+		b.add(template)
+	}
+}
+
+func (b *memorySearchBackend) Remove(templateID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(templateID)
+}
+
+// Search 在用户的模板中按 BM25 计算相关度
+func (b *memorySearchBackend) Search(userMobile, query string, limit int) ([]TemplateSearchHit, error) {
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	docs := b.byMobile[userMobile]
+	terms := uniqueTokens(searchTokens(query))
+	if len(docs) == 0 || len(terms) == 0 {
+		return nil, nil
+	}
+
+	var totalLength int
+	df := make(map[string]int, len(terms))
+	for _, doc := range docs {
+		totalLength += doc.length
+		for _, term := range terms {
+			if doc.terms[term] > 0 {
+				df[term]++
+			}
+		}
+	}
+	n := float64(len(docs))
+	avgLength := math.Max(float64(totalLength)/n, 1)
+
+	var hits []TemplateSearchHit
+	for id, doc := range docs {
+		var score float64
+		for _, term := range terms {
+			tf := float64(doc.terms[term])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgLength))
+		}
+		if score > 0 {
+			hits = append(hits, TemplateSearchHit{TemplateID: id, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].TemplateID > hits[j].TemplateID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// ============ 分词与高亮 ============
+
+// isCJK 判断是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// searchTokens 分词：连续的中日韩文字按二元切分（与 MySQL ngram_token_size=2 一致），单独的一个字自成一词；
+// 其他字母数字按单词切分；统一转为小写
+func searchTokens(text string) []string {
+	runes := lowerRunes(text)
+	var tokens []string
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		j := i + 1
+		switch {
+		case isCJK(r):
+			for j < len(runes) && isCJK(runes[j]) {
+				j++
+			}
+			if j-i == 1 {
+				tokens = append(tokens, string(r))
+			}
+			for k := i; k+1 < j; k++ {
+				tokens = append(tokens, string(runes[k:k+2]))
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) && !isCJK(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+		}
+		i = j
+	}
+	return tokens
+}
+
+// lowerRunes 逐字转为小写，保持字符位置不变以便计算高亮区间
+func lowerRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// uniqueTokens 去重并保持顺序
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	result := tokens[:0:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// templateContents 按 templateFields 的顺序返回模板的主题与六要素
+func templateContents(template *models.Template) []string {
+	return []string{
+		template.Topic,
+		template.TaskObjective,
+		template.AIRole,
+		template.MyRole,
+		template.KeyInformation,
+		template.BehaviorRule,
+		template.DeliveryFormat,
+	}
+}
+
+// highlightRanges 查找检索词在文本中出现的字符区间，重叠或相邻的区间合并
+func highlightRanges(text []rune, tokens []string) [][2]int {
+	var ranges [][2]int
+	for _, token := range tokens {
+		t := []rune(token)
+		for i := 0; i+len(t) <= len(text); i++ {
+			if string(text[i:i+len(t)]) == token {
+				ranges = append(ranges, [2]int{i, i + len(t)})
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			last[1] = max(last[1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// buildTemplateSnippets 截取命中字段的内容片段并标出命中区间，片段最长 length 个字符（不含省略号）
+func buildTemplateSnippets(template *models.Template, query string, length int) []models.TemplateSnippet {
+	tokens := uniqueTokens(searchTokens(query))
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var snippets []models.TemplateSnippet
+	for i, content := range templateContents(template) {
+		text := []rune(strings.Join(strings.Fields(content), " "))
+		ranges := highlightRanges(lowerRunes(string(text)), tokens)
+		if len(ranges) == 0 {
+			continue
+		}
+
+		start, end := 0, len(text)
+		if end > length {
+			start = max(0, ranges[0][0]-length/4)
+			end = min(len(text), start+length)
+			start = max(0, end-length)
+		}
+
+		var b strings.Builder
+		offset := -start
+		if start > 0 {
+			b.WriteString("…")
+			offset++
+		}
+		b.WriteString(string(text[start:end]))
+		if end < len(text) {
+			b.WriteString("…")
+		}
+
+		highlights := [][2]int{}
+		for _, r := range ranges {
+			if r[1] <= start || r[0] >= end {
+				continue
+			}
+			highlights = append(highlights, [2]int{max(r[0], start) + offset, min(r[1], end) + offset})
+		}
+
+		snippets = append(snippets, models.TemplateSnippet{
+			Field:      templateFields[i].name,
+			Label:      templateFields[i].label,
+			Text:       b.String(),
+			Highlights: highlights,
+		})
+		if len(snippets) == maxSearchSnippets {
+			break
+		}
+	}
+	return snippets
+}
+
+// ============ 模板列表检索 ============
+
+// searchTemplates 全文检索用户的模板，在列表的其他过滤条件基础上按相关度排序并分页
+func (s *TemplateService) searchTemplates(userMobile, q string, query *gorm.DB, req *TemplateQueryRequest) ([]models.Template, int64, error) {
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		return nil, 0, ErrTemplateSearchQuery
+	}
+	cfg := config.GetConfig().Search
+	maxResults := cfg.MaxResults
+	if maxResults <= 0 {
+		maxResults = 500
+	}
+	snippetLength := cfg.SnippetLength
+	if snippetLength <= 0 {
+		snippetLength = 80
+	}
+
+	hits, err := templateSearch().Search(userMobile, q, maxResults)
+	if err != nil {
+		utils.Error("全文检索失败", zap.String("query", q), zap.Error(err))
+		return nil, 0, errors.New("全文检索失败")
+	}
+	if len(hits) == 0 {
+		return []models.Template{}, 0, nil
+	}
+
+	ids := make([]uint64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.TemplateID
+	}
+	var matched []uint64
+	if err := query.Where("id IN ?", ids).Pluck("id", &matched).Error; err != nil {
+		return nil, 0, err
+	}
+	keep := make(map[uint64]bool, len(matched))
+	for _, id := range matched {
+		keep[id] = true
+	}
+	ordered := hits[:0:0]
+	for _, hit := range hits {
+		if keep[hit.TemplateID] {
+			ordered = append(ordered, hit)
+		}
+	}
+
+	total := int64(len(ordered))
+	start := (req.Page - 1) * req.PageSize
+	if start >= len(ordered) {
+		return []models.Template{}, total, nil
+	}
+	ordered = ordered[start:min(start+req.PageSize, len(ordered))]
+
+	position := make(map[uint64]int, len(ordered))
+	pageIDs := make([]uint64, len(ordered))
+	for i, hit := range ordered {
+		position[hit.TemplateID] = i
+		pageIDs[i] = hit.TemplateID
+	}
+	var templates []models.Template
+	if err := config.DB.Where("id IN ?", pageIDs).Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(templates, func(i, j int) bool { return position[templates[i].ID] < position[templates[j].ID] })
+	if err := loadTemplateTags(templates); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range templates {
+		templates[i].Search = &models.TemplateSearchMatch{
+			Score:    ordered[position[templates[i].ID]].Score,
+			Snippets: buildTemplateSnippets(&templates[i], q, snippetLength),
+		}
+	}
+	return templates, total, nil
+}
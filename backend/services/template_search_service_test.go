@@ -0,0 +1,104 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestSearchTokens(t *testing.T) {
+	tests := map[string][]string{
+		"小红书文案":          {"小红", "红书", "书文", "文案"},
+		"写 SEO 文章":       {"写", "seo", "文章"},
+		"GPT4生成Markdown": {"gpt4", "生成", "markdown"},
+		"，。！":            nil,
+	}
+	for text, want := range tests {
+		if got := searchTokens(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("searchTokens(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestHighlightRanges(t *testing.T) {
+	text := lowerRunes("为新品撰写小红书种草文案")
+	got := highlightRanges(text, searchTokens("小红书文案"))
+	want := [][2]int{{5, 8}, {10, 12}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("highlightRanges() = %v, want %v", got, want)
+	}
+	if got := highlightRanges(text, []string{"营销"}); got != nil {
+		t.Errorf("highlightRanges() = %v, want nil", got)
+	}
+}
+
+func TestBuildTemplateSnippets(t *testing.T) {
+	template := &models.Template{
+		Topic:         "小红书文案助手",
+		TaskObjective: strings.Repeat("背景", 30) + "撰写小红书种草文案" + strings.Repeat("说明", 30),
+		AIRole:        "资深 Copywriter",
+	}
+
+	snippets := buildTemplateSnippets(template, "小红书 copywriter", 20)
+	if len(snippets) != 3 {
+		t.Fatalf("buildTemplateSnippets() returned %d snippets, want 3", len(snippets))
+	}
+
+	topic := snippets[0]
+	if topic.Field != "topic" || topic.Text != "小红书文案助手" || !reflect.DeepEqual(topic.Highlights, [][2]int{{0, 3}}) {
+		t.Errorf("topic snippet = %+v", topic)
+	}
+
+	task := snippets[1]
+	runes := []rune(task.Text)
+	if task.Field != "task_objective" || !strings.HasPrefix(task.Text, "…") || !strings.HasSuffix(task.Text, "…") || len(runes) != 22 {
+		t.Errorf("task snippet = %+v", task)
+	}
+	for _, h := range task.Highlights {
+		if got := string(runes[h[0]:h[1]]); got != "小红书" {
+			t.Errorf("task highlight %v = %q, want 小红书", h, got)
+		}
+	}
+
+	role := snippets[2]
+	if role.Label != "AI的角色" || string([]rune(role.Text)[role.Highlights[0][0]:role.Highlights[0][1]]) != "Copywriter" {
+		t.Errorf("role snippet = %+v", role)
+	}
+
+	if got := buildTemplateSnippets(template, "营销", 20); got != nil {
+		t.Errorf("buildTemplateSnippets() = %+v, want nil", got)
+	}
+}
+
+func TestMemorySearchBackend(t *testing.T) {
+	backend := newMemorySearchBackend()
+	backend.loaded = true
+	backend.Index(&models.Template{ID: 1, Mobile: "13800138000", Topic: "周报助手", TaskObjective: "整理本周工作并生成周报"})
+	backend.Index(&models.Template{ID: 2, Mobile: "13800138000", Topic: "小红书文案", TaskObjective: "撰写种草文案"})
+	backend.Index(&models.Template{ID: 3, Mobile: "13800138000", Topic: "营销邮件", KeyInformation: "可参考小红书上的文案风格"})
+	backend.Index(&models.Template{ID: 4, Mobile: "13900139000", Topic: "小红书文案"})
+
+	hits, err := backend.Search("13800138000", "小红书文案", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	var ids []uint64
+	for _, hit := range hits {
+		ids = append(ids, hit.TemplateID)
+	}
+	if !reflect.DeepEqual(ids, []uint64{2, 3}) {
+		t.Errorf("Search() ids = %v, want [2 3]", ids)
+	}
+
+	if hits, _ := backend.Search("13800138000", "小红书", 1); len(hits) != 1 || hits[0].TemplateID != 2 {
+		t.Errorf("Search() with limit = %+v", hits)
+	}
+
+	backend.Index(&models.Template{ID: 2, Mobile: "13800138000", Topic: "朋友圈文案"})
+	backend.Remove(3)
+	if hits, _ := backend.Search("13800138000", "小红书", 10); len(hits) != 0 {
+		t.Errorf("Search() after update = %+v, want none", hits)
+	}
+}
@@ -3,9 +3,12 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/zsy619/cese-qoder/backend/config"
 	"github.com/zsy619/cese-qoder/backend/models"
+	"gorm.io/gorm"
 )
 
 // TemplateService 模板服务
@@ -20,10 +23,14 @@ type TemplateRequest struct {
 	KeyInformation string `json:"key_information"`
 	BehaviorRule   string `json:"behavior_rule"`
 	DeliveryFormat string `json:"delivery_format"`
+	ChangeNote     string `json:"change_note,omitempty"` // 可选：修改说明，记录在修订历史中
+
+	Variables *models.TemplateVariables `json:"variables,omitempty"` // 可选：变量定义，更新时不传表示保持不变，传空数组表示清空
 }
 
 // TemplateQueryRequest 模板查询请求
 type TemplateQueryRequest struct {
+	Q              string `form:"q"`               // 全文检索主题与六要素，按相关度排序
 	UserID         string `form:"user_id"`         // 精确匹配（手机号）
 	Topic          string `form:"topic"`           // 模糊匹配
 	TaskObjective  string `form:"task_objective"`  // 模糊匹配
@@ -32,10 +39,22 @@ type TemplateQueryRequest struct {
 	KeyInformation string `form:"key_information"` // 模糊匹配
 	BehaviorRule   string `form:"behavior_rule"`   // 模糊匹配
 	DeliveryFormat string `form:"delivery_format"` // 模糊匹配
+	Tag            string `form:"tag"`             // 标签名称，多个以逗号分隔，需同时包含
+	FolderID       string `form:"folder_id"`       // 文件夹ID，0 表示未分类
+	Favorite       bool   `form:"favorite"`        // 仅查询收藏的模板
 	Page           int    `form:"page"`            // 页码，默认 1
 	PageSize       int    `form:"page_size"`       // 每页数量，默认 15
 }
 
+// validateTemplateRequest 校验主题长度并检查六要素内容，规则与批量生成、导入一致
+// 内容检查未通过时返回 *InputRejection
+func validateTemplateRequest(req *TemplateRequest) error {
+	if utf8.RuneCountInString(req.Topic) > maxTopicLength {
+		return fmt.Errorf("主题不能超过%d个字符", maxTopicLength)
+	}
+	return ScreenTemplateInput(req)
+}
+
 // CreateTemplate 创建模板
 func (s *TemplateService) CreateTemplate(userMobile string, req *TemplateRequest) (*models.Template, error) {
 	// 验证用户存在
@@ -43,7 +62,21 @@ func (s *TemplateService) CreateTemplate(userMobile string, req *TemplateRequest
 		return nil, err
 	}
 
-	// 创建模板
+	if err := validateChangeNote(req.ChangeNote); err != nil {
+		return nil, err
+	}
+	if err := validateTemplateRequest(req); err != nil {
+		return nil, err
+	}
+	var variables models.TemplateVariables
+	if req.Variables != nil {
+		var err error
+		if variables, err = normalizeTemplateVariables(*req.Variables); err != nil {
+			return nil, err
+		}
+	}
+
+	// 创建模板及第一个修订版本
 	template := &models.Template{
 		Mobile:         userMobile,
 		Topic:          req.Topic,
@@ -53,15 +86,38 @@ func (s *TemplateService) CreateTemplate(userMobile string, req *TemplateRequest
 		KeyInformation: req.KeyInformation,
 		BehaviorRule:   req.BehaviorRule,
 		DeliveryFormat: req.DeliveryFormat,
+		Variables:      variables,
 	}
-
-	if err := config.DB.Create(template).Error; err != nil {
+	if err := createTemplate(template, req.ChangeNote); err != nil {
 		return nil, err
 	}
-
 	return template, nil
 }
 
+// createTemplate 保存新模板及其第一个修订版本，新模板默认仅自己可见
+func createTemplate(template *models.Template, note string) error {
+	template.Version = 1
+	if template.Visibility == "" {
+		template.Visibility = models.TemplateVisibilityPrivate
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+		revision := newTemplateRevision(template, template.Mobile, note)
+		return tx.Create(&revision).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	templateSearch().Index(template)
+	scheduleTemplateEmbedding(template)
+	EmitWebhookEvent(template.Mobile, models.WebhookEventTemplateCreated, template)
+	return nil
+}
+
 // GetTemplates 查询模板列表（支持多条件查询和分页）
 func (s *TemplateService) GetTemplates(userMobile string, req *TemplateQueryRequest) ([]models.Template, int64, error) {
 	// 验证用户存在
@@ -106,22 +162,39 @@ func (s *TemplateService) GetTemplates(userMobile string, req *TemplateQueryRequ
 		query = query.Where("delivery_format LIKE ?", "%"+req.DeliveryFormat+"%")
 	}
 
+	// 添加标签、文件夹与收藏条件
+	query, err := applyTemplateOrganizeFilters(query, userMobile, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 全文检索时按相关度排序
+	if q := strings.TrimSpace(req.Q); q != "" {
+		return s.searchTemplates(userMobile, q, query, req)
+	}
+
 	// 查询总数
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// 查询数据（按创建时间倒序）
+	// 查询数据（置顶在前，按创建时间倒序）
 	var templates []models.Template
 	offset := (req.Page - 1) * req.PageSize
-	if err := query.Order("created_at DESC").Offset(offset).Limit(req.PageSize).Find(&templates).Error; err != nil {
+	if err := query.Order("pinned DESC, created_at DESC").Offset(offset).Limit(req.PageSize).Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := loadTemplateTags(templates); err != nil {
 		return nil, 0, err
 	}
 
 	return templates, total, nil
 }
 
+// ErrTemplateNotFound 模板不存在或当前用户无权访问
+var ErrTemplateNotFound = errors.New("模板不存在或无权访问")
+
 // GetTemplateByID 获取模板详情
 func (s *TemplateService) GetTemplateByID(userMobile string, templateID uint64) (*models.Template, error) {
 	// 验证用户存在
@@ -132,18 +205,24 @@ func (s *TemplateService) GetTemplateByID(userMobile string, templateID uint64)
 	// 查询模板
 	var template models.Template
 	if err := config.DB.Where("id = ? AND mobile = ?", templateID, userMobile).First(&template).Error; err != nil {
-		return nil, errors.New("模板不存在或无权访问")
+		return nil, ErrTemplateNotFound
 	}
 
 	return &template, nil
 }
 
-// UpdateTemplate 更新模板
+// UpdateTemplate 更新模板，内容有变化时生成新的修订版本
 func (s *TemplateService) UpdateTemplate(userMobile string, templateID uint64, req *TemplateRequest) (*models.Template, error) {
 	// 验证用户存在
 	if _, err := GetUserByMobile(userMobile); err != nil {
 		return nil, err
 	}
+	if err := validateChangeNote(req.ChangeNote); err != nil {
+		return nil, err
+	}
+	if err := validateTemplateRequest(req); err != nil {
+		return nil, err
+	}
 
 	// 查询模板
 	var template models.Template
@@ -159,15 +238,18 @@ func (s *TemplateService) UpdateTemplate(userMobile string, templateID uint64, r
 	template.KeyInformation = req.KeyInformation
 	template.BehaviorRule = req.BehaviorRule
 	template.DeliveryFormat = req.DeliveryFormat
-
-	if err := config.DB.Save(&template).Error; err != nil {
-		return nil, err
+	if req.Variables != nil {
+		variables, err := normalizeTemplateVariables(*req.Variables)
+		if err != nil {
+			return nil, err
+		}
+		template.Variables = variables
 	}
 
-	return &template, nil
+	return s.saveTemplateRevision(userMobile, &template, req.ChangeNote)
 }
 
-// DeleteTemplate 删除模板
+// DeleteTemplate 删除模板，移入回收站，保留期内可恢复
 func (s *TemplateService) DeleteTemplate(userMobile string, templateID uint64) error {
 	// 验证用户存在
 	if _, err := GetUserByMobile(userMobile); err != nil {
@@ -180,10 +262,50 @@ func (s *TemplateService) DeleteTemplate(userMobile string, templateID uint64) e
 		return errors.New("模板不存在或无权操作")
 	}
 
-	// 删除模板
+	// 移入回收站，修订历史、共享记录、标签关联与向量保留到彻底删除时
 	if err := config.DB.Delete(&template).Error; err != nil {
 		return fmt.Errorf("删除失败: %w", err)
 	}
-
+	templateSearch().Remove(templateID)
 	return nil
 }
+
+// templateSection 提示词中的一个六要素章节
+type templateSection struct {
+	title   string
+	content string
+}
+
+// templateSections 按固定顺序返回模板的六要素章节
+func templateSections(template *models.Template) []templateSection {
+	return []templateSection{
+		{"任务目标", template.TaskObjective},
+		{"AI的角色", template.AIRole},
+		{"我的角色", template.MyRole},
+		{"关键信息", template.KeyInformation},
+		{"行为规则", template.BehaviorRule},
+		{"交付格式", template.DeliveryFormat},
+	}
+}
+
+// writeTemplateSections 以 "## 标题" 的Markdown格式输出章节，skipEmpty 为 true 时空章节不输出
+func writeTemplateSections(b *strings.Builder, sections []templateSection, skipEmpty bool) {
+	for _, section := range sections {
+		content := strings.TrimSpace(section.content)
+		if content == "" && skipEmpty {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "## %s\n%s\n", section.title, content)
+	}
+}
+
+// BuildTemplatePrompt 将六要素模板组装为Markdown格式的提示词，空要素不输出
+// 格式与前端模板预览一致
+func BuildTemplatePrompt(template *models.Template) string {
+	var b strings.Builder
+	writeTemplateSections(&b, templateSections(template), true)
+	return b.String()
+}
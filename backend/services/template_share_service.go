@@ -0,0 +1,351 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxTemplateShares 单个模板最多共享的用户数
+const maxTemplateShares = 50
+
+// 模板广场排序方式
+const (
+	GallerySortPopular = "popular" // 按被复制次数
+	GallerySortLatest  = "latest"  // 按更新时间
+)
+
+// 模板共享相关错误
+var (
+	ErrTemplateVisibility = errors.New("可见范围仅支持 private、shared、public")
+	ErrGalleryNotFound    = errors.New("公开模板不存在")
+	ErrForkOwnTemplate    = errors.New("不能复制自己的模板")
+)
+
+// TemplateSharingRequest 设置模板可见范围请求
+type TemplateSharingRequest struct {
+	Visibility string   `json:"visibility" binding:"required"` // private / shared / public
+	ShareWith  []string `json:"share_with"`                    // visibility 为 shared 时可查看的用户手机号
+}
+
+// TemplateSharing 模板的可见范围与共享用户
+type TemplateSharing struct {
+	TemplateID uint64   `json:"template_id"`
+	Visibility string   `json:"visibility"`
+	ShareWith  []string `json:"share_with"`
+}
+
+// GalleryQuery 模板广场查询参数
+type GalleryQuery struct {
+	Keyword  string `form:"keyword"`   // 模糊匹配主题、任务目标、AI的角色
+	Sort     string `form:"sort"`      // popular（默认）/ latest
+	Page     int    `form:"page"`      // 页码，默认 1
+	PageSize int    `form:"page_size"` // 每页数量，默认 15
+}
+
+// GalleryTemplate 模板广场中的公开模板，作者手机号脱敏
+type GalleryTemplate struct {
+	ID               uint64                   `json:"id"`
+	Author           string                   `json:"author"`
+	Topic            string                   `json:"topic"`
+	TaskObjective    string                   `json:"task_objective"`
+	AIRole           string                   `json:"ai_role"`
+	MyRole           string                   `json:"my_role"`
+	KeyInformation   string                   `json:"key_information"`
+	BehaviorRule     string                   `json:"behavior_rule"`
+	DeliveryFormat   string                   `json:"delivery_format"`
+	Variables        models.TemplateVariables `json:"variables,omitempty"`
+	ForkCount        int                      `json:"fork_count"`
+	SourceTemplateID *uint64                  `json:"source_template_id,omitempty"`
+	SourceAuthor     string                   `json:"source_author,omitempty"`
+	CreatedAt        time.Time                `json:"created_at"`
+	UpdatedAt        time.Time                `json:"updated_at"`
+}
+
+// maskMobile 手机号脱敏，保留前3位与后4位
+func maskMobile(mobile string) string {
+	if len(mobile) < 8 {
+		return "****"
+	}
+	return mobile[:3] + "****" + mobile[len(mobile)-4:]
+}
+
+// newGalleryTemplate 转换为模板广场展示的结构
+func newGalleryTemplate(template *models.Template) GalleryTemplate {
+	return GalleryTemplate{
+		ID:               template.ID,
+		Author:           maskMobile(template.Mobile),
+		Topic:            template.Topic,
+		TaskObjective:    template.TaskObjective,
+		AIRole:           template.AIRole,
+		MyRole:           template.MyRole,
+		KeyInformation:   template.KeyInformation,
+		BehaviorRule:     template.BehaviorRule,
+		DeliveryFormat:   template.DeliveryFormat,
+		Variables:        template.Variables,
+		ForkCount:        template.ForkCount,
+		SourceTemplateID: template.SourceTemplateID,
+		SourceAuthor:     template.SourceAuthor,
+		CreatedAt:        template.CreatedAt,
+		UpdatedAt:        template.UpdatedAt,
+	}
+}
+
+// normalizeShareMobiles 去除空白与重复的手机号，校验数量与格式，不能共享给自己
+func normalizeShareMobiles(owner string, mobiles []string) ([]string, error) {
+	seen := make(map[string]bool, len(mobiles))
+	result := make([]string, 0, len(mobiles))
+	for _, mobile := range mobiles {
+		mobile = strings.TrimSpace(mobile)
+		if mobile == "" || seen[mobile] {
+			continue
+		}
+		if mobile == owner {
+			return nil, errors.New("不能共享给自己")
+		}
+		if !utils.ValidatePhone(mobile) {
+			return nil, fmt.Errorf("手机号格式错误: %s", mobile)
+		}
+		seen[mobile] = true
+		result = append(result, mobile)
+	}
+	if len(result) > maxTemplateShares {
+		return nil, fmt.Errorf("最多共享给%d个用户", maxTemplateShares)
+	}
+	return result, nil
+}
+
+// visibleTemplateQuery 用户可查看的模板：自己的、公开的、共享给自己的
+func visibleTemplateQuery(db *gorm.DB, userMobile string) *gorm.DB {
+	return db.Where("(mobile = ? OR visibility = ? OR (visibility = ? AND EXISTS (SELECT 1 FROM cese_template_share s WHERE s.template_id = cese_template.id AND s.mobile = ?)))",
+		userMobile, models.TemplateVisibilityPublic, models.TemplateVisibilityShared, userMobile)
+}
+
+// GetVisibleTemplate 获取用户可查看的模板（自己的、公开的或共享给自己的）
+func (s *TemplateService) GetVisibleTemplate(userMobile string, templateID uint64) (*models.Template, error) {
+	var template models.Template
+	if err := visibleTemplateQuery(config.DB.Where("id = ?", templateID), userMobile).First(&template).Error; err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	return &template, nil
+}
+
+// ViewTemplate 查看模板详情，非本人的模板隐藏作者完整手机号及其整理信息
+func (s *TemplateService) ViewTemplate(userMobile string, templateID uint64) (*models.Template, error) {
+	template, err := s.GetVisibleTemplate(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.Mobile != userMobile {
+		template.Mobile = maskMobile(template.Mobile)
+		hideTemplateOrganization(template)
+		return template, nil
+	}
+
+	templates := []models.Template{*template}
+	if err := loadTemplateTags(templates); err != nil {
+		return nil, err
+	}
+	return &templates[0], nil
+}
+
+// GetTemplateSharing 获取模板的可见范围与共享用户，仅模板所有者可查看
+func (s *TemplateService) GetTemplateSharing(userMobile string, templateID uint64) (*TemplateSharing, error) {
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	sharing := &TemplateSharing{TemplateID: template.ID, Visibility: template.Visibility, ShareWith: []string{}}
+	if err := config.DB.Model(&models.TemplateShare{}).Where("template_id = ?", templateID).
+		Order("id ASC").Pluck("mobile", &sharing.ShareWith).Error; err != nil {
+		return nil, err
+	}
+	return sharing, nil
+}
+
+// SetTemplateSharing 设置模板的可见范围，shared 时以 share_with 整体替换共享用户，其他范围清空共享用户
+func (s *TemplateService) SetTemplateSharing(userMobile string, templateID uint64, req *TemplateSharingRequest) (*TemplateSharing, error) {
+	switch req.Visibility {
+	case models.TemplateVisibilityPrivate, models.TemplateVisibilityShared, models.TemplateVisibilityPublic:
+	default:
+		return nil, ErrTemplateVisibility
+	}
+
+	template, err := s.GetTemplateByID(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mobiles []string
+	if req.Visibility == models.TemplateVisibilityShared {
+		if mobiles, err = normalizeShareMobiles(userMobile, req.ShareWith); err != nil {
+			return nil, err
+		}
+		if len(mobiles) == 0 {
+			return nil, errors.New("请指定共享的用户")
+		}
+		var count int64
+		if err := config.DB.Model(&models.User{}).Where("mobile IN ?", mobiles).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if int(count) != len(mobiles) {
+			return nil, errors.New("共享的用户不存在")
+		}
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(template).UpdateColumn("visibility", req.Visibility).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", templateID).Delete(&models.TemplateShare{}).Error; err != nil {
+			return err
+		}
+		for _, mobile := range mobiles {
+			if err := tx.Create(&models.TemplateShare{TemplateID: templateID, Mobile: mobile}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("设置失败: %w", err)
+	}
+
+	utils.Info("模板可见范围已更新", zap.Uint64("template_id", templateID), zap.String("visibility", req.Visibility), zap.Int("shares", len(mobiles)))
+	if mobiles == nil {
+		mobiles = []string{}
+	}
+	return &TemplateSharing{TemplateID: templateID, Visibility: req.Visibility, ShareWith: mobiles}, nil
+}
+
+// ListSharedTemplates 分页查询其他用户共享给自己的模板
+func (s *TemplateService) ListSharedTemplates(userMobile string, page, pageSize int) ([]models.Template, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.DB.Model(&models.Template{}).
+		Joins("JOIN cese_template_share s ON s.template_id = cese_template.id").
+		Where("s.mobile = ? AND cese_template.visibility = ?", userMobile, models.TemplateVisibilityShared)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var templates []models.Template
+	if err := query.Select("cese_template.*").Order("cese_template.updated_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range templates {
+		hideTemplateOrganization(&templates[i])
+		templates[i].Mobile = maskMobile(templates[i].Mobile)
+	}
+	return templates, total, nil
+}
+
+// ListGalleryTemplates 分页查询模板广场中的公开模板
+func ListGalleryTemplates(q *GalleryQuery) ([]GalleryTemplate, int64, error) {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 15
+	}
+	if q.PageSize > 100 {
+		q.PageSize = 100
+	}
+
+	query := config.DB.Model(&models.Template{}).Where("visibility = ?", models.TemplateVisibilityPublic)
+	if keyword := strings.TrimSpace(q.Keyword); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("topic LIKE ? OR task_objective LIKE ? OR ai_role LIKE ?", like, like, like)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "fork_count DESC, updated_at DESC"
+	if q.Sort == GallerySortLatest {
+		order = "updated_at DESC"
+	}
+	var templates []models.Template
+	if err := query.Order(order).Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]GalleryTemplate, 0, len(templates))
+	for i := range templates {
+		list = append(list, newGalleryTemplate(&templates[i]))
+	}
+	return list, total, nil
+}
+
+// GetGalleryTemplate 获取模板广场中的公开模板
+func GetGalleryTemplate(templateID uint64) (*GalleryTemplate, error) {
+	var template models.Template
+	if err := config.DB.Where("id = ? AND visibility = ?", templateID, models.TemplateVisibilityPublic).First(&template).Error; err != nil {
+		return nil, ErrGalleryNotFound
+	}
+	gallery := newGalleryTemplate(&template)
+	return &gallery, nil
+}
+
+// ForkTemplate 将公开或共享给自己的模板复制到自己的账户，保留来源模板与原作者
+func (s *TemplateService) ForkTemplate(userMobile string, templateID uint64) (*models.Template, error) {
+	if _, err := GetUserByMobile(userMobile); err != nil {
+		return nil, err
+	}
+	source, err := s.GetVisibleTemplate(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Mobile == userMobile {
+		return nil, ErrForkOwnTemplate
+	}
+
+	// 复制的模板再次被复制时，原作者仍指向最初的作者
+	author := source.SourceAuthor
+	if author == "" {
+		author = maskMobile(source.Mobile)
+	}
+	template := &models.Template{
+		Mobile:           userMobile,
+		Topic:            source.Topic,
+		TaskObjective:    source.TaskObjective,
+		AIRole:           source.AIRole,
+		MyRole:           source.MyRole,
+		KeyInformation:   source.KeyInformation,
+		BehaviorRule:     source.BehaviorRule,
+		DeliveryFormat:   source.DeliveryFormat,
+		Variables:        source.Variables,
+		SourceTemplateID: &source.ID,
+		SourceAuthor:     author,
+	}
+	if err := createTemplate(template, fmt.Sprintf("复制自模板#%d（作者 %s）", source.ID, maskMobile(source.Mobile))); err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(&models.Template{}).Where("id = ?", source.ID).
+		UpdateColumn("fork_count", gorm.Expr("fork_count + 1")).Error; err != nil {
+		utils.Warn("更新模板复制次数失败", zap.Uint64("template_id", source.ID), zap.Error(err))
+	}
+	utils.Info("模板已复制", zap.Uint64("source_id", source.ID), zap.Uint64("template_id", template.ID))
+	return template, nil
+}
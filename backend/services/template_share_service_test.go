@@ -0,0 +1,95 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestMaskMobile(t *testing.T) {
+	tests := map[string]string{
+		"13800138000": "138****8000",
+		"1380":        "****",
+		"":            "****",
+	}
+	for mobile, want := range tests {
+		if got := maskMobile(mobile); got != want {
+			t.Errorf("maskMobile(%q) = %q, want %q", mobile, got, want)
+		}
+	}
+}
+
+func TestNormalizeShareMobiles(t *testing.T) {
+	got, err := normalizeShareMobiles("13800138000", []string{" 13900139000 ", "", "13900139000", "13700137000"})
+	if err != nil || !reflect.DeepEqual(got, []string{"13900139000", "13700137000"}) {
+		t.Errorf("normalizeShareMobiles() = %v, %v", got, err)
+	}
+
+	invalid := [][]string{
+		{"13800138000"},
+		{"12345"},
+	}
+	for _, mobiles := range invalid {
+		if _, err := normalizeShareMobiles("13800138000", mobiles); err == nil {
+			t.Errorf("normalizeShareMobiles(%v) expected error", mobiles)
+		}
+	}
+}
+
+func TestNewGalleryTemplate(t *testing.T) {
+	sourceID := uint64(3)
+	got := newGalleryTemplate(&models.Template{
+		ID:               9,
+		Mobile:           "13900139000",
+		Topic:            "写作助手",
+		ForkCount:        5,
+		SourceTemplateID: &sourceID,
+		SourceAuthor:     "138****8000",
+	})
+	if got.Author != "139****9000" || got.ForkCount != 5 || got.SourceAuthor != "138****8000" || *got.SourceTemplateID != 3 {
+		t.Errorf("newGalleryTemplate() = %+v", got)
+	}
+}
+
+func TestSetTemplateSharingInvalidVisibility(t *testing.T) {
+	_, err := (&TemplateService{}).SetTemplateSharing("13800138000", 1, &TemplateSharingRequest{Visibility: "friends"})
+	if err != ErrTemplateVisibility {
+		t.Errorf("SetTemplateSharing() error = %v, want %v", err, ErrTemplateVisibility)
+	}
+}
+
+func TestListSharedTemplatesMasksOwnerMobile(t *testing.T) {
+	setupAPIProviderTest(t)
+	db := config.GetDB()
+
+	owner := &models.User{Mobile: "13900139043", Password: "x"}
+	viewer := &models.User{Mobile: "13900139044", Password: "x"}
+	for _, u := range []*models.User{owner, viewer} {
+		db.Create(u)
+		defer db.Delete(u)
+	}
+
+	template := &models.Template{Mobile: owner.Mobile, Topic: "共享模板", Visibility: models.TemplateVisibilityShared}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatalf("create template error = %v", err)
+	}
+	defer db.Unscoped().Delete(template)
+	share := &models.TemplateShare{TemplateID: template.ID, Mobile: viewer.Mobile}
+	if err := db.Create(share).Error; err != nil {
+		t.Fatalf("create share error = %v", err)
+	}
+	defer db.Delete(share)
+
+	templates, total, err := (&TemplateService{}).ListSharedTemplates(viewer.Mobile, 1, 15)
+	if err != nil {
+		t.Fatalf("ListSharedTemplates() error = %v", err)
+	}
+	if total != 1 || len(templates) != 1 {
+		t.Fatalf("ListSharedTemplates() = %d templates, total %d, want 1", len(templates), total)
+	}
+	if got := templates[0].Mobile; got != maskMobile(owner.Mobile) {
+		t.Errorf("owner mobile = %q, want %q", got, maskMobile(owner.Mobile))
+	}
+}
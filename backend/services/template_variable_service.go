@@ -0,0 +1,288 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+// 模板变量限制
+const (
+	maxTemplateVariables         = 30
+	maxTemplateVariableName      = 50
+	maxTemplateVariableDesc      = 200
+	maxTemplateVariableOptions   = 50
+	maxTemplateVariableValueSize = 5000 // 单个变量取值的最大字符数
+)
+
+var (
+	ErrTemplateVariable       = errors.New("变量定义错误")
+	ErrTemplateVariableValues = errors.New("变量取值错误")
+)
+
+// templatePlaceholderPattern 要素中的变量占位符 {{变量名}}，变量名两侧允许空白
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([\p{L}_][\p{L}\p{N}_]*)\s*\}\}`)
+
+// templateVariableNamePattern 变量名：字母（含中文）或下划线开头，后接字母、数字、下划线
+var templateVariableNamePattern = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_]*$`)
+
+// TemplateRenderRequest 渲染模板请求，values 为变量名到取值的映射
+type TemplateRenderRequest struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+// TemplateRenderResult 渲染结果：替换变量后的要素、完整提示词及实际使用的变量取值
+type TemplateRenderResult struct {
+	TemplateDocument
+	Prompt     string            `json:"prompt"`               // 标准Markdown格式的完整提示词
+	Values     map[string]string `json:"values"`               // 实际使用的取值（含默认值）
+	Undeclared []string          `json:"undeclared,omitempty"` // 要素中未声明的占位符，保持原样
+}
+
+// normalizeTemplateVariables 校验并规范化变量定义：类型默认为 string，默认值转换为对应类型
+func normalizeTemplateVariables(variables models.TemplateVariables) (models.TemplateVariables, error) {
+	if len(variables) == 0 {
+		return nil, nil
+	}
+	if len(variables) > maxTemplateVariables {
+		return nil, fmt.Errorf("%w: 最多定义%d个变量", ErrTemplateVariable, maxTemplateVariables)
+	}
+
+	seen := make(map[string]bool, len(variables))
+	result := make(models.TemplateVariables, 0, len(variables))
+	for _, v := range variables {
+		v.Name = strings.TrimSpace(v.Name)
+		v.Description = strings.TrimSpace(v.Description)
+		if v.Type == "" {
+			v.Type = models.TemplateVariableString
+		}
+
+		switch {
+		case !templateVariableNamePattern.MatchString(v.Name) || utf8.RuneCountInString(v.Name) > maxTemplateVariableName:
+			return nil, fmt.Errorf("%w: 变量名「%s」只能包含字母、数字、下划线且不能以数字开头，不超过%d个字符", ErrTemplateVariable, v.Name, maxTemplateVariableName)
+		case seen[v.Name]:
+			return nil, fmt.Errorf("%w: 变量「%s」重复", ErrTemplateVariable, v.Name)
+		case utf8.RuneCountInString(v.Description) > maxTemplateVariableDesc:
+			return nil, fmt.Errorf("%w: 变量「%s」的说明不能超过%d个字符", ErrTemplateVariable, v.Name, maxTemplateVariableDesc)
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case models.TemplateVariableString, models.TemplateVariableNumber, models.TemplateVariableBoolean:
+			v.Options = nil
+		case models.TemplateVariableEnum:
+			options, err := normalizeVariableOptions(v.Options)
+			if err != nil {
+				return nil, fmt.Errorf("%w: 变量「%s」%v", ErrTemplateVariable, v.Name, err)
+			}
+			v.Options = options
+		default:
+			return nil, fmt.Errorf("%w: 变量「%s」的类型仅支持string、number、boolean、enum", ErrTemplateVariable, v.Name)
+		}
+
+		if v.Default != nil {
+			value, err := variableValue(&v, v.Default)
+			if err != nil {
+				return nil, fmt.Errorf("%w: 变量「%s」的默认值%v", ErrTemplateVariable, v.Name, err)
+			}
+			v.Default = value
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// normalizeVariableOptions 去除空白与重复的枚举可选值
+func normalizeVariableOptions(options []string) ([]string, error) {
+	var result []string
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option != "" && !slices.Contains(result, option) {
+			result = append(result, option)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("至少需要一个可选值")
+	}
+	if len(result) > maxTemplateVariableOptions {
+		return nil, fmt.Errorf("最多%d个可选值", maxTemplateVariableOptions)
+	}
+	return result, nil
+}
+
+// variableValue 将取值转换为变量类型对应的值：string/enum 为字符串，number 为 float64，boolean 为 bool
+// 数字与布尔值也接受字符串形式，如 "3.5"、"true"
+func variableValue(v *models.TemplateVariable, value interface{}) (interface{}, error) {
+	switch v.Type {
+	case models.TemplateVariableNumber:
+		switch value := value.(type) {
+		case float64:
+			return value, nil
+		case int:
+			return float64(value), nil
+		case json.Number:
+			return value.Float64()
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, errors.New("应为数字")
+	case models.TemplateVariableBoolean:
+		switch value := value.(type) {
+		case bool:
+			return value, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, errors.New("应为true或false")
+	case models.TemplateVariableEnum:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(v.Options, s) {
+			return nil, fmt.Errorf("应为%s之一", strings.Join(v.Options, "、"))
+		}
+		return s, nil
+	default:
+		var s string
+		switch value := value.(type) {
+		case string:
+			s = value
+		case float64, int, bool, json.Number:
+			s = fmt.Sprint(value)
+		default:
+			return nil, errors.New("应为文本")
+		}
+		if utf8.RuneCountInString(s) > maxTemplateVariableValueSize {
+			return nil, fmt.Errorf("不能超过%d个字符", maxTemplateVariableValueSize)
+		}
+		return s, nil
+	}
+}
+
+// formatVariableValue 将变量值转换为替换到要素中的文本
+func formatVariableValue(value interface{}) string {
+	switch value := value.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		if value {
+			return "是"
+		}
+		return "否"
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// resolveTemplateValues 按变量定义校验取值并补充默认值，返回变量名到替换文本的映射
+// 未声明的变量、类型不符、缺少必填变量时返回全部问题
+func resolveTemplateValues(variables models.TemplateVariables, values map[string]interface{}) (map[string]string, error) {
+	var problems []string
+	for name := range values {
+		if !slices.ContainsFunc(variables, func(v models.TemplateVariable) bool { return v.Name == name }) {
+			problems = append(problems, fmt.Sprintf("未声明的变量「%s」", name))
+		}
+	}
+	slices.Sort(problems)
+
+	resolved := make(map[string]string, len(variables))
+	for i := range variables {
+		v := &variables[i]
+		// 未提供、null 与空字符串均视为未填写，使用默认值
+		value := values[v.Name]
+		if value == nil || value == "" {
+			if v.Default == nil && v.Required {
+				problems = append(problems, fmt.Sprintf("缺少必填变量「%s」", v.Name))
+				continue
+			}
+			resolved[v.Name] = formatVariableValue(v.Default)
+			continue
+		}
+		typed, err := variableValue(v, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("变量「%s」%v", v.Name, err))
+			continue
+		}
+		resolved[v.Name] = formatVariableValue(typed)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateVariableValues, strings.Join(problems, "；"))
+	}
+	return resolved, nil
+}
+
+// renderTemplateText 替换文本中已声明变量的占位符，未声明的占位符保持原样
+func renderTemplateText(text string, values map[string]string) string {
+	return templatePlaceholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := templatePlaceholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// templatePlaceholders 按出现顺序返回主题与六要素中引用的变量名（去重）
+func templatePlaceholders(template *models.Template) []string {
+	var names []string
+	for _, content := range templateContents(template) {
+		for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(content, -1) {
+			if !slices.Contains(names, match[1]) {
+				names = append(names, match[1])
+			}
+		}
+	}
+	return names
+}
+
+// RenderTemplate 用变量取值渲染模板，可渲染自己的、公开的或共享给自己的模板
+func (s *TemplateService) RenderTemplate(userMobile string, templateID uint64, values map[string]interface{}) (*TemplateRenderResult, error) {
+	template, err := s.GetVisibleTemplate(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return renderTemplateVariables(template, values)
+}
+
+// renderTemplateVariables 校验取值并替换主题与六要素中的变量
+func renderTemplateVariables(template *models.Template, values map[string]interface{}) (*TemplateRenderResult, error) {
+	resolved, err := resolveTemplateValues(template.Variables, values)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := *template
+	for _, field := range []*string{
+		&rendered.Topic, &rendered.TaskObjective, &rendered.AIRole, &rendered.MyRole,
+		&rendered.KeyInformation, &rendered.BehaviorRule, &rendered.DeliveryFormat,
+	} {
+		*field = renderTemplateText(*field, resolved)
+	}
+
+	var undeclared []string
+	for _, name := range templatePlaceholders(template) {
+		if _, ok := resolved[name]; !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+
+	document := newTemplateDocument(&rendered)
+	document.Variables = nil
+	return &TemplateRenderResult{
+		TemplateDocument: document,
+		Prompt:           BuildTemplateMarkdown(&rendered),
+		Values:           resolved,
+		Undeclared:       undeclared,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+var variableTestTemplate = models.Template{
+	Topic:          "{{客户}}的邮件",
+	TaskObjective:  "为{{ 客户 }}撰写{{count}}封跟进邮件",
+	KeyInformation: "语气：{{tone}}；附带报价：{{quote}}；{{unknown}}",
+	Variables: models.TemplateVariables{
+		{Name: "客户", Type: models.TemplateVariableString, Required: true},
+		{Name: "count", Type: models.TemplateVariableNumber, Default: float64(2)},
+		{Name: "tone", Type: models.TemplateVariableEnum, Options: []string{"正式", "轻松"}, Default: "正式"},
+		{Name: "quote", Type: models.TemplateVariableBoolean},
+	},
+}
+
+func TestNormalizeTemplateVariables(t *testing.T) {
+	variables, err := normalizeTemplateVariables(models.TemplateVariables{
+		{Name: " city ", Description: " 城市 "},
+		{Name: "days", Type: models.TemplateVariableNumber, Default: "3"},
+		{Name: "formal", Type: models.TemplateVariableBoolean, Default: "true"},
+		{Name: "level", Type: models.TemplateVariableEnum, Options: []string{" 初级", "初级", "", "高级"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.TemplateVariables{
+		{Name: "city", Type: models.TemplateVariableString, Description: "城市"},
+		{Name: "days", Type: models.TemplateVariableNumber, Default: float64(3)},
+		{Name: "formal", Type: models.TemplateVariableBoolean, Default: true},
+		{Name: "level", Type: models.TemplateVariableEnum, Options: []string{"初级", "高级"}},
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("normalizeTemplateVariables() = %+v, want %+v", variables, want)
+	}
+
+	if variables, err := normalizeTemplateVariables(models.TemplateVariables{}); err != nil || variables != nil {
+		t.Errorf("normalizeTemplateVariables(empty) = %v, %v", variables, err)
+	}
+
+	invalid := []models.TemplateVariables{
+		{{Name: "1st"}},
+		{{Name: "a b"}},
+		{{Name: "x"}, {Name: "x"}},
+		{{Name: "x", Type: "date"}},
+		{{Name: "x", Type: models.TemplateVariableEnum}},
+		{{Name: "x", Type: models.TemplateVariableEnum, Options: []string{"a"}, Default: "b"}},
+		{{Name: "x", Type: models.TemplateVariableNumber, Default: "abc"}},
+		{{Name: "x", Description: strings.Repeat("长", maxTemplateVariableDesc+1)}},
+	}
+	for _, variables := range invalid {
+		if _, err := normalizeTemplateVariables(variables); !errors.Is(err, ErrTemplateVariable) {
+			t.Errorf("normalizeTemplateVariables(%+v) error = %v, want ErrTemplateVariable", variables, err)
+		}
+	}
+}
+
+func TestRenderTemplateVariables(t *testing.T) {
+	result, err := renderTemplateVariables(&variableTestTemplate, map[string]interface{}{
+		"客户":    "张三",
+		"quote": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Topic != "张三的邮件" || result.TaskObjective != "为张三撰写2封跟进邮件" {
+		t.Errorf("rendered = %q / %q", result.Topic, result.TaskObjective)
+	}
+	if result.KeyInformation != "语气：正式；附带报价：是；{{unknown}}" {
+		t.Errorf("KeyInformation = %q", result.KeyInformation)
+	}
+	if !reflect.DeepEqual(result.Undeclared, []string{"unknown"}) {
+		t.Errorf("Undeclared = %v", result.Undeclared)
+	}
+	if !strings.HasPrefix(result.Prompt, "# 张三的邮件\n") {
+		t.Errorf("Prompt = %q", result.Prompt)
+	}
+	if want := map[string]string{"客户": "张三", "count": "2", "tone": "正式", "quote": "是"}; !reflect.DeepEqual(result.Values, want) {
+		t.Errorf("Values = %v, want %v", result.Values, want)
+	}
+}
+
+func TestResolveTemplateValuesErrors(t *testing.T) {
+	_, err := resolveTemplateValues(variableTestTemplate.Variables, map[string]interface{}{
+		"count": "many",
+		"tone":  "随意",
+		"extra": 1,
+	})
+	if !errors.Is(err, ErrTemplateVariableValues) {
+		t.Fatalf("resolveTemplateValues() error = %v", err)
+	}
+	for _, problem := range []string{"未声明的变量「extra」", "缺少必填变量「客户」", "变量「count」应为数字", "变量「tone」应为正式、轻松之一"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error %q missing %q", err, problem)
+		}
+	}
+
+	values, err := resolveTemplateValues(variableTestTemplate.Variables, map[string]interface{}{"客户": "李四", "count": "1.5", "quote": "false"})
+	if err != nil || values["count"] != "1.5" || values["quote"] != "否" {
+		t.Errorf("resolveTemplateValues() = %v, %v", values, err)
+	}
+}
+
+func TestTemplateVariablesScan(t *testing.T) {
+	value, err := variableTestTemplate.Variables.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var variables models.TemplateVariables
+	if err := variables.Scan([]byte(value.(string))); err != nil || !reflect.DeepEqual(variables, variableTestTemplate.Variables) {
+		t.Errorf("Scan() = %+v, %v", variables, err)
+	}
+	if value, _ := (models.TemplateVariables{}).Value(); value != nil {
+		t.Errorf("Value(empty) = %v, want nil", value)
+	}
+	if err := variables.Scan(nil); err != nil || variables != nil {
+		t.Errorf("Scan(nil) = %v, %v", variables, err)
+	}
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+)
+
+// 对话格式的token开销（参考 OpenAI 的计算方式）
+const (
+	tokensPerMessage = 4   // 每条消息的角色与分隔符
+	tokensReplyPrime = 3   // 回复起始标记
+	tokensPerImage   = 765 // 每张图片按 1024x1024 高清模式估算
+)
+
+// bpePatterns 各BPE编码的预分词正则
+// tiktoken 使用的 \s+(?!\S) 在 RE2 中不可用，由 splitPieces 处理等价的回退
+var bpePatterns = map[string]*regexp.Regexp{
+	models.EncodingCL100K: regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{Z}\p{L}\p{N}]+[\r\n]*|[\s\p{Z}]*[\r\n]+|[\s\p{Z}]+`),
+	models.EncodingO200K:  regexp.MustCompile(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{Z}\p{L}\p{N}]+[\r\n/]*|[\s\p{Z}]*[\r\n]+|[\s\p{Z}]+`),
+}
+
+// bpeEncoding tiktoken 兼容的BPE编码
+type bpeEncoding struct {
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+var (
+	bpeMu        sync.Mutex
+	bpeEncodings = map[string]*bpeEncoding{} // 加载失败时记录为 nil，不再重复加载
+)
+
+// getBPEEncoding 返回BPE编码，首次使用时从 token.bpe_dir 加载词表，词表不存在时返回 nil
+func getBPEEncoding(name string) *bpeEncoding {
+	bpeMu.Lock()
+	defer bpeMu.Unlock()
+
+	if enc, ok := bpeEncodings[name]; ok {
+		return enc
+	}
+	enc, err := loadBPEEncoding(config.GetConfig().Token.BPEDir, name)
+	if err != nil {
+		utils.Warn("BPE词表加载失败，将按字符估算token数", zap.String("encoding", name), zap.Error(err))
+	} else {
+		utils.Info("BPE词表加载成功", zap.String("encoding", name), zap.Int("ranks", len(enc.ranks)))
+	}
+	bpeEncodings[name] = enc
+	return enc
+}
+
+// loadBPEEncoding 读取 {dir}/{name}.tiktoken 词表
+func loadBPEEncoding(dir, name string) (*bpeEncoding, error) {
+	pattern, ok := bpePatterns[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的BPE编码: %s", name)
+	}
+
+	f, err := os.Open(filepath.Join(dir, name+".tiktoken"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks, err := parseBPERanks(f)
+	if err != nil {
+		return nil, err
+	}
+	return &bpeEncoding{ranks: ranks, pattern: pattern}, nil
+}
+
+// parseBPERanks 解析 tiktoken 词表：每行为 “Base64编码的字节序列 排名”
+func parseBPERanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("第%d行格式错误", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("第%d行Base64解码失败: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("第%d行排名无效: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	return ranks, scanner.Err()
+}
+
+// count 计算文本的token数
+func (e *bpeEncoding) count(text string) int {
+	n := 0
+	for _, piece := range splitPieces(e.pattern, text) {
+		if _, ok := e.ranks[piece]; ok {
+			n++
+			continue
+		}
+		n += bytePairMerge(piece, e.ranks)
+	}
+	return n
+}
+
+// splitPieces 按编码的正则预分词
+// 连续空白后跟非空白时，最后一个空白字符留给后续片段，等价于 tiktoken 的 \s+(?!\S)
+func splitPieces(pattern *regexp.Regexp, text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := pattern.FindStringIndex(text)
+		if loc == nil || loc[0] != 0 || loc[1] == 0 {
+			// 所有字符都能被某个分支匹配，这里只是防御
+			pieces = append(pieces, text)
+			break
+		}
+		piece := text[:loc[1]]
+		if loc[1] < len(text) && isTrailingSpaceRun(piece) {
+			_, size := utf8.DecodeLastRuneInString(piece)
+			piece = piece[:len(piece)-size]
+		}
+		pieces = append(pieces, piece)
+		text = text[len(piece):]
+	}
+	return pieces
+}
+
+// isTrailingSpaceRun 是否为不以换行结尾、多于一个字符的纯空白片段
+func isTrailingSpaceRun(piece string) bool {
+	if utf8.RuneCountInString(piece) < 2 || strings.HasSuffix(piece, "\n") || strings.HasSuffix(piece, "\r") {
+		return false
+	}
+	for _, r := range piece {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// bytePairMerge 对片段的字节序列反复合并排名最低的相邻字节对，返回最终的token数
+func bytePairMerge(piece string, ranks map[string]int) int {
+	if len(piece) <= 1 {
+		return len(piece)
+	}
+
+	// parts[i] 为第i个token的起始位置，rank[i] 为第i、i+1个token合并后的排名
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+	rank := make([]int, len(piece))
+	pairRank := func(i int) int {
+		if i+2 >= len(parts) {
+			return math.MaxInt
+		}
+		if r, ok := ranks[piece[parts[i]:parts[i+2]]]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+	for i := range rank {
+		rank[i] = pairRank(i)
+	}
+
+	for {
+		best, lowest := -1, math.MaxInt
+		for i := 0; i < len(parts)-2; i++ {
+			if rank[i] < lowest {
+				best, lowest = i, rank[i]
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+		rank = append(rank[:best+1], rank[best+2:]...)
+		rank[best] = pairRank(best)
+		if best > 0 {
+			rank[best-1] = pairRank(best - 1)
+		}
+	}
+	return len(parts) - 1
+}
+
+// EstimateTokens 粗略估算文本的token数：中日韩字符按每字1个token，其他字符按每4个字符1个token
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// tokenCounter 按模型计算token数，模型有可用的BPE词表时精确计算，否则估算
+type tokenCounter struct {
+	encoding *bpeEncoding
+}
+
+func (t tokenCounter) count(text string) int {
+	if text == "" {
+		return 0
+	}
+	if t.encoding != nil {
+		return t.encoding.count(text)
+	}
+	return EstimateTokens(text)
+}
+
+// LookupModelInfo 在内置模型目录及 token.models 配置中查找模型
+func LookupModelInfo(model string) (*models.ModelInfo, bool) {
+	var extra []models.ModelInfo
+	for _, m := range config.GetConfig().Token.Models {
+		extra = append(extra, models.ModelInfo{
+			Prefix:        m.Prefix,
+			ContextWindow: m.ContextWindow,
+			MaxOutput:     m.MaxOutput,
+			Encoding:      m.Encoding,
+		})
+	}
+	return models.LookupModel(model, extra)
+}
+
+// newTokenCounter 返回模型的token计数器
+func newTokenCounter(info *models.ModelInfo) tokenCounter {
+	if info == nil || info.Encoding == "" {
+		return tokenCounter{}
+	}
+	return tokenCounter{encoding: getBPEEncoding(info.Encoding)}
+}
+
+// CountTokens 计算文本在指定模型下的token数，exact 表示是否按BPE词表精确计算
+func CountTokens(model, text string) (tokens int, exact bool) {
+	info, _ := LookupModelInfo(model)
+	counter := newTokenCounter(info)
+	return counter.count(text), counter.encoding != nil
+}
+
+// TokenEstimate 生成请求的token预估与上下文窗口预算
+type TokenEstimate struct {
+	Model              string `json:"model"`                    // 实际使用的模型
+	KnownModel         bool   `json:"known_model"`              // 模型是否在目录中
+	Encoding           string `json:"encoding,omitempty"`       // 精确计算时使用的BPE编码
+	Exact              bool   `json:"exact"`                    // 输入token数是否精确计算
+	PromptTokens       int    `json:"prompt_tokens"`            // 输入token数（含system、历史消息、工具定义与图片）
+	ContextWindow      int    `json:"context_window,omitempty"` // 上下文窗口，未知模型为0
+	MaxOutput          int    `json:"max_output,omitempty"`     // 模型单次最大输出，0表示不单独限制
+	Remaining          int    `json:"remaining"`                // 上下文窗口扣除输入后剩余的token数，未知模型为0
+	RequestedMaxTokens int    `json:"requested_max_tokens"`     // 请求的max_tokens，未指定时为默认值
+	MaxTokens          int    `json:"max_tokens"`               // 调整后实际使用的max_tokens
+	Clamped            bool   `json:"clamped"`                  // max_tokens 是否被调整
+	Exceeded           bool   `json:"exceeded"`                 // 输入是否已超出上下文窗口
+}
+
+// resolveGenerateModel 返回请求实际使用的模型：请求中指定的模型优先，否则使用Provider配置的模型
+func resolveGenerateModel(provider *models.APIProvider, req *GenerateRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return provider.APIModel
+}
+
+// countRequestTokens 计算生成请求的输入token数
+func countRequestTokens(counter tokenCounter, req *GenerateRequest) int {
+	messages := len(req.Messages) + 1
+	tokens := counter.count(req.Prompt)
+	if req.System != "" {
+		messages++
+		tokens += counter.count(req.System)
+	}
+	for _, m := range req.Messages {
+		tokens += counter.count(m.Content) + counter.count(m.Name)
+		for _, call := range m.ToolCalls {
+			tokens += counter.count(call.Function.Name) + counter.count(call.Function.Arguments)
+		}
+		tokens += len(m.Images) * tokensPerImage
+	}
+	if len(req.Tools) > 0 {
+		if data, err := json.Marshal(req.Tools); err == nil {
+			tokens += counter.count(string(data))
+		}
+	}
+	images := len(req.Images)
+	if len(req.Attachments) > images {
+		images = len(req.Attachments)
+	}
+	tokens += images * tokensPerImage
+	return tokens + messages*tokensPerMessage + tokensReplyPrime
+}
+
+// EstimateGenerateTokens 计算生成请求的输入token数及可用的max_tokens
+// 目录中的模型（或指定了 num_ctx 的Ollama模型）会将 max_tokens 限制在模型最大输出与剩余上下文之内
+func EstimateGenerateTokens(provider *models.APIProvider, req *GenerateRequest) *TokenEstimate {
+	model := resolveGenerateModel(provider, req)
+	info, known := LookupModelInfo(model)
+	counter := newTokenCounter(info)
+
+	estimate := &TokenEstimate{
+		Model:              model,
+		KnownModel:         known,
+		Exact:              counter.encoding != nil,
+		PromptTokens:       countRequestTokens(counter, req),
+		RequestedMaxTokens: req.MaxTokens,
+	}
+	if estimate.Exact {
+		estimate.Encoding = info.Encoding
+	}
+	if estimate.RequestedMaxTokens <= 0 {
+		estimate.RequestedMaxTokens = config.GetConfig().Token.DefaultMaxTokens
+	}
+	estimate.MaxTokens = estimate.RequestedMaxTokens
+
+	if known {
+		estimate.ContextWindow = info.ContextWindow
+		estimate.MaxOutput = info.MaxOutput
+	}
+	if provider.APIKind == models.APIKindOllama && req.NumCtx > 0 {
+		estimate.ContextWindow = req.NumCtx
+	}
+	if estimate.ContextWindow <= 0 {
+		return estimate
+	}
+
+	estimate.Remaining = estimate.ContextWindow - estimate.PromptTokens
+	if estimate.Remaining <= 0 {
+		estimate.Remaining = 0
+		estimate.Exceeded = true
+		return estimate
+	}
+	limit := estimate.Remaining
+	if estimate.MaxOutput > 0 && estimate.MaxOutput < limit {
+		limit = estimate.MaxOutput
+	}
+	if estimate.MaxTokens > limit {
+		estimate.MaxTokens = limit
+		estimate.Clamped = true
+	}
+	return estimate
+}
+
+// checkContextWindow 输入超出模型上下文窗口时返回错误
+func checkContextWindow(provider *models.APIProvider, req *GenerateRequest) error {
+	estimate := EstimateGenerateTokens(provider, req)
+	if !estimate.Exceeded {
+		return nil
+	}
+	utils.Warn("输入超出模型上下文窗口",
+		zap.String("model", estimate.Model),
+		zap.Int("prompt_tokens", estimate.PromptTokens),
+		zap.Int("context_window", estimate.ContextWindow))
+	return fmt.Errorf("输入约%d个token，超出模型 %s 的上下文窗口（%d），请精简提示词或历史消息",
+		estimate.PromptTokens, estimate.Model, estimate.ContextWindow)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"你好世界", 4},
+		{"hello world!", 3},
+		{"写一首诗 about spring", 4 + 4},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     []string
+	}{
+		{models.EncodingCL100K, "Hello world", []string{"Hello", " world"}},
+		{models.EncodingCL100K, "I'm  fine", []string{"I", "'m", " ", " fine"}},
+		{models.EncodingCL100K, "x  'a", []string{"x", " ", " '", "a"}},
+		{models.EncodingCL100K, "12345 abc\n\n  end  ", []string{"123", "45", " abc", "\n\n", " ", " end", "  "}},
+		{models.EncodingCL100K, "你好，世界", []string{"你好", "，世界"}},
+		{models.EncodingO200K, "HelloWorld's a/b", []string{"Hello", "World's", " a", "/b"}},
+	}
+	for _, tt := range tests {
+		if got := splitPieces(bpePatterns[tt.encoding], tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPieces(%s, %q) = %q, want %q", tt.encoding, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBytePairMerge(t *testing.T) {
+	ranks := map[string]int{"a": 0, "b": 1, "c": 2, "ab": 3, "bc": 4, "abc": 5, "cab": 6}
+	tests := []struct {
+		piece string
+		want  int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abc", 1},   // ab -> abc
+		{"abcab", 2}, // ab,c,ab -> abc,ab
+		{"cba", 3},   // 没有可合并的字节对
+	}
+	for _, tt := range tests {
+		if got := bytePairMerge(tt.piece, ranks); got != tt.want {
+			t.Errorf("bytePairMerge(%q) = %d, want %d", tt.piece, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBPEEncoding(t *testing.T) {
+	dir := t.TempDir()
+	var b strings.Builder
+	for i, token := range []string{"H", "e", "l", "o", " ", "w", "r", "d", "He", "ll", "llo", "Hello", " w", "or", " wor"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, models.EncodingCL100K+".tiktoken"), []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := loadBPEEncoding(dir, models.EncodingCL100K)
+	if err != nil {
+		t.Fatalf("loadBPEEncoding() error = %v", err)
+	}
+	// Hello | " wor" + "l" + "d"
+	if got := enc.count("Hello world"); got != 4 {
+		t.Errorf("count() = %d, want 4", got)
+	}
+
+	if _, err := loadBPEEncoding(dir, models.EncodingO200K); err == nil {
+		t.Error("loadBPEEncoding() expected error for missing file")
+	}
+	if _, err := loadBPEEncoding(dir, "p50k_base"); err == nil {
+		t.Error("loadBPEEncoding() expected error for unsupported encoding")
+	}
+	if _, err := parseBPERanks(strings.NewReader("SGVsbG8=\n")); err == nil {
+		t.Error("parseBPERanks() expected error for malformed line")
+	}
+}
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model  string
+		prefix string
+	}{
+		{"gpt-4o-mini", "gpt-4o"},
+		{"GPT-4-Turbo-2024-04-09", "gpt-4-turbo"},
+		{"openai/gpt-4o", "gpt-4o"},
+		{"claude-3-5-sonnet-20241022", "claude-3-5"},
+		{"qwen2.5:7b", "qwen2"},
+	}
+	for _, tt := range tests {
+		info, ok := models.LookupModel(tt.model, nil)
+		if !ok || info.Prefix != tt.prefix {
+			t.Errorf("LookupModel(%q) = %+v, %v, want prefix %q", tt.model, info, ok, tt.prefix)
+		}
+	}
+	if _, ok := models.LookupModel("my-finetune", nil); ok {
+		t.Error("LookupModel() expected unknown model")
+	}
+
+	extra := []models.ModelInfo{{Prefix: "gpt-4o", ContextWindow: 1000}}
+	if info, _ := models.LookupModel("gpt-4o-mini", extra); info.ContextWindow != 1000 {
+		t.Errorf("LookupModel() should prefer extra catalog, got %+v", info)
+	}
+}
+
+func TestEstimateGenerateTokens(t *testing.T) {
+	provider := &models.APIProvider{APIKind: models.APIKindDeepSeek, APIModel: "deepseek-chat"}
+
+	// 未指定 max_tokens 时使用默认值，未超出模型限制时不调整
+	estimate := EstimateGenerateTokens(provider, &GenerateRequest{Prompt: "你好"})
+	if !estimate.KnownModel || estimate.Exact || estimate.ContextWindow != 65536 || estimate.MaxTokens != 2000 || estimate.Clamped {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+	if estimate.PromptTokens != 2+tokensPerMessage+tokensReplyPrime {
+		t.Errorf("PromptTokens = %d", estimate.PromptTokens)
+	}
+
+	// 超出模型最大输出
+	estimate = EstimateGenerateTokens(provider, &GenerateRequest{Prompt: "你好", MaxTokens: 20000})
+	if estimate.MaxTokens != 8192 || !estimate.Clamped || estimate.RequestedMaxTokens != 20000 {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+
+	// 超出剩余上下文（Ollama 以 num_ctx 为准）
+	ollama := &models.APIProvider{APIKind: models.APIKindOllama, APIModel: "llama3.1:8b"}
+	estimate = EstimateGenerateTokens(ollama, &GenerateRequest{Prompt: strings.Repeat("字", 900), NumCtx: 1024})
+	if estimate.ContextWindow != 1024 || estimate.MaxTokens != estimate.Remaining || estimate.Remaining != 1024-estimate.PromptTokens {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+
+	// 输入超出上下文窗口
+	req := &GenerateRequest{Prompt: strings.Repeat("字", 2000), NumCtx: 1024}
+	if estimate = EstimateGenerateTokens(ollama, req); !estimate.Exceeded || estimate.Remaining != 0 {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+	if err := checkContextWindow(ollama, req); err == nil {
+		t.Error("checkContextWindow() expected error")
+	}
+
+	// 未知模型不调整
+	unknown := &models.APIProvider{APIKind: models.APIKindDeepSeek, APIModel: "my-finetune"}
+	estimate = EstimateGenerateTokens(unknown, &GenerateRequest{Prompt: "hi", MaxTokens: 100000})
+	if estimate.KnownModel || estimate.Clamped || estimate.MaxTokens != 100000 || estimate.ContextWindow != 0 {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 回收站项目类型
+const (
+	TrashTypeTemplate    = "template"
+	TrashTypeAPIProvider = "api_provider"
+)
+
+// 回收站相关错误
+var (
+	ErrTrashItemNotFound = errors.New("回收站中不存在该项目")
+	ErrTrashType         = errors.New("回收站项目类型仅支持template、api_provider")
+)
+
+// TrashItem 回收站中的项目
+type TrashItem struct {
+	Type      string     `json:"type"` // template / api_provider
+	ID        uint64     `json:"id"`
+	Name      string     `json:"name"` // 模板主题或Provider名称
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"` // 到期后自动彻底删除，未开启自动清理时为空
+}
+
+// trashRetention 回收站保留时长，为 0 表示不自动清理
+func trashRetention() time.Duration {
+	return time.Duration(max(config.GetConfig().Trash.RetentionDays, 0)) * 24 * time.Hour
+}
+
+// newTrashItem 生成回收站项目，按保留时长计算自动清理时间
+func newTrashItem(itemType string, id uint64, name string, deletedAt time.Time, retention time.Duration) TrashItem {
+	item := TrashItem{Type: itemType, ID: id, Name: name, DeletedAt: deletedAt}
+	if retention > 0 {
+		purgeAt := deletedAt.Add(retention)
+		item.PurgeAt = &purgeAt
+	}
+	return item
+}
+
+// ListTrash 分页查询回收站，itemType 为空时同时列出模板与API Provider，按删除时间倒序
+func ListTrash(userMobile, itemType string, page, pageSize int) ([]TrashItem, int64, error) {
+	if itemType != "" && itemType != TrashTypeTemplate && itemType != TrashTypeAPIProvider {
+		return nil, 0, ErrTrashType
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	retention := trashRetention()
+	items := []TrashItem{}
+	if itemType != TrashTypeAPIProvider {
+		var templates []models.Template
+		if err := config.DB.Unscoped().Select("id", "topic", "deleted_at").
+			Where("mobile = ? AND deleted_at IS NOT NULL", userMobile).Find(&templates).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, t := range templates {
+			items = append(items, newTrashItem(TrashTypeTemplate, t.ID, t.Topic, t.DeletedAt.Time, retention))
+		}
+	}
+	if itemType != TrashTypeTemplate {
+		var providers []models.APIProvider
+		if err := config.GetDB().Unscoped().Select("id", "name", "deleted_at").
+			Where("mobile = ? AND deleted_at IS NOT NULL", userMobile).Find(&providers).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, p := range providers {
+			items = append(items, newTrashItem(TrashTypeAPIProvider, uint64(p.ID), p.Name, p.DeletedAt.Time, retention))
+		}
+	}
+
+	return pageTrashItems(items, page, pageSize), int64(len(items)), nil
+}
+
+// pageTrashItems 按删除时间倒序排列后返回指定页
+func pageTrashItems(items []TrashItem, page, pageSize int) []TrashItem {
+	slices.SortStableFunc(items, func(a, b TrashItem) int { return b.DeletedAt.Compare(a.DeletedAt) })
+	start := min((page-1)*pageSize, len(items))
+	end := min(start+pageSize, len(items))
+	return items[start:end]
+}
+
+// getTrashedTemplate 查询用户回收站中的模板
+func getTrashedTemplate(userMobile string, templateID uint64) (*models.Template, error) {
+	var template models.Template
+	if err := config.DB.Unscoped().Where("id = ? AND mobile = ? AND deleted_at IS NOT NULL", templateID, userMobile).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// RestoreTemplate 从回收站恢复模板
+func (s *TemplateService) RestoreTemplate(userMobile string, templateID uint64) (*models.Template, error) {
+	template, err := getTrashedTemplate(userMobile, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.DB.Unscoped().Model(template).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("恢复失败: %w", err)
+	}
+	template.DeletedAt = gorm.DeletedAt{}
+	templateSearch().Index(template)
+	return template, nil
+}
+
+// PurgeTemplate 彻底删除回收站中的模板
+func (s *TemplateService) PurgeTemplate(userMobile string, templateID uint64) error {
+	template, err := getTrashedTemplate(userMobile, templateID)
+	if err != nil {
+		return err
+	}
+	return purgeTemplate(template)
+}
+
+// purgeTemplate 彻底删除模板及修订历史、共享记录、标签关联、向量与单独的输出过滤设置
+// 评测、批量生成、用量与任务记录保留，但不再引用该模板
+func purgeTemplate(template *models.Template) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, related := range []interface{}{
+			&models.TemplateRevision{}, &models.TemplateShare{}, &models.TemplateTagLink{}, &models.TemplateEmbedding{},
+		} {
+			if err := tx.Where("template_id = ?", template.ID).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+		for _, ref := range []struct {
+			model interface{}
+			value interface{}
+		}{
+			{&models.Evaluation{}, nil},
+			{&models.BatchRow{}, nil},
+			{&models.GenerationUsage{}, 0}, // 0表示未指定模板
+		} {
+			if err := tx.Model(ref.model).Where("template_id = ?", template.ID).Update("template_id", ref.value).Error; err != nil {
+				return err
+			}
+		}
+		if err := clearJobTemplateID(tx, template.ID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(template).Error
+	})
+	if err != nil {
+		return fmt.Errorf("删除失败: %w", err)
+	}
+	templateSearch().Remove(template.ID)
+
+	if err := config.DB.Where("mobile = ? AND template_id = ?", template.Mobile, template.ID).Delete(&models.OutputFilterSetting{}).Error; err != nil {
+		utils.Warn("删除模板输出过滤设置失败", zap.Uint64("template_id", template.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// clearJobTemplateID 从任务保存的生成请求中移除指定的模板ID
+// 模板ID只存在于请求JSON中，先按文本粗略筛选，再解析确认顶层的 template_id
+func clearJobTemplateID(tx *gorm.DB, templateID uint64) error {
+	id := strconv.FormatUint(templateID, 10)
+	var jobs []models.Job
+	if err := tx.Select("id", "request").
+		Where("request LIKE ? OR request LIKE ?", `%"template_id":`+id+`,%`, `%"template_id":`+id+`}%`).
+		Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		var request map[string]json.RawMessage
+		if err := json.Unmarshal(job.Request, &request); err != nil {
+			utils.Warn("解析任务请求失败", zap.Uint("job_id", job.ID), zap.Error(err))
+			continue
+		}
+		if string(request["template_id"]) != id {
+			continue
+		}
+		delete(request, "template_id")
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Job{}).Where("id = ?", job.ID).Update("request", data).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getTrashedAPIProvider 查询用户回收站中的API Provider
+func getTrashedAPIProvider(userMobile string, providerID uint) (*models.APIProvider, error) {
+	var provider models.APIProvider
+	if err := config.GetDB().Unscoped().Where("id = ? AND mobile = ? AND deleted_at IS NOT NULL", providerID, userMobile).First(&provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// RestoreAPIProvider 从回收站恢复API Provider
+func RestoreAPIProvider(userMobile string, providerID uint) (*models.APIProviderResponse, error) {
+	provider, err := getTrashedAPIProvider(userMobile, providerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.GetDB().Unscoped().Model(provider).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("恢复失败: %w", err)
+	}
+	provider.DeletedAt = gorm.DeletedAt{}
+	return provider.ToResponse(), nil
+}
+
+// PurgeAPIProvider 彻底删除回收站中的API Provider
+func PurgeAPIProvider(userMobile string, providerID uint) error {
+	provider, err := getTrashedAPIProvider(userMobile, providerID)
+	if err != nil {
+		return err
+	}
+	return purgeAPIProvider(provider)
+}
+
+// purgeAPIProvider 彻底删除API Provider及其模型价格
+func purgeAPIProvider(provider *models.APIProvider) error {
+	return config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", provider.ID).Delete(&models.ModelPrice{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(provider).Error
+	})
+}
+
+// PurgeExpiredTrash 彻底删除在回收站中超过保留期的模板与API Provider，返回删除的数量
+func PurgeExpiredTrash() (int, error) {
+	retention := trashRetention()
+	if retention <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-retention)
+
+	var templates []models.Template
+	if err := config.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&templates).Error; err != nil {
+		return 0, err
+	}
+	var providers []models.APIProvider
+	if err := config.GetDB().Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&providers).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range templates {
+		if err := purgeTemplate(&templates[i]); err != nil {
+			utils.Warn("清理回收站模板失败", zap.Uint64("template_id", templates[i].ID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	for i := range providers {
+		if err := purgeAPIProvider(&providers[i]); err != nil {
+			utils.Warn("清理回收站API Provider失败", zap.Uint("provider_id", providers[i].ID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// trashPurger 定期清理回收站的后台任务
+var trashPurger struct {
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// StartTrashPurger 启动回收站定期清理，启动时立即清理一次
+func StartTrashPurger(cfg config.TrashConfig) {
+	if cfg.RetentionDays <= 0 {
+		utils.Info("回收站自动清理未开启")
+		return
+	}
+	interval := time.Duration(max(cfg.PurgeInterval, 1)) * time.Minute
+	ctx, stop := context.WithCancel(context.Background())
+	trashPurger.stop = stop
+	trashPurger.wg.Add(1)
+	go func() {
+		defer trashPurger.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if purged, err := PurgeExpiredTrash(); err != nil {
+				utils.Error("清理回收站失败", zap.Error(err))
+			} else if purged > 0 {
+				utils.Info("已清理回收站过期项目", zap.Int("count", purged))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	utils.Info("回收站自动清理已启动", zap.Int("retention_days", cfg.RetentionDays), zap.Duration("interval", interval))
+}
+
+// StopTrashPurger 停止回收站定期清理
+func StopTrashPurger() {
+	if trashPurger.stop == nil {
+		return
+	}
+	trashPurger.stop()
+	trashPurger.stop = nil
+	trashPurger.wg.Wait()
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestNewTrashItem(t *testing.T) {
+	deletedAt := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	item := newTrashItem(TrashTypeTemplate, 1, "周报助手", deletedAt, 30*24*time.Hour)
+	if item.PurgeAt == nil || !item.PurgeAt.Equal(deletedAt.AddDate(0, 0, 30)) {
+		t.Errorf("newTrashItem() PurgeAt = %v", item.PurgeAt)
+	}
+	if item := newTrashItem(TrashTypeAPIProvider, 2, "OpenAI", deletedAt, 0); item.PurgeAt != nil {
+		t.Errorf("newTrashItem(no retention) PurgeAt = %v, want nil", item.PurgeAt)
+	}
+}
+
+func TestPageTrashItems(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []TrashItem{
+		{Type: TrashTypeTemplate, ID: 1, DeletedAt: base},
+		{Type: TrashTypeAPIProvider, ID: 2, DeletedAt: base.Add(2 * time.Hour)},
+		{Type: TrashTypeTemplate, ID: 3, DeletedAt: base.Add(time.Hour)},
+	}
+
+	page := pageTrashItems(items, 1, 2)
+	if len(page) != 2 || page[0].ID != 2 || page[1].ID != 3 {
+		t.Errorf("pageTrashItems(page 1) = %+v", page)
+	}
+	if page := pageTrashItems(items, 2, 2); len(page) != 1 || page[0].ID != 1 {
+		t.Errorf("pageTrashItems(page 2) = %+v", page)
+	}
+	if page := pageTrashItems(items, 3, 2); len(page) != 0 {
+		t.Errorf("pageTrashItems(page 3) = %+v", page)
+	}
+}
+
+func TestListTrashType(t *testing.T) {
+	if _, _, err := ListTrash("13800138000", "folder", 1, 10); err != ErrTrashType {
+		t.Errorf("ListTrash(folder) error = %v, want ErrTrashType", err)
+	}
+}
+
+func TestPurgeTemplateClearsReferences(t *testing.T) {
+	setupAPIProviderTest(t)
+	db := config.GetDB()
+
+	testMobile := "13900139050"
+	user := &models.User{Mobile: testMobile, Password: "x"}
+	db.Create(user)
+	defer db.Delete(user)
+
+	template := &models.Template{Mobile: testMobile, Topic: "待彻底删除的模板"}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatalf("create template error = %v", err)
+	}
+	db.Delete(template) // 移入回收站
+
+	evaluation := &models.Evaluation{Mobile: testMobile, TemplateID: &template.ID, Prompt: "评测"}
+	db.Create(evaluation)
+	defer db.Select("Results").Delete(evaluation)
+
+	batch := &models.Batch{Mobile: testMobile, Format: "csv", Status: models.BatchStatusCompleted,
+		Rows: []models.BatchRow{{RowNo: 1, Topic: "周报", Status: models.BatchStatusCompleted, TemplateID: &template.ID}}}
+	db.Create(batch)
+	defer db.Select("Rows").Delete(batch)
+
+	usage := &models.GenerationUsage{Mobile: testMobile, Model: "gpt-4o", TemplateID: template.ID}
+	db.Create(usage)
+	defer db.Delete(usage)
+
+	request, _ := json.Marshal(GenerateRequest{Prompt: "生成", TemplateID: template.ID, User: testMobile})
+	job := &models.Job{Mobile: testMobile, Status: models.JobStatusCompleted, Request: request}
+	db.Create(job)
+	defer db.Delete(job)
+
+	if err := (&TemplateService{}).PurgeTemplate(testMobile, template.ID); err != nil {
+		t.Fatalf("PurgeTemplate() error = %v", err)
+	}
+
+	if _, total, err := ListEvaluations(testMobile, template.ID, 1, 10); err != nil || total != 0 {
+		t.Errorf("ListEvaluations(purged template) total = %d, err = %v, want 0", total, err)
+	}
+	if got, err := GetEvaluation(testMobile, evaluation.ID); err != nil || got.TemplateID != nil {
+		t.Errorf("GetEvaluation() = %+v, err = %v, want template_id cleared", got, err)
+	}
+
+	if got, err := GetBatch(testMobile, batch.ID); err != nil || len(got.Rows) != 1 || got.Rows[0].TemplateID != nil {
+		t.Errorf("GetBatch() rows = %+v, err = %v, want template_id cleared", got.Rows, err)
+	}
+
+	report, err := GetSpendReport(testMobile, SpendReportQuery{GroupBy: SpendGroupTemplate})
+	if err != nil {
+		t.Fatalf("GetSpendReport() error = %v", err)
+	}
+	for _, row := range report.Rows {
+		if row.TemplateID == template.ID {
+			t.Errorf("GetSpendReport() still groups by purged template: %+v", row)
+		}
+	}
+
+	got, err := GetJob(testMobile, job.ID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	var req GenerateRequest
+	if err := json.Unmarshal(got.Request, &req); err != nil || req.TemplateID != 0 || req.Prompt != "生成" {
+		t.Errorf("job request = %s, want template_id removed", got.Request)
+	}
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxModelPrices 每个Provider最多设置的价格条目数
+const maxModelPrices = 100
+
+// maxSpendReportDays 费用报告最大查询天数
+const maxSpendReportDays = 366
+
+// spendDateLayout 费用报告的日期格式
+const spendDateLayout = "2006-01-02"
+
+// 费用报告的分组维度
+const (
+	SpendGroupDay      = "day"      // 按日期
+	SpendGroupProvider = "provider" // 按API Provider
+	SpendGroupModel    = "model"    // 按模型
+	SpendGroupTemplate = "template" // 按模板
+)
+
+// SpendGroups 支持的分组维度，未指定时按全部维度分组
+var SpendGroups = []string{SpendGroupDay, SpendGroupProvider, SpendGroupModel, SpendGroupTemplate}
+
+// spendGroupColumns 分组维度对应的查询列与分组列
+var spendGroupColumns = map[string][2]string{
+	SpendGroupDay:      {"DATE_FORMAT(created_at, '%Y-%m-%d') AS day", "day"},
+	SpendGroupProvider: {"provider_id", "provider_id"},
+	SpendGroupModel:    {"model", "model"},
+	SpendGroupTemplate: {"template_id", "template_id"},
+}
+
+// ErrSpendReportFormat 不支持的报告格式
+var ErrSpendReportFormat = errors.New("不支持的报告格式，仅支持 json 或 csv")
+
+// ModelPriceRequest 模型价格设置
+type ModelPriceRequest struct {
+	Model       string  `json:"model"`        // 模型名称前缀，为空表示默认价格
+	InputPrice  float64 `json:"input_price"`  // 输入价格（每1K token）
+	OutputPrice float64 `json:"output_price"` // 输出价格（每1K token）
+}
+
+// ListModelPrices 获取Provider的价格表，调用方需先确认Provider属于当前用户
+func ListModelPrices(providerID uint) ([]models.ModelPrice, error) {
+	var prices []models.ModelPrice
+	if err := config.GetDB().Where("provider_id = ?", providerID).Order("model").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// SaveModelPrices 以整表替换的方式保存Provider的价格表，调用方需先确认Provider属于当前用户
+func SaveModelPrices(providerID uint, reqs []ModelPriceRequest) ([]models.ModelPrice, error) {
+	prices, err := normalizeModelPrices(providerID, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", providerID).Delete(&models.ModelPrice{}).Error; err != nil {
+			return err
+		}
+		if len(prices) == 0 {
+			return nil
+		}
+		return tx.Create(&prices).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utils.Info("保存模型价格成功", zap.Uint("provider_id", providerID), zap.Int("count", len(prices)))
+	return prices, nil
+}
+
+// normalizeModelPrices 校验价格表：模型前缀不能重复，价格不能为负数
+func normalizeModelPrices(providerID uint, reqs []ModelPriceRequest) ([]models.ModelPrice, error) {
+	if len(reqs) > maxModelPrices {
+		return nil, fmt.Errorf("价格条目不能超过%d个", maxModelPrices)
+	}
+
+	prices := make([]models.ModelPrice, 0, len(reqs))
+	seen := make(map[string]bool)
+	for _, r := range reqs {
+		model := strings.ToLower(strings.TrimSpace(r.Model))
+		if utf8.RuneCountInString(model) > 100 {
+			return nil, fmt.Errorf("模型名称不能超过100个字符: %s", model)
+		}
+		if seen[model] {
+			return nil, fmt.Errorf("模型价格重复: %s", model)
+		}
+		if r.InputPrice < 0 || r.OutputPrice < 0 || math.IsNaN(r.InputPrice) || math.IsNaN(r.OutputPrice) {
+			return nil, fmt.Errorf("模型价格不能为负数: %s", model)
+		}
+		seen[model] = true
+		prices = append(prices, models.ModelPrice{
+			ProviderID:  providerID,
+			Model:       model,
+			InputPrice:  r.InputPrice,
+			OutputPrice: r.OutputPrice,
+		})
+	}
+	return prices, nil
+}
+
+// matchModelPrice 按模型名称最长前缀匹配价格，没有匹配时使用默认价格（模型为空的条目）
+func matchModelPrice(prices []models.ModelPrice, model string) *models.ModelPrice {
+	name := strings.ToLower(strings.TrimSpace(model))
+	var best *models.ModelPrice
+	for i := range prices {
+		if strings.HasPrefix(name, prices[i].Model) && (best == nil || len(prices[i].Model) > len(best.Model)) {
+			best = &prices[i]
+		}
+	}
+	return best
+}
+
+// computeCost 按每1K token的价格计算费用，保留6位小数
+func computeCost(promptTokens, completionTokens int, price *models.ModelPrice) float64 {
+	if price == nil {
+		return 0
+	}
+	cost := float64(promptTokens)/1000*price.InputPrice + float64(completionTokens)/1000*price.OutputPrice
+	return math.Round(cost*1e6) / 1e6
+}
+
+// RecordGenerationUsage 记录一次生成的token用量与费用
+// Provider未返回用量时按模型的token计算方式估算；记录失败只记日志，不影响生成结果
+func RecordGenerationUsage(provider *models.APIProvider, req *GenerateRequest, model string, result *GenerateResult) {
+	db := config.GetDB()
+	if db == nil {
+		return
+	}
+
+	// 评审等内部调用未填充用户时，费用记在Provider所属用户名下
+	mobile := req.User
+	if mobile == "" {
+		mobile = provider.Mobile
+	}
+	if mobile == "" {
+		return
+	}
+
+	record := models.GenerationUsage{
+		Mobile:     mobile,
+		ProviderID: provider.ID,
+		Model:      model,
+		TemplateID: req.TemplateID,
+	}
+	if result.Usage != nil && result.Usage.PromptTokens+result.Usage.CompletionTokens > 0 {
+		record.PromptTokens = result.Usage.PromptTokens
+		record.CompletionTokens = result.Usage.CompletionTokens
+	} else {
+		record.Estimated = true
+		record.PromptTokens = EstimateGenerateTokens(provider, req).PromptTokens
+		record.CompletionTokens, _ = CountTokens(model, result.Content)
+		for _, call := range result.ToolCalls {
+			n, _ := CountTokens(model, call.Function.Name+call.Function.Arguments)
+			record.CompletionTokens += n
+		}
+	}
+	record.TotalTokens = record.PromptTokens + record.CompletionTokens
+
+	var prices []models.ModelPrice
+	if err := db.Where("provider_id = ?", provider.ID).Find(&prices).Error; err != nil {
+		utils.Warn("查询模型价格失败", zap.Uint("provider_id", provider.ID), zap.Error(err))
+	}
+	if price := matchModelPrice(prices, model); price != nil {
+		record.InputPrice = price.InputPrice
+		record.OutputPrice = price.OutputPrice
+		record.Cost = computeCost(record.PromptTokens, record.CompletionTokens, price)
+	}
+
+	if err := db.Create(&record).Error; err != nil {
+		utils.Error("记录生成用量失败", zap.Uint("provider_id", provider.ID), zap.Error(err))
+	}
+}
+
+// usageSink 流式生成完成时记录用量
+type usageSink struct {
+	StreamSink
+	provider *models.APIProvider
+	req      *GenerateRequest
+	model    string
+}
+
+func (s *usageSink) Done(result *GenerateResult) {
+	RecordGenerationUsage(s.provider, s.req, s.model, result)
+	s.StreamSink.Done(result)
+}
+
+// SpendReportQuery 费用报告查询条件
+type SpendReportQuery struct {
+	StartDate string // 开始日期（含），默认30天前
+	EndDate   string // 结束日期（含），默认今天
+	GroupBy   string // 分组维度，逗号分隔：day/provider/model/template，默认全部
+}
+
+// SpendReportRow 费用报告中的一行，未参与分组的维度为空
+type SpendReportRow struct {
+	Day              string  `json:"day,omitempty"`
+	ProviderID       uint    `json:"provider_id,omitempty"`
+	ProviderName     string  `json:"provider_name,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	TemplateID       uint64  `json:"template_id,omitempty"`
+	TemplateTopic    string  `json:"template_topic,omitempty"`
+	Generations      int64   `json:"generations"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// SpendReport 费用报告
+type SpendReport struct {
+	StartDate string           `json:"start_date"`
+	EndDate   string           `json:"end_date"`
+	GroupBy   []string         `json:"group_by"`
+	Rows      []SpendReportRow `json:"rows"`
+	Total     SpendReportRow   `json:"total"`
+}
+
+// parseSpendGroups 解析分组维度，按 SpendGroups 的顺序返回
+func parseSpendGroups(groupBy string) ([]string, error) {
+	if strings.TrimSpace(groupBy) == "" {
+		return SpendGroups, nil
+	}
+	selected := make(map[string]bool)
+	for _, g := range strings.Split(groupBy, ",") {
+		g = strings.TrimSpace(g)
+		if _, ok := spendGroupColumns[g]; !ok {
+			return nil, fmt.Errorf("不支持的分组维度: %s", g)
+		}
+		selected[g] = true
+	}
+	var groups []string
+	for _, g := range SpendGroups {
+		if selected[g] {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// parseSpendDateRange 解析查询日期范围，返回 [start, end) 区间
+func parseSpendDateRange(startDate, endDate string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	end := today
+	if endDate != "" {
+		t, err := time.ParseInLocation(spendDateLayout, endDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("结束日期格式错误，应为 YYYY-MM-DD")
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -29)
+	if startDate != "" {
+		t, err := time.ParseInLocation(spendDateLayout, startDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("开始日期格式错误，应为 YYYY-MM-DD")
+		}
+		start = t
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, errors.New("开始日期不能晚于结束日期")
+	}
+	if end.Sub(start) >= maxSpendReportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("查询范围不能超过%d天", maxSpendReportDays)
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// GetSpendReport 按日期、Provider、模型、模板汇总用户的生成费用
+func GetSpendReport(userMobile string, q SpendReportQuery) (*SpendReport, error) {
+	groups, err := parseSpendGroups(q.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := parseSpendDateRange(q.StartDate, q.EndDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{
+		"COUNT(*) AS generations",
+		"SUM(prompt_tokens) AS prompt_tokens",
+		"SUM(completion_tokens) AS completion_tokens",
+		"SUM(total_tokens) AS total_tokens",
+		"SUM(cost) AS cost",
+	}
+	var groupColumns []string
+	for _, g := range groups {
+		columns = append(columns, spendGroupColumns[g][0])
+		groupColumns = append(groupColumns, spendGroupColumns[g][1])
+	}
+
+	query := config.GetDB().Model(&models.GenerationUsage{}).
+		Select(strings.Join(columns, ", ")).
+		Where("mobile = ? AND created_at >= ? AND created_at < ?", userMobile, start, end)
+	if len(groupColumns) > 0 {
+		query = query.Group(strings.Join(groupColumns, ", ")).Order(strings.Join(groupColumns, ", "))
+	}
+
+	var rows []SpendReportRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = []SpendReportRow{}
+	}
+	fillSpendReportNames(userMobile, rows)
+
+	report := &SpendReport{
+		StartDate: start.Format(spendDateLayout),
+		EndDate:   end.AddDate(0, 0, -1).Format(spendDateLayout),
+		GroupBy:   groups,
+		Rows:      rows,
+	}
+	for _, row := range rows {
+		report.Total.Generations += row.Generations
+		report.Total.PromptTokens += row.PromptTokens
+		report.Total.CompletionTokens += row.CompletionTokens
+		report.Total.TotalTokens += row.TotalTokens
+		report.Total.Cost += row.Cost
+	}
+	report.Total.Cost = math.Round(report.Total.Cost*1e6) / 1e6
+	return report, nil
+}
+
+// fillSpendReportNames 填充Provider名称与模板主题，已删除的显示为空
+func fillSpendReportNames(userMobile string, rows []SpendReportRow) {
+	var providerIDs []uint
+	var templateIDs []uint64
+	for _, row := range rows {
+		if row.ProviderID > 0 {
+			providerIDs = append(providerIDs, row.ProviderID)
+		}
+		if row.TemplateID > 0 {
+			templateIDs = append(templateIDs, row.TemplateID)
+		}
+	}
+
+	providerNames := make(map[uint]string)
+	if len(providerIDs) > 0 {
+		var providers []models.APIProvider
+		config.GetDB().Select("id", "name").Where("mobile = ? AND id IN ?", userMobile, providerIDs).Find(&providers)
+		for _, p := range providers {
+			providerNames[p.ID] = p.Name
+		}
+	}
+	templateTopics := make(map[uint64]string)
+	if len(templateIDs) > 0 {
+		var templates []models.Template
+		config.GetDB().Select("id", "topic").Where("mobile = ? AND id IN ?", userMobile, templateIDs).Find(&templates)
+		for _, t := range templates {
+			templateTopics[t.ID] = t.Topic
+		}
+	}
+
+	for i := range rows {
+		rows[i].ProviderName = providerNames[rows[i].ProviderID]
+		rows[i].TemplateTopic = templateTopics[rows[i].TemplateID]
+	}
+}
+
+// BuildSpendReportCSV 导出费用报告为CSV，返回文件内容与文件名
+// CSV 带 UTF-8 BOM 以便 Excel 正确识别中文，最后一行为合计
+func BuildSpendReportCSV(report *SpendReport) ([]byte, string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xef\xbb\xbf")
+	writer := csv.NewWriter(&buf)
+
+	var header []string
+	for _, g := range report.GroupBy {
+		switch g {
+		case SpendGroupDay:
+			header = append(header, "day")
+		case SpendGroupProvider:
+			header = append(header, "provider_id", "provider_name")
+		case SpendGroupModel:
+			header = append(header, "model")
+		case SpendGroupTemplate:
+			header = append(header, "template_id", "template_topic")
+		}
+	}
+	writer.Write(append(header, "generations", "prompt_tokens", "completion_tokens", "total_tokens", "cost"))
+
+	writeRow := func(row SpendReportRow, total bool) {
+		var record []string
+		for _, g := range report.GroupBy {
+			switch {
+			case total:
+				record = append(record, "")
+				if g == SpendGroupProvider || g == SpendGroupTemplate {
+					record = append(record, "")
+				}
+			case g == SpendGroupDay:
+				record = append(record, row.Day)
+			case g == SpendGroupProvider:
+				record = append(record, strconv.FormatUint(uint64(row.ProviderID), 10), row.ProviderName)
+			case g == SpendGroupModel:
+				record = append(record, row.Model)
+			case g == SpendGroupTemplate:
+				record = append(record, strconv.FormatUint(row.TemplateID, 10), row.TemplateTopic)
+			}
+		}
+		if total && len(record) > 0 {
+			record[0] = "合计"
+		}
+		writer.Write(append(record,
+			strconv.FormatInt(row.Generations, 10),
+			strconv.FormatInt(row.PromptTokens, 10),
+			strconv.FormatInt(row.CompletionTokens, 10),
+			strconv.FormatInt(row.TotalTokens, 10),
+			strconv.FormatFloat(row.Cost, 'f', 6, 64)))
+	}
+	for _, row := range report.Rows {
+		writeRow(row, false)
+	}
+	writeRow(report.Total, true)
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), fmt.Sprintf("spend-%s-%s.csv", report.StartDate, report.EndDate), nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestNormalizeModelPrices(t *testing.T) {
+	prices, err := normalizeModelPrices(3, []ModelPriceRequest{
+		{Model: " GPT-4o ", InputPrice: 0.0025, OutputPrice: 0.01},
+		{Model: "", InputPrice: 0.001, OutputPrice: 0.002},
+	})
+	if err != nil || len(prices) != 2 || prices[0].Model != "gpt-4o" || prices[0].ProviderID != 3 {
+		t.Errorf("normalizeModelPrices() = %+v, %v", prices, err)
+	}
+
+	invalid := [][]ModelPriceRequest{
+		{{Model: "gpt-4o"}, {Model: "GPT-4o"}},
+		{{Model: "gpt-4o", InputPrice: -1}},
+		{{Model: strings.Repeat("m", 101)}},
+	}
+	for _, reqs := range invalid {
+		if _, err := normalizeModelPrices(3, reqs); err == nil {
+			t.Errorf("normalizeModelPrices(%+v) expected error", reqs)
+		}
+	}
+}
+
+func TestMatchModelPrice(t *testing.T) {
+	prices := []models.ModelPrice{
+		{Model: "", InputPrice: 1},
+		{Model: "gpt-4o", InputPrice: 2},
+		{Model: "gpt-4o-mini", InputPrice: 3},
+	}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"gpt-4o-mini-2024-07-18", 3},
+		{"GPT-4o", 2},
+		{"deepseek-chat", 1},
+	}
+	for _, tt := range tests {
+		if got := matchModelPrice(prices, tt.model); got == nil || got.InputPrice != tt.want {
+			t.Errorf("matchModelPrice(%q) = %+v, want input price %v", tt.model, got, tt.want)
+		}
+	}
+	if got := matchModelPrice(prices[1:], "deepseek-chat"); got != nil {
+		t.Errorf("matchModelPrice() = %+v, want nil without default price", got)
+	}
+}
+
+func TestComputeCost(t *testing.T) {
+	price := &models.ModelPrice{InputPrice: 0.0025, OutputPrice: 0.01}
+	if got := computeCost(1200, 300, price); got != 0.006 {
+		t.Errorf("computeCost() = %v, want 0.006", got)
+	}
+	if got := computeCost(1200, 300, nil); got != 0 {
+		t.Errorf("computeCost(nil) = %v, want 0", got)
+	}
+}
+
+func TestParseSpendGroups(t *testing.T) {
+	if got, err := parseSpendGroups(""); err != nil || !reflect.DeepEqual(got, SpendGroups) {
+		t.Errorf("parseSpendGroups(\"\") = %v, %v", got, err)
+	}
+	if got, err := parseSpendGroups("model, day"); err != nil || !reflect.DeepEqual(got, []string{SpendGroupDay, SpendGroupModel}) {
+		t.Errorf("parseSpendGroups() = %v, %v", got, err)
+	}
+	if _, err := parseSpendGroups("user"); err == nil {
+		t.Error("parseSpendGroups() expected error for unknown group")
+	}
+}
+
+func TestParseSpendDateRange(t *testing.T) {
+	now := time.Date(2025, 10, 31, 15, 4, 5, 0, time.Local)
+
+	start, end, err := parseSpendDateRange("", "", now)
+	if err != nil || start.Format(spendDateLayout) != "2025-10-02" || end.Format(spendDateLayout) != "2025-11-01" {
+		t.Errorf("parseSpendDateRange() = %v, %v, %v", start, end, err)
+	}
+
+	start, end, err = parseSpendDateRange("2025-10-01", "2025-10-01", now)
+	if err != nil || end.Sub(start) != 24*time.Hour {
+		t.Errorf("parseSpendDateRange() = %v, %v, %v", start, end, err)
+	}
+
+	for _, r := range [][2]string{{"2025/10/01", ""}, {"2025-10-02", "2025-10-01"}, {"2024-01-01", "2025-10-01"}} {
+		if _, _, err := parseSpendDateRange(r[0], r[1], now); err == nil {
+			t.Errorf("parseSpendDateRange(%q, %q) expected error", r[0], r[1])
+		}
+	}
+}
+
+func TestBuildSpendReportCSV(t *testing.T) {
+	report := &SpendReport{
+		StartDate: "2025-10-01",
+		EndDate:   "2025-10-31",
+		GroupBy:   []string{SpendGroupDay, SpendGroupProvider},
+		Rows: []SpendReportRow{
+			{Day: "2025-10-01", ProviderID: 1, ProviderName: "OpenAI", Generations: 2, PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, Cost: 0.5},
+		},
+		Total: SpendReportRow{Generations: 2, PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, Cost: 0.5},
+	}
+	data, fileName, err := BuildSpendReportCSV(report)
+	if err != nil || fileName != "spend-2025-10-01-2025-10-31.csv" {
+		t.Fatalf("BuildSpendReportCSV() = %q, %v", fileName, err)
+	}
+	want := "\xef\xbb\xbfday,provider_id,provider_name,generations,prompt_tokens,completion_tokens,total_tokens,cost\n" +
+		"2025-10-01,1,OpenAI,2,100,50,150,0.500000\n" +
+		"合计,,,2,100,50,150,0.500000\n"
+	if string(data) != want {
+		t.Errorf("BuildSpendReportCSV() = %q, want %q", data, want)
+	}
+}
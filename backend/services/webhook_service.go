@@ -0,0 +1,724 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+	"github.com/zsy619/cese-qoder/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Webhook 请求头
+const (
+	WebhookHeaderEvent     = "X-CESE-Event"
+	WebhookHeaderDelivery  = "X-CESE-Delivery"
+	WebhookHeaderSignature = "X-CESE-Signature-256"
+)
+
+// webhookPollInterval 扫描待重试投递记录的间隔
+const webhookPollInterval = 5 * time.Second
+
+// webhookMaxBackoff 重试间隔上限
+const webhookMaxBackoff = time.Hour
+
+// webhookMaxResponseBody 投递记录中保存的响应内容最大字节数
+const webhookMaxResponseBody = 2048
+
+// maxWebhookSecretLength 签名密钥最大长度
+const maxWebhookSecretLength = 100
+
+// Webhook相关错误
+var (
+	ErrWebhookNotFound         = errors.New("Webhook不存在")
+	ErrWebhookDeliveryNotFound = errors.New("投递记录不存在")
+)
+
+// WebhookRequest 创建或更新Webhook请求
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"` // 创建时为空则自动生成；更新时为空表示不修改
+	Events      []string `json:"events"`
+	Status      *int8    `json:"status"` // 仅更新时有效
+	Description string   `json:"description"`
+}
+
+// WebhookPayload 投递的事件内容
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// GenerationEventData 生成完成或失败事件的数据
+type GenerationEventData struct {
+	Source       string     `json:"source"` // generate：生成接口；job：异步任务
+	JobID        uint       `json:"job_id,omitempty"`
+	ProviderID   uint       `json:"provider_id"`
+	Content      string     `json:"content,omitempty"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	Usage        *Usage     `json:"usage,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// errWebhookPrivateAddress 回调地址指向本机或内网
+var errWebhookPrivateAddress = errors.New("回调地址不能指向本机或内网地址")
+
+// lookupWebhookHost 解析回调地址的主机名，测试中可替换
+var lookupWebhookHost = net.DefaultResolver.LookupIPAddr
+
+// validateWebhookURL 校验回调地址，未开启 allow_private_network 时拒绝解析到本机或内网的地址
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("回调地址必须是有效的HTTP(S)地址")
+	}
+	if len(rawURL) > 500 {
+		return errors.New("回调地址不能超过500个字符")
+	}
+	if config.GetConfig().Webhook.AllowPrivateNetwork {
+		return nil
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if isBlockedWebhookIP(ip) {
+			return errWebhookPrivateAddress
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := lookupWebhookHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("回调地址无法解析: %s", host)
+	}
+	for _, addr := range addrs {
+		if isBlockedWebhookIP(addr.IP) {
+			return errWebhookPrivateAddress
+		}
+	}
+	return nil
+}
+
+// isBlockedWebhookIP 判断是否为不允许投递的地址：回环、私有、链路本地、组播与未指定地址
+func isBlockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// webhookDialControl 建立连接前检查实际连接的地址，避免注册后通过DNS重绑定访问内网
+func webhookDialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || isBlockedWebhookIP(ip) {
+		return errWebhookPrivateAddress
+	}
+	return nil
+}
+
+// newWebhookClient 创建投递使用的HTTP客户端
+// 未开启 allow_private_network 时在拨号阶段拒绝内网地址；不使用环境变量中的代理，否则检查的将是代理地址
+func newWebhookClient(cfg config.WebhookConfig) *http.Client {
+	timeout := time.Duration(max(cfg.Timeout, 1)) * time.Second
+	if cfg.AllowPrivateNetwork {
+		return &http.Client{Timeout: timeout}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   webhookDialControl,
+	}).DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// normalizeWebhookEvents 校验并去重订阅的事件，按 models.WebhookEvents 的顺序返回
+func normalizeWebhookEvents(events []string) (string, error) {
+	selected := make(map[string]bool, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		valid := false
+		for _, e := range models.WebhookEvents {
+			if e == event {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("不支持的事件类型: %s，可选: %s", event, strings.Join(models.WebhookEvents, ", "))
+		}
+		selected[event] = true
+	}
+	if len(selected) == 0 {
+		return "", errors.New("请选择订阅的事件")
+	}
+
+	var result []string
+	for _, e := range models.WebhookEvents {
+		if selected[e] {
+			result = append(result, e)
+		}
+	}
+	return strings.Join(result, ","), nil
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// encryptWebhookSecret 加密签名密钥
+func encryptWebhookSecret(secret string) (string, error) {
+	key, err := getOrCreateEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return utils.EncryptData(secret, key)
+}
+
+// decryptWebhookSecret 解密签名密钥
+func decryptWebhookSecret(webhook *models.Webhook) (string, error) {
+	key, err := getOrCreateEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return utils.DecryptData(webhook.Secret, key)
+}
+
+// signWebhookPayload 计算投递内容的 HMAC-SHA256 签名
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook 注册Webhook，返回的响应包含签名密钥明文
+func CreateWebhook(userMobile string, req *WebhookRequest) (*models.WebhookResponse, error) {
+	req.URL = strings.TrimSpace(req.URL)
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" {
+		secret = "whsec_" + randomHex(24)
+	}
+	if len(secret) > maxWebhookSecretLength {
+		return nil, fmt.Errorf("签名密钥不能超过%d个字符", maxWebhookSecretLength)
+	}
+	encrypted, err := encryptWebhookSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		Mobile:      userMobile,
+		URL:         req.URL,
+		Secret:      encrypted,
+		Events:      events,
+		Status:      1, // 默认启用
+		Description: req.Description,
+	}
+	if err := config.GetDB().Create(webhook).Error; err != nil {
+		return nil, err
+	}
+
+	resp := webhook.ToResponse()
+	resp.Secret = secret
+	return resp, nil
+}
+
+// GetWebhook 获取用户的Webhook
+func GetWebhook(userMobile string, webhookID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := config.GetDB().Where("id = ? AND mobile = ?", webhookID, userMobile).First(&webhook).Error; err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks 获取用户的全部Webhook
+func ListWebhooks(userMobile string) ([]*models.WebhookResponse, error) {
+	var webhooks []models.Webhook
+	if err := config.GetDB().Where("mobile = ?", userMobile).Order("id DESC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		result = append(result, webhooks[i].ToResponse())
+	}
+	return result, nil
+}
+
+// UpdateWebhook 更新Webhook，指定新的签名密钥时响应包含密钥明文
+func UpdateWebhook(userMobile string, webhookID uint, req *WebhookRequest) (*models.WebhookResponse, error) {
+	webhook, err := GetWebhook(userMobile, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL = strings.TrimSpace(req.URL); req.URL != "" {
+		if err := validateWebhookURL(req.URL); err != nil {
+			return nil, err
+		}
+		updates["url"] = req.URL
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		updates["events"] = events
+	}
+	secret := strings.TrimSpace(req.Secret)
+	if secret != "" {
+		if len(secret) > maxWebhookSecretLength {
+			return nil, fmt.Errorf("签名密钥不能超过%d个字符", maxWebhookSecretLength)
+		}
+		encrypted, err := encryptWebhookSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		updates["secret"] = encrypted
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+
+	if len(updates) > 0 {
+		if err := config.GetDB().Model(webhook).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		if webhook, err = GetWebhook(userMobile, webhookID); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := webhook.ToResponse()
+	resp.Secret = secret
+	return resp, nil
+}
+
+// DeleteWebhook 删除Webhook及其投递记录
+func DeleteWebhook(userMobile string, webhookID uint) error {
+	return config.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND mobile = ?", webhookID, userMobile).Delete(&models.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return tx.Where("webhook_id = ?", webhookID).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+// ListWebhookDeliveries 分页查询Webhook的投递记录（不含投递内容与响应内容），可按状态过滤
+func ListWebhookDeliveries(userMobile string, webhookID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	if _, err := GetWebhook(userMobile, webhookID); err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 15
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.GetDB().Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Omit("payload", "response_body").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// GetWebhookDelivery 获取投递记录详情
+func GetWebhookDelivery(userMobile string, webhookID, deliveryID uint) (*models.WebhookDelivery, error) {
+	if _, err := GetWebhook(userMobile, webhookID); err != nil {
+		return nil, err
+	}
+	var delivery models.WebhookDelivery
+	if err := config.GetDB().Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&delivery).Error; err != nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+// RedeliverWebhook 以相同的事件ID与内容重新投递，创建新的投递记录
+func RedeliverWebhook(userMobile string, webhookID, deliveryID uint) (*models.WebhookDelivery, error) {
+	original, err := GetWebhookDelivery(userMobile, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WebhookID:   webhookID,
+		EventID:     original.EventID,
+		Event:       original.Event,
+		Payload:     original.Payload,
+		Status:      models.WebhookDeliveryPending,
+		NextRetryAt: &now,
+	}
+	if err := config.GetDB().Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	if d := webhookDispatcher.Load(); d != nil {
+		d.enqueue(delivery.ID)
+	}
+	utils.Info("重新投递Webhook", zap.Uint("webhook_id", webhookID), zap.Uint("delivery_id", delivery.ID), zap.String("event", delivery.Event))
+	return delivery, nil
+}
+
+// WebhookDispatcher Webhook投递器：数据库记录待投递事件，工作协程投递并按指数退避重试
+type WebhookDispatcher struct {
+	client        *http.Client
+	maxAttempts   int
+	retryInterval time.Duration
+	queue         chan uint
+	ctx           context.Context
+	stop          context.CancelFunc
+	wg            sync.WaitGroup
+
+	mu       sync.Mutex
+	inflight map[uint]bool // 已入队或投递中的记录，避免重复投递
+}
+
+// webhookDispatcher 全局Webhook投递器，未启动时不记录事件；请求协程并发读取，使用原子指针
+var webhookDispatcher atomic.Pointer[WebhookDispatcher]
+
+// StartWebhookDispatcher 启动Webhook投递器，继续投递上次未完成的记录
+func StartWebhookDispatcher(cfg config.WebhookConfig) {
+	workers := max(cfg.Workers, 1)
+	ctx, stop := context.WithCancel(context.Background())
+	d := &WebhookDispatcher{
+		client:        newWebhookClient(cfg),
+		maxAttempts:   max(cfg.MaxAttempts, 1),
+		retryInterval: time.Duration(max(cfg.RetryInterval, 1)) * time.Second,
+		queue:         make(chan uint, 256),
+		ctx:           ctx,
+		stop:          stop,
+		inflight:      make(map[uint]bool),
+	}
+
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	d.wg.Add(1)
+	go d.poll()
+
+	webhookDispatcher.Store(d)
+	utils.Info("Webhook投递器已启动", zap.Int("workers", workers), zap.Int("max_attempts", d.maxAttempts))
+}
+
+// StopWebhookDispatcher 停止Webhook投递器，未投递的记录在下次启动后继续投递
+func StopWebhookDispatcher() {
+	d := webhookDispatcher.Swap(nil)
+	if d == nil {
+		return
+	}
+	d.stop()
+	d.wg.Wait()
+	utils.Info("Webhook投递器已停止")
+}
+
+// EmitWebhookEvent 向用户订阅了该事件的Webhook投递事件，异步执行不阻塞调用方
+func EmitWebhookEvent(userMobile, event string, data interface{}) {
+	d := webhookDispatcher.Load()
+	if d == nil {
+		return
+	}
+	payload := WebhookPayload{
+		ID:        randomHex(16),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	go d.emit(userMobile, payload)
+}
+
+// emit 为订阅了事件的Webhook创建投递记录并入队
+func (d *WebhookDispatcher) emit(userMobile string, payload WebhookPayload) {
+	db := config.GetDB()
+	var webhooks []models.Webhook
+	if err := db.Where("mobile = ? AND status = 1", userMobile).Find(&webhooks).Error; err != nil {
+		utils.Error("查询Webhook失败", zap.Error(err))
+		return
+	}
+
+	var body []byte
+	for i := range webhooks {
+		if !webhooks[i].Subscribes(payload.Event) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(payload); err != nil {
+				utils.Error("序列化Webhook事件失败", zap.String("event", payload.Event), zap.Error(err))
+				return
+			}
+		}
+
+		now := time.Now()
+		delivery := &models.WebhookDelivery{
+			WebhookID:   webhooks[i].ID,
+			EventID:     payload.ID,
+			Event:       payload.Event,
+			Payload:     string(body),
+			Status:      models.WebhookDeliveryPending,
+			NextRetryAt: &now,
+		}
+		if err := db.Create(delivery).Error; err != nil {
+			utils.Error("创建Webhook投递记录失败", zap.Uint("webhook_id", webhooks[i].ID), zap.Error(err))
+			continue
+		}
+		d.enqueue(delivery.ID)
+	}
+}
+
+// enqueue 将投递记录加入队列，队列已满时等待下次扫描
+func (d *WebhookDispatcher) enqueue(id uint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inflight[id] {
+		return
+	}
+	select {
+	case d.queue <- id:
+		d.inflight[id] = true
+	default:
+	}
+}
+
+// poll 定期扫描到期的待投递记录
+func (d *WebhookDispatcher) poll() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var ids []uint
+		if err := config.GetDB().Model(&models.WebhookDelivery{}).
+			Where("status = ? AND next_retry_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_retry_at ASC").Limit(cap(d.queue)).Pluck("id", &ids).Error; err != nil {
+			utils.Error("查询待投递Webhook失败", zap.Error(err))
+		}
+		for _, id := range ids {
+			d.enqueue(id)
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// worker 从队列中取出记录并投递
+func (d *WebhookDispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case id := <-d.queue:
+			d.deliver(id)
+			d.mu.Lock()
+			delete(d.inflight, id)
+			d.mu.Unlock()
+		}
+	}
+}
+
+// deliver 投递一次并记录结果，失败时安排重试
+func (d *WebhookDispatcher) deliver(id uint) {
+	db := config.GetDB()
+	var delivery models.WebhookDelivery
+	if err := db.Where("id = ? AND status = ?", id, models.WebhookDeliveryPending).First(&delivery).Error; err != nil {
+		return
+	}
+
+	fields := map[string]interface{}{"attempts": delivery.Attempts + 1}
+	var webhook models.Webhook
+	if err := db.First(&webhook, delivery.WebhookID).Error; err != nil || webhook.Status != 1 {
+		fields["status"] = models.WebhookDeliveryFailed
+		fields["error"] = "Webhook已删除或未启用"
+		fields["next_retry_at"] = nil
+		db.Model(&delivery).Updates(fields)
+		return
+	}
+	secret, err := decryptWebhookSecret(&webhook)
+	if err != nil {
+		utils.Error("解密Webhook签名密钥失败", zap.Uint("webhook_id", webhook.ID), zap.Error(err))
+		fields["status"] = models.WebhookDeliveryFailed
+		fields["error"] = "Webhook签名密钥无效，请重新设置密钥"
+		fields["next_retry_at"] = nil
+		db.Model(&delivery).Updates(fields)
+		return
+	}
+
+	result := sendWebhook(d.ctx, d.client, webhook.URL, secret, &delivery)
+	if d.ctx.Err() != nil {
+		// 服务停止导致的失败不计入投递次数
+		return
+	}
+	fields["response_status"] = result.StatusCode
+	fields["response_body"] = result.Body
+	fields["error"] = result.Error
+	fields["duration_ms"] = result.DurationMs
+
+	switch {
+	case result.Error == "":
+		fields["status"] = models.WebhookDeliverySucceeded
+		fields["delivered_at"] = time.Now()
+		fields["next_retry_at"] = nil
+	case delivery.Attempts+1 >= d.maxAttempts:
+		fields["status"] = models.WebhookDeliveryFailed
+		fields["next_retry_at"] = nil
+		utils.Warn("Webhook投递失败，重试次数已用尽", zap.Uint("delivery_id", id), zap.String("error", result.Error))
+	default:
+		fields["next_retry_at"] = time.Now().Add(webhookBackoff(d.retryInterval, delivery.Attempts+1))
+	}
+	if err := db.Model(&delivery).Updates(fields).Error; err != nil {
+		utils.Error("更新Webhook投递记录失败", zap.Uint("delivery_id", id), zap.Error(err))
+	}
+}
+
+// webhookBackoff 第 attempts 次投递失败后的重试间隔：interval * 2^(attempts-1)，不超过1小时
+func webhookBackoff(interval time.Duration, attempts int) time.Duration {
+	backoff := interval
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
+
+// webhookResult 单次投递结果
+type webhookResult struct {
+	StatusCode int
+	Body       string
+	Error      string
+	DurationMs int64
+}
+
+// sendWebhook 签名并发送投递内容，响应状态码为 2xx 视为成功
+func sendWebhook(ctx context.Context, client *http.Client, targetURL, secret string, delivery *models.WebhookDelivery) webhookResult {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return webhookResult{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CESE-Qoder-Webhook")
+	req.Header.Set(WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(WebhookHeaderDelivery, delivery.EventID)
+	req.Header.Set(WebhookHeaderSignature, signWebhookPayload(secret, body))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result := webhookResult{DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseBody))
+	result.StatusCode = resp.StatusCode
+	result.Body = strings.ToValidUTF8(string(respBody), "")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("回调地址返回状态码 %d", resp.StatusCode)
+	}
+	return result
+}
+
+// webhookSink 在流式生成结束时投递生成完成或失败事件
+type webhookSink struct {
+	StreamSink
+	userMobile string
+	providerID uint
+}
+
+// WithGenerationWebhook 包装流式输出，生成结束时向用户的Webhook投递事件
+func WithGenerationWebhook(userMobile string, providerID uint, sink StreamSink) StreamSink {
+	return &webhookSink{StreamSink: sink, userMobile: userMobile, providerID: providerID}
+}
+
+func (s *webhookSink) Done(result *GenerateResult) {
+	s.StreamSink.Done(result)
+	EmitWebhookEvent(s.userMobile, models.WebhookEventGenerationCompleted, generationEventData("generate", 0, s.providerID, result, ""))
+}
+
+func (s *webhookSink) Error(message string) {
+	s.StreamSink.Error(message)
+	EmitWebhookEvent(s.userMobile, models.WebhookEventGenerationFailed, generationEventData("generate", 0, s.providerID, nil, message))
+}
+
+// generationEventData 构建生成事件数据
+func generationEventData(source string, jobID, providerID uint, result *GenerateResult, errMessage string) *GenerationEventData {
+	data := &GenerationEventData{
+		Source:     source,
+		JobID:      jobID,
+		ProviderID: providerID,
+		Error:      errMessage,
+	}
+	if result != nil {
+		data.Content = result.Content
+		data.ToolCalls = result.ToolCalls
+		data.FinishReason = result.FinishReason
+		data.Usage = result.Usage
+	}
+	return data
+}
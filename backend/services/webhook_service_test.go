@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zsy619/cese-qoder/backend/config"
+	"github.com/zsy619/cese-qoder/backend/models"
+)
+
+func TestNormalizeWebhookEvents(t *testing.T) {
+	got, err := normalizeWebhookEvents([]string{"template.updated", " generation.completed", "template.updated"})
+	if err != nil {
+		t.Fatalf("normalizeWebhookEvents() error = %v", err)
+	}
+	if got != "generation.completed,template.updated" {
+		t.Errorf("normalizeWebhookEvents() = %q", got)
+	}
+
+	if _, err := normalizeWebhookEvents(nil); err == nil {
+		t.Error("normalizeWebhookEvents() expected error for empty events")
+	}
+	if _, err := normalizeWebhookEvents([]string{"template.deleted"}); err == nil {
+		t.Error("normalizeWebhookEvents() expected error for unknown event")
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	original := lookupWebhookHost
+	lookupWebhookHost = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		case "rebind.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.0.0.8")}}, nil
+		case "localhost":
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	defer func() { lookupWebhookHost = original }()
+
+	for _, u := range []string{"https://example.com/hook", "http://93.184.216.34:8080/cb"} {
+		if err := validateWebhookURL(u); err != nil {
+			t.Errorf("validateWebhookURL(%q) error = %v", u, err)
+		}
+	}
+	for _, u := range []string{"", "example.com/hook", "ftp://example.com", "https://", "https://unknown.invalid/hook"} {
+		if err := validateWebhookURL(u); err == nil {
+			t.Errorf("validateWebhookURL(%q) expected error", u)
+		}
+	}
+	for _, u := range []string{
+		"http://127.0.0.1:8080/cb", "http://localhost/cb", "http://169.254.169.254/latest/meta-data",
+		"http://10.1.2.3/cb", "http://172.16.0.1/cb", "http://192.168.1.1/cb", "http://0.0.0.0/cb",
+		"http://[::1]/cb", "http://[fe80::1]/cb", "https://rebind.example.com/hook",
+	} {
+		if err := validateWebhookURL(u); err != errWebhookPrivateAddress {
+			t.Errorf("validateWebhookURL(%q) error = %v, want errWebhookPrivateAddress", u, err)
+		}
+	}
+}
+
+func TestNewWebhookClientBlocksPrivateNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// 解析结果在注册后变为内网地址时，连接前的检查仍然拒绝
+	if _, err := newWebhookClient(config.WebhookConfig{Timeout: 1}).Get(server.URL); err == nil || !strings.Contains(err.Error(), errWebhookPrivateAddress.Error()) {
+		t.Errorf("Get(%s) error = %v, want private address error", server.URL, err)
+	}
+
+	resp, err := newWebhookClient(config.WebhookConfig{Timeout: 1, AllowPrivateNetwork: true}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get(%s) with allow_private_network error = %v", server.URL, err)
+	}
+	resp.Body.Close()
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	webhook := &models.Webhook{Events: "generation.completed,template.created"}
+	if !webhook.Subscribes(models.WebhookEventTemplateCreated) || webhook.Subscribes(models.WebhookEventTemplateUpdated) {
+		t.Errorf("unexpected subscriptions for %q", webhook.Events)
+	}
+	if events := (&models.Webhook{}).EventList(); len(events) != 0 {
+		t.Errorf("EventList() = %v, want empty", events)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(30*time.Second, tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(30s, %d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	const secret = "whsec_test"
+	payload := `{"id":"evt_1","event":"template.created","data":{}}`
+
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if r.Header.Get(WebhookHeaderSignature) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("signature mismatch: %s", r.Header.Get(WebhookHeaderSignature))
+		}
+		if r.Header.Get(WebhookHeaderEvent) != "template.created" || r.Header.Get(WebhookHeaderDelivery) != "evt_1" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, strings.Repeat("x", webhookMaxResponseBody+10))
+	}))
+	defer server.Close()
+
+	delivery := &models.WebhookDelivery{EventID: "evt_1", Event: "template.created", Payload: payload}
+
+	status = http.StatusNoContent
+	result := sendWebhook(context.Background(), server.Client(), server.URL, secret, delivery)
+	if result.Error != "" || result.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	status = http.StatusBadGateway
+	result = sendWebhook(context.Background(), server.Client(), server.URL, secret, delivery)
+	if result.Error == "" || result.StatusCode != http.StatusBadGateway || len(result.Body) != webhookMaxResponseBody {
+		t.Errorf("unexpected result for 502: status=%d error=%q body=%d", result.StatusCode, result.Error, len(result.Body))
+	}
+
+	result = sendWebhook(context.Background(), server.Client(), "http://127.0.0.1:1", secret, delivery)
+	if result.Error == "" {
+		t.Error("sendWebhook() expected error for unreachable url")
+	}
+}
+
+func TestWithGenerationWebhook(t *testing.T) {
+	// 投递器未启动时只转发，不投递事件
+	inner := &recordingSink{}
+	sink := WithGenerationWebhook("13800138000", 1, inner)
+	sink.Content("你好")
+	sink.Done(&GenerateResult{Content: "你好"})
+	if len(inner.contents) != 1 || inner.result == nil || inner.result.Content != "你好" {
+		t.Errorf("unexpected forwarded output: %+v", inner)
+	}
+
+	data := generationEventData("job", 3, 1, &GenerateResult{Content: "完成", FinishReason: "stop"}, "")
+	if data.Source != "job" || data.JobID != 3 || data.Content != "完成" || data.FinishReason != "stop" {
+		t.Errorf("unexpected event data: %+v", data)
+	}
+}
+
+func TestDeliverInvalidSecret(t *testing.T) {
+	setupAPIProviderTest(t)
+	db := config.GetDB()
+
+	user := &models.User{Mobile: "13900139035", Password: "x"}
+	db.Create(user)
+	defer db.Delete(user)
+
+	webhook := &models.Webhook{Mobile: user.Mobile, URL: "https://example.com/hook", Secret: "not-encrypted", Events: models.WebhookEventTemplateCreated, Status: 1}
+	if err := db.Create(webhook).Error; err != nil {
+		t.Fatalf("create webhook error = %v", err)
+	}
+	defer db.Delete(webhook)
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{WebhookID: webhook.ID, EventID: "evt_1", Event: models.WebhookEventTemplateCreated,
+		Payload: "{}", Status: models.WebhookDeliveryPending, NextRetryAt: &now}
+	if err := db.Create(delivery).Error; err != nil {
+		t.Fatalf("create delivery error = %v", err)
+	}
+	defer db.Delete(delivery)
+
+	d := &WebhookDispatcher{client: http.DefaultClient, maxAttempts: 3, ctx: context.Background()}
+	d.deliver(delivery.ID)
+
+	var got models.WebhookDelivery
+	if err := db.First(&got, delivery.ID).Error; err != nil {
+		t.Fatalf("reload delivery error = %v", err)
+	}
+	if got.Status != models.WebhookDeliveryFailed || got.NextRetryAt != nil || got.Error == "" || got.Attempts != 1 {
+		t.Errorf("delivery = %+v, want failed with error and no retry", got)
+	}
+}
+
+func TestWebhookDispatcherConcurrentStop(t *testing.T) {
+	setupAPIProviderTest(t)
+
+	StartWebhookDispatcher(config.WebhookConfig{Workers: 1, Timeout: 1, MaxAttempts: 1, RetryInterval: 1})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				EmitWebhookEvent("13900139036", models.WebhookEventTemplateCreated, nil)
+			}
+		}()
+	}
+	StopWebhookDispatcher()
+	wg.Wait()
+
+	if webhookDispatcher.Load() != nil {
+		t.Error("StopWebhookDispatcher() should clear the dispatcher")
+	}
+	StopWebhookDispatcher() // 重复停止不应出错
+}
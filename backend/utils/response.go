@@ -42,6 +42,7 @@ const (
 	CodeTokenExpired     = 1007 // Token 过期
 	CodeTemplateNotFound = 2001 // 模板不存在
 	CodeTemplateNoAuth   = 2002 // 无权操作该模板
+	CodeInputRejected    = 3001 // 输入未通过安全检查
 )
 
 // Success 成功响应
@@ -124,6 +125,7 @@ func GetErrorMessage(code int) string {
 		CodeTokenExpired:     "Token 已过期",
 		CodeTemplateNotFound: "模板不存在",
 		CodeTemplateNoAuth:   "无权操作该模板",
+		CodeInputRejected:    "输入未通过安全检查",
 	}
 
 	if msg, ok := messages[code]; ok {
@@ -11,6 +11,24 @@ USE context_engine;
 -- ============================================
 -- 删除旧表（如果存在）
 -- ============================================
+DROP TABLE IF EXISTS `cese_template_embedding`;
+DROP TABLE IF EXISTS `cese_embedding_setting`;
+DROP TABLE IF EXISTS `cese_template_tag_link`;
+DROP TABLE IF EXISTS `cese_template_tag`;
+DROP TABLE IF EXISTS `cese_template_folder`;
+DROP TABLE IF EXISTS `cese_template_share`;
+DROP TABLE IF EXISTS `cese_template_revision`;
+DROP TABLE IF EXISTS `cese_generation_usage`;
+DROP TABLE IF EXISTS `cese_model_price`;
+DROP TABLE IF EXISTS `cese_output_filter`;
+DROP TABLE IF EXISTS `cese_webhook_delivery`;
+DROP TABLE IF EXISTS `cese_webhook`;
+DROP TABLE IF EXISTS `cese_batch_row`;
+DROP TABLE IF EXISTS `cese_batch`;
+DROP TABLE IF EXISTS `cese_job`;
+DROP TABLE IF EXISTS `cese_evaluation_result`;
+DROP TABLE IF EXISTS `cese_evaluation`;
+DROP TABLE IF EXISTS `cese_image`;
 DROP TABLE IF EXISTS `cese_api_provider`;
 DROP TABLE IF EXISTS `cese_template`;
 DROP TABLE IF EXISTS `cese_user`;
@@ -45,11 +63,26 @@ CREATE TABLE `cese_template` (
   `key_information` TEXT COMMENT '关键信息',
   `behavior_rule` TEXT COMMENT '行为规则',
   `delivery_format` TEXT COMMENT '交付格式',
+  `version` INT NOT NULL DEFAULT 1 COMMENT '当前版本号',
+  `visibility` VARCHAR(16) NOT NULL DEFAULT 'private' COMMENT '可见范围：private-仅自己，shared-指定用户，public-公开',
+  `fork_count` INT NOT NULL DEFAULT 0 COMMENT '被复制次数',
+  `source_template_id` BIGINT UNSIGNED NULL COMMENT '复制来源模板ID',
+  `source_author` VARCHAR(32) NOT NULL DEFAULT '' COMMENT '原作者（脱敏手机号）',
+  `folder_id` BIGINT UNSIGNED NULL COMMENT '所在文件夹ID，为空表示未分类',
+  `favorite` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否收藏',
+  `pinned` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否置顶',
+  `variables` TEXT NULL COMMENT '变量定义（JSON），要素中以 {{变量名}} 引用',
   `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `deleted_at` DATETIME(3) NULL COMMENT '移入回收站时间，为空表示未删除',
   INDEX `idx_mobile` (`mobile`),
   INDEX `idx_topic` (`topic`),
   INDEX `idx_created_at` (`created_at`),
+  INDEX `idx_visibility_forks` (`visibility`, `fork_count`),
+  INDEX `idx_source_template_id` (`source_template_id`),
+  INDEX `idx_folder_id` (`folder_id`),
+  INDEX `idx_deleted_at` (`deleted_at`),
+  FULLTEXT INDEX `ft_template_content` (`topic`, `task_objective`, `ai_role`, `my_role`, `key_information`, `behavior_rule`, `delivery_format`) WITH PARSER ngram,
   CONSTRAINT `fk_template_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='六要素模板表';
 
@@ -70,13 +103,336 @@ CREATE TABLE `cese_api_provider` (
   `api_remark` TEXT COMMENT '备注说明',
   `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `deleted_at` DATETIME(3) NULL COMMENT '移入回收站时间，为空表示未删除',
   INDEX `idx_mobile` (`mobile`),
   INDEX `idx_status` (`api_status`),
   INDEX `idx_api_open` (`api_open`),
   INDEX `idx_api_kind` (`api_kind`),
+  INDEX `idx_deleted_at` (`deleted_at`),
   CONSTRAINT `fk_provider_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='API Provider配置表';
 
+-- ============================================
+-- 生成图片表 (cese_image)
+-- ============================================
+CREATE TABLE `cese_image` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '图片ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `file_name` VARCHAR(255) NOT NULL COMMENT '原始文件名',
+  `mime_type` VARCHAR(50) NOT NULL COMMENT '图片类型',
+  `size` BIGINT NOT NULL COMMENT '文件大小（字节）',
+  `storage_path` VARCHAR(500) NOT NULL COMMENT '存储路径',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '上传时间',
+  INDEX `idx_mobile` (`mobile`),
+  CONSTRAINT `fk_image_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='生成图片表';
+
+-- ============================================
+-- 提示词评测表 (cese_evaluation)
+-- ============================================
+CREATE TABLE `cese_evaluation` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '评测ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `template_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '评测的模板ID',
+  `prompt` TEXT NOT NULL COMMENT '评测提示词',
+  `system` TEXT COMMENT '系统消息',
+  `criteria` TEXT COMMENT '评分标准',
+  `judge_provider_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '评审模型Provider ID',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  INDEX `idx_mobile` (`mobile`),
+  INDEX `idx_template_id` (`template_id`),
+  INDEX `idx_created_at` (`created_at`),
+  CONSTRAINT `fk_evaluation_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提示词评测表';
+
+-- ============================================
+-- 提示词评测结果表 (cese_evaluation_result)
+-- ============================================
+CREATE TABLE `cese_evaluation_result` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '结果ID',
+  `evaluation_id` BIGINT UNSIGNED NOT NULL COMMENT '评测ID',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `provider_name` VARCHAR(100) DEFAULT NULL COMMENT 'Provider名称',
+  `model` VARCHAR(100) DEFAULT NULL COMMENT '模型名称',
+  `content` MEDIUMTEXT COMMENT '生成内容',
+  `latency_ms` BIGINT DEFAULT 0 COMMENT '耗时（毫秒）',
+  `prompt_tokens` INT DEFAULT 0 COMMENT '提示词token数',
+  `completion_tokens` INT DEFAULT 0 COMMENT '生成token数',
+  `total_tokens` INT DEFAULT 0 COMMENT '总token数',
+  `error` TEXT COMMENT '错误信息',
+  `score` DOUBLE DEFAULT NULL COMMENT '评审打分（0-10）',
+  `score_reason` TEXT COMMENT '评分理由',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  INDEX `idx_evaluation_id` (`evaluation_id`),
+  CONSTRAINT `fk_result_evaluation` FOREIGN KEY (`evaluation_id`) REFERENCES `cese_evaluation`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提示词评测结果表';
+
+-- ============================================
+-- 异步生成任务表 (cese_job)
+-- ============================================
+CREATE TABLE `cese_job` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '任务ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed/canceled',
+  `request` MEDIUMTEXT NOT NULL COMMENT '生成请求（JSON）',
+  `progress` INT DEFAULT 0 COMMENT '已生成的字符数',
+  `content` MEDIUMTEXT COMMENT '生成内容',
+  `tool_calls` TEXT COMMENT '工具调用（JSON）',
+  `finish_reason` VARCHAR(50) DEFAULT NULL COMMENT '结束原因',
+  `prompt_tokens` INT DEFAULT 0 COMMENT '提示词token数',
+  `completion_tokens` INT DEFAULT 0 COMMENT '生成token数',
+  `total_tokens` INT DEFAULT 0 COMMENT '总token数',
+  `error` TEXT COMMENT '错误信息',
+  `started_at` TIMESTAMP NULL DEFAULT NULL COMMENT '开始执行时间',
+  `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '结束时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile` (`mobile`),
+  INDEX `idx_status` (`status`),
+  INDEX `idx_created_at` (`created_at`),
+  CONSTRAINT `fk_job_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='异步生成任务表';
+
+-- ============================================
+-- 批量生成表 (cese_batch)
+-- ============================================
+CREATE TABLE `cese_batch` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '批量生成ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `file_name` VARCHAR(255) DEFAULT NULL COMMENT '上传文件名',
+  `format` VARCHAR(10) NOT NULL COMMENT '文件格式：csv/jsonl',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed',
+  `concurrency` INT DEFAULT 0 COMMENT '并发生成的行数',
+  `total` INT DEFAULT 0 COMMENT '总行数',
+  `succeeded` INT DEFAULT 0 COMMENT '成功行数',
+  `failed` INT DEFAULT 0 COMMENT '失败行数',
+  `error` TEXT COMMENT '错误信息',
+  `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '结束时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile` (`mobile`),
+  INDEX `idx_created_at` (`created_at`),
+  CONSTRAINT `fk_batch_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='批量生成表';
+
+-- ============================================
+-- 批量生成结果表 (cese_batch_row)
+-- ============================================
+CREATE TABLE `cese_batch_row` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '结果ID',
+  `batch_id` BIGINT UNSIGNED NOT NULL COMMENT '批量生成ID',
+  `row_no` INT NOT NULL COMMENT '在上传文件中的行号',
+  `topic` VARCHAR(255) DEFAULT NULL COMMENT '主题',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/completed/failed',
+  `template_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '生成的模板ID',
+  `error` TEXT COMMENT '错误信息',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_batch_id` (`batch_id`),
+  CONSTRAINT `fk_row_batch` FOREIGN KEY (`batch_id`) REFERENCES `cese_batch`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='批量生成结果表';
+
+-- ============================================
+-- Webhook表 (cese_webhook)
+-- ============================================
+CREATE TABLE `cese_webhook` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT 'Webhook ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `url` VARCHAR(500) NOT NULL COMMENT '回调地址',
+  `secret` VARCHAR(255) NOT NULL COMMENT '签名密钥（加密存储）',
+  `events` VARCHAR(255) NOT NULL COMMENT '订阅的事件，逗号分隔',
+  `status` TINYINT(1) DEFAULT 1 COMMENT '状态：1启用 0停用',
+  `description` VARCHAR(255) DEFAULT NULL COMMENT '描述',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile` (`mobile`),
+  CONSTRAINT `fk_webhook_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Webhook表';
+
+-- ============================================
+-- Webhook投递记录表 (cese_webhook_delivery)
+-- ============================================
+CREATE TABLE `cese_webhook_delivery` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '投递记录ID',
+  `webhook_id` BIGINT UNSIGNED NOT NULL COMMENT 'Webhook ID',
+  `event_id` VARCHAR(64) NOT NULL COMMENT '事件ID，重新投递时不变',
+  `event` VARCHAR(50) NOT NULL COMMENT '事件类型',
+  `payload` MEDIUMTEXT NOT NULL COMMENT '投递内容（JSON）',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/succeeded/failed',
+  `attempts` INT DEFAULT 0 COMMENT '已投递次数',
+  `response_status` INT DEFAULT 0 COMMENT '最近一次响应状态码',
+  `response_body` TEXT COMMENT '最近一次响应内容',
+  `error` TEXT COMMENT '最近一次错误信息',
+  `duration_ms` BIGINT DEFAULT 0 COMMENT '最近一次耗时（毫秒）',
+  `next_retry_at` TIMESTAMP NULL DEFAULT NULL COMMENT '下次投递时间',
+  `delivered_at` TIMESTAMP NULL DEFAULT NULL COMMENT '投递成功时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_webhook_id` (`webhook_id`),
+  INDEX `idx_next_retry_at` (`next_retry_at`),
+  CONSTRAINT `fk_delivery_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `cese_webhook`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Webhook投递记录表';
+
+-- ============================================
+-- 输出过滤设置表 (cese_output_filter)
+-- ============================================
+CREATE TABLE `cese_output_filter` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '设置ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `template_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '模板ID，0表示用户默认设置',
+  `filters` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '启用的过滤器，逗号分隔：sensitive_word/pii_mask/markdown/truncate',
+  `max_length` INT DEFAULT 0 COMMENT '截断长度（字符数），0表示使用系统默认',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_mobile_template` (`mobile`, `template_id`),
+  CONSTRAINT `fk_output_filter_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='输出过滤设置表';
+
+-- ============================================
+-- 模型价格表 (cese_model_price)
+-- ============================================
+CREATE TABLE `cese_model_price` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '价格ID',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `model` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '模型名称前缀，空表示默认价格',
+  `input_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '输入价格（每1K token）',
+  `output_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '输出价格（每1K token）',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_provider_model` (`provider_id`, `model`),
+  CONSTRAINT `fk_price_provider` FOREIGN KEY (`provider_id`) REFERENCES `cese_api_provider`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模型价格表';
+
+-- ============================================
+-- 生成用量表 (cese_generation_usage)
+-- ============================================
+CREATE TABLE `cese_generation_usage` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '记录ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `model` VARCHAR(100) NOT NULL COMMENT '模型',
+  `template_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '模板ID，0表示未指定',
+  `prompt_tokens` INT DEFAULT 0 COMMENT '输入token数',
+  `completion_tokens` INT DEFAULT 0 COMMENT '输出token数',
+  `total_tokens` INT DEFAULT 0 COMMENT '总token数',
+  `estimated` TINYINT(1) DEFAULT 0 COMMENT 'Provider未返回用量时为估算值',
+  `input_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '计费时的输入价格',
+  `output_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '计费时的输出价格',
+  `cost` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '费用',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  INDEX `idx_mobile_created` (`mobile`, `created_at`),
+  CONSTRAINT `fk_usage_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='生成用量表';
+
+-- ============================================
+-- 模板修订表 (cese_template_revision)
+-- ============================================
+CREATE TABLE `cese_template_revision` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '修订ID',
+  `template_id` BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+  `version` INT NOT NULL COMMENT '版本号',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '修改人手机号',
+  `topic` VARCHAR(255) NOT NULL COMMENT '主题',
+  `task_objective` TEXT COMMENT '任务目标',
+  `ai_role` TEXT COMMENT 'AI的角色',
+  `my_role` TEXT COMMENT '我的角色',
+  `key_information` TEXT COMMENT '关键信息',
+  `behavior_rule` TEXT COMMENT '行为规则',
+  `delivery_format` TEXT COMMENT '交付格式',
+  `variables` TEXT NULL COMMENT '变量定义（JSON）',
+  `change_note` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '修改说明',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  UNIQUE KEY `uk_template_version` (`template_id`, `version`),
+  CONSTRAINT `fk_revision_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板修订表';
+
+-- ============================================
+-- 模板共享表 (cese_template_share)
+-- ============================================
+CREATE TABLE `cese_template_share` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '共享ID',
+  `template_id` BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '被共享用户手机号',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  UNIQUE KEY `uk_template_mobile` (`template_id`, `mobile`),
+  INDEX `idx_mobile` (`mobile`),
+  CONSTRAINT `fk_share_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_share_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板共享表';
+
+-- ============================================
+-- 模板文件夹表 (cese_template_folder)
+-- ============================================
+CREATE TABLE `cese_template_folder` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '文件夹ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `name` VARCHAR(50) NOT NULL COMMENT '文件夹名称',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_mobile_name` (`mobile`, `name`),
+  CONSTRAINT `fk_folder_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板文件夹表';
+
+-- ============================================
+-- 模板标签表 (cese_template_tag)
+-- ============================================
+CREATE TABLE `cese_template_tag` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '标签ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `name` VARCHAR(50) NOT NULL COMMENT '标签名称',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  UNIQUE KEY `uk_mobile_name` (`mobile`, `name`),
+  CONSTRAINT `fk_tag_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板标签表';
+
+-- ============================================
+-- 模板标签关联表 (cese_template_tag_link)
+-- ============================================
+CREATE TABLE `cese_template_tag_link` (
+  `template_id` BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+  `tag_id` BIGINT UNSIGNED NOT NULL COMMENT '标签ID',
+  PRIMARY KEY (`template_id`, `tag_id`),
+  INDEX `idx_tag_id` (`tag_id`),
+  CONSTRAINT `fk_tag_link_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_tag_link_tag` FOREIGN KEY (`tag_id`) REFERENCES `cese_template_tag`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板标签关联表';
+
+-- ============================================
+-- 向量化设置表 (cese_embedding_setting)
+-- ============================================
+CREATE TABLE `cese_embedding_setting` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '设置ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT '用于向量化的API Provider',
+  `model` VARCHAR(100) NOT NULL COMMENT '向量模型',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'idle' COMMENT '回填状态：idle/running/completed/failed',
+  `error` TEXT NULL COMMENT '回填失败原因',
+  `backfill_at` TIMESTAMP NULL COMMENT '最近一次回填完成时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_mobile` (`mobile`),
+  INDEX `idx_status` (`status`),
+  CONSTRAINT `fk_embedding_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE,
+  CONSTRAINT `fk_embedding_provider` FOREIGN KEY (`provider_id`) REFERENCES `cese_api_provider`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='向量化设置表';
+
+-- ============================================
+-- 模板向量表 (cese_template_embedding)
+-- ============================================
+CREATE TABLE `cese_template_embedding` (
+  `template_id` BIGINT UNSIGNED NOT NULL PRIMARY KEY COMMENT '模板ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `model` VARCHAR(100) NOT NULL COMMENT '向量模型',
+  `content_hash` CHAR(64) NOT NULL COMMENT '向量化内容与模型的SHA-256摘要',
+  `dimensions` INT NOT NULL COMMENT '向量维度',
+  `vector` MEDIUMBLOB NOT NULL COMMENT '归一化向量（float32小端序）',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile_model` (`mobile`, `model`),
+  CONSTRAINT `fk_embedding_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板向量表';
+
 -- ============================================
 -- 插入测试数据
 -- ============================================
@@ -90,6 +446,10 @@ INSERT INTO `cese_template` (`mobile`, `topic`, `task_objective`, `ai_role`, `my
 ('13800138000', '写作助手', '帮助用户生成高质量的文章内容', '写作专家', '内容创作者', '需要创作的文章主题和目标读者', '使用清晰的结构和生动的语言', 'Markdown格式'),
 ('13800138000', '代码审查助手', '协助进行代码质量审查和优化建议', '高级软件工程师', '开发人员', '代码片段、项目技术栈、代码规范', '遵循最佳实践、提供具体改进建议', '结构化文本报告');
 
+-- 示例模板的初始修订版本
+INSERT INTO `cese_template_revision` (`template_id`, `version`, `mobile`, `topic`, `task_objective`, `ai_role`, `my_role`, `key_information`, `behavior_rule`, `delivery_format`, `change_note`)
+SELECT `id`, `version`, `mobile`, `topic`, `task_objective`, `ai_role`, `my_role`, `key_information`, `behavior_rule`, `delivery_format`, '初始版本' FROM `cese_template`;
+
 -- 插入示例API Provider配置
 INSERT INTO `cese_api_provider` (`mobile`, `name`, `api_kind`, `api_key`, `api_url`, `api_model`, `api_open`, `api_remark`) VALUES
 ('13800138000', 'DeepSeek官方', 'DeepSeek', 'sk-your-deepseek-key', 'https://api.deepseek.com', 'deepseek-chat', 0, 'DeepSeek官方API-私有'),
@@ -0,0 +1,28 @@
+-- ============================================
+-- 数据库迁移脚本：新增生成图片表
+-- 说明：支持在生成请求中附带上传的图片（多模态输入）
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建图片表
+CREATE TABLE IF NOT EXISTS `cese_image` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '图片ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `file_name` VARCHAR(255) NOT NULL COMMENT '原始文件名',
+  `mime_type` VARCHAR(50) NOT NULL COMMENT '图片类型',
+  `size` BIGINT NOT NULL COMMENT '文件大小（字节）',
+  `storage_path` VARCHAR(500) NOT NULL COMMENT '存储路径',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '上传时间',
+  INDEX `idx_mobile` (`mobile`),
+  CONSTRAINT `fk_image_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='生成图片表';
+
+-- 2. 显示表结构
+SHOW FULL COLUMNS FROM `cese_image`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 005_add_image.sql
+-- ============================================
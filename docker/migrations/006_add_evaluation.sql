@@ -0,0 +1,51 @@
+-- ============================================
+-- 数据库迁移脚本：新增提示词评测表
+-- 说明：支持同一提示词在多个API Provider上对比生成结果，并由评审模型打分
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建评测表与评测结果表
+CREATE TABLE IF NOT EXISTS `cese_evaluation` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '评测ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `template_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '评测的模板ID',
+  `prompt` TEXT NOT NULL COMMENT '评测提示词',
+  `system` TEXT COMMENT '系统消息',
+  `criteria` TEXT COMMENT '评分标准',
+  `judge_provider_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '评审模型Provider ID',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  INDEX `idx_mobile` (`mobile`),
+  INDEX `idx_template_id` (`template_id`),
+  INDEX `idx_created_at` (`created_at`),
+  CONSTRAINT `fk_evaluation_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提示词评测表';
+
+CREATE TABLE IF NOT EXISTS `cese_evaluation_result` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '结果ID',
+  `evaluation_id` BIGINT UNSIGNED NOT NULL COMMENT '评测ID',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `provider_name` VARCHAR(100) DEFAULT NULL COMMENT 'Provider名称',
+  `model` VARCHAR(100) DEFAULT NULL COMMENT '模型名称',
+  `content` MEDIUMTEXT COMMENT '生成内容',
+  `latency_ms` BIGINT DEFAULT 0 COMMENT '耗时（毫秒）',
+  `prompt_tokens` INT DEFAULT 0 COMMENT '提示词token数',
+  `completion_tokens` INT DEFAULT 0 COMMENT '生成token数',
+  `total_tokens` INT DEFAULT 0 COMMENT '总token数',
+  `error` TEXT COMMENT '错误信息',
+  `score` DOUBLE DEFAULT NULL COMMENT '评审打分（0-10）',
+  `score_reason` TEXT COMMENT '评分理由',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  INDEX `idx_evaluation_id` (`evaluation_id`),
+  CONSTRAINT `fk_result_evaluation` FOREIGN KEY (`evaluation_id`) REFERENCES `cese_evaluation`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提示词评测结果表';
+
+-- 2. 显示表结构
+SHOW FULL COLUMNS FROM `cese_evaluation`;
+SHOW FULL COLUMNS FROM `cese_evaluation_result`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 006_add_evaluation.sql
+-- ============================================
@@ -0,0 +1,40 @@
+-- ============================================
+-- 数据库迁移脚本：新增异步生成任务表
+-- 说明：生成请求入队后由后台工作协程执行，状态、进度与结果持久化，服务重启后未完成的任务重新执行
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建异步生成任务表
+CREATE TABLE IF NOT EXISTS `cese_job` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '任务ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed/canceled',
+  `request` MEDIUMTEXT NOT NULL COMMENT '生成请求（JSON）',
+  `progress` INT DEFAULT 0 COMMENT '已生成的字符数',
+  `content` MEDIUMTEXT COMMENT '生成内容',
+  `tool_calls` TEXT COMMENT '工具调用（JSON）',
+  `finish_reason` VARCHAR(50) DEFAULT NULL COMMENT '结束原因',
+  `prompt_tokens` INT DEFAULT 0 COMMENT '提示词token数',
+  `completion_tokens` INT DEFAULT 0 COMMENT '生成token数',
+  `total_tokens` INT DEFAULT 0 COMMENT '总token数',
+  `error` TEXT COMMENT '错误信息',
+  `started_at` TIMESTAMP NULL DEFAULT NULL COMMENT '开始执行时间',
+  `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '结束时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile` (`mobile`),
+  INDEX `idx_status` (`status`),
+  INDEX `idx_created_at` (`created_at`),
+  CONSTRAINT `fk_job_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='异步生成任务表';
+
+-- 2. 显示表结构
+SHOW FULL COLUMNS FROM `cese_job`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 007_add_job.sql
+-- ============================================
@@ -0,0 +1,51 @@
+-- ============================================
+-- 数据库迁移脚本：新增批量生成表
+-- 说明：上传CSV/JSONL主题文件批量生成六要素模板，记录每行的生成结果
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建批量生成表与批量生成结果表
+CREATE TABLE IF NOT EXISTS `cese_batch` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '批量生成ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `file_name` VARCHAR(255) DEFAULT NULL COMMENT '上传文件名',
+  `format` VARCHAR(10) NOT NULL COMMENT '文件格式：csv/jsonl',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed',
+  `concurrency` INT DEFAULT 0 COMMENT '并发生成的行数',
+  `total` INT DEFAULT 0 COMMENT '总行数',
+  `succeeded` INT DEFAULT 0 COMMENT '成功行数',
+  `failed` INT DEFAULT 0 COMMENT '失败行数',
+  `error` TEXT COMMENT '错误信息',
+  `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '结束时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile` (`mobile`),
+  INDEX `idx_created_at` (`created_at`),
+  CONSTRAINT `fk_batch_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='批量生成表';
+
+CREATE TABLE IF NOT EXISTS `cese_batch_row` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '结果ID',
+  `batch_id` BIGINT UNSIGNED NOT NULL COMMENT '批量生成ID',
+  `row_no` INT NOT NULL COMMENT '在上传文件中的行号',
+  `topic` VARCHAR(255) DEFAULT NULL COMMENT '主题',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/completed/failed',
+  `template_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '生成的模板ID',
+  `error` TEXT COMMENT '错误信息',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_batch_id` (`batch_id`),
+  CONSTRAINT `fk_row_batch` FOREIGN KEY (`batch_id`) REFERENCES `cese_batch`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='批量生成结果表';
+
+-- 2. 显示表结构
+SHOW FULL COLUMNS FROM `cese_batch`;
+SHOW FULL COLUMNS FROM `cese_batch_row`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 008_add_batch.sql
+-- ============================================
@@ -0,0 +1,52 @@
+-- ============================================
+-- 数据库迁移脚本：新增Webhook表
+-- 说明：生成完成/失败、模板创建/更新时向用户注册的回调地址投递签名事件，记录投递结果并支持重试
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建Webhook表与投递记录表
+CREATE TABLE IF NOT EXISTS `cese_webhook` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT 'Webhook ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `url` VARCHAR(500) NOT NULL COMMENT '回调地址',
+  `secret` VARCHAR(255) NOT NULL COMMENT '签名密钥（加密存储）',
+  `events` VARCHAR(255) NOT NULL COMMENT '订阅的事件，逗号分隔',
+  `status` TINYINT(1) DEFAULT 1 COMMENT '状态：1启用 0停用',
+  `description` VARCHAR(255) DEFAULT NULL COMMENT '描述',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile` (`mobile`),
+  CONSTRAINT `fk_webhook_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Webhook表';
+
+CREATE TABLE IF NOT EXISTS `cese_webhook_delivery` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '投递记录ID',
+  `webhook_id` BIGINT UNSIGNED NOT NULL COMMENT 'Webhook ID',
+  `event_id` VARCHAR(64) NOT NULL COMMENT '事件ID，重新投递时不变',
+  `event` VARCHAR(50) NOT NULL COMMENT '事件类型',
+  `payload` MEDIUMTEXT NOT NULL COMMENT '投递内容（JSON）',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/succeeded/failed',
+  `attempts` INT DEFAULT 0 COMMENT '已投递次数',
+  `response_status` INT DEFAULT 0 COMMENT '最近一次响应状态码',
+  `response_body` TEXT COMMENT '最近一次响应内容',
+  `error` TEXT COMMENT '最近一次错误信息',
+  `duration_ms` BIGINT DEFAULT 0 COMMENT '最近一次耗时（毫秒）',
+  `next_retry_at` TIMESTAMP NULL DEFAULT NULL COMMENT '下次投递时间',
+  `delivered_at` TIMESTAMP NULL DEFAULT NULL COMMENT '投递成功时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_webhook_id` (`webhook_id`),
+  INDEX `idx_next_retry_at` (`next_retry_at`),
+  CONSTRAINT `fk_delivery_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `cese_webhook`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Webhook投递记录表';
+
+-- 2. 显示表结构
+SHOW FULL COLUMNS FROM `cese_webhook`;
+SHOW FULL COLUMNS FROM `cese_webhook_delivery`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 009_add_webhook.sql
+-- ============================================
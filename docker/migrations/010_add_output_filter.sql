@@ -0,0 +1,28 @@
+-- ============================================
+-- 数据库迁移脚本：新增输出过滤设置表
+-- 说明：生成内容可按用户或模板设置敏感词替换、个人信息脱敏、Markdown规范化与超长截断
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建输出过滤设置表
+CREATE TABLE IF NOT EXISTS `cese_output_filter` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '设置ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `template_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '模板ID，0表示用户默认设置',
+  `filters` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '启用的过滤器，逗号分隔：sensitive_word/pii_mask/markdown/truncate',
+  `max_length` INT DEFAULT 0 COMMENT '截断长度（字符数），0表示使用系统默认',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_mobile_template` (`mobile`, `template_id`),
+  CONSTRAINT `fk_output_filter_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='输出过滤设置表';
+
+-- 2. 显示表结构
+SHOW FULL COLUMNS FROM `cese_output_filter`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 010_add_output_filter.sql
+-- ============================================
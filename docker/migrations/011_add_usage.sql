@@ -0,0 +1,48 @@
+-- ============================================
+-- 数据库迁移脚本：新增模型价格表与生成用量表
+-- 说明：按API Provider设置模型价格（每1K token），每次生成记录token用量与费用，用于费用报告
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建模型价格表
+CREATE TABLE IF NOT EXISTS `cese_model_price` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '价格ID',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `model` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '模型名称前缀，空表示默认价格',
+  `input_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '输入价格（每1K token）',
+  `output_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '输出价格（每1K token）',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_provider_model` (`provider_id`, `model`),
+  CONSTRAINT `fk_price_provider` FOREIGN KEY (`provider_id`) REFERENCES `cese_api_provider`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模型价格表';
+
+-- 2. 创建生成用量表
+CREATE TABLE IF NOT EXISTS `cese_generation_usage` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '记录ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT 'API Provider ID',
+  `model` VARCHAR(100) NOT NULL COMMENT '模型',
+  `template_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '模板ID，0表示未指定',
+  `prompt_tokens` INT DEFAULT 0 COMMENT '输入token数',
+  `completion_tokens` INT DEFAULT 0 COMMENT '输出token数',
+  `total_tokens` INT DEFAULT 0 COMMENT '总token数',
+  `estimated` TINYINT(1) DEFAULT 0 COMMENT 'Provider未返回用量时为估算值',
+  `input_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '计费时的输入价格',
+  `output_price` DECIMAL(12,6) NOT NULL DEFAULT 0 COMMENT '计费时的输出价格',
+  `cost` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '费用',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  INDEX `idx_mobile_created` (`mobile`, `created_at`),
+  CONSTRAINT `fk_usage_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='生成用量表';
+
+-- 3. 显示表结构
+SHOW FULL COLUMNS FROM `cese_model_price`;
+SHOW FULL COLUMNS FROM `cese_generation_usage`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 011_add_usage.sql
+-- ============================================
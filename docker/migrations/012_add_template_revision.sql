@@ -0,0 +1,43 @@
+-- ============================================
+-- 数据库迁移脚本：新增模板修订历史
+-- 说明：模板每次修改生成不可变的修订版本（修改人、时间、完整快照、修改说明），支持对比与恢复
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 模板表增加当前版本号
+ALTER TABLE `cese_template`
+ADD COLUMN `version` INT NOT NULL DEFAULT 1 COMMENT '当前版本号' AFTER `delivery_format`;
+
+-- 2. 创建模板修订表
+CREATE TABLE IF NOT EXISTS `cese_template_revision` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '修订ID',
+  `template_id` BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+  `version` INT NOT NULL COMMENT '版本号',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '修改人手机号',
+  `topic` VARCHAR(255) NOT NULL COMMENT '主题',
+  `task_objective` TEXT COMMENT '任务目标',
+  `ai_role` TEXT COMMENT 'AI的角色',
+  `my_role` TEXT COMMENT '我的角色',
+  `key_information` TEXT COMMENT '关键信息',
+  `behavior_rule` TEXT COMMENT '行为规则',
+  `delivery_format` TEXT COMMENT '交付格式',
+  `change_note` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '修改说明',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  UNIQUE KEY `uk_template_version` (`template_id`, `version`),
+  CONSTRAINT `fk_revision_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板修订表';
+
+-- 3. 为已有模板生成初始修订版本
+INSERT IGNORE INTO `cese_template_revision` (`template_id`, `version`, `mobile`, `topic`, `task_objective`, `ai_role`, `my_role`, `key_information`, `behavior_rule`, `delivery_format`, `change_note`, `created_at`)
+SELECT `id`, `version`, `mobile`, `topic`, `task_objective`, `ai_role`, `my_role`, `key_information`, `behavior_rule`, `delivery_format`, '初始版本', `updated_at`
+FROM `cese_template`;
+
+-- 4. 显示表结构
+SHOW FULL COLUMNS FROM `cese_template_revision`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 012_add_template_revision.sql
+-- ============================================
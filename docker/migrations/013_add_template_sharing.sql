@@ -0,0 +1,37 @@
+-- ============================================
+-- 数据库迁移脚本：模板共享与模板广场
+-- 说明：模板增加可见范围（private / shared / public）、被复制次数与复制来源，新增模板共享表
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 模板表增加可见范围、被复制次数与复制来源
+ALTER TABLE `cese_template`
+ADD COLUMN `visibility` VARCHAR(16) NOT NULL DEFAULT 'private' COMMENT '可见范围：private-仅自己，shared-指定用户，public-公开' AFTER `version`,
+ADD COLUMN `fork_count` INT NOT NULL DEFAULT 0 COMMENT '被复制次数' AFTER `visibility`,
+ADD COLUMN `source_template_id` BIGINT UNSIGNED NULL COMMENT '复制来源模板ID' AFTER `fork_count`,
+ADD COLUMN `source_author` VARCHAR(32) NOT NULL DEFAULT '' COMMENT '原作者（脱敏手机号）' AFTER `source_template_id`,
+ADD INDEX `idx_visibility_forks` (`visibility`, `fork_count`),
+ADD INDEX `idx_source_template_id` (`source_template_id`);
+
+-- 2. 创建模板共享表
+CREATE TABLE IF NOT EXISTS `cese_template_share` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '共享ID',
+  `template_id` BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '被共享用户手机号',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  UNIQUE KEY `uk_template_mobile` (`template_id`, `mobile`),
+  INDEX `idx_mobile` (`mobile`),
+  CONSTRAINT `fk_share_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_share_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板共享表';
+
+-- 3. 显示表结构
+SHOW FULL COLUMNS FROM `cese_template`;
+SHOW FULL COLUMNS FROM `cese_template_share`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 013_add_template_sharing.sql
+-- ============================================
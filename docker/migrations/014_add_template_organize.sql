@@ -0,0 +1,56 @@
+-- ============================================
+-- 数据库迁移脚本：模板标签、文件夹与收藏
+-- 说明：模板增加所在文件夹、收藏与置顶标记，新增文件夹表、标签表及模板标签关联表
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 模板表增加文件夹、收藏与置顶
+ALTER TABLE `cese_template`
+ADD COLUMN `folder_id` BIGINT UNSIGNED NULL COMMENT '所在文件夹ID，为空表示未分类' AFTER `source_author`,
+ADD COLUMN `favorite` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否收藏' AFTER `folder_id`,
+ADD COLUMN `pinned` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否置顶' AFTER `favorite`,
+ADD INDEX `idx_folder_id` (`folder_id`);
+
+-- 2. 创建模板文件夹表
+CREATE TABLE IF NOT EXISTS `cese_template_folder` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '文件夹ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `name` VARCHAR(50) NOT NULL COMMENT '文件夹名称',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_mobile_name` (`mobile`, `name`),
+  CONSTRAINT `fk_folder_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板文件夹表';
+
+-- 3. 创建模板标签表
+CREATE TABLE IF NOT EXISTS `cese_template_tag` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '标签ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `name` VARCHAR(50) NOT NULL COMMENT '标签名称',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  UNIQUE KEY `uk_mobile_name` (`mobile`, `name`),
+  CONSTRAINT `fk_tag_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板标签表';
+
+-- 4. 创建模板标签关联表
+CREATE TABLE IF NOT EXISTS `cese_template_tag_link` (
+  `template_id` BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+  `tag_id` BIGINT UNSIGNED NOT NULL COMMENT '标签ID',
+  PRIMARY KEY (`template_id`, `tag_id`),
+  INDEX `idx_tag_id` (`tag_id`),
+  CONSTRAINT `fk_tag_link_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_tag_link_tag` FOREIGN KEY (`tag_id`) REFERENCES `cese_template_tag`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板标签关联表';
+
+-- 5. 显示表结构
+SHOW FULL COLUMNS FROM `cese_template`;
+SHOW FULL COLUMNS FROM `cese_template_folder`;
+SHOW FULL COLUMNS FROM `cese_template_tag`;
+SHOW FULL COLUMNS FROM `cese_template_tag_link`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 014_add_template_organize.sql
+-- ============================================
@@ -0,0 +1,20 @@
+-- ============================================
+-- 数据库迁移脚本：模板全文检索
+-- 说明：为模板的主题与六要素建立 FULLTEXT 索引，使用 ngram 分词以支持中文检索
+-- 要求：MySQL 5.7.6 及以上；分词长度由 ngram_token_size 决定，默认 2
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建全文索引（列顺序需与后端检索语句中的 MATCH 列一致）
+ALTER TABLE `cese_template`
+ADD FULLTEXT INDEX `ft_template_content` (`topic`, `task_objective`, `ai_role`, `my_role`, `key_information`, `behavior_rule`, `delivery_format`) WITH PARSER ngram;
+
+-- 2. 显示索引
+SHOW INDEX FROM `cese_template` WHERE `Key_name` = 'ft_template_content';
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 015_add_template_fulltext.sql
+-- ============================================
@@ -0,0 +1,46 @@
+-- ============================================
+-- 数据库迁移脚本：模板语义检索
+-- 说明：新增向量化设置表与模板向量表，用于按语义检索模板及查询相似模板
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 创建向量化设置表
+CREATE TABLE IF NOT EXISTS `cese_embedding_setting` (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '设置ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `provider_id` BIGINT UNSIGNED NOT NULL COMMENT '用于向量化的API Provider',
+  `model` VARCHAR(100) NOT NULL COMMENT '向量模型',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'idle' COMMENT '回填状态：idle/running/completed/failed',
+  `error` TEXT NULL COMMENT '回填失败原因',
+  `backfill_at` TIMESTAMP NULL COMMENT '最近一次回填完成时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  UNIQUE KEY `uk_mobile` (`mobile`),
+  INDEX `idx_status` (`status`),
+  CONSTRAINT `fk_embedding_user` FOREIGN KEY (`mobile`) REFERENCES `cese_user`(`mobile`) ON DELETE CASCADE,
+  CONSTRAINT `fk_embedding_provider` FOREIGN KEY (`provider_id`) REFERENCES `cese_api_provider`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='向量化设置表';
+
+-- 2. 创建模板向量表
+CREATE TABLE IF NOT EXISTS `cese_template_embedding` (
+  `template_id` BIGINT UNSIGNED NOT NULL PRIMARY KEY COMMENT '模板ID',
+  `mobile` VARCHAR(32) NOT NULL COMMENT '用户手机号',
+  `model` VARCHAR(100) NOT NULL COMMENT '向量模型',
+  `content_hash` CHAR(64) NOT NULL COMMENT '向量化内容与模型的SHA-256摘要',
+  `dimensions` INT NOT NULL COMMENT '向量维度',
+  `vector` MEDIUMBLOB NOT NULL COMMENT '归一化向量（float32小端序）',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  INDEX `idx_mobile_model` (`mobile`, `model`),
+  CONSTRAINT `fk_embedding_template` FOREIGN KEY (`template_id`) REFERENCES `cese_template`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模板向量表';
+
+-- 3. 显示表结构
+SHOW FULL COLUMNS FROM `cese_embedding_setting`;
+SHOW FULL COLUMNS FROM `cese_template_embedding`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 016_add_template_embedding.sql
+-- ============================================
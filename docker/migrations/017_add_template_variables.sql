@@ -0,0 +1,24 @@
+-- ============================================
+-- 数据库迁移脚本：模板变量
+-- 说明：模板及修订记录增加变量定义，要素中以 {{变量名}} 引用，渲染时替换为取值
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 模板表增加变量定义
+ALTER TABLE `cese_template`
+ADD COLUMN `variables` TEXT NULL COMMENT '变量定义（JSON），要素中以 {{变量名}} 引用' AFTER `pinned`;
+
+-- 2. 修订记录表增加变量定义，恢复版本时一并恢复
+ALTER TABLE `cese_template_revision`
+ADD COLUMN `variables` TEXT NULL COMMENT '变量定义（JSON）' AFTER `delivery_format`;
+
+-- 3. 显示表结构
+SHOW FULL COLUMNS FROM `cese_template`;
+SHOW FULL COLUMNS FROM `cese_template_revision`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 017_add_template_variables.sql
+-- ============================================
@@ -0,0 +1,26 @@
+-- ============================================
+-- 数据库迁移脚本：回收站（软删除）
+-- 说明：模板与API Provider删除后先移入回收站，可恢复；超过保留期（trash.retention_days）后由后台任务彻底删除
+-- ============================================
+
+USE `context_engine`;
+
+-- 1. 模板表增加删除时间
+ALTER TABLE `cese_template`
+ADD COLUMN `deleted_at` DATETIME(3) NULL COMMENT '移入回收站时间，为空表示未删除' AFTER `updated_at`,
+ADD INDEX `idx_deleted_at` (`deleted_at`);
+
+-- 2. API Provider表增加删除时间
+ALTER TABLE `cese_api_provider`
+ADD COLUMN `deleted_at` DATETIME(3) NULL COMMENT '移入回收站时间，为空表示未删除' AFTER `updated_at`,
+ADD INDEX `idx_deleted_at` (`deleted_at`);
+
+-- 3. 显示表结构
+SHOW FULL COLUMNS FROM `cese_template`;
+SHOW FULL COLUMNS FROM `cese_api_provider`;
+
+-- ============================================
+-- 迁移完成
+-- 执行命令（示例）：
+-- docker exec -i mysql mysql -uroot -pPASSWORD --default-character-set=utf8mb4 context_engine < 018_add_soft_delete.sql
+-- ============================================
@@ -0,0 +1,208 @@
+import React, { useState } from 'react';
+import { TemplateElementField, TemplateFieldDiff, TemplateService } from '../services';
+import '../styles/app.css';
+import AIProviderSelector from './AIProviderSelector';
+import Toast from './Toast';
+
+/**
+ * AI优化组件的Props接口
+ */
+export interface AIRefiningProps {
+    /** 是否显示组件 */
+    visible: boolean;
+    /** 关闭组件的回调 */
+    onClose: () => void;
+    /** 要素名称（如：行为规则） */
+    title: string;
+    /** 模板ID，优化基于已保存的模板内容 */
+    templateId: number;
+    /** 要优化的要素 */
+    field: TemplateElementField;
+    /** 确认保存的回调，返回新内容与修改说明，由父组件保存为新的修订版本 */
+    onSave: (value: string, changeNote: string) => Promise<void>;
+}
+
+/**
+ * 常用修改要求
+ */
+const PRESET_INSTRUCTIONS = ['规则更严格', '翻译成英文', '精简', '更具体', '改为条目列表'];
+
+/**
+ * 优化状态
+ */
+type RefineStatus = 'idle' | 'generating' | 'review' | 'saving' | 'error';
+
+/**
+ * AIRefining 组件
+ * 按修改要求改写单个要素，流式展示新内容，完成后展示与当前值的对比，确认后保存为新版本
+ *
+ * @component
+ */
+const AIRefining: React.FC<AIRefiningProps> = ({
+    visible,
+    onClose,
+    title,
+    templateId,
+    field,
+    onSave,
+}) => {
+    const [status, setStatus] = useState<RefineStatus>('idle');
+    const [instruction, setInstruction] = useState('');
+    const [providerId, setProviderId] = useState<number | undefined>();
+    const [streamed, setStreamed] = useState('');
+    const [diff, setDiff] = useState<TemplateFieldDiff | null>(null);
+    const [errorMessage, setErrorMessage] = useState('');
+    const [toast, setToast] = useState<{ message: string; type: 'success' | 'error' | 'warning' | 'info' } | null>(null);
+
+    /**
+     * 调用优化接口
+     */
+    const handleRefine = async () => {
+        if (!providerId) {
+            setToast({ message: '请先选择API Provider', type: 'warning' });
+            return;
+        }
+        if (!instruction.trim()) {
+            setToast({ message: '请输入修改要求', type: 'warning' });
+            return;
+        }
+
+        setStatus('generating');
+        setStreamed('');
+        setDiff(null);
+        setErrorMessage('');
+        try {
+            const result = await TemplateService.refine(
+                templateId,
+                { provider_id: providerId, field, instruction: instruction.trim() },
+                (chunk) => setStreamed(prev => prev + chunk)
+            );
+            setDiff(result);
+            setStatus('review');
+        } catch (error: any) {
+            console.error('AI优化失败:', error);
+            setStatus('error');
+            setErrorMessage(error.message || '优化失败，请重试');
+        }
+    };
+
+    /**
+     * 保存为新版本
+     */
+    const handleSave = async () => {
+        if (!diff) return;
+        setStatus('saving');
+        try {
+            await onSave(diff.to, `AI优化${title}：${instruction.trim()}`);
+            onClose();
+        } catch (error: any) {
+            setStatus('review');
+            setToast({ message: error.message || '保存失败，请重试', type: 'error' });
+        }
+    };
+
+    if (!visible) {
+        return null;
+    }
+
+    const busy = status === 'generating' || status === 'saving';
+
+    return (
+        <>
+            {toast && (
+                <Toast
+                    message={toast.message}
+                    type={toast.type}
+                    onClose={() => setToast(null)}
+                />
+            )}
+
+            <div className="fullscreen-modal">
+                <div className="fullscreen-content">
+                    <div className="fullscreen-header">
+                        <h3>{title} - AI优化</h3>
+                        <button className="close-button" onClick={onClose}>×</button>
+                    </div>
+
+                    <div className="ai-creating-body">
+                        <AIProviderSelector selectedProviderId={providerId} onChange={setProviderId} />
+
+                        <div className="refine-instruction">
+                            <input
+                                type="text"
+                                className="form-input"
+                                value={instruction}
+                                onChange={(e) => setInstruction(e.target.value)}
+                                placeholder="输入修改要求，如：规则更严格、翻译成英文、精简"
+                                maxLength={500}
+                                disabled={busy}
+                            />
+                            <div className="refine-presets">
+                                {PRESET_INSTRUCTIONS.map(preset => (
+                                    <button
+                                        key={preset}
+                                        className="btn btn-text"
+                                        onClick={() => setInstruction(preset)}
+                                        disabled={busy}
+                                    >
+                                        {preset}
+                                    </button>
+                                ))}
+                            </div>
+                        </div>
+
+                        {/* 生成中展示流式内容 */}
+                        {status === 'generating' && (
+                            <div className="ai-content">
+                                <textarea className="fullscreen-textarea" value={streamed} readOnly placeholder="AI正在修改..." />
+                            </div>
+                        )}
+
+                        {/* 生成完成后展示对比 */}
+                        {diff && (status === 'review' || status === 'saving') && (
+                            diff.changed ? (
+                                <div className="refine-diff">
+                                    {(diff.lines || []).map((line, index) => (
+                                        <div key={index} className={`diff-line diff-${line.op}`}>
+                                            <span className="diff-sign">{line.op === 'add' ? '+' : line.op === 'delete' ? '-' : ' '}</span>
+                                            {line.text}
+                                        </div>
+                                    ))}
+                                </div>
+                            ) : (
+                                <p className="refine-unchanged">内容没有变化</p>
+                            )
+                        )}
+
+                        {status === 'error' && (
+                            <div className="ai-error">
+                                <p className="error-icon">⚠️</p>
+                                <p className="error-text">{errorMessage}</p>
+                            </div>
+                        )}
+                    </div>
+
+                    <div className="fullscreen-footer">
+                        <div className="footer-right">
+                            <button className="btn btn-secondary" onClick={handleRefine} disabled={busy}>
+                                {diff || status === 'error' ? '重新优化' : '开始优化'}
+                            </button>
+                            <button
+                                className="btn btn-primary"
+                                onClick={handleSave}
+                                disabled={status !== 'review' || !diff?.changed}
+                            >
+                                保存为新版本
+                            </button>
+                            <button className="btn btn-secondary" onClick={onClose}>
+                                取消
+                            </button>
+                        </div>
+                    </div>
+                </div>
+            </div>
+        </>
+    );
+};
+
+export default AIRefining;
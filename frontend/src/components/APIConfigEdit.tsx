@@ -1,5 +1,5 @@
 import React, { useEffect, useState } from 'react';
-import { APIProvider, APIProviderData, APIProviderService, APIProviderUpdateData } from '../services';
+import { APIKindInfo, APIProvider, APIProviderData, APIProviderService, APIProviderUpdateData } from '../services';
 import '../styles/login.css'; // 复用登录页面样式
 
 interface APIConfigEditProps {
@@ -14,9 +14,20 @@ interface APIConfigEditProps {
 }
 
 /**
- * 常用API Provider配置
+ * 模型类型预设
  */
-const COMMON_PROVIDERS = [
+interface ProviderPreset {
+  kind: string;
+  name: string;
+  api_url: string;
+  models: string[];
+  key_hint?: string;
+}
+
+/**
+ * 常用API Provider配置（后端模型类型目录加载失败时使用）
+ */
+const COMMON_PROVIDERS: ProviderPreset[] = [
   {
     kind: 'OpenRouter',
     name: 'OpenRouter',
@@ -35,6 +46,12 @@ const COMMON_PROVIDERS = [
     api_url: 'https://api.openai.com/v1',
     models: ['gpt-4', 'gpt-4-turbo', 'gpt-3.5-turbo', 'gpt-4o'],
   },
+  {
+    kind: 'Azure OpenAI',
+    name: 'Azure OpenAI',
+    api_url: 'https://{your-resource}.openai.azure.com',
+    models: ['gpt-4o', 'gpt-4', 'gpt-35-turbo'],
+  },
   {
     kind: 'Anthropic',
     name: 'Anthropic Claude',
@@ -71,12 +88,24 @@ const COMMON_PROVIDERS = [
     api_url: 'https://dashscope.aliyuncs.com/compatible-mode/v1',
     models: ['qwen-turbo', 'qwen-plus', 'qwen-max'],
   },
+  {
+    kind: 'Moonshot',
+    name: 'Moonshot（Kimi）',
+    api_url: 'https://api.moonshot.cn/v1',
+    models: ['moonshot-v1-8k', 'moonshot-v1-32k', 'moonshot-v1-128k'],
+  },
   {
     kind: '豆包',
     name: '豆包',
     api_url: 'https://ark.cn-beijing.volces.com/api/v3',
     models: ['doubao-pro', 'doubao-lite'],
   },
+  {
+    kind: 'Coze',
+    name: 'Coze 智能体',
+    api_url: 'https://api.coze.cn',
+    models: [],
+  },
   {
     kind: '智普',
     name: '智景 AI',
@@ -130,6 +159,31 @@ const APIConfigEdit: React.FC<APIConfigEditProps> = ({
   // UI状态
   const [errors, setErrors] = useState<Record<string, string>>({});
   const [loading, setLoading] = useState(false);
+  const [providerPresets, setProviderPresets] = useState<ProviderPreset[]>(COMMON_PROVIDERS);
+
+  /**
+   * 从后端加载模型类型目录
+   */
+  useEffect(() => {
+    if (!visible) {
+      return;
+    }
+    APIProviderService.listKinds()
+      .then((kinds: APIKindInfo[]) => {
+        if (kinds.length > 0) {
+          setProviderPresets(kinds.map((k) => ({
+            kind: k.kind,
+            name: k.label,
+            api_url: k.default_url,
+            models: k.models,
+            key_hint: k.key_hint,
+          })));
+        }
+      })
+      .catch(() => {
+        // 加载失败时使用内置配置
+      });
+  }, [visible]);
 
   /**
    * 初始化表单数据
@@ -184,7 +238,7 @@ const APIConfigEdit: React.FC<APIConfigEditProps> = ({
     const kind = e.target.value;
     setSelectedKind(kind);
 
-    const config = COMMON_PROVIDERS.find(p => p.kind === kind);
+    const config = providerPresets.find(p => p.kind === kind);
     if (config) {
       setFormData(prev => ({
         ...prev,
@@ -342,7 +396,7 @@ const APIConfigEdit: React.FC<APIConfigEditProps> = ({
                 style={{ paddingLeft: '16px' }}
                 disabled={loading}
               >
-                {COMMON_PROVIDERS.map((p) => (
+                {providerPresets.map((p) => (
                   <option key={p.kind} value={p.kind}>
                     {p.kind}
                   </option>
@@ -387,7 +441,7 @@ const APIConfigEdit: React.FC<APIConfigEditProps> = ({
                 name="api_key"
                 value={formData.api_key}
                 onChange={handleChange}
-                placeholder={isEditMode ? "留空表示不修改" : "可选：sk-xxxxxx 或 API Key"}
+                placeholder={isEditMode ? "留空表示不修改" : (providerPresets.find(p => p.kind === selectedKind)?.key_hint || "可选：sk-xxxxxx 或 API Key")}
                 className={`login-input ${errors.api_key ? 'error' : ''}`}
                 style={{ paddingLeft: '16px' }}
                 disabled={loading}
@@ -443,7 +497,7 @@ const APIConfigEdit: React.FC<APIConfigEditProps> = ({
                   list="model-suggestions"
                 />
                 <datalist id="model-suggestions">
-                  {COMMON_PROVIDERS.find(p => p.kind === selectedKind)?.models.map((model) => (
+                  {providerPresets.find(p => p.kind === selectedKind)?.models.map((model) => (
                     <option key={model} value={model} />
                   ))}
                 </datalist>
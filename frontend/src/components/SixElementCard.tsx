@@ -1,6 +1,8 @@
 import React, { useRef, useState } from 'react';
 import '../styles/app.css';
+import { TemplateElementField } from '../services';
 import AICreating from './AICreating';
+import AIRefining from './AIRefining';
 import Toast from './Toast';
 
 // 扩展 Window 接口以支持 webkitSpeechRecognition
@@ -21,6 +23,12 @@ interface SixElementCardProps {
   promptTemplate?: string;
   /** 替换占位符的数据 */
   placeholders?: Record<string, string>;
+  /** 已保存的模板ID，与 field、onRefined 同时提供时可使用AI优化 */
+  templateId?: number;
+  /** 要素字段名 */
+  field?: TemplateElementField;
+  /** AI优化确认保存的回调，由父组件保存为新的修订版本 */
+  onRefined?: (value: string, changeNote: string) => Promise<void>;
 }
 
 /**
@@ -36,11 +44,15 @@ const SixElementCard: React.FC<SixElementCardProps> = ({
   isTextarea = false, 
   error,
   promptTemplate,
-  placeholders 
+  placeholders,
+  templateId,
+  field,
+  onRefined
 }) => {
   const [isListening, setIsListening] = useState(false);
   const [showFullscreen, setShowFullscreen] = useState(false);
   const [showAICreating, setShowAICreating] = useState(false);
+  const [showAIRefining, setShowAIRefining] = useState(false);
   const [toast, setToast] = useState<{ message: string; type: 'success' | 'error' | 'warning' | 'info' } | null>(null);
   const textareaRef = useRef<HTMLTextAreaElement>(null);
   const recognitionRef = useRef<any>(null);
@@ -177,6 +189,13 @@ const SixElementCard: React.FC<SixElementCardProps> = ({
     </svg>
   );
 
+  // AI优化图标SVG
+  const RefineIcon = () => (
+    <svg width="16" height="16" viewBox="0 0 24 24" fill="currentColor">
+      <path d="M7.5 5.6L10 7 8.6 4.5 10 2 7.5 3.4 5 2l1.4 2.5L5 7zm12 9.8L17 14l1.4 2.5L17 19l2.5-1.4L22 19l-1.4-2.5L22 14zM22 2l-2.5 1.4L17 2l1.4 2.5L17 7l2.5-1.4L22 7l-1.4-2.5zm-7.63 5.29a.996.996 0 00-1.41 0L1.29 18.96a.996.996 0 000 1.41l2.34 2.34c.39.39 1.02.39 1.41 0L16.7 11.05a.996.996 0 000-1.41l-2.33-2.35zm-1.03 5.49l-2.12-2.12 2.44-2.44 2.12 2.12-2.44 2.44z"/>
+    </svg>
+  );
+
   // 全屏图标SVG
   const FullscreenIcon = () => (
     <svg width="16" height="16" viewBox="0 0 24 24" fill="currentColor">
@@ -220,6 +239,15 @@ const SixElementCard: React.FC<SixElementCardProps> = ({
                       <AIIcon />
                     </button>
                   )}
+                  {templateId && field && onRefined && (
+                    <button 
+                      className="ai-generate-button"
+                      onClick={() => setShowAIRefining(true)}
+                      title="AI优化"
+                    >
+                      <RefineIcon />
+                    </button>
+                  )}
                   <button 
                     className="fullscreen-button"
                     onClick={handleFullscreen}
@@ -286,6 +314,18 @@ const SixElementCard: React.FC<SixElementCardProps> = ({
         />
       )}
 
+      {/* AI优化模态框 */}
+      {showAIRefining && templateId && field && onRefined && (
+        <AIRefining
+          visible={showAIRefining}
+          onClose={() => setShowAIRefining(false)}
+          title={title}
+          templateId={templateId}
+          field={field}
+          onSave={onRefined}
+        />
+      )}
+
       {/* Toast 提示 */}
       {toast && (
         <Toast
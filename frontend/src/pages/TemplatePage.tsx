@@ -99,6 +99,11 @@ const TemplatePage = () => {
    */
   const [showBatchGenerate, setShowBatchGenerate] = useState(false);
 
+  /**
+   * 已保存的模板ID，保存后可使用AI优化单个要素
+   */
+  const [savedTemplateId, setSavedTemplateId] = useState<number | undefined>();
+
   /**
    * 提示词模板缓存
    */
@@ -171,6 +176,29 @@ const TemplatePage = () => {
     }
   };
 
+  /**
+   * 将AI优化的结果保存为已保存模板的新版本
+   * @param {FieldName} field - 字段名
+   */
+  const handleRefined = (field: FieldName) => async (value: string, changeNote: string) => {
+    if (!savedTemplateId) {
+      return;
+    }
+    const data = { ...templateData, [field]: value };
+    await TemplateService.update(savedTemplateId, {
+      topic: data.topic,
+      task_objective: data.taskObjective,
+      ai_role: data.aiRole,
+      my_role: data.myRole,
+      key_information: data.keyInformation,
+      behavior_rule: data.behaviorRules,
+      delivery_format: data.deliveryFormat,
+      change_note: changeNote,
+    });
+    handleInputChange(field, value);
+    showToast('已保存为新版本', 'success');
+  };
+
   /**
    * 处理生成模板按钮点击
    * 验证表单并生成模板
@@ -218,7 +246,7 @@ const TemplatePage = () => {
 
     try {
       // 调用后端API保存模板
-      await TemplateService.create({
+      const saved = await TemplateService.create({
         topic: templateData.topic,
         task_objective: templateData.taskObjective,
         ai_role: templateData.aiRole,
@@ -227,6 +255,7 @@ const TemplatePage = () => {
         behavior_rule: templateData.behaviorRules,
         delivery_format: templateData.deliveryFormat,
       });
+      setSavedTemplateId(saved.id);
       
       showToast('模板保存成功！', 'success');
     } catch (error: any) {
@@ -412,6 +441,9 @@ ${templateData.deliveryFormat || '[指定输出格式，如：Markdown表格、J
             title="任务目标"
             value={templateData.taskObjective}
             onChange={(value) => handleInputChange('taskObjective', value)}
+            templateId={savedTemplateId}
+            field="task_objective"
+            onRefined={handleRefined('taskObjective')}
             placeholder="[清晰描述你希望AI完成的具体任务]"
             isTextarea={true}
             error={errors.taskObjective}
@@ -424,6 +456,9 @@ ${templateData.deliveryFormat || '[指定输出格式，如：Markdown表格、J
             title="AI的角色"
             value={templateData.aiRole}
             onChange={(value) => handleInputChange('aiRole', value)}
+            templateId={savedTemplateId}
+            field="ai_role"
+            onRefined={handleRefined('aiRole')}
             placeholder="[指定AI扮演的角色，如：专业文案、数据分析师、客服代表等]"
             isTextarea={true}
             error={errors.aiRole}
@@ -437,6 +472,9 @@ ${templateData.deliveryFormat || '[指定输出格式，如：Markdown表格、J
             title="我的角色"
             value={templateData.myRole}
             onChange={(value) => handleInputChange('myRole', value)}
+            templateId={savedTemplateId}
+            field="my_role"
+            onRefined={handleRefined('myRole')}
             placeholder="[说明你是谁，你在任务中的身份，如：产品经理、学习者、客户等]"
             isTextarea={true}
             error={errors.myRole}
@@ -450,6 +488,9 @@ ${templateData.deliveryFormat || '[指定输出格式，如：Markdown表格、J
             title="关键信息"
             value={templateData.keyInformation}
             onChange={(value) => handleInputChange('keyInformation', value)}
+            templateId={savedTemplateId}
+            field="key_information"
+            onRefined={handleRefined('keyInformation')}
             placeholder="[提供任务必需的背景信息、数据、参考资料或文件链接]"
             isTextarea={true}
             error={errors.keyInformation}
@@ -464,6 +505,9 @@ ${templateData.deliveryFormat || '[指定输出格式，如：Markdown表格、J
             title="行为规则"
             value={templateData.behaviorRules}
             onChange={(value) => handleInputChange('behaviorRules', value)}
+            templateId={savedTemplateId}
+            field="behavior_rule"
+            onRefined={handleRefined('behaviorRules')}
             placeholder="[必须遵守的规则1]\n[必须遵守的规则2]\n[不可做的事情1]\n[不可做的事情2]"
             isTextarea={true}
             error={errors.behaviorRules}
@@ -479,6 +523,9 @@ ${templateData.deliveryFormat || '[指定输出格式，如：Markdown表格、J
             title="交付格式"
             value={templateData.deliveryFormat}
             onChange={(value) => handleInputChange('deliveryFormat', value)}
+            templateId={savedTemplateId}
+            field="delivery_format"
+            onRefined={handleRefined('deliveryFormat')}
             placeholder="[指定输出格式，如：Markdown表格、JSON、邮件正文、PPT大纲等]"
             isTextarea={true}
             error={errors.deliveryFormat}
@@ -34,12 +34,85 @@ export interface BackendGenerateRequest {
   prompt: string;
   /** 温度参数 0-2，默认0.7 */
   temperature?: number;
-  /** 最大token数，默认2000 */
+  /** 最大token数，默认2000，已知模型会限制在剩余上下文之内 */
   max_tokens?: number;
   /** 是否流式响应，默认true */
   stream?: boolean;
   /** 可选：覆盖Provider配置的模型 */
   model?: string;
+  /** 可选：附带的图片（已上传图片ID或图片URL） */
+  images?: GenerateImageRef[];
+  /** 可选：生成所用的模板ID，按该模板的输出过滤设置处理生成内容，并检查模板关键信息中的提示词注入 */
+  template_id?: number;
+}
+
+/**
+ * 输入检查未通过的原因（错误码 3001 的 data）
+ */
+export interface InputRejection {
+  /** 命中的规则 */
+  rule: 'prompt_length' | 'token_limit' | 'blocked_pattern' | 'prompt_injection';
+  /** 命中的字段：prompt/system/messages/key_information等 */
+  field: string;
+  /** 可读的拒绝原因 */
+  reason: string;
+  /** 命中的内容或注入特征 */
+  match?: string;
+  /** 长度类规则的上限 */
+  limit?: number;
+  /** 长度类规则的实际值 */
+  actual?: number;
+}
+
+/**
+ * 生成请求的token预估与上下文窗口预算
+ */
+export interface TokenEstimate {
+  /** 实际使用的模型 */
+  model: string;
+  /** 模型是否在目录中 */
+  known_model: boolean;
+  /** 精确计算时使用的BPE编码 */
+  encoding?: string;
+  /** 输入token数是否精确计算 */
+  exact: boolean;
+  /** 输入token数（含system、历史消息、工具定义与图片） */
+  prompt_tokens: number;
+  /** 上下文窗口，未知模型为0 */
+  context_window?: number;
+  /** 模型单次最大输出 */
+  max_output?: number;
+  /** 上下文窗口扣除输入后剩余的token数 */
+  remaining: number;
+  /** 请求的max_tokens，未指定时为默认值 */
+  requested_max_tokens: number;
+  /** 调整后实际使用的max_tokens */
+  max_tokens: number;
+  /** max_tokens 是否被调整 */
+  clamped: boolean;
+  /** 输入是否已超出上下文窗口 */
+  exceeded: boolean;
+}
+
+/**
+ * 生成请求引用的图片，id 与 url 二选一
+ */
+export interface GenerateImageRef {
+  /** 已上传图片ID */
+  id?: number;
+  /** 图片URL */
+  url?: string;
+}
+
+/**
+ * 上传的图片信息
+ */
+export interface UploadedImage {
+  id: number;
+  file_name: string;
+  mime_type: string;
+  size: number;
+  created_at: string;
 }
 
 /**
@@ -313,7 +386,8 @@ export class AIService {
     prompt: string,
     onStream?: (chunk: string) => void,
     temperature: number = 0.7,
-    maxTokens: number = 2000
+    maxTokens: number = 2000,
+    images?: GenerateImageRef[]
   ): Promise<AIGenerateResponse> {
     try {
       // 构建请求URL，使用全局配置
@@ -333,6 +407,9 @@ export class AIService {
         max_tokens: maxTokens,
         stream: !!onStream,
       };
+      if (images && images.length > 0) {
+        requestBody.images = images;
+      }
 
       const headers: HeadersInit = {
         'Content-Type': 'application/json',
@@ -350,6 +427,14 @@ export class AIService {
         throw new Error(errorData.message || `API调用失败: ${response.status}`);
       }
 
+      // 参数错误、输入检查未通过等情况返回JSON错误而非SSE
+      if (response.headers.get('Content-Type')?.includes('application/json')) {
+        const errorData = await response.clone().json().catch(() => ({}));
+        if (errorData.code !== undefined && errorData.code !== 0) {
+          throw new Error(errorData.message || '生成失败，请重试');
+        }
+      }
+
       // 流式响应
       if (onStream && response.body) {
         return await this.handleBackendStreamResponse(response, onStream);
@@ -375,6 +460,62 @@ export class AIService {
     }
   }
 
+  /**
+   * 上传生成请求使用的图片
+   * @param file 图片文件
+   * @returns 上传后的图片信息
+   */
+  static async uploadImage(file: File): Promise<UploadedImage> {
+    const token = localStorage.getItem('auth_token');
+    if (!token) {
+      throw new Error('未登录，请先登录');
+    }
+
+    const formData = new FormData();
+    formData.append('file', file);
+
+    const response = await fetch(getApiUrl('generate/image'), {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+      },
+      body: formData,
+    });
+
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok || data.code !== 0) {
+      throw new Error(data.message || `图片上传失败: ${response.status}`);
+    }
+    return data.data as UploadedImage;
+  }
+
+  /**
+   * 预估生成请求的token数，不调用模型
+   * @param request 与生成接口相同的请求参数
+   * @returns token预估与调整后的max_tokens
+   */
+  static async estimateTokens(request: BackendGenerateRequest): Promise<TokenEstimate> {
+    const token = localStorage.getItem('auth_token');
+    if (!token) {
+      throw new Error('未登录，请先登录');
+    }
+
+    const response = await fetch(getApiUrl('generate/estimate'), {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${token}`,
+      },
+      body: JSON.stringify(request),
+    });
+
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok || data.code !== 0) {
+      throw new Error(data.message || `token预估失败: ${response.status}`);
+    }
+    return data.data as TokenEstimate;
+  }
+
   /**
    * 处理后端的流式响应
    */
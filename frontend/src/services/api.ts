@@ -4,7 +4,7 @@
  */
 
 import HttpClient from './auth';
-import { PageParams, PageResponse } from './common';
+import { getApiUrl, PageParams, PageResponse } from './common';
 
 /**
  * 模板数据接口
@@ -24,6 +24,139 @@ export interface TemplateData {
   behavior_rule?: string;
   /** 交付格式 */
   delivery_format?: string;
+  /** 修改说明（可选，记录在本次生成的修订版本中） */
+  change_note?: string;
+  /** 变量定义，要素中以 {{变量名}} 引用；更新时不传表示保持不变，传空数组表示清空 */
+  variables?: TemplateVariable[];
+}
+
+/**
+ * 模板变量类型
+ */
+export type TemplateVariableType = 'string' | 'number' | 'boolean' | 'enum';
+
+/**
+ * 模板变量定义
+ */
+export interface TemplateVariable {
+  /** 变量名，字母（含中文）或下划线开头 */
+  name: string;
+  /** 类型，默认 string */
+  type?: TemplateVariableType;
+  /** 默认值，需符合类型 */
+  default?: string | number | boolean;
+  /** 是否必填 */
+  required?: boolean;
+  /** 说明 */
+  description?: string;
+  /** enum 类型的可选值 */
+  options?: string[];
+}
+
+/**
+ * 模板渲染结果
+ */
+export interface TemplateRenderResult {
+  topic: string;
+  task_objective: string;
+  ai_role: string;
+  my_role: string;
+  key_information: string;
+  behavior_rule: string;
+  delivery_format: string;
+  /** 标准Markdown格式的完整提示词 */
+  prompt: string;
+  /** 实际使用的变量取值（含默认值） */
+  values: Record<string, string>;
+  /** 要素中未声明的占位符 */
+  undeclared?: string[];
+}
+
+/**
+ * 可由AI优化的要素
+ */
+export type TemplateElementField =
+  | 'task_objective'
+  | 'ai_role'
+  | 'my_role'
+  | 'key_information'
+  | 'behavior_rule'
+  | 'delivery_format';
+
+/**
+ * 优化单个要素请求
+ */
+export interface TemplateRefineRequest {
+  /** API Provider ID */
+  provider_id: number;
+  /** 要修改的要素 */
+  field: TemplateElementField;
+  /** 修改要求，如"规则更严格""翻译成英文""精简" */
+  instruction: string;
+  /** 覆盖Provider配置的模型 */
+  model?: string;
+  /** 温度参数，默认0.7 */
+  temperature?: number;
+}
+
+/**
+ * 模板质量检查问题级别
+ */
+export type TemplateLintSeverity = 'error' | 'warning' | 'info';
+
+/**
+ * 模板质量检查问题，field 为空表示针对整个模板
+ */
+export interface TemplateLintFinding {
+  field: string;
+  label: string;
+  /** 规则，如 empty、vague_objective、unresolved_placeholder；大模型建议为 llm_review */
+  rule: string;
+  severity: TemplateLintSeverity;
+  message: string;
+}
+
+/**
+ * 模板质量检查结果
+ */
+export interface TemplateLintResult {
+  /** 规则评分 0-100 */
+  score: number;
+  findings: TemplateLintFinding[];
+  /** 大模型评审，仅在指定 provider_id 时返回 */
+  review?: {
+    provider_id: number;
+    score?: number;
+    summary?: string;
+    suggestions?: TemplateLintFinding[];
+    /** 评审失败原因 */
+    error?: string;
+  };
+}
+
+/**
+ * 模板质量检查选项
+ */
+export interface TemplateLintOptions {
+  /** 评审模型的 API Provider ID，为空时只做规则检查 */
+  provider_id?: number;
+  /** 覆盖Provider配置的模型 */
+  model?: string;
+}
+
+/**
+ * 模板可见范围：private 仅自己，shared 指定用户，public 公开到模板广场
+ */
+export type TemplateVisibility = 'private' | 'shared' | 'public';
+
+/**
+ * 模板的可见范围与共享用户
+ */
+export interface TemplateSharing {
+  template_id: number;
+  visibility: TemplateVisibility;
+  /** 可查看的用户手机号（visibility 为 shared 时） */
+  share_with: string[];
 }
 
 /**
@@ -34,16 +167,120 @@ export interface Template extends TemplateData {
   id: number;
   /** 用户手机号 */
   mobile: string;
+  /** 当前版本号 */
+  version: number;
+  /** 可见范围 */
+  visibility: TemplateVisibility;
+  /** 被复制次数 */
+  fork_count: number;
+  /** 复制来源模板 */
+  source_template_id?: number;
+  /** 原作者（脱敏手机号） */
+  source_author?: string;
+  /** 所在文件夹，为空表示未分类 */
+  folder_id?: number;
+  /** 是否收藏 */
+  favorite: boolean;
+  /** 是否置顶 */
+  pinned: boolean;
+  /** 标签名称（仅自己的模板） */
+  tags?: string[];
+  /** 全文检索时的相关度与高亮片段 */
+  search?: TemplateSearchMatch;
   /** 创建时间 */
   created_at: string;
   /** 更新时间 */
   updated_at: string;
 }
 
+/**
+ * 全文检索命中字段的内容片段
+ */
+export interface TemplateSnippet {
+  /** 字段名 */
+  field: string;
+  /** 字段中文名 */
+  label: string;
+  text: string;
+  /** 命中内容在 text 中的字符区间 [起始, 结束) */
+  highlights: [number, number][];
+}
+
+/**
+ * 模板的全文检索结果
+ */
+export interface TemplateSearchMatch {
+  score: number;
+  snippets: TemplateSnippet[];
+}
+
+/**
+ * 模板修订版本（完整快照）
+ */
+export interface TemplateRevision {
+  /** 修订ID */
+  id: number;
+  /** 模板ID */
+  template_id: number;
+  /** 版本号 */
+  version: number;
+  /** 修改人手机号 */
+  mobile: string;
+  topic: string;
+  task_objective: string;
+  ai_role: string;
+  my_role: string;
+  key_information: string;
+  behavior_rule: string;
+  delivery_format: string;
+  /** 变量定义 */
+  variables?: TemplateVariable[];
+  /** 修改说明 */
+  change_note: string;
+  /** 创建时间 */
+  created_at: string;
+}
+
+/**
+ * 逐行对比的一行
+ */
+export interface TemplateDiffLine {
+  op: 'equal' | 'delete' | 'add';
+  text: string;
+}
+
+/**
+ * 单个字段的对比结果
+ */
+export interface TemplateFieldDiff {
+  /** 字段名 */
+  field: string;
+  /** 字段中文名 */
+  label: string;
+  /** 是否有变化 */
+  changed: boolean;
+  from: string;
+  to: string;
+  /** 有变化时的逐行对比 */
+  lines?: TemplateDiffLine[];
+}
+
+/**
+ * 两个修订版本的对比结果
+ */
+export interface TemplateRevisionDiff {
+  template_id: number;
+  from: number;
+  to: number;
+  fields: TemplateFieldDiff[];
+}
+
 /**
  * 模板查询参数
  */
 export interface TemplateQueryParams extends PageParams {
+  /** 全文检索主题与六要素，按相关度排序 */
+  q?: string;
   /** 主题（模糊匹配） */
   topic?: string;
   /** 任务目标（模糊匹配） */
@@ -58,8 +295,125 @@ export interface TemplateQueryParams extends PageParams {
   behavior_rule?: string;
   /** 交付格式（模糊匹配） */
   delivery_format?: string;
+  /** 标签名称，多个以逗号分隔，需同时包含 */
+  tag?: string;
+  /** 文件夹ID，0 表示未分类 */
+  folder_id?: number;
+  /** 仅查询收藏的模板 */
+  favorite?: boolean;
+}
+
+/**
+ * 整理模板请求，未提供的字段保持不变
+ */
+export interface TemplateOrganizeData {
+  /** 以该列表整体替换模板标签 */
+  tags?: string[];
+  /** 移入的文件夹，0 表示移出文件夹 */
+  folder_id?: number;
+  favorite?: boolean;
+  pinned?: boolean;
+}
+
+/**
+ * 标签及使用该标签的模板数
+ */
+export interface TemplateTag {
+  id: number;
+  name: string;
+  template_count: number;
+}
+
+/**
+ * 模板文件夹及其中的模板数
+ */
+export interface TemplateFolder {
+  id: number;
+  name: string;
+  template_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 语义相似的模板
+ */
+export interface SimilarTemplate extends Template {
+  /** 余弦相似度 */
+  similarity: number;
+}
+
+/**
+ * 服务端导出格式
+ * - markdown: "# 主题" 加六要素章节
+ * - coze: 仅六要素章节，可直接作为Coze智能体提示词
+ * - openai: OpenAI messages（system + user）
+ */
+export type TemplateExportFormat = 'markdown' | 'coze' | 'json' | 'yaml' | 'openai';
+
+/**
+ * 导入文件中解析出的一个模板
+ */
+export interface TemplateImportItem {
+  template: TemplateData;
+  /** 缺少的要素 */
+  missing?: string[];
+  /** 导入成功后的模板ID */
+  template_id?: number;
+  error?: string;
+}
+
+/**
+ * 单个文件的导入结果，error 表示整个文件无法解析
+ */
+export interface TemplateImportFileResult {
+  file: string;
+  error?: string;
+  templates: TemplateImportItem[];
 }
 
+/**
+ * 模板导入结果
+ */
+export interface TemplateImportResult {
+  /** 是否为预览（只解析不保存） */
+  preview: boolean;
+  /** 解析出的模板数 */
+  total: number;
+  /** 导入成功（预览时为可导入）的模板数 */
+  imported: number;
+  /** 失败的模板数与无法解析的文件数 */
+  failed: number;
+  files: TemplateImportFileResult[];
+}
+
+/**
+ * 获取认证Token
+ */
+const getToken = (): string => {
+  const token = localStorage.getItem('auth_token');
+  if (!token) {
+    throw new Error('未登录，请先登录');
+  }
+  return token;
+};
+
+/**
+ * 下载服务端生成的文件
+ */
+const fetchFile = async (path: string): Promise<Blob> => {
+  const response = await fetch(getApiUrl(path), {
+    headers: {
+      'Authorization': `Bearer ${getToken()}`,
+    },
+  });
+  if (!response.ok) {
+    const errorData = await response.json().catch(() => ({}));
+    throw new Error(errorData.message || `导出失败: ${response.status}`);
+  }
+  return response.blob();
+};
+
 /**
  * 模板服务类
  * @description 提供模板相关的所有API操作
@@ -190,7 +544,7 @@ export class TemplateService {
    * ```typescript
    * try {
    *   await TemplateService.delete(1);
-   *   console.log('删除成功');
+   *   console.log('已移入回收站');
    * } catch (error) {
    *   console.error('删除失败:', error.message);
    * }
@@ -204,6 +558,463 @@ export class TemplateService {
     });
   }
 
+  /**
+   * 获取模板的可见范围与共享用户（仅所有者）
+   * @param id - 模板ID
+   * @returns Promise<TemplateSharing> 共享设置
+   */
+  static async getSharing(id: number): Promise<TemplateSharing> {
+    return HttpClient.get<TemplateSharing>(`/template/${id}/sharing`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 设置模板的可见范围，shared 时以 shareWith 整体替换共享用户
+   * @param id - 模板ID
+   * @param visibility - 可见范围
+   * @param shareWith - 可查看的用户手机号（visibility 为 shared 时必填）
+   * @returns Promise<TemplateSharing> 新的共享设置
+   *
+   * @example
+   * ```typescript
+   * await TemplateService.setSharing(1, 'shared', ['13900139000']);
+   * await TemplateService.setSharing(1, 'public');
+   * ```
+   */
+  static async setSharing(
+    id: number,
+    visibility: TemplateVisibility,
+    shareWith: string[] = []
+  ): Promise<TemplateSharing> {
+    return HttpClient.put<TemplateSharing>(
+      `/template/${id}/sharing`,
+      { visibility, share_with: shareWith },
+      {
+        requireAuth: true,
+        showLoading: true,
+        showError: true,
+      }
+    );
+  }
+
+  /**
+   * 用变量取值渲染模板，可渲染自己的、公开的或共享给自己的模板
+   * @param id - 模板ID
+   * @param values - 变量名到取值的映射，未提供的变量使用默认值
+   * @returns Promise<TemplateRenderResult> 替换变量后的要素与完整提示词
+   *
+   * @example
+   * ```typescript
+   * const { prompt } = await TemplateService.render(1, { 客户: '星辰科技', count: 3 });
+   * ```
+   */
+  static async render(
+    id: number,
+    values: Record<string, string | number | boolean>
+  ): Promise<TemplateRenderResult> {
+    return HttpClient.post<TemplateRenderResult>(`/template/${id}/render`, { values }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 检查模板质量，返回评分与各要素的问题
+   * @param id - 模板ID
+   * @param options - 可选的大模型评审
+   * @returns Promise<TemplateLintResult> 检查结果
+   */
+  static async lint(id: number, options?: TemplateLintOptions): Promise<TemplateLintResult> {
+    return HttpClient.post<TemplateLintResult>(`/template/${id}/lint`, options ?? {}, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 检查尚未保存的模板内容
+   * @param data - 模板内容
+   * @param options - 可选的大模型评审
+   * @returns Promise<TemplateLintResult> 检查结果
+   */
+  static async lintDraft(data: Partial<TemplateData>, options?: TemplateLintOptions): Promise<TemplateLintResult> {
+    return HttpClient.post<TemplateLintResult>('/template/lint', { ...data, ...options }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 使用大模型按修改要求改写单个要素，流式返回新内容，结果不会自动保存
+   * @param id - 模板ID
+   * @param request - 要素与修改要求
+   * @param onStream - 接收内容片段的回调
+   * @returns Promise<TemplateFieldDiff> 新内容（to）与当前值的对比，确认后通过 update 保存为新版本
+   *
+   * @example
+   * ```typescript
+   * const diff = await TemplateService.refine(1, { provider_id: 2, field: 'behavior_rule', instruction: '规则更严格' }, chunk => console.log(chunk));
+   * await TemplateService.update(1, { ...data, behavior_rule: diff.to, change_note: '规则更严格' });
+   * ```
+   */
+  static async refine(
+    id: number,
+    request: TemplateRefineRequest,
+    onStream?: (chunk: string) => void
+  ): Promise<TemplateFieldDiff> {
+    const response = await fetch(getApiUrl(`template/${id}/refine`), {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${getToken()}`,
+      },
+      body: JSON.stringify(request),
+    });
+
+    // 开始生成前的错误以JSON返回
+    if (!response.ok || response.headers.get('Content-Type')?.includes('application/json')) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.message || `优化失败: ${response.status}`);
+    }
+
+    const reader = response.body!.getReader();
+    const decoder = new TextDecoder('utf-8');
+    let buffer = '';
+    let diff: TemplateFieldDiff | undefined;
+    while (true) {
+      const { done, value } = await reader.read();
+      if (done) break;
+
+      buffer += decoder.decode(value, { stream: true });
+      const events = buffer.split('\n\n');
+      buffer = events.pop() || '';
+      for (const event of events) {
+        if (!event.startsWith('data: ')) continue;
+        const json = JSON.parse(event.slice(6));
+        if (json.error) {
+          throw new Error(json.error);
+        }
+        if (json.type === 'diff') {
+          diff = json.diff;
+        } else if (json.content && onStream) {
+          onStream(json.content);
+        }
+      }
+    }
+
+    if (!diff) {
+      throw new Error('优化失败，请重试');
+    }
+    return diff;
+  }
+
+  /**
+   * 分页查询其他用户共享给自己的模板
+   * @param params - 分页参数
+   * @returns Promise<PageResponse<Template>> 共享给自己的模板
+   */
+  static async listShared(params?: PageParams): Promise<PageResponse<Template>> {
+    return HttpClient.get<PageResponse<Template>>('/template/shared', params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 将公开或共享给自己的模板复制到自己的账户
+   * @param id - 来源模板ID
+   * @returns Promise<Template> 复制得到的模板
+   */
+  static async fork(id: number): Promise<Template> {
+    return HttpClient.post<Template>(`/template/${id}/fork`, undefined, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 设置模板的标签、文件夹、收藏与置顶（仅所有者）
+   * @param id - 模板ID
+   * @param data - 需要修改的字段
+   * @returns Promise<Template> 整理后的模板
+   *
+   * @example
+   * ```typescript
+   * await TemplateService.organize(1, { tags: ['营销', '短文'], folder_id: 3 });
+   * await TemplateService.organize(1, { pinned: true });
+   * ```
+   */
+  static async organize(id: number, data: TemplateOrganizeData): Promise<Template> {
+    return HttpClient.put<Template>(`/template/${id}/organize`, data, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 查询标签，prefix 非空时按名称前缀联想
+   * @param prefix - 名称前缀
+   * @param limit - 返回数量，默认 10
+   * @returns Promise<TemplateTag[]> 按使用次数降序的标签
+   */
+  static async listTags(prefix?: string, limit?: number): Promise<TemplateTag[]> {
+    return HttpClient.get<TemplateTag[]>('/template/tags', { prefix, limit }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+  }
+
+  /**
+   * 重命名标签，新名称已存在时合并到该标签
+   * @param id - 标签ID
+   * @param name - 新名称
+   * @returns Promise 重命名后的标签及是否发生合并
+   */
+  static async renameTag(id: number, name: string): Promise<{ tag: TemplateTag; merged: boolean }> {
+    return HttpClient.put<{ tag: TemplateTag; merged: boolean }>(`/template/tags/${id}`, { name }, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 将多个标签合并到目标标签
+   * @param tagIds - 被合并的标签
+   * @param targetId - 目标标签
+   * @returns Promise<TemplateTag> 合并后的目标标签
+   */
+  static async mergeTags(tagIds: number[], targetId: number): Promise<TemplateTag> {
+    return HttpClient.post<TemplateTag>('/template/tags/merge', { tag_ids: tagIds, target_id: targetId }, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 删除标签并从所有模板上移除
+   * @param id - 标签ID
+   */
+  static async deleteTag(id: number): Promise<void> {
+    return HttpClient.delete<void>(`/template/tags/${id}`, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 查询文件夹及其中的模板数
+   * @returns Promise<TemplateFolder[]> 按名称排序的文件夹
+   */
+  static async listFolders(): Promise<TemplateFolder[]> {
+    return HttpClient.get<TemplateFolder[]>('/template/folders', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 创建文件夹
+   * @param name - 文件夹名称
+   * @returns Promise<TemplateFolder> 新建的文件夹
+   */
+  static async createFolder(name: string): Promise<TemplateFolder> {
+    return HttpClient.post<TemplateFolder>('/template/folders', { name }, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 重命名文件夹
+   * @param id - 文件夹ID
+   * @param name - 新名称
+   * @returns Promise<TemplateFolder> 重命名后的文件夹
+   */
+  static async renameFolder(id: number, name: string): Promise<TemplateFolder> {
+    return HttpClient.put<TemplateFolder>(`/template/folders/${id}`, { name }, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 删除文件夹，其中的模板移回未分类
+   * @param id - 文件夹ID
+   */
+  static async deleteFolder(id: number): Promise<void> {
+    return HttpClient.delete<void>(`/template/folders/${id}`, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 按语义检索模板，需先配置向量模型
+   * @param q - 检索内容
+   * @param limit - 返回数量，默认10
+   * @returns Promise<SimilarTemplate[]> 按相似度降序的模板
+   */
+  static async searchSimilar(q: string, limit?: number): Promise<SimilarTemplate[]> {
+    return HttpClient.get<SimilarTemplate[]>('/template/similar', { q, limit }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 查询与指定模板相似的其他模板
+   * @param id - 模板ID
+   * @param limit - 返回数量，默认10
+   * @returns Promise<SimilarTemplate[]> 按相似度降序的模板
+   */
+  static async listSimilar(id: number, limit?: number): Promise<SimilarTemplate[]> {
+    return HttpClient.get<SimilarTemplate[]>(`/template/${id}/similar`, { limit }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 查询模板的修订历史（按版本号倒序）
+   * @param id - 模板ID
+   * @param params - 分页参数
+   * @returns Promise<PageResponse<TemplateRevision>> 分页的修订列表
+   */
+  static async listRevisions(
+    id: number,
+    params?: PageParams
+  ): Promise<PageResponse<TemplateRevision>> {
+    return HttpClient.get<PageResponse<TemplateRevision>>(`/template/${id}/revisions`, params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取指定修订版本
+   * @param id - 模板ID
+   * @param version - 版本号
+   * @returns Promise<TemplateRevision> 修订版本快照
+   */
+  static async getRevision(id: number, version: number): Promise<TemplateRevision> {
+    return HttpClient.get<TemplateRevision>(`/template/${id}/revisions/${version}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 逐字段对比两个修订版本
+   * @param id - 模板ID
+   * @param from - 起始版本号
+   * @param to - 目标版本号，不传时与当前版本对比
+   * @returns Promise<TemplateRevisionDiff> 对比结果
+   *
+   * @example
+   * ```typescript
+   * const diff = await TemplateService.diffRevisions(1, 1, 3);
+   * diff.fields.filter(f => f.changed).forEach(f => console.log(f.label, f.lines));
+   * ```
+   */
+  static async diffRevisions(id: number, from: number, to?: number): Promise<TemplateRevisionDiff> {
+    return HttpClient.get<TemplateRevisionDiff>(`/template/${id}/diff`, { from, to }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 恢复指定修订版本，恢复本身会生成新的修订版本
+   * @param id - 模板ID
+   * @param version - 要恢复的版本号
+   * @param changeNote - 修改说明（可选）
+   * @returns Promise<Template> 恢复后的模板
+   */
+  static async restoreRevision(id: number, version: number, changeNote?: string): Promise<Template> {
+    return HttpClient.post<Template>(
+      `/template/${id}/revisions/${version}/restore`,
+      { change_note: changeNote },
+      {
+        requireAuth: true,
+        showLoading: true,
+        showError: true,
+      }
+    );
+  }
+
+  /**
+   * 由服务端按指定格式导出单个模板
+   * @param id - 模板ID
+   * @param format - 导出格式，默认markdown
+   * @returns Promise<Blob> 导出文件
+   */
+  static async exportFile(id: number, format: TemplateExportFormat = 'markdown'): Promise<Blob> {
+    return fetchFile(`template/${id}/export?format=${format}`);
+  }
+
+  /**
+   * 将全部模板导出为zip压缩包，每个模板一个文件
+   * @param format - 导出格式，默认markdown
+   * @returns Promise<Blob> zip文件
+   */
+  static async exportAll(format: TemplateExportFormat = 'markdown'): Promise<Blob> {
+    return fetchFile(`template/export?format=${format}`);
+  }
+
+  /**
+   * 导入Markdown/JSON/YAML文件或zip压缩包
+   * @param files - 待导入的文件
+   * @param preview - 为true时只解析不保存
+   * @returns Promise<TemplateImportResult> 各文件的解析与导入结果
+   *
+   * @example
+   * ```typescript
+   * const parsed = await TemplateService.importFiles(files, true);
+   * if (parsed.imported > 0) {
+   *   await TemplateService.importFiles(files);
+   * }
+   * ```
+   */
+  static async importFiles(files: File[], preview = false): Promise<TemplateImportResult> {
+    const formData = new FormData();
+    files.forEach(file => formData.append('files', file));
+
+    const response = await fetch(getApiUrl(`template/import${preview ? '?preview=true' : ''}`), {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${getToken()}`,
+      },
+      body: formData,
+    });
+    const result = await response.json().catch(() => ({}));
+    if (!response.ok || result.code !== 0) {
+      throw new Error(result.message || `导入失败: ${response.status}`);
+    }
+    return result.data as TemplateImportResult;
+  }
+
   /**
    * 导出模板为Markdown格式
    * @param template - 模板数据
@@ -101,21 +101,6 @@ export interface APIProviderListResponse {
   list: APIProvider[];
 }
 
-/**
- * API类型枚举（已废弃，因api_type字段已移除）
- * @deprecated 该枚举已废弃，保留仅为向后兼容
- */
-export enum APIType {
-  /** OpenAI官方API */
-  OPENAI = 'openai',
-  /** DeepSeek */
-  DEEPSEEK = 'deepseek',
-  /** Ollama本地部署 */
-  OLLAMA = 'ollama',
-  /** 自定义（兼容OpenAI格式） */
-  CUSTOM = 'custom',
-}
-
 /**
  * API Provider状态枚举
  */
@@ -137,56 +122,42 @@ export enum APIProviderOpenType {
 }
 
 /**
- * API类型配置信息（已废弃，因api_type字段已移除）
- * @deprecated 该接口已废弃，保留仅为向后兼容
+ * 模型类型目录项（由后端 GET /api-provider/kinds 提供）
  */
-export interface APITypeConfig {
-  /** 类型标识 */
-  type: APIType;
+export interface APIKindInfo {
+  /** 模型类型，对应 api_kind */
+  kind: string;
   /** 显示名称 */
   label: string;
-  /** 默认URL */
-  defaultURL: string;
+  /** 默认API地址 */
+  default_url: string;
   /** 常用模型列表 */
   models: string[];
+  /** 认证方式：bearer、api-key、x-api-key、query-key、zhipu-jwt、qianfan-token、none */
+  auth_scheme: string;
+  /** API Key填写提示 */
+  key_hint?: string;
   /** 描述信息 */
   description: string;
+  /** 流式响应是否返回token用量 */
+  stream_usage: boolean;
+  /** 是否支持工具调用 */
+  tools: boolean;
+  /** 是否支持图片输入 */
+  vision: boolean;
+  /** 常用向量模型，为空表示不支持向量化 */
+  embedding_models?: string[];
 }
 
 /**
- * 支持的API类型配置（已废弃，因api_type字段已移除）
- * @deprecated 该配置已废弃，保留仅为向后兼容
+ * 模型类型目录响应
  */
-export const API_TYPE_CONFIGS: Record<APIType, APITypeConfig> = {
-  [APIType.OPENAI]: {
-    type: APIType.OPENAI,
-    label: 'OpenAI',
-    defaultURL: 'https://api.openai.com',
-    models: ['gpt-4', 'gpt-4-turbo', 'gpt-3.5-turbo'],
-    description: 'OpenAI官方API，支持GPT系列模型',
-  },
-  [APIType.DEEPSEEK]: {
-    type: APIType.DEEPSEEK,
-    label: 'DeepSeek',
-    defaultURL: 'https://api.deepseek.com',
-    models: ['deepseek-chat', 'deepseek-coder'],
-    description: '国产大模型，性价比高',
-  },
-  [APIType.OLLAMA]: {
-    type: APIType.OLLAMA,
-    label: 'Ollama',
-    defaultURL: 'http://localhost:11434',
-    models: ['llama2', 'mistral', 'codellama'],
-    description: '本地部署的大模型服务',
-  },
-  [APIType.CUSTOM]: {
-    type: APIType.CUSTOM,
-    label: '自定义',
-    defaultURL: '',
-    models: [],
-    description: '兼容OpenAI格式的自定义API',
-  },
-};
+export interface APIKindListResponse {
+  /** 总数 */
+  total: number;
+  /** 模型类型列表 */
+  list: APIKindInfo[];
+}
 
 /**
  * API Provider服务类
@@ -223,6 +194,27 @@ export class APIProviderService {
     });
   }
 
+  /**
+   * 获取支持的模型类型目录
+   * @returns Promise<APIKindInfo[]> 模型类型列表（含默认地址、常用模型、认证方式）
+   * @throws {ApiError} 查询失败抛出错误
+   *
+   * @example
+   * ```typescript
+   * const kinds = await APIProviderService.listKinds();
+   * const qwen = kinds.find(k => k.kind === '阿里千问');
+   * console.log('默认URL:', qwen?.default_url);
+   * ```
+   */
+  static async listKinds(): Promise<APIKindInfo[]> {
+    const result = await HttpClient.get<APIKindListResponse>('/api-provider/kinds', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+    return result.list;
+  }
+
   /**
    * 获取API Provider列表
    * @param params - 查询参数
@@ -0,0 +1,172 @@
+/**
+ * 批量生成服务
+ * @description 上传CSV/JSONL主题文件，批量生成六要素并保存为模板
+ */
+
+import HttpClient from './auth';
+import { getApiUrl, PageParams, PageResponse } from './common';
+
+/**
+ * 批量生成状态
+ */
+export type BatchStatus = 'pending' | 'running' | 'completed' | 'failed';
+
+/**
+ * 报告格式
+ */
+export type BatchReportFormat = 'csv' | 'jsonl';
+
+/**
+ * 创建批量生成参数
+ */
+export interface BatchRequest {
+  /** CSV或JSONL文件 */
+  file: File;
+  /** 生成使用的API Provider ID */
+  provider_id: number;
+  /** 文件格式，默认按扩展名判断 */
+  format?: BatchReportFormat;
+  /** 并发生成的行数 */
+  concurrency?: number;
+  /** 温度参数 */
+  temperature?: number;
+  /** 单个要素的最大token数 */
+  max_tokens?: number;
+}
+
+/**
+ * 单行的生成结果
+ */
+export interface BatchRow {
+  id: number;
+  batch_id: number;
+  /** 在上传文件中的行号 */
+  row_no: number;
+  topic: string;
+  status: 'pending' | 'completed' | 'failed';
+  /** 生成成功后保存的模板ID */
+  template_id?: number;
+  error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 批量生成记录
+ */
+export interface Batch {
+  id: number;
+  mobile: string;
+  provider_id: number;
+  file_name: string;
+  format: BatchReportFormat;
+  status: BatchStatus;
+  concurrency: number;
+  total: number;
+  succeeded: number;
+  failed: number;
+  error?: string;
+  finished_at?: string;
+  created_at: string;
+  updated_at: string;
+  /** 各行结果，列表接口不返回 */
+  rows?: BatchRow[];
+}
+
+/**
+ * 获取认证Token
+ */
+const getToken = (): string => {
+  const token = localStorage.getItem('auth_token');
+  if (!token) {
+    throw new Error('未登录，请先登录');
+  }
+  return token;
+};
+
+/**
+ * 批量生成服务类
+ */
+export class BatchService {
+  /**
+   * 上传主题文件并开始批量生成
+   * @param data - 文件及生成参数
+   * @returns Promise<Batch> 批量生成记录，生成在后台进行
+   *
+   * @example
+   * ```typescript
+   * const batch = await BatchService.create({ file, provider_id: 1, concurrency: 5 });
+   * const detail = await BatchService.getById(batch.id);
+   * console.log(detail.succeeded, detail.failed, detail.total);
+   * ```
+   */
+  static async create(data: BatchRequest): Promise<Batch> {
+    const formData = new FormData();
+    Object.entries(data).forEach(([key, value]) => {
+      if (value !== undefined && value !== null) {
+        formData.append(key, value instanceof File ? value : String(value));
+      }
+    });
+
+    const response = await fetch(getApiUrl('batch'), {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${getToken()}`,
+      },
+      body: formData,
+    });
+
+    const result = await response.json().catch(() => ({}));
+    if (!response.ok || result.code !== 0) {
+      throw new Error(result.message || `批量生成失败: ${response.status}`);
+    }
+    return result.data as Batch;
+  }
+
+  /**
+   * 分页查询批量生成记录
+   * @param params - 分页参数
+   * @returns Promise<PageResponse<Batch>> 批量生成记录（不含各行结果）
+   */
+  static async list(params?: PageParams): Promise<PageResponse<Batch>> {
+    return HttpClient.get<PageResponse<Batch>>('/batch', params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取批量生成进度及各行结果
+   * @param id - 批量生成ID
+   * @returns Promise<Batch> 批量生成记录及各行结果
+   */
+  static async getById(id: number): Promise<Batch> {
+    return HttpClient.get<Batch>(`/batch/${id}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+  }
+
+  /**
+   * 下载结果报告
+   * @param id - 批量生成ID
+   * @param format - 报告格式，默认csv
+   * @returns Promise<Blob> 报告文件
+   */
+  static async downloadReport(id: number, format: BatchReportFormat = 'csv'): Promise<Blob> {
+    const response = await fetch(getApiUrl(`batch/${id}/report?format=${format}`), {
+      headers: {
+        'Authorization': `Bearer ${getToken()}`,
+      },
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.message || `下载报告失败: ${response.status}`);
+    }
+    return response.blob();
+  }
+}
+
+export default BatchService;
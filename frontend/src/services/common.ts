@@ -99,6 +99,8 @@ export enum ErrorCode {
     TEMPLATE_NOT_FOUND = 2001,
     /** 无权操作该模板 */
     TEMPLATE_NO_AUTH = 2002,
+    /** 输入未通过安全检查 */
+    INPUT_REJECTED = 3001,
 }
 
 /**
@@ -122,6 +124,7 @@ export const ERROR_MESSAGES: Record<ErrorCode, string> = {
     [ErrorCode.TOKEN_EXPIRED]: 'Token 已过期',
     [ErrorCode.TEMPLATE_NOT_FOUND]: '模板不存在',
     [ErrorCode.TEMPLATE_NO_AUTH]: '无权操作该模板',
+    [ErrorCode.INPUT_REJECTED]: '输入未通过安全检查',
 };
 
 /**
@@ -0,0 +1,105 @@
+/**
+ * 向量检索设置服务
+ * @description 选择用于模板向量化的API Provider与向量模型，回填已有模板的向量
+ */
+
+import HttpClient from './auth';
+
+/**
+ * 回填状态
+ */
+export type EmbeddingBackfillStatus = 'idle' | 'running' | 'completed' | 'failed';
+
+/**
+ * 保存向量设置参数
+ */
+export interface EmbeddingSettingData {
+  /** 用于向量化的API Provider */
+  provider_id: number;
+  /** 向量模型，为空时使用该模型类型的第一个向量模型 */
+  model?: string;
+}
+
+/**
+ * 向量设置
+ */
+export interface EmbeddingSetting {
+  id: number;
+  provider_id: number;
+  model: string;
+  status: EmbeddingBackfillStatus;
+  /** 回填失败原因 */
+  error?: string;
+  /** 最近一次回填完成时间 */
+  backfill_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 向量设置及回填进度
+ */
+export interface EmbeddingStatus extends EmbeddingSetting {
+  /** 模板总数 */
+  template_count: number;
+  /** 已按当前模型向量化的模板数 */
+  embedded_count: number;
+}
+
+/**
+ * 向量检索设置服务类
+ */
+export class EmbeddingService {
+  /**
+   * 获取向量设置及回填进度，未配置时返回404
+   */
+  static async get(): Promise<EmbeddingStatus> {
+    return HttpClient.get<EmbeddingStatus>('/embedding/settings', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+  }
+
+  /**
+   * 保存向量设置，更换模型时会删除旧模型生成的向量
+   * @param data - Provider与向量模型
+   *
+   * @example
+   * ```typescript
+   * await EmbeddingService.save({ provider_id: 3, model: 'text-embedding-3-small' });
+   * await EmbeddingService.backfill();
+   * ```
+   */
+  static async save(data: EmbeddingSettingData): Promise<EmbeddingSetting> {
+    return HttpClient.put<EmbeddingSetting>('/embedding/settings', data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 关闭向量化并删除已生成的向量
+   */
+  static async remove(): Promise<void> {
+    return HttpClient.delete<void>('/embedding/settings', {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 在后台为已有模板生成向量，进度通过 get() 查询
+   */
+  static async backfill(): Promise<EmbeddingSetting> {
+    return HttpClient.post<EmbeddingSetting>('/embedding/backfill', undefined, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+}
+
+export default EmbeddingService;
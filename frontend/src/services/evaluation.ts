@@ -0,0 +1,148 @@
+/**
+ * 提示词评测服务
+ * @description 同一提示词在多个API Provider上对比生成结果，可选由评审模型打分
+ */
+
+import HttpClient from './auth';
+import { PageParams, PageResponse } from './common';
+
+/**
+ * 创建评测请求参数（template_id 与 prompt 至少指定一个）
+ */
+export interface EvaluationRequest {
+  /** 评测的模板ID，prompt为空时由模板六要素组装提示词 */
+  template_id?: number;
+  /** 评测的提示词 */
+  prompt?: string;
+  /** 系统消息 */
+  system?: string;
+  /** 参与对比的API Provider ID，最多8个 */
+  provider_ids: number[];
+  /** 评审模型Provider ID，为空时不打分 */
+  judge_provider_id?: number;
+  /** 评分标准，默认取模板的交付格式 */
+  criteria?: string;
+  /** 温度参数 */
+  temperature?: number;
+  /** 最大token数 */
+  max_tokens?: number;
+}
+
+/**
+ * 单个API Provider的评测结果
+ */
+export interface EvaluationResult {
+  id: number;
+  evaluation_id: number;
+  provider_id: number;
+  provider_name: string;
+  model: string;
+  /** 生成内容 */
+  content: string;
+  /** 耗时（毫秒） */
+  latency_ms: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  /** 生成失败时的错误信息 */
+  error?: string;
+  /** 评审打分（0-10），未评审或评审失败时为空 */
+  score?: number;
+  /** 评分理由或评审失败原因 */
+  score_reason?: string;
+  created_at: string;
+}
+
+/**
+ * 评测记录
+ */
+export interface Evaluation {
+  id: number;
+  mobile: string;
+  template_id?: number;
+  prompt: string;
+  system?: string;
+  /** 评分标准 */
+  criteria?: string;
+  judge_provider_id?: number;
+  created_at: string;
+  /** 各Provider的结果，列表接口不返回 */
+  results?: EvaluationResult[];
+}
+
+/**
+ * 评测查询参数
+ */
+export interface EvaluationQueryParams extends PageParams {
+  /** 按模板过滤 */
+  template_id?: number;
+}
+
+/**
+ * 评测服务类
+ */
+export class EvaluationService {
+  /**
+   * 创建评测，所有Provider返回后才响应
+   * @param data - 评测参数
+   * @returns Promise<Evaluation> 评测记录及各Provider的结果
+   *
+   * @example
+   * ```typescript
+   * const evaluation = await EvaluationService.create({
+   *   template_id: 1,
+   *   provider_ids: [1, 2],
+   *   judge_provider_id: 3,
+   * });
+   * evaluation.results?.forEach(r => console.log(r.provider_name, r.latency_ms, r.score));
+   * ```
+   */
+  static async create(data: EvaluationRequest): Promise<Evaluation> {
+    return HttpClient.post<Evaluation>('/evaluation', data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+      timeout: 180000,
+    });
+  }
+
+  /**
+   * 分页查询评测记录
+   * @param params - 查询参数
+   * @returns Promise<PageResponse<Evaluation>> 评测记录（不含结果详情）
+   */
+  static async list(params?: EvaluationQueryParams): Promise<PageResponse<Evaluation>> {
+    return HttpClient.get<PageResponse<Evaluation>>('/evaluation', params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取评测详情
+   * @param id - 评测ID
+   * @returns Promise<Evaluation> 评测记录及各Provider的结果
+   */
+  static async getById(id: number): Promise<Evaluation> {
+    return HttpClient.get<Evaluation>(`/evaluation/${id}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 删除评测记录
+   * @param id - 评测ID
+   */
+  static async delete(id: number): Promise<void> {
+    return HttpClient.delete<void>(`/evaluation/${id}`, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+}
+
+export default EvaluationService;
@@ -0,0 +1,87 @@
+/**
+ * 模板广场服务
+ * @description 浏览所有用户公开的六要素模板（无需登录），复制请使用 TemplateService.fork
+ */
+
+import HttpClient from './auth';
+import type { TemplateVariable } from './api';
+import { PageParams, PageResponse } from './common';
+
+/**
+ * 排序方式：popular 按被复制次数，latest 按更新时间
+ */
+export type GallerySort = 'popular' | 'latest';
+
+/**
+ * 模板广场查询参数
+ */
+export interface GalleryQueryParams extends PageParams {
+  /** 关键词，模糊匹配主题、任务目标、AI的角色 */
+  keyword?: string;
+  /** 排序方式，默认popular */
+  sort?: GallerySort;
+}
+
+/**
+ * 公开模板
+ */
+export interface GalleryTemplate {
+  id: number;
+  /** 作者（脱敏手机号） */
+  author: string;
+  topic: string;
+  task_objective: string;
+  ai_role: string;
+  my_role: string;
+  key_information: string;
+  behavior_rule: string;
+  delivery_format: string;
+  /** 变量定义 */
+  variables?: TemplateVariable[];
+  /** 被复制次数 */
+  fork_count: number;
+  /** 复制来源模板 */
+  source_template_id?: number;
+  /** 原作者（脱敏手机号） */
+  source_author?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 模板广场服务类
+ */
+export class GalleryService {
+  /**
+   * 分页查询公开模板
+   * @param params - 关键词、排序与分页参数
+   * @returns Promise<PageResponse<GalleryTemplate>> 公开模板列表
+   *
+   * @example
+   * ```typescript
+   * const result = await GalleryService.list({ keyword: '写作', sort: 'popular' });
+   * ```
+   */
+  static async list(params?: GalleryQueryParams): Promise<PageResponse<GalleryTemplate>> {
+    return HttpClient.get<PageResponse<GalleryTemplate>>('/gallery', params, {
+      requireAuth: false,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取公开模板详情
+   * @param id - 模板ID
+   * @returns Promise<GalleryTemplate> 公开模板
+   */
+  static async getById(id: number): Promise<GalleryTemplate> {
+    return HttpClient.get<GalleryTemplate>(`/gallery/${id}`, undefined, {
+      requireAuth: false,
+      showLoading: false,
+      showError: true,
+    });
+  }
+}
+
+export default GalleryService;
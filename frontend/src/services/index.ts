@@ -19,21 +19,67 @@ export type {
 } from './user';
 
 // 导出API Provider服务
-export { API_TYPE_CONFIGS, APIProviderOpenType, default as APIProviderService, APIProviderStatus, APIType } from './api_provider';
+export { APIProviderOpenType, default as APIProviderService, APIProviderStatus } from './api_provider';
 export type {
-    APIProvider,
-    APIProviderData, APIProviderListResponse, APIProviderQueryParams, APIProviderUpdateData, APITypeConfig
+    APIKindInfo, APIKindListResponse, APIProvider,
+    APIProviderData, APIProviderListResponse, APIProviderQueryParams, APIProviderUpdateData
 } from './api_provider';
 
 // 导出模板服务
 export { default as TemplateService } from './api';
 export type {
-    Template, TemplateData, TemplateQueryParams
+    SimilarTemplate, Template, TemplateData, TemplateDiffLine, TemplateElementField, TemplateExportFormat, TemplateFieldDiff, TemplateFolder, TemplateImportFileResult, TemplateImportItem, TemplateImportResult, TemplateLintFinding, TemplateLintOptions, TemplateLintResult, TemplateLintSeverity, TemplateOrganizeData, TemplateQueryParams, TemplateRefineRequest, TemplateRenderResult, TemplateRevision, TemplateRevisionDiff, TemplateSearchMatch, TemplateSharing, TemplateSnippet, TemplateTag, TemplateVariable, TemplateVariableType, TemplateVisibility
 } from './api';
 
+// 导出向量检索设置服务
+export { default as EmbeddingService } from './embedding';
+export type { EmbeddingBackfillStatus, EmbeddingSetting, EmbeddingSettingData, EmbeddingStatus } from './embedding';
+
+// 导出模板广场服务
+export { default as GalleryService } from './gallery';
+export type { GallerySort, GalleryQueryParams, GalleryTemplate } from './gallery';
+
+// 导出回收站服务
+export { default as TrashService } from './trash';
+export type { TrashItem, TrashItemType, TrashQueryParams } from './trash';
+
 // 导出AI生成服务
 export { AIService } from './ai_service';
-export type { AIGenerateRequest, AIGenerateResponse } from './ai_service';
+export type {
+    AIGenerateRequest, AIGenerateResponse, BackendGenerateRequest, GenerateImageRef, InputRejection, TokenEstimate, UploadedImage
+} from './ai_service';
+
+// 导出评测服务
+export { default as EvaluationService } from './evaluation';
+
+// 导出异步任务服务
+export { default as JobService } from './job';
+export type { Job, JobQueryParams, JobRequest, JobStatus } from './job';
+
+// 导出批量生成服务
+export { default as BatchService } from './batch';
+export type { Batch, BatchReportFormat, BatchRequest, BatchRow, BatchStatus } from './batch';
+
+// 导出Webhook服务
+export { default as WebhookService } from './webhook';
+
+// 导出用量与费用服务
+export { default as UsageService } from './usage';
+export type {
+    ModelPrice, ModelPriceData, SpendGroup, SpendReport, SpendReportParams, SpendReportRow
+} from './usage';
+
+// 导出输出过滤服务
+export { default as OutputFilterService } from './output_filter';
+export type {
+    OutputFilterData, OutputFilterName, OutputFilterPreview, OutputFilterSetting, OutputFilterSource
+} from './output_filter';
+export type {
+    Webhook, WebhookData, WebhookDelivery, WebhookDeliveryQueryParams, WebhookDeliveryStatus, WebhookEvent
+} from './webhook';
+export type {
+    Evaluation, EvaluationQueryParams, EvaluationRequest, EvaluationResult
+} from './evaluation';
 
 // 向后兼容的导出
 export {
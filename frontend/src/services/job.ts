@@ -0,0 +1,170 @@
+/**
+ * 异步生成任务服务
+ * @description 生成请求入队后台执行，可轮询任务状态或通过SSE获取生成内容
+ */
+
+import { BackendGenerateRequest } from './ai_service';
+import HttpClient from './auth';
+import { getApiUrl, PageParams, PageResponse } from './common';
+
+/**
+ * 任务状态
+ */
+export type JobStatus = 'pending' | 'running' | 'completed' | 'failed' | 'canceled';
+
+/**
+ * 提交任务请求参数，同后端统一生成接口（stream 参数无效）
+ */
+export type JobRequest = Omit<BackendGenerateRequest, 'stream'>;
+
+/**
+ * 异步生成任务
+ */
+export interface Job {
+  id: number;
+  mobile: string;
+  provider_id: number;
+  status: JobStatus;
+  /** 已生成的字符数 */
+  progress: number;
+  /** 生成内容，列表接口不返回 */
+  content?: string;
+  /** 工具调用，列表接口不返回 */
+  tool_calls?: unknown[];
+  finish_reason?: string;
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  /** 失败或取消原因 */
+  error?: string;
+  started_at?: string;
+  finished_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 任务查询参数
+ */
+export interface JobQueryParams extends PageParams {
+  /** 按状态过滤 */
+  status?: JobStatus;
+}
+
+/**
+ * 任务服务类
+ */
+export class JobService {
+  /**
+   * 提交异步生成任务，立即返回
+   * @param data - 生成请求参数
+   * @returns Promise<Job> 排队中的任务
+   *
+   * @example
+   * ```typescript
+   * const job = await JobService.submit({ provider_id: 1, prompt: '写一篇产品介绍' });
+   * const content = await JobService.stream(job.id, chunk => console.log(chunk));
+   * ```
+   */
+  static async submit(data: JobRequest): Promise<Job> {
+    return HttpClient.post<Job>('/jobs', data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 分页查询任务
+   * @param params - 查询参数
+   * @returns Promise<PageResponse<Job>> 任务列表（不含生成内容）
+   */
+  static async list(params?: JobQueryParams): Promise<PageResponse<Job>> {
+    return HttpClient.get<PageResponse<Job>>('/jobs', params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取任务状态、进度与结果
+   * @param id - 任务ID
+   * @returns Promise<Job> 任务详情
+   */
+  static async getById(id: number): Promise<Job> {
+    return HttpClient.get<Job>(`/jobs/${id}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+  }
+
+  /**
+   * 取消排队中或执行中的任务
+   * @param id - 任务ID
+   */
+  static async cancel(id: number): Promise<void> {
+    return HttpClient.post<void>(`/jobs/${id}/cancel`, undefined, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取任务的生成内容：先返回已生成的内容，再实时返回后续内容，任务结束后返回完整内容
+   * @param id - 任务ID
+   * @param onStream - 内容片段回调
+   * @returns Promise<string> 完整生成内容，任务失败或取消时抛出错误
+   */
+  static async stream(id: number, onStream: (chunk: string) => void): Promise<string> {
+    const token = localStorage.getItem('auth_token');
+    if (!token) {
+      throw new Error('未登录，请先登录');
+    }
+
+    const response = await fetch(getApiUrl(`jobs/${id}/stream`), {
+      headers: {
+        'Authorization': `Bearer ${token}`,
+      },
+    });
+    if (!response.ok || !response.body) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.message || `获取任务内容失败: ${response.status}`);
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder('utf-8');
+    let buffer = '';
+    let fullContent = '';
+
+    while (true) {
+      const { done, value } = await reader.read();
+      if (done) break;
+
+      buffer += decoder.decode(value, { stream: true });
+      const lines = buffer.split('\n');
+      buffer = lines.pop() || '';
+
+      for (const line of lines) {
+        if (!line.startsWith('data: ')) continue;
+
+        const json = JSON.parse(line.slice(6));
+        if (json.error) {
+          throw new Error(json.error);
+        }
+        if (json.done) {
+          return fullContent;
+        }
+        if (json.content) {
+          fullContent += json.content;
+          onStream(json.content);
+        }
+      }
+    }
+    return fullContent;
+  }
+}
+
+export default JobService;
@@ -0,0 +1,140 @@
+/**
+ * 输出过滤服务
+ * @description 按用户或模板设置生成内容的敏感词替换、个人信息脱敏、Markdown规范化与超长截断
+ */
+
+import HttpClient from './auth';
+
+/**
+ * 输出过滤器
+ */
+export type OutputFilterName = 'sensitive_word' | 'pii_mask' | 'markdown' | 'truncate';
+
+/**
+ * 设置来源：系统默认 / 用户设置 / 模板设置
+ */
+export type OutputFilterSource = 'default' | 'user' | 'template';
+
+/**
+ * 保存输出过滤设置参数
+ */
+export interface OutputFilterData {
+  /** 启用的过滤器，空数组表示全部关闭 */
+  filters: OutputFilterName[];
+  /** 截断长度（字符数），0表示使用系统默认 */
+  max_length?: number;
+}
+
+/**
+ * 生效的输出过滤设置
+ */
+export interface OutputFilterSetting {
+  template_id?: number;
+  filters: OutputFilterName[];
+  max_length: number;
+  source: OutputFilterSource;
+}
+
+/**
+ * 过滤效果预览结果
+ */
+export interface OutputFilterPreview {
+  text: string;
+  setting: OutputFilterSetting;
+}
+
+/**
+ * 输出过滤服务类
+ */
+export class OutputFilterService {
+  /**
+   * 获取用户生效的输出过滤设置
+   */
+  static async get(): Promise<OutputFilterSetting> {
+    return HttpClient.get<OutputFilterSetting>('/output-filters', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 保存用户的输出过滤设置
+   * @param data - 启用的过滤器及截断长度
+   *
+   * @example
+   * ```typescript
+   * await OutputFilterService.save({ filters: ['sensitive_word', 'pii_mask'] });
+   * ```
+   */
+  static async save(data: OutputFilterData): Promise<OutputFilterSetting> {
+    return HttpClient.put<OutputFilterSetting>('/output-filters', data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取支持的输出过滤器
+   */
+  static async listFilters(): Promise<OutputFilterName[]> {
+    return HttpClient.get<OutputFilterName[]>('/output-filters/filters', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+  }
+
+  /**
+   * 获取模板生效的输出过滤设置
+   * @param templateId - 模板ID
+   */
+  static async getForTemplate(templateId: number): Promise<OutputFilterSetting> {
+    return HttpClient.get<OutputFilterSetting>(`/output-filters/templates/${templateId}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 单独设置模板的输出过滤
+   * @param templateId - 模板ID
+   * @param data - 启用的过滤器及截断长度
+   */
+  static async saveForTemplate(templateId: number, data: OutputFilterData): Promise<OutputFilterSetting> {
+    return HttpClient.put<OutputFilterSetting>(`/output-filters/templates/${templateId}`, data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 删除模板的输出过滤设置，恢复使用用户设置
+   * @param templateId - 模板ID
+   */
+  static async resetTemplate(templateId: number): Promise<void> {
+    return HttpClient.delete<void>(`/output-filters/templates/${templateId}`, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 按当前设置过滤文本，预览过滤效果
+   * @param text - 待过滤的文本
+   * @param templateId - 可选，按模板的设置过滤
+   */
+  static async preview(text: string, templateId?: number): Promise<OutputFilterPreview> {
+    return HttpClient.post<OutputFilterPreview>('/output-filters/preview', { text, template_id: templateId }, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+}
+
+export default OutputFilterService;
@@ -0,0 +1,107 @@
+/**
+ * 回收站服务
+ * @description 查询、恢复、彻底删除已删除的模板与API Provider，超过保留期的项目由后台自动彻底删除
+ */
+
+import HttpClient from './auth';
+import type { Template } from './api';
+import type { APIProvider } from './api_provider';
+import { PageParams, PageResponse } from './common';
+
+/**
+ * 回收站项目类型
+ */
+export type TrashItemType = 'template' | 'api_provider';
+
+/**
+ * 回收站查询参数
+ */
+export interface TrashQueryParams extends PageParams {
+  /** 项目类型，为空时同时返回模板与API Provider */
+  type?: TrashItemType;
+}
+
+/**
+ * 回收站中的项目
+ */
+export interface TrashItem {
+  type: TrashItemType;
+  id: number;
+  /** 模板主题或Provider名称 */
+  name: string;
+  /** 移入回收站时间 */
+  deleted_at: string;
+  /** 自动彻底删除时间，未开启自动清理时为空 */
+  purge_at?: string;
+}
+
+/**
+ * 各类型项目的接口路径
+ */
+const TRASH_PATHS: Record<TrashItemType, string> = {
+  template: 'templates',
+  api_provider: 'api-providers',
+};
+
+/**
+ * 回收站服务类
+ */
+export class TrashService {
+  /**
+   * 分页查询回收站，按删除时间倒序
+   * @param params - 类型与分页参数
+   * @returns Promise<PageResponse<TrashItem>> 回收站项目列表
+   *
+   * @example
+   * ```typescript
+   * const result = await TrashService.list({ type: 'template', page: 1 });
+   * ```
+   */
+  static async list(params?: TrashQueryParams): Promise<PageResponse<TrashItem>> {
+    return HttpClient.get<PageResponse<TrashItem>>('/trash', params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 恢复模板
+   * @param id - 模板ID
+   * @returns Promise<Template> 恢复后的模板
+   */
+  static async restoreTemplate(id: number): Promise<Template> {
+    return HttpClient.post<Template>(`/trash/${TRASH_PATHS.template}/${id}/restore`, undefined, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 恢复API Provider
+   * @param id - Provider ID
+   * @returns Promise<APIProvider> 恢复后的Provider
+   */
+  static async restoreAPIProvider(id: number): Promise<APIProvider> {
+    return HttpClient.post<APIProvider>(`/trash/${TRASH_PATHS.api_provider}/${id}/restore`, undefined, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 彻底删除回收站中的项目，不可恢复
+   * @param item - 回收站项目
+   */
+  static async purge(item: Pick<TrashItem, 'type' | 'id'>): Promise<void> {
+    return HttpClient.delete<void>(`/trash/${TRASH_PATHS[item.type]}/${item.id}`, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+}
+
+export default TrashService;
@@ -0,0 +1,165 @@
+/**
+ * 用量与费用服务
+ * @description 设置API Provider的模型价格，查询或导出按日期、Provider、模型、模板汇总的费用报告
+ */
+
+import HttpClient from './auth';
+import { getApiUrl } from './common';
+
+/**
+ * 模型价格（每1K token）
+ */
+export interface ModelPriceData {
+  /** 模型名称前缀，按最长前缀匹配；为空表示该Provider的默认价格 */
+  model: string;
+  /** 输入价格 */
+  input_price: number;
+  /** 输出价格 */
+  output_price: number;
+}
+
+/**
+ * 已保存的模型价格
+ */
+export interface ModelPrice extends ModelPriceData {
+  id: number;
+  provider_id: number;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 费用报告的分组维度
+ */
+export type SpendGroup = 'day' | 'provider' | 'model' | 'template';
+
+/**
+ * 费用报告查询参数
+ */
+export interface SpendReportParams {
+  /** 开始日期（含），YYYY-MM-DD，默认结束日期前29天 */
+  start_date?: string;
+  /** 结束日期（含），默认今天 */
+  end_date?: string;
+  /** 分组维度，默认全部 */
+  group_by?: SpendGroup[];
+}
+
+/**
+ * 费用报告中的一行，未参与分组的维度为空
+ */
+export interface SpendReportRow {
+  day?: string;
+  provider_id?: number;
+  provider_name?: string;
+  model?: string;
+  template_id?: number;
+  template_topic?: string;
+  generations: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  cost: number;
+}
+
+/**
+ * 费用报告
+ */
+export interface SpendReport {
+  start_date: string;
+  end_date: string;
+  group_by: SpendGroup[];
+  rows: SpendReportRow[];
+  total: SpendReportRow;
+}
+
+/**
+ * 获取认证Token
+ */
+const getToken = (): string => {
+  const token = localStorage.getItem('auth_token');
+  if (!token) {
+    throw new Error('未登录，请先登录');
+  }
+  return token;
+};
+
+/**
+ * 转换查询参数
+ */
+const toQuery = (params?: SpendReportParams): Record<string, string> => {
+  const query: Record<string, string> = {};
+  if (params?.start_date) query.start_date = params.start_date;
+  if (params?.end_date) query.end_date = params.end_date;
+  if (params?.group_by?.length) query.group_by = params.group_by.join(',');
+  return query;
+};
+
+/**
+ * 用量与费用服务类
+ */
+export class UsageService {
+  /**
+   * 获取API Provider的价格表
+   * @param providerId - API Provider ID
+   */
+  static async getPrices(providerId: number): Promise<ModelPrice[]> {
+    return HttpClient.get<ModelPrice[]>(`/api-provider/${providerId}/prices`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 保存API Provider的价格表（整表替换）
+   * @param providerId - API Provider ID
+   * @param prices - 价格条目
+   */
+  static async savePrices(providerId: number, prices: ModelPriceData[]): Promise<ModelPrice[]> {
+    return HttpClient.put<ModelPrice[]>(`/api-provider/${providerId}/prices`, { prices }, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 查询费用报告
+   * @param params - 日期范围与分组维度
+   *
+   * @example
+   * ```typescript
+   * const report = await UsageService.getReport({ group_by: ['day', 'model'] });
+   * console.log(report.total.cost);
+   * ```
+   */
+  static async getReport(params?: SpendReportParams): Promise<SpendReport> {
+    return HttpClient.get<SpendReport>('/usage/report', toQuery(params), {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 导出费用报告为CSV
+   * @param params - 日期范围与分组维度
+   * @returns Promise<Blob> CSV文件
+   */
+  static async exportReport(params?: SpendReportParams): Promise<Blob> {
+    const query = new URLSearchParams({ ...toQuery(params), format: 'csv' });
+    const response = await fetch(getApiUrl(`usage/report?${query.toString()}`), {
+      headers: {
+        'Authorization': `Bearer ${getToken()}`,
+      },
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.message || `导出费用报告失败: ${response.status}`);
+    }
+    return response.blob();
+  }
+}
+
+export default UsageService;
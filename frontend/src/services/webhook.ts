@@ -0,0 +1,214 @@
+/**
+ * Webhook服务
+ * @description 注册回调地址，在生成完成/失败、模板创建/更新时接收签名事件
+ */
+
+import HttpClient from './auth';
+import { PageParams, PageResponse } from './common';
+
+/**
+ * 支持订阅的事件类型
+ */
+export type WebhookEvent =
+  | 'generation.completed'
+  | 'generation.failed'
+  | 'template.created'
+  | 'template.updated';
+
+/**
+ * 投递状态
+ */
+export type WebhookDeliveryStatus = 'pending' | 'succeeded' | 'failed';
+
+/**
+ * 创建或更新Webhook参数
+ */
+export interface WebhookData {
+  /** 回调地址 */
+  url?: string;
+  /** 签名密钥，创建时为空则自动生成，更新时为空表示不修改 */
+  secret?: string;
+  /** 订阅的事件 */
+  events?: WebhookEvent[];
+  /** 状态：1启用 0停用（仅更新） */
+  status?: number;
+  /** 描述 */
+  description?: string;
+}
+
+/**
+ * Webhook
+ */
+export interface Webhook {
+  id: number;
+  mobile: string;
+  url: string;
+  /** 签名密钥明文，仅创建或更新密钥时返回 */
+  secret?: string;
+  events: WebhookEvent[];
+  status: number;
+  description?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 投递记录
+ */
+export interface WebhookDelivery {
+  id: number;
+  webhook_id: number;
+  /** 事件ID，重新投递时不变 */
+  event_id: string;
+  event: WebhookEvent;
+  /** 投递内容，列表接口不返回 */
+  payload?: string;
+  status: WebhookDeliveryStatus;
+  /** 已投递次数 */
+  attempts: number;
+  response_status?: number;
+  /** 响应内容，列表接口不返回 */
+  response_body?: string;
+  error?: string;
+  duration_ms: number;
+  next_retry_at?: string;
+  delivered_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * 投递记录查询参数
+ */
+export interface WebhookDeliveryQueryParams extends PageParams {
+  /** 按状态过滤 */
+  status?: WebhookDeliveryStatus;
+}
+
+/**
+ * Webhook服务类
+ */
+export class WebhookService {
+  /**
+   * 注册Webhook
+   * @param data - Webhook参数，url与events必填
+   * @returns Promise<Webhook> 包含签名密钥明文（仅此一次）
+   *
+   * @example
+   * ```typescript
+   * const webhook = await WebhookService.create({
+   *   url: 'https://example.com/cese/webhook',
+   *   events: ['generation.completed', 'generation.failed'],
+   * });
+   * console.log('请保存签名密钥:', webhook.secret);
+   * ```
+   */
+  static async create(data: WebhookData): Promise<Webhook> {
+    return HttpClient.post<Webhook>('/webhooks', data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取Webhook列表
+   */
+  static async list(): Promise<Webhook[]> {
+    return HttpClient.get<Webhook[]>('/webhooks', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取支持订阅的事件类型
+   */
+  static async listEvents(): Promise<WebhookEvent[]> {
+    return HttpClient.get<WebhookEvent[]>('/webhooks/events', undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: false,
+    });
+  }
+
+  /**
+   * 获取Webhook详情
+   * @param id - Webhook ID
+   */
+  static async getById(id: number): Promise<Webhook> {
+    return HttpClient.get<Webhook>(`/webhooks/${id}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 更新Webhook，未指定的字段不修改
+   * @param id - Webhook ID
+   * @param data - 更新参数
+   */
+  static async update(id: number, data: WebhookData): Promise<Webhook> {
+    return HttpClient.put<Webhook>(`/webhooks/${id}`, data, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 删除Webhook及其投递记录
+   * @param id - Webhook ID
+   */
+  static async delete(id: number): Promise<void> {
+    return HttpClient.delete<void>(`/webhooks/${id}`, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+
+  /**
+   * 分页查询投递记录
+   * @param id - Webhook ID
+   * @param params - 查询参数
+   */
+  static async listDeliveries(id: number, params?: WebhookDeliveryQueryParams): Promise<PageResponse<WebhookDelivery>> {
+    return HttpClient.get<PageResponse<WebhookDelivery>>(`/webhooks/${id}/deliveries`, params, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 获取投递记录详情（含投递内容与响应内容）
+   * @param id - Webhook ID
+   * @param deliveryId - 投递记录ID
+   */
+  static async getDelivery(id: number, deliveryId: number): Promise<WebhookDelivery> {
+    return HttpClient.get<WebhookDelivery>(`/webhooks/${id}/deliveries/${deliveryId}`, undefined, {
+      requireAuth: true,
+      showLoading: false,
+      showError: true,
+    });
+  }
+
+  /**
+   * 重新投递
+   * @param id - Webhook ID
+   * @param deliveryId - 投递记录ID
+   * @returns Promise<WebhookDelivery> 新的投递记录
+   */
+  static async redeliver(id: number, deliveryId: number): Promise<WebhookDelivery> {
+    return HttpClient.post<WebhookDelivery>(`/webhooks/${id}/deliveries/${deliveryId}/redeliver`, undefined, {
+      requireAuth: true,
+      showLoading: true,
+      showError: true,
+    });
+  }
+}
+
+export default WebhookService;